@@ -2,100 +2,189 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"philcali.me/recipes/internal/auth"
 	"philcali.me/recipes/internal/data"
 	"philcali.me/recipes/internal/dynamodb/apitokens"
+	"philcali.me/recipes/internal/dynamodb/revocations"
 	"philcali.me/recipes/internal/dynamodb/token"
+	"philcali.me/recipes/internal/exceptions"
 )
 
 type AuthThunk func(ctx context.Context, apiToken string) (*events.APIGatewayV2CustomAuthorizerSimpleResponse, error)
 
-func JWTAuthThunk(ctx context.Context, apiToken string) (*events.APIGatewayV2CustomAuthorizerSimpleResponse, error) {
-	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/oauth2/userInfo", os.Getenv("AUTH_POOL_URL")), nil)
+type App struct {
+	Signer    *auth.Signer
+	Verifier  *auth.JWKSVerifier
+	DenyList  *auth.DenyList
+	Usage     *auth.UsageRecorder
+	ApiTokens data.ApiTokenDataService
+}
+
+// Clients are built once so warm invocations reuse them and the deny-list cache
+func NewApp() App {
+	tableName := os.Getenv("TABLE_NAME")
+	cfg, err := config.LoadDefaultConfig(context.TODO())
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
+		panic("Failed to load AWS config.")
+	}
+	client := dynamodb.NewFromConfig(cfg)
+	marshaler := token.NewGCM()
+	apiTokens := apitokens.NewApiTokenService(tableName, *client, marshaler)
+	return App{
+		Signer:    auth.NewSignerFromEnv(),
+		Verifier:  auth.NewJWKSVerifierFromEnv(),
+		DenyList:  auth.NewDenyList(revocations.NewRevokedTokenService(tableName, *client, marshaler)),
+		Usage:     auth.NewUsageRecorder(apiTokens),
+		ApiTokens: apiTokens,
+	}
+}
+
+func _bearerToken(apiToken string) (string, error) {
+	bearerToken := strings.Split(apiToken, " ")
+	if len(bearerToken) != 2 {
+		return "", fmt.Errorf("token provided is invalid: %s", apiToken)
+	}
+	return bearerToken[1], nil
+}
+
+// Usage is best effort and never blocks an authorization
+func (app *App) recordUsage(tokenId string) {
+	if err := app.Usage.Record(tokenId); err != nil {
+		fmt.Printf("Failed to record usage for %s: %v\n", tokenId, err)
 	}
-	req.Header.Add("Authorization", apiToken)
-	client := &http.Client{}
-	resp, err := client.Do(req)
+}
+
+func (app *App) SignedTokenAuth(ctx context.Context, apiToken string) (*events.APIGatewayV2CustomAuthorizerSimpleResponse, error) {
+	if app.Signer == nil {
+		return nil, fmt.Errorf("signed tokens are not enabled")
+	}
+	bearerToken, err := _bearerToken(apiToken)
 	if err != nil {
-		return nil, fmt.Errorf("failed to invoke request: %v", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("invalid %s with token: %s", req.URL.String(), apiToken)
+	claims, err := app.Signer.Verify(bearerToken, time.Now())
+	if err == auth.ErrMalformedToken {
+		return nil, err
 	}
-	body, err := io.ReadAll(resp.Body)
+	// A token we signed that fails verification is never valid elsewhere
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %v", err)
+		fmt.Printf("Denying signed token due to %v\n", err)
+		return &events.APIGatewayV2CustomAuthorizerSimpleResponse{IsAuthorized: false}, nil
 	}
-	var claims map[string]string
-	if err := json.Unmarshal(body, &claims); err != nil {
-		return nil, fmt.Errorf("failed to parse claims: %v", err)
+	revoked, err := app.DenyList.IsRevoked(*claims)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		fmt.Printf("Denying revoked token %s\n", claims.Id)
+		return &events.APIGatewayV2CustomAuthorizerSimpleResponse{IsAuthorized: false}, nil
+	}
+	// OAuth access tokens are tracked by their grant, not an API token
+	if claims.ClientId == "" {
+		app.recordUsage(claims.Id)
 	}
-	// We assume that a JWT auth is local user admin
 	return &events.APIGatewayV2CustomAuthorizerSimpleResponse{
 		IsAuthorized: true,
 		Context: map[string]interface{}{
-			"jwt": claims,
-			"scopes": []string{
-				string(data.RECIPE_WRITE),
-				string(data.LIST_WRITE),
-				string(data.SUBSCRIPTIONS_WRITE),
-				string(data.TOKENS_WRITE),
-				string(data.SETTINGS_WRITE),
-				string(data.AUDIT_WRITE),
-				string(data.SHARE_WRITE),
-				string(data.PROVIDER_WRITE),
-			},
+			"jwt":     claims.Claims,
+			"scopes":  claims.Scopes,
+			"tokenId": claims.Id,
 		},
 	}, nil
 }
 
-func ApiTokenAuth(ctx context.Context, apiToken string) (*events.APIGatewayV2CustomAuthorizerSimpleResponse, error) {
-	tableName := os.Getenv("TABLE_NAME")
-	cfg, err := config.LoadDefaultConfig(ctx)
+// User pool tokens are verified locally, scopes come from groups and token scopes
+func (app *App) JWTAuth(ctx context.Context, apiToken string) (*events.APIGatewayV2CustomAuthorizerSimpleResponse, error) {
+	if app.Verifier == nil {
+		return nil, fmt.Errorf("user pool tokens are not enabled")
+	}
+	bearerToken, err := _bearerToken(apiToken)
 	if err != nil {
 		return nil, err
 	}
-	client := dynamodb.NewFromConfig(cfg)
-	marshaler := token.NewGCM()
-	apiTokens := apitokens.NewApiTokenService(tableName, *client, marshaler)
-	bearerToken := strings.Split(apiToken, " ")
-	if len(bearerToken) != 2 {
-		return nil, fmt.Errorf("token provided is invalid: %s", apiToken)
+	claims, err := app.Verifier.Verify(bearerToken)
+	if err == auth.ErrMalformedToken {
+		return nil, err
 	}
-	tokenDTO, err := apiTokens.Get("Global", bearerToken[1])
+	if err != nil {
+		fmt.Printf("Denying user pool token due to %v\n", err)
+		return &events.APIGatewayV2CustomAuthorizerSimpleResponse{IsAuthorized: false}, nil
+	}
+	return &events.APIGatewayV2CustomAuthorizerSimpleResponse{
+		IsAuthorized: true,
+		Context: map[string]interface{}{
+			"jwt":    claims.Claims(),
+			"scopes": claims.Scopes(),
+		},
+	}, nil
+}
+
+// Tokens are "{tokenId}.{secret}", anything else is a secret issued before hashing
+func (app *App) lookupToken(bearerToken string) (data.ApiTokenDTO, bool, error) {
+	if tokenId, secret, ok := auth.ParseToken(bearerToken); ok {
+		tokenDTO, err := app.ApiTokens.Get("Global", tokenId)
+		return tokenDTO, err == nil && auth.VerifyTokenSecret(tokenDTO, secret, time.Now()), err
+	}
+	secretHash := auth.HashSecret(bearerToken)
+	tokenDTO, err := app.ApiTokens.Get("Global", secretHash)
+	if _, ok := err.(*exceptions.NotFoundError); ok {
+		legacy, err := app.ApiTokens.Get("Global", bearerToken)
+		if err != nil || legacy.SecretHash != "" {
+			return legacy, false, err
+		}
+		tokenDTO, err = auth.MigrateLegacyToken(app.ApiTokens, legacy)
+		return tokenDTO, err == nil, err
+	}
+	return tokenDTO, err == nil && auth.VerifyTokenSecret(tokenDTO, bearerToken, time.Now()), err
+}
+
+func (app *App) ApiTokenAuth(ctx context.Context, apiToken string) (*events.APIGatewayV2CustomAuthorizerSimpleResponse, error) {
+	bearerToken, err := _bearerToken(apiToken)
+	if err != nil {
+		return nil, err
+	}
+	tokenDTO, valid, err := app.lookupToken(bearerToken)
 	if err != nil {
 		return nil, err
 	}
+	if !valid {
+		fmt.Printf("Denying invalid secret for token %s\n", tokenDTO.SK)
+		return &events.APIGatewayV2CustomAuthorizerSimpleResponse{IsAuthorized: false}, nil
+	}
+	// Table TTL removes expired tokens eventually, but not on time
+	if auth.IsExpired(tokenDTO.ExpiresIn, time.Now()) {
+		fmt.Printf("Denying expired token %s\n", tokenDTO.SK)
+		return &events.APIGatewayV2CustomAuthorizerSimpleResponse{IsAuthorized: false}, nil
+	}
+	app.recordUsage(tokenDTO.SK)
 	return &events.APIGatewayV2CustomAuthorizerSimpleResponse{
 		IsAuthorized: true,
 		Context: map[string]interface{}{
-			"jwt":    tokenDTO.Claims,
-			"scopes": tokenDTO.Scopes,
+			"jwt":     tokenDTO.Claims,
+			"scopes":  tokenDTO.Scopes,
+			"tokenId": tokenDTO.SK,
 		},
 	}, nil
 }
 
-func HandleRequest(ctx context.Context, event events.APIGatewayV2CustomAuthorizerV2Request) (events.APIGatewayV2CustomAuthorizerSimpleResponse, error) {
+func (app *App) HandleRequest(ctx context.Context, event events.APIGatewayV2CustomAuthorizerV2Request) (events.APIGatewayV2CustomAuthorizerSimpleResponse, error) {
 	response := events.APIGatewayV2CustomAuthorizerSimpleResponse{
 		IsAuthorized: false,
 	}
 	apiToken, ok := event.Headers["authorization"]
 	thunks := []AuthThunk{
-		JWTAuthThunk,
-		ApiTokenAuth,
+		app.SignedTokenAuth,
+		app.JWTAuth,
+		app.ApiTokenAuth,
 	}
 	if ok {
 		for _, authThunk := range thunks {
@@ -113,5 +202,6 @@ func HandleRequest(ctx context.Context, event events.APIGatewayV2CustomAuthorize
 }
 
 func main() {
-	lambda.Start(HandleRequest)
+	app := NewApp()
+	lambda.Start(app.HandleRequest)
 }
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	oauthData "philcali.me/recipes/internal/dynamodb/oauth"
+	"philcali.me/recipes/internal/dynamodb/token"
+	"philcali.me/recipes/internal/routes"
+	"philcali.me/recipes/internal/routes/filters"
+	"philcali.me/recipes/internal/routes/oauth"
+)
+
+type App struct {
+	Router routes.Router
+}
+
+// The token endpoint sits outside the authorizer, clients authenticate themselves
+func NewApp() App {
+	tableName := os.Getenv("TABLE_NAME")
+	cfg, err := config.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		panic("Failed to load AWS config.")
+	}
+	client := dynamodb.NewFromConfig(cfg)
+	marshaler := token.NewGCM()
+	router := routes.NewRouterWithFilters(
+		[]filters.RequestFilter{filters.DefaultCorsFilter()},
+		oauth.NewTokenRoute(
+			oauthData.NewClientService(tableName, *client, marshaler),
+			oauthData.NewConsentService(tableName, *client, marshaler),
+			oauthData.NewAuthorizationCodeService(tableName, *client, marshaler),
+			oauthData.NewRefreshTokenService(tableName, *client, marshaler),
+		),
+	)
+	return App{
+		Router: *router,
+	}
+}
+
+func (app *App) HandleRequest(ctx context.Context, request events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+	return app.Router.Invoke(request, ctx), nil
+}
+
+func main() {
+	app := NewApp()
+	lambda.Start(app.HandleRequest)
+}
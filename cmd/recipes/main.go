@@ -9,23 +9,25 @@ import (
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"philcali.me/recipes/internal/auth"
 	tokenData "philcali.me/recipes/internal/dynamodb/apitokens"
 	auditData "philcali.me/recipes/internal/dynamodb/audits"
+	deliveryData "philcali.me/recipes/internal/dynamodb/deliveries"
+	limitData "philcali.me/recipes/internal/dynamodb/limits"
+	membershipData "philcali.me/recipes/internal/dynamodb/memberships"
+	oauthData "philcali.me/recipes/internal/dynamodb/oauth"
 	recipeData "philcali.me/recipes/internal/dynamodb/recipes"
+	revocationData "philcali.me/recipes/internal/dynamodb/revocations"
 	settingsData "philcali.me/recipes/internal/dynamodb/settings"
 	shareData "philcali.me/recipes/internal/dynamodb/shares"
 	shoppingData "philcali.me/recipes/internal/dynamodb/shopping"
 	subscriberData "philcali.me/recipes/internal/dynamodb/subscriptions"
+	templateData "philcali.me/recipes/internal/dynamodb/templates"
 	"philcali.me/recipes/internal/dynamodb/token"
+	"philcali.me/recipes/internal/limits"
 	"philcali.me/recipes/internal/routes"
-	"philcali.me/recipes/internal/routes/apitokens"
-	"philcali.me/recipes/internal/routes/audits"
-	"philcali.me/recipes/internal/routes/external"
-	"philcali.me/recipes/internal/routes/recipes"
-	"philcali.me/recipes/internal/routes/settings"
-	"philcali.me/recipes/internal/routes/shares"
-	"philcali.me/recipes/internal/routes/shopping"
-	"philcali.me/recipes/internal/routes/subscriptions"
+	"philcali.me/recipes/internal/routes/api"
+	"philcali.me/recipes/internal/routes/filters"
 	"philcali.me/recipes/internal/sns/services"
 )
 
@@ -43,22 +45,54 @@ func NewApp() App {
 	client := dynamodb.NewFromConfig(cfg)
 	snsClient := sns.NewFromConfig(cfg)
 	marshaler := token.NewGCM()
-	router := routes.NewRouter(
-		external.NewExternalService(),
-		recipes.NewRoute(recipeData.NewRecipeService(tableName, *client, marshaler)),
-		shopping.NewRoute(shoppingData.NewShoppingListService(tableName, *client, marshaler)),
-		apitokens.NewRoute(tokenData.NewApiTokenService(tableName, *client, marshaler)),
-		audits.NewRoute(auditData.NewAuditService(tableName, *client, marshaler)),
-		settings.NewRoute(settingsData.NewSettingService(tableName, *client, marshaler)),
-		shares.NewRoute(shareData.NewShareService(tableName, *client, marshaler)),
-		subscriptions.NewRoute(
-			subscriberData.NewSubscriptionService(tableName, *client, marshaler),
-			&services.NotificationSNSService{
+	revocations := revocationData.NewRevokedTokenService(tableName, *client, marshaler)
+	memberships := membershipData.NewMembershipService(tableName, *client, marshaler)
+	settingsRepo := settingsData.NewSettingService(tableName, *client, marshaler)
+	limit, err := limits.NewLimitFromEnv()
+	if err != nil {
+		panic(err)
+	}
+	quotaConfig, err := limits.QuotasFromEnv()
+	if err != nil {
+		panic(err)
+	}
+	quotas := limits.NewQuotas(limitData.NewQuotaService(tableName, *client), quotaConfig)
+	recipeRepo := limits.NewQuotaRepository(recipeData.NewRecipeService(tableName, *client, marshaler), quotas, "recipes")
+	listRepo := limits.NewQuotaRepository(shoppingData.NewShoppingListService(tableName, *client, marshaler), quotas, "lists")
+	router := routes.NewRouterWithFilters(
+		[]filters.RequestFilter{
+			filters.DefaultCorsFilter(),
+			filters.NewRateLimitFilter(limitData.NewRateLimitService(tableName, *client), limit),
+			filters.DefaultAuthorizationFilter(),
+			filters.NewHouseholdFilter(memberships),
+		},
+		api.Services(api.Data{
+			Recipes:            recipeRepo,
+			Lists:              listRepo,
+			Templates:          templateData.NewShoppingListTemplateService(tableName, *client, marshaler),
+			Settings:           settingsRepo,
+			ApiTokens:          tokenData.NewApiTokenService(tableName, *client, marshaler),
+			Revocations:        revocations,
+			OAuthClients:       oauthData.NewClientService(tableName, *client, marshaler),
+			OAuthConsents:      oauthData.NewConsentService(tableName, *client, marshaler),
+			AuthorizationCodes: oauthData.NewAuthorizationCodeService(tableName, *client, marshaler),
+			Audits:             auditData.NewAuditService(tableName, *client, marshaler),
+			Shares:             shareData.NewShareService(tableName, *client, marshaler),
+			Memberships:        memberships,
+			Subscriptions:      subscriberData.NewSubscriptionService(tableName, *client, marshaler),
+			Deliveries:         deliveryData.NewDeliveryService(tableName, *client, marshaler),
+			Notifications: &services.NotificationSNSService{
 				Sns:      *snsClient,
 				TopicArn: topicArn,
 			},
-		),
+			Signer:    auth.NewSignerFromEnv(),
+			IndexName: os.Getenv("INDEX_NAME_1"),
+		})...,
 	)
+	router.Register(routes.NewOpenAPIService(router, routes.OpenAPIInfo{
+		Title:   "Recipes API",
+		Version: "1.0.0",
+	}))
 	return App{
 		Router: *router,
 	}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/deadletters"
+	"philcali.me/recipes/internal/events"
+)
+
+func _letters(store deadletters.Store, id string, handlerName string) ([]data.DeadLetterDTO, error) {
+	if id != "" {
+		letter, err := store.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		return []data.DeadLetterDTO{letter}, nil
+	}
+	// Letters are read before any are replayed, so replays do not move pages
+	var letters []data.DeadLetterDTO
+	params := data.QueryParams{Limit: 100}
+	for {
+		page, err := store.List(params)
+		if err != nil {
+			return nil, err
+		}
+		for _, letter := range page.Items {
+			if handlerName == "" || letter.HandlerName == handlerName {
+				letters = append(letters, letter)
+			}
+		}
+		if page.NextToken == nil {
+			return letters, nil
+		}
+		params.NextToken = page.NextToken
+	}
+}
+
+func main() {
+	list := flag.Bool("list", false, "list the dead letters rather than replay them")
+	id := flag.String("id", "", "only the dead letter with this id")
+	handlerName := flag.String("handler", "", "only the dead letters of this handler")
+	file := flag.String("file", "", "the dead letters in this file, rather than the table")
+	flag.Parse()
+
+	ctx := context.Background()
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		panic(err)
+	}
+	router, err := events.DefaultRouter(cfg, os.Getenv("TABLE_NAME"))
+	if err != nil {
+		panic(err)
+	}
+	if *file != "" {
+		router.DeadLetters = deadletters.NewFileStore(*file)
+	}
+	letters, err := _letters(router.DeadLetters, *id, *handlerName)
+	if err != nil {
+		panic(err)
+	}
+	if *list {
+		for _, letter := range letters {
+			fmt.Printf("%s\t%s\t%s\t%d\t%s\n", letter.SK, letter.HandlerName, letter.UpdateTime.Format(time.RFC3339), letter.Attempts, letter.Error)
+		}
+		return
+	}
+	var failures []error
+	replayed := 0
+	for _, letter := range letters {
+		// Letters below the last attempt are still retried by the stream
+		if *id == "" && letter.Attempts < router.MaxAttempts {
+			fmt.Printf("Skipping %s, still retried after %d attempts\n", letter.SK, letter.Attempts)
+			continue
+		}
+		if err := router.Replay(letter); err != nil {
+			failures = append(failures, err)
+			continue
+		}
+		replayed++
+		fmt.Printf("Replayed %s through %s\n", letter.SK, letter.HandlerName)
+	}
+	fmt.Printf("Replayed %d of %d dead letters\n", replayed, len(letters))
+	if len(failures) > 0 {
+		panic(errors.Join(failures...))
+	}
+}
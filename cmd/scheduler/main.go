@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+	// Lambda runtimes may not have the time zone database recurrences need
+	_ "time/tzdata"
+
+	lambdaEvents "github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"philcali.me/recipes/internal/dynamodb/apitokens"
+	"philcali.me/recipes/internal/dynamodb/deliveries"
+	limitData "philcali.me/recipes/internal/dynamodb/limits"
+	migrationData "philcali.me/recipes/internal/dynamodb/migrations"
+	"philcali.me/recipes/internal/dynamodb/shares"
+	"philcali.me/recipes/internal/dynamodb/shopping"
+	"philcali.me/recipes/internal/dynamodb/subscriptions"
+	templateData "philcali.me/recipes/internal/dynamodb/templates"
+	"philcali.me/recipes/internal/dynamodb/token"
+	"philcali.me/recipes/internal/events"
+	"philcali.me/recipes/internal/jobs"
+	"philcali.me/recipes/internal/limits"
+	"philcali.me/recipes/internal/sns/services"
+	"philcali.me/recipes/internal/templates"
+)
+
+// Selects jobs by name, ie: {"jobs": ["purge-expired"]}, rather than by schedule
+type ScheduleDetail struct {
+	Jobs []string `json:"jobs"`
+}
+
+// The EventBridge schedule invokes the scheduler every five minutes, ie:
+// rate(5 minutes), as often as the webhook retries are due. Each invocation
+// runs the jobs scheduled since the previous one.
+const SCHEDULE_INTERVAL = 5 * time.Minute
+
+func NewRegistry(ctx context.Context) (*jobs.Registry, error) {
+	tableName := os.Getenv("TABLE_NAME")
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	client := dynamodb.NewFromConfig(cfg)
+	marshaler := token.NewGCM()
+	shareData := shares.NewShareService(tableName, *client, marshaler)
+	subscriptionData := subscriptions.NewSubscriptionService(tableName, *client, marshaler)
+	deliveryData := deliveries.NewDeliveryService(tableName, *client, marshaler)
+	quotaCounter := limitData.NewQuotaService(tableName, *client)
+	quotas, err := limits.QuotasFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	publisher := &services.NotificationSNSService{
+		Sns:      *sns.NewFromConfig(cfg),
+		TopicArn: os.Getenv("TOPIC_ARN"),
+	}
+
+	registry := jobs.NewRegistry()
+	for _, entry := range []struct {
+		schedule string
+		job      jobs.Job
+	}{
+		{"0 * * * *", &jobs.InstantiateTemplatesJob{
+			Scheduler: &templates.Scheduler{
+				Instantiator: templates.Instantiator{
+					Templates: templateData.NewShoppingListTemplateService(tableName, *client, marshaler),
+					Lists: limits.NewQuotaRepository(
+						shopping.NewShoppingListService(tableName, *client, marshaler),
+						limits.NewQuotas(quotaCounter, quotas),
+						"lists",
+					),
+				},
+				IndexAccount: templateData.GLOBAL_ACCOUNT,
+				IndexName:    os.Getenv("INDEX_NAME_1"),
+			},
+		}},
+		{"0 * * * *", &jobs.FilterSubscriptionsJob{
+			Notifications: publisher,
+			Migrations:    migrationData.NewMigrationService(tableName, *client),
+			DynamoDB:      client,
+			TableName:     tableName,
+		}},
+		{"*/5 * * * *", &jobs.RetryWebhooksJob{
+			Webhooks:  events.DefaultWebhookHandler(subscriptionData, deliveryData),
+			DynamoDB:  client,
+			TableName: tableName,
+		}},
+		{"0 0 * * *", &jobs.FillListItemIdsJob{
+			Lists:     shopping.NewShoppingListService(tableName, *client, marshaler),
+			DynamoDB:  client,
+			TableName: tableName,
+		}},
+		{"0 1 * * *", &jobs.CountQuotasJob{
+			Counter:   quotaCounter,
+			DynamoDB:  client,
+			TableName: tableName,
+		}},
+		{"0 2 * * *", &jobs.MigrateLegacyTokensJob{
+			ApiTokens: apitokens.NewApiTokenService(tableName, *client, marshaler),
+			DynamoDB:  client,
+			TableName: tableName,
+		}},
+		{"0 3 * * *", &jobs.PurgeExpiredJob{
+			DynamoDB:  client,
+			TableName: tableName,
+		}},
+		{"0 4 * * *", &jobs.ReconcileSharedCopiesJob{
+			Sharing:   shareData,
+			DynamoDB:  client,
+			TableName: tableName,
+		}},
+		{"0 5 * * 0", &jobs.CleanupSubscriptionsJob{
+			Subscriptions: subscriptionData,
+			Notifications: publisher,
+			DynamoDB:      client,
+			TableName:     tableName,
+		}},
+	} {
+		if err := registry.Register(entry.schedule, entry.job); err != nil {
+			return nil, err
+		}
+	}
+	return registry, nil
+}
+
+func HandleRequest(ctx context.Context, event lambdaEvents.EventBridgeEvent) error {
+	registry, err := NewRegistry(ctx)
+	if err != nil {
+		return err
+	}
+	now := event.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+	var detail ScheduleDetail
+	if len(event.Detail) > 0 {
+		if err := json.Unmarshal(event.Detail, &detail); err != nil {
+			return err
+		}
+	}
+	due := registry.Due(now.Add(-SCHEDULE_INTERVAL), now)
+	if len(detail.Jobs) > 0 {
+		if due, err = registry.Named(detail.Jobs...); err != nil {
+			return err
+		}
+	}
+	return jobs.Run(ctx, now, due)
+}
+
+func main() {
+	local := flag.Bool("local", false, "run jobs on their schedules until interrupted, rather than as a Lambda")
+	run := flag.String("run", "", "run the comma separated jobs once and exit")
+	flag.Parse()
+	if !*local && *run == "" {
+		lambda.Start(HandleRequest)
+		return
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	registry, err := NewRegistry(ctx)
+	if err != nil {
+		panic(err)
+	}
+	if *run != "" {
+		named, err := registry.Named(strings.Split(*run, ",")...)
+		if err == nil {
+			err = jobs.Run(ctx, time.Now(), named)
+		}
+		if err != nil {
+			panic(err)
+		}
+		return
+	}
+	registry.RunLocal(ctx)
+}
@@ -0,0 +1,214 @@
+package aisles
+
+import (
+	"slices"
+	"strings"
+)
+
+const (
+	PRODUCE   = "Produce"
+	BAKERY    = "Bakery"
+	MEAT      = "Meat & Seafood"
+	DAIRY     = "Dairy & Eggs"
+	PANTRY    = "Pantry"
+	BAKING    = "Baking & Spices"
+	FROZEN    = "Frozen"
+	BEVERAGES = "Beverages"
+	HOUSEHOLD = "Household"
+	OTHER     = "Other"
+)
+
+// The order of a typical store, from the entrance
+var DEFAULT_LAYOUT = []string{PRODUCE, BAKERY, MEAT, DAIRY, PANTRY, BAKING, FROZEN, BEVERAGES, HOUSEHOLD}
+
+// Ingredients by their singular, lower case names
+var DICTIONARY = map[string]string{
+	"apple":         PRODUCE,
+	"avocado":       PRODUCE,
+	"banana":        PRODUCE,
+	"basil":         PRODUCE,
+	"bean sprout":   PRODUCE,
+	"bell pepper":   PRODUCE,
+	"berry":         PRODUCE,
+	"broccoli":      PRODUCE,
+	"cabbage":       PRODUCE,
+	"carrot":        PRODUCE,
+	"celery":        PRODUCE,
+	"cilantro":      PRODUCE,
+	"cucumber":      PRODUCE,
+	"garlic":        PRODUCE,
+	"ginger":        PRODUCE,
+	"grape":         PRODUCE,
+	"kale":          PRODUCE,
+	"lemon":         PRODUCE,
+	"lettuce":       PRODUCE,
+	"lime":          PRODUCE,
+	"mushroom":      PRODUCE,
+	"onion":         PRODUCE,
+	"orange":        PRODUCE,
+	"parsley":       PRODUCE,
+	"potato":        PRODUCE,
+	"scallion":      PRODUCE,
+	"spinach":       PRODUCE,
+	"tomato":        PRODUCE,
+	"zucchini":      PRODUCE,
+	"bagel":         BAKERY,
+	"baguette":      BAKERY,
+	"bread":         BAKERY,
+	"bun":           BAKERY,
+	"croissant":     BAKERY,
+	"muffin":        BAKERY,
+	"roll":          BAKERY,
+	"tortilla":      BAKERY,
+	"bacon":         MEAT,
+	"beef":          MEAT,
+	"chicken":       MEAT,
+	"fish":          MEAT,
+	"ham":           MEAT,
+	"lamb":          MEAT,
+	"pork":          MEAT,
+	"salmon":        MEAT,
+	"sausage":       MEAT,
+	"shrimp":        MEAT,
+	"steak":         MEAT,
+	"tuna":          MEAT,
+	"turkey":        MEAT,
+	"butter":        DAIRY,
+	"cheese":        DAIRY,
+	"cream":         DAIRY,
+	"egg":           DAIRY,
+	"milk":          DAIRY,
+	"parmesan":      DAIRY,
+	"sour cream":    DAIRY,
+	"yogurt":        DAIRY,
+	"broth":         PANTRY,
+	"cereal":        PANTRY,
+	"chickpea":      PANTRY,
+	"honey":         PANTRY,
+	"ketchup":       PANTRY,
+	"lentil":        PANTRY,
+	"mayonnaise":    PANTRY,
+	"mustard":       PANTRY,
+	"noodle":        PANTRY,
+	"oat":           PANTRY,
+	"oil":           PANTRY,
+	"olive oil":     PANTRY,
+	"pasta":         PANTRY,
+	"peanut butter": PANTRY,
+	"rice":          PANTRY,
+	"soy sauce":     PANTRY,
+	"stock":         PANTRY,
+	"tomato paste":  PANTRY,
+	"tomato sauce":  PANTRY,
+	"vinegar":       PANTRY,
+	"baking powder": BAKING,
+	"baking soda":   BAKING,
+	"cinnamon":      BAKING,
+	"cumin":         BAKING,
+	"flour":         BAKING,
+	"oregano":       BAKING,
+	"paprika":       BAKING,
+	"pepper":        BAKING,
+	"salt":          BAKING,
+	"sugar":         BAKING,
+	"vanilla":       BAKING,
+	"yeast":         BAKING,
+	"ice cream":     FROZEN,
+	"frozen pea":    FROZEN,
+	"pea":           FROZEN,
+	"beer":          BEVERAGES,
+	"coffee":        BEVERAGES,
+	"juice":         BEVERAGES,
+	"soda":          BEVERAGES,
+	"tea":           BEVERAGES,
+	"water":         BEVERAGES,
+	"wine":          BEVERAGES,
+	"aluminum foil": HOUSEHOLD,
+	"detergent":     HOUSEHOLD,
+	"paper towel":   HOUSEHOLD,
+	"soap":          HOUSEHOLD,
+	"sponge":        HOUSEHOLD,
+	"toilet paper":  HOUSEHOLD,
+	"trash bag":     HOUSEHOLD,
+}
+
+func Normalize(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// Tries the name as written, then without a plural ending
+func _singulars(name string) []string {
+	candidates := []string{name}
+	for _, suffix := range []string{"ies", "es", "s"} {
+		if trimmed, ok := strings.CutSuffix(name, suffix); ok && trimmed != "" {
+			if suffix == "ies" {
+				trimmed += "y"
+			}
+			candidates = append(candidates, trimmed)
+		}
+	}
+	return candidates
+}
+
+// Categorizes items by their names, account overrides win over the dictionary
+type Categorizer struct {
+	Overrides map[string]string
+}
+
+func NewCategorizer(overrides map[string]string) *Categorizer {
+	normalized := make(map[string]string, len(overrides))
+	for name, category := range overrides {
+		normalized[Normalize(name)] = category
+	}
+	return &Categorizer{
+		Overrides: normalized,
+	}
+}
+
+func (c *Categorizer) _lookup(name string) (string, bool) {
+	for _, candidate := range _singulars(name) {
+		if category, ok := c.Overrides[candidate]; ok {
+			return category, true
+		}
+		if category, ok := DICTIONARY[candidate]; ok {
+			return category, true
+		}
+	}
+	return "", false
+}
+
+// Matches the whole name first, then its trailing words, so "whole milk" is
+// found as "milk" while "peanut butter" is not found as "butter"
+func (c *Categorizer) Categorize(name string) string {
+	words := strings.Fields(Normalize(name))
+	for start := range words {
+		if category, ok := c._lookup(strings.Join(words[start:], " ")); ok {
+			return category
+		}
+	}
+	return OTHER
+}
+
+// Orders categories by the layout, those outside of it by the default layout
+// and then alphabetically. Other always comes last.
+func Sort(categories []string, layout []string) {
+	rank := func(category string) int {
+		matches := func(l string) bool { return strings.EqualFold(l, category) }
+		if category == OTHER {
+			return len(layout) + len(DEFAULT_LAYOUT) + 1
+		}
+		if index := slices.IndexFunc(layout, matches); index >= 0 {
+			return index
+		}
+		if index := slices.IndexFunc(DEFAULT_LAYOUT, matches); index >= 0 {
+			return len(layout) + index
+		}
+		return len(layout) + len(DEFAULT_LAYOUT)
+	}
+	slices.SortStableFunc(categories, func(a, b string) int {
+		if byRank := rank(a) - rank(b); byRank != 0 {
+			return byRank
+		}
+		return strings.Compare(a, b)
+	})
+}
@@ -0,0 +1,42 @@
+package aisles
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCategorize(t *testing.T) {
+	categorizer := NewCategorizer(map[string]string{
+		"Oat Milk": "Plant Based",
+	})
+	expected := map[string]string{
+		"Whole Milk":      DAIRY,
+		"eggs":            DAIRY,
+		"Cherry Tomatoes": PRODUCE,
+		"berries":         PRODUCE,
+		"bell pepper":     PRODUCE,
+		"black pepper":    BAKING,
+		"peanut butter":   PANTRY,
+		"oat  milk":       "Plant Based",
+		"widgets":         OTHER,
+	}
+	for name, category := range expected {
+		if actual := categorizer.Categorize(name); actual != category {
+			t.Fatalf("Expected %s to be in %s, got %s", name, category, actual)
+		}
+	}
+}
+
+func TestSort(t *testing.T) {
+	categories := []string{OTHER, "Plant Based", DAIRY, PRODUCE, "Deli"}
+	Sort(categories, nil)
+	expected := []string{PRODUCE, DAIRY, "Deli", "Plant Based", OTHER}
+	if !slices.Equal(categories, expected) {
+		t.Fatalf("Expected %v, got %v", expected, categories)
+	}
+	Sort(categories, []string{"plant based", DAIRY})
+	expected = []string{"Plant Based", DAIRY, PRODUCE, "Deli", OTHER}
+	if !slices.Equal(categories, expected) {
+		t.Fatalf("Expected %v, got %v", expected, categories)
+	}
+}
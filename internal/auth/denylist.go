@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/exceptions"
+)
+
+// Revocations take at most this long to reach a warm authorizer
+const DENY_LIST_REFRESH = time.Minute
+
+type DenyList struct {
+	Revocations     data.RevokedTokenDataService
+	RefreshInterval time.Duration
+	Now             func() time.Time
+
+	mutex       sync.Mutex
+	revoked     map[string]data.RevokedTokenDTO
+	refreshTime time.Time
+}
+
+func NewDenyList(revocations data.RevokedTokenDataService) *DenyList {
+	return &DenyList{
+		Revocations:     revocations,
+		RefreshInterval: DENY_LIST_REFRESH,
+		Now:             time.Now,
+	}
+}
+
+func (dl *DenyList) _refresh() error {
+	revoked := make(map[string]data.RevokedTokenDTO)
+	var nextToken *string
+	truncated := true
+	for truncated {
+		items, err := dl.Revocations.List("Global", data.QueryParams{
+			Limit:     100,
+			NextToken: nextToken,
+		})
+		if err != nil {
+			return err
+		}
+		for _, item := range items.Items {
+			revoked[item.SK] = item
+		}
+		nextToken = items.NextToken
+		truncated = nextToken != nil
+	}
+	dl.revoked = revoked
+	dl.refreshTime = dl.Now()
+	return nil
+}
+
+func (dl *DenyList) IsRevoked(claims ApiTokenClaims) (bool, error) {
+	dl.mutex.Lock()
+	defer dl.mutex.Unlock()
+	now := dl.Now()
+	if dl.revoked == nil || now.Sub(dl.refreshTime) >= dl.RefreshInterval {
+		if err := dl._refresh(); err != nil {
+			return false, err
+		}
+	}
+	revoked, ok := dl.revoked[claims.Id]
+	if !ok {
+		return false, nil
+	}
+	// Revocations recorded before rotation carry no generation and deny everything
+	if revoked.Generation > 0 && claims.Generation >= revoked.Generation {
+		return false, nil
+	}
+	return revoked.EffectiveTime == nil || !now.Before(*revoked.EffectiveTime), nil
+}
+
+func _revoke(revocations data.RevokedTokenDataService, tokenId string, input data.RevokedTokenInputDTO) error {
+	_, err := revocations.CreateWithItemId("Global", input, tokenId)
+	if _, ok := err.(*exceptions.ConflictError); ok {
+		_, err = revocations.Update("Global", tokenId, input)
+	}
+	return err
+}
+
+// Denies every generation of the token below generation once effectiveTime passes
+func Revoke(revocations data.RevokedTokenDataService, tokenDTO data.ApiTokenDTO, generation int, effectiveTime time.Time) error {
+	var expiresIn *int
+	if tokenDTO.ExpiresIn != nil {
+		expiresIn = aws.Int(int(time.UnixMilli(int64(*tokenDTO.ExpiresIn)).Unix()))
+	}
+	return _revoke(revocations, tokenDTO.SK, data.RevokedTokenInputDTO{
+		AccountId:     aws.String(tokenDTO.AccountId),
+		Generation:    aws.Int(generation),
+		EffectiveTime: aws.Time(effectiveTime),
+		ExpiresIn:     expiresIn,
+	})
+}
+
+// Denies access tokens issued under a grant, which never outlive lifetime
+func RevokeGrant(revocations data.RevokedTokenDataService, grantId string, accountId string, lifetime time.Duration) error {
+	now := time.Now()
+	return _revoke(revocations, grantId, data.RevokedTokenInputDTO{
+		AccountId:     aws.String(accountId),
+		Generation:    aws.Int(1),
+		EffectiveTime: aws.Time(now),
+		ExpiresIn:     aws.Int(int(now.Add(lifetime).Unix())),
+	})
+}
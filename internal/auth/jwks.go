@@ -0,0 +1,289 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"philcali.me/recipes/internal/data"
+)
+
+const (
+	ISSUER_ENV        = "JWT_ISSUER"
+	AUDIENCES_ENV     = "JWT_AUDIENCES"
+	JWKS_LOCATION_ENV = "JWKS_LOCATION"
+	// Keys rotate rarely, an unknown kid forces a refresh sooner
+	JWKS_REFRESH = 6 * time.Hour
+	// Bounds how often unknown kids can trigger a fetch
+	JWKS_MIN_REFRESH = time.Minute
+	// Fetches hold the verifier lock, so a slow issuer must not stall every request
+	JWKS_FETCH_TIMEOUT = 5 * time.Second
+	// The only group granted every scope, other groups must be named after a scope
+	ADMIN_GROUP = "admin"
+)
+
+var (
+	ErrUnknownKey      = errors.New("token is signed by an unknown key")
+	ErrInvalidIssuer   = errors.New("token issuer is not trusted")
+	ErrInvalidAudience = errors.New("token audience is not trusted")
+	ErrInvalidTokenUse = errors.New("token use is not supported")
+)
+
+// Admins are granted every resource, including ones added later
+var ADMIN_SCOPES = data.SCOPE_RESOURCES
+
+type JSONWebKey struct {
+	KeyId     string `json:"kid"`
+	KeyType   string `json:"kty"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	Modulus   string `json:"n"`
+	Exponent  string `json:"e"`
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// The subset of Cognito ID and access token claims the authorizer relies on
+type UserPoolClaims struct {
+	Subject         string   `json:"sub"`
+	Issuer          string   `json:"iss"`
+	Audience        string   `json:"aud"`
+	ClientId        string   `json:"client_id"`
+	TokenUse        string   `json:"token_use"`
+	Username        string   `json:"username"`
+	CognitoUsername string   `json:"cognito:username"`
+	Email           string   `json:"email"`
+	Groups          []string `json:"cognito:groups"`
+	Scope           string   `json:"scope"`
+	ExpiresAt       int64    `json:"exp"`
+}
+
+// The claims routes expect, matching what userInfo used to return
+func (uc *UserPoolClaims) Claims() map[string]string {
+	claims := map[string]string{
+		"sub":      uc.Subject,
+		"username": uc.Username,
+	}
+	if uc.CognitoUsername != "" {
+		claims["username"] = uc.CognitoUsername
+	}
+	if uc.Email != "" {
+		claims["email"] = uc.Email
+	}
+	return claims
+}
+
+// Groups and token scopes are granted when they name a scope, resource
+// server prefixes on custom scopes are ignored
+func (uc *UserPoolClaims) Scopes() []data.Scope {
+	var scopes []data.Scope
+	grant := func(scope data.Scope) {
+		if scope.Validate() == nil && !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	for _, group := range uc.Groups {
+		if group == ADMIN_GROUP {
+			for _, scope := range ADMIN_SCOPES {
+				grant(scope)
+			}
+			continue
+		}
+		grant(data.Scope(group))
+	}
+	for _, scope := range strings.Fields(uc.Scope) {
+		grant(data.Scope(scope[strings.LastIndex(scope, "/")+1:]))
+	}
+	return scopes
+}
+
+type _keyHeader struct {
+	Algorithm string `json:"alg"`
+	KeyId     string `json:"kid"`
+}
+
+type JWKSVerifier struct {
+	Issuer    string
+	Audiences []string
+	// Either an http(s) URL or a path to a local file
+	Location string
+	Client   *http.Client
+	Now      func() time.Time
+
+	mutex       sync.Mutex
+	keys        map[string]*rsa.PublicKey
+	refreshTime time.Time
+}
+
+func NewJWKSVerifier(issuer string, audiences []string, location string) *JWKSVerifier {
+	return &JWKSVerifier{
+		Issuer:    issuer,
+		Audiences: audiences,
+		Location:  location,
+		Client:    &http.Client{Timeout: JWKS_FETCH_TIMEOUT},
+		Now:       time.Now,
+	}
+}
+
+// Local verification is disabled when the environment holds no issuer
+func NewJWKSVerifierFromEnv() *JWKSVerifier {
+	issuer := os.Getenv(ISSUER_ENV)
+	if issuer == "" {
+		return nil
+	}
+	location := os.Getenv(JWKS_LOCATION_ENV)
+	if location == "" {
+		location = strings.TrimSuffix(issuer, "/") + "/.well-known/jwks.json"
+	}
+	var audiences []string
+	for _, audience := range strings.Split(os.Getenv(AUDIENCES_ENV), ",") {
+		if audience = strings.TrimSpace(audience); audience != "" {
+			audiences = append(audiences, audience)
+		}
+	}
+	return NewJWKSVerifier(issuer, audiences, location)
+}
+
+func (jv *JWKSVerifier) _fetch() ([]byte, error) {
+	if !strings.HasPrefix(jv.Location, "https://") && !strings.HasPrefix(jv.Location, "http://") {
+		return os.ReadFile(jv.Location)
+	}
+	resp, err := jv.Client.Get(jv.Location)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: %d", jv.Location, resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func _publicKey(key JSONWebKey) (*rsa.PublicKey, error) {
+	modulus, err := base64.RawURLEncoding.DecodeString(key.Modulus)
+	if err != nil {
+		return nil, err
+	}
+	exponent, err := base64.RawURLEncoding.DecodeString(key.Exponent)
+	if err != nil {
+		return nil, err
+	}
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(modulus),
+		E: int(new(big.Int).SetBytes(exponent).Int64()),
+	}, nil
+}
+
+func (jv *JWKSVerifier) _refresh() error {
+	content, err := jv._fetch()
+	if err != nil {
+		return err
+	}
+	var keySet JSONWebKeySet
+	if err := json.Unmarshal(content, &keySet); err != nil {
+		return err
+	}
+	keys := make(map[string]*rsa.PublicKey, len(keySet.Keys))
+	for _, key := range keySet.Keys {
+		if key.KeyType != "RSA" || (key.Use != "" && key.Use != "sig") {
+			continue
+		}
+		publicKey, err := _publicKey(key)
+		if err != nil {
+			return err
+		}
+		keys[key.KeyId] = publicKey
+	}
+	jv.keys = keys
+	jv.refreshTime = jv.Now()
+	return nil
+}
+
+func (jv *JWKSVerifier) _key(keyId string) (*rsa.PublicKey, error) {
+	jv.mutex.Lock()
+	defer jv.mutex.Unlock()
+	since := jv.Now().Sub(jv.refreshTime)
+	_, known := jv.keys[keyId]
+	if jv.keys == nil || since >= JWKS_REFRESH || (!known && since >= JWKS_MIN_REFRESH) {
+		if err := jv._refresh(); err != nil {
+			return nil, err
+		}
+	}
+	key, ok := jv.keys[keyId]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	return key, nil
+}
+
+// ID tokens name the app client in aud, access tokens in client_id
+func (jv *JWKSVerifier) _validate(claims *UserPoolClaims) error {
+	if claims.Issuer != jv.Issuer {
+		return ErrInvalidIssuer
+	}
+	if jv.Now().Unix() >= claims.ExpiresAt {
+		return ErrExpiredToken
+	}
+	switch claims.TokenUse {
+	case "id":
+		if !slices.Contains(jv.Audiences, claims.Audience) {
+			return ErrInvalidAudience
+		}
+	case "access":
+		if !slices.Contains(jv.Audiences, claims.ClientId) {
+			return ErrInvalidAudience
+		}
+	default:
+		return ErrInvalidTokenUse
+	}
+	return nil
+}
+
+// Tokens that are not RS256 JWTs are malformed, so other authorizers can try them
+func (jv *JWKSVerifier) Verify(token string) (*UserPoolClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformedToken
+	}
+	var header _keyHeader
+	if err := _decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	if header.Algorithm != "RS256" || header.KeyId == "" {
+		return nil, ErrMalformedToken
+	}
+	key, err := jv._key(header.KeyId)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+	hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], signature); err != nil {
+		return nil, ErrInvalidToken
+	}
+	var claims UserPoolClaims
+	if err := _decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	if err := jv._validate(&claims); err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
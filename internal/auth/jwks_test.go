@@ -0,0 +1,164 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"philcali.me/recipes/internal/data"
+)
+
+const _testIssuer = "https://cognito-idp.us-east-1.amazonaws.com/us-east-1_example"
+
+func _signUserPoolToken(t *testing.T, key *rsa.PrivateKey, keyId string, claims map[string]any) string {
+	header, err := _encodeSegment(_keyHeader{Algorithm: "RS256", KeyId: keyId})
+	if err != nil {
+		t.Fatalf("Failed to encode header: %v", err)
+	}
+	payload, err := _encodeSegment(claims)
+	if err != nil {
+		t.Fatalf("Failed to encode claims: %v", err)
+	}
+	hash := sha256.Sum256([]byte(header + "." + payload))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hash[:])
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return header + "." + payload + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func _writeKeySet(t *testing.T, location string, keys map[string]*rsa.PrivateKey) {
+	var keySet JSONWebKeySet
+	for keyId, key := range keys {
+		keySet.Keys = append(keySet.Keys, JSONWebKey{
+			KeyId:     keyId,
+			KeyType:   "RSA",
+			Algorithm: "RS256",
+			Use:       "sig",
+			Modulus:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			Exponent:  base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	content, err := json.Marshal(keySet)
+	if err != nil {
+		t.Fatalf("Failed to encode key set: %v", err)
+	}
+	if err := os.WriteFile(location, content, 0600); err != nil {
+		t.Fatalf("Failed to write key set: %v", err)
+	}
+}
+
+func TestJWKSVerifier(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	location := filepath.Join(t.TempDir(), "jwks.json")
+	_writeKeySet(t, location, map[string]*rsa.PrivateKey{"key-1": key})
+	now := time.Now()
+	verifier := NewJWKSVerifier(_testIssuer, []string{"app-client"}, location)
+	idClaims := func() map[string]any {
+		return map[string]any{
+			"sub":              "abc-123",
+			"iss":              _testIssuer,
+			"aud":              "app-client",
+			"token_use":        "id",
+			"cognito:username": "nobody",
+			"email":            "nobody@email.com",
+			"cognito:groups":   []string{"recipes", "lists.readonly", "everyone"},
+			"exp":              now.Add(time.Hour).Unix(),
+		}
+	}
+
+	t.Run("IdToken", func(t *testing.T) {
+		claims, err := verifier.Verify(_signUserPoolToken(t, key, "key-1", idClaims()))
+		if err != nil {
+			t.Fatalf("Failed to verify token: %v", err)
+		}
+		if claims.Claims()["username"] != "nobody" || claims.Claims()["email"] != "nobody@email.com" {
+			t.Fatalf("Unexpected claims %v", claims.Claims())
+		}
+		expected := []data.Scope{data.RECIPE_WRITE, data.LIST_READ}
+		if !slices.Equal(claims.Scopes(), expected) {
+			t.Fatalf("Expected scopes %v, got %v", expected, claims.Scopes())
+		}
+	})
+
+	t.Run("AccessToken", func(t *testing.T) {
+		token := _signUserPoolToken(t, key, "key-1", map[string]any{
+			"sub":            "abc-123",
+			"iss":            _testIssuer,
+			"client_id":      "app-client",
+			"token_use":      "access",
+			"username":       "nobody",
+			"cognito:groups": []string{ADMIN_GROUP},
+			"scope":          "openid https://api.example.com/recipes.readonly",
+			"exp":            now.Add(time.Hour).Unix(),
+		})
+		claims, err := verifier.Verify(token)
+		if err != nil {
+			t.Fatalf("Failed to verify token: %v", err)
+		}
+		if claims.Claims()["username"] != "nobody" {
+			t.Fatalf("Unexpected claims %v", claims.Claims())
+		}
+		if len(claims.Scopes()) != len(ADMIN_SCOPES)+1 || !slices.Contains(claims.Scopes(), data.RECIPE_READ) {
+			t.Fatalf("Expected admin and token scopes, got %v", claims.Scopes())
+		}
+	})
+
+	t.Run("Rejected", func(t *testing.T) {
+		for name, override := range map[string]map[string]any{
+			"Issuer":   {"iss": "https://issuer.example.com"},
+			"Audience": {"aud": "other-client"},
+			"Expired":  {"exp": now.Add(-time.Minute).Unix()},
+			"TokenUse": {"token_use": "refresh"},
+		} {
+			claims := idClaims()
+			for k, v := range override {
+				claims[k] = v
+			}
+			if _, err := verifier.Verify(_signUserPoolToken(t, key, "key-1", claims)); err == nil {
+				t.Fatalf("Expected the %s check to reject the token", name)
+			}
+		}
+		other, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatalf("Failed to generate key: %v", err)
+		}
+		if _, err := verifier.Verify(_signUserPoolToken(t, other, "key-1", idClaims())); err != ErrInvalidToken {
+			t.Fatalf("Expected a forged signature to be invalid, got %v", err)
+		}
+		if _, err := verifier.Verify(_signUserPoolToken(t, other, "key-2", idClaims())); err != ErrUnknownKey {
+			t.Fatalf("Expected an unknown key, got %v", err)
+		}
+		if _, err := verifier.Verify("abc.secret"); err != ErrMalformedToken {
+			t.Fatalf("Expected an api token to be malformed, got %v", err)
+		}
+	})
+
+	t.Run("KeyRotation", func(t *testing.T) {
+		rotated, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatalf("Failed to generate key: %v", err)
+		}
+		_writeKeySet(t, location, map[string]*rsa.PrivateKey{"key-1": key, "key-2": rotated})
+		token := _signUserPoolToken(t, rotated, "key-2", idClaims())
+		if _, err := verifier.Verify(token); err != ErrUnknownKey {
+			t.Fatalf("Expected unknown kids to not refetch immediately, got %v", err)
+		}
+		verifier.Now = func() time.Time { return now.Add(2 * JWKS_MIN_REFRESH) }
+		if _, err := verifier.Verify(token); err != nil {
+			t.Fatalf("Expected the rotated key to be fetched, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,23 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+)
+
+// Only S256 is accepted, the plain method offers no protection against interception
+const PKCE_METHOD_S256 = "S256"
+
+func CodeChallenge(verifier string) string {
+	hash := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+// Verifiers are 43 to 128 characters, per RFC 7636
+func VerifyCodeChallenge(method string, challenge string, verifier string) bool {
+	if method != PKCE_METHOD_S256 || len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(challenge), []byte(CodeChallenge(verifier))) == 1
+}
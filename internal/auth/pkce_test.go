@@ -0,0 +1,20 @@
+package auth
+
+import "testing"
+
+func TestVerifyCodeChallenge(t *testing.T) {
+	verifier := "dBjftJeZ4CVP-mJ92K1ZEBF8Q0mtqOyEyhxQzj2kQjk"
+	challenge := "fbrYeoRioIbxPjdanDkT0ojswuClMAtx_c6IrW8EKi0"
+	if CodeChallenge(verifier) != challenge {
+		t.Fatalf("Expected challenge %s, got %s", challenge, CodeChallenge(verifier))
+	}
+	if !VerifyCodeChallenge(PKCE_METHOD_S256, challenge, verifier) {
+		t.Fatal("Expected the verifier to match the challenge")
+	}
+	if VerifyCodeChallenge("plain", verifier, verifier) {
+		t.Fatal("Expected the plain method to be rejected")
+	}
+	if VerifyCodeChallenge(PKCE_METHOD_S256, CodeChallenge("short"), "short") {
+		t.Fatal("Expected a short verifier to be rejected")
+	}
+}
@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/exceptions"
+)
+
+const (
+	TOKEN_ID_BYTES     = 8
+	TOKEN_SECRET_BYTES = 32
+)
+
+func _randomHex(length int) (string, error) {
+	randomBytes := make([]byte, length)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(randomBytes), nil
+}
+
+func GenerateSecret() (string, error) {
+	return _randomHex(TOKEN_SECRET_BYTES)
+}
+
+// The token id is public and addresses the token, only the secret is a credential
+func GenerateToken() (string, string, error) {
+	tokenId, err := _randomHex(TOKEN_ID_BYTES)
+	if err != nil {
+		return "", "", err
+	}
+	secret, err := GenerateSecret()
+	if err != nil {
+		return "", "", err
+	}
+	return tokenId, secret, nil
+}
+
+func HashSecret(secret string) string {
+	hash := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(hash[:])
+}
+
+func VerifySecret(secretHash string, secret string) bool {
+	if secretHash == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(secretHash), []byte(HashSecret(secret))) == 1
+}
+
+// Accepts the current secret, or the rotated one while its grace period lasts
+func VerifyTokenSecret(tokenDTO data.ApiTokenDTO, secret string, now time.Time) bool {
+	if VerifySecret(tokenDTO.SecretHash, secret) {
+		return true
+	}
+	return tokenDTO.PreviousSecretExpiresIn != nil &&
+		!IsExpired(tokenDTO.PreviousSecretExpiresIn, now) &&
+		VerifySecret(tokenDTO.PreviousSecretHash, secret)
+}
+
+func FormatToken(tokenId string, secret string) string {
+	return tokenId + "." + secret
+}
+
+func ParseToken(value string) (string, string, bool) {
+	parts := strings.Split(value, ".")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// Tokens issued before hashing used the plaintext secret as their id. They are
+// moved to the hash of that secret, which is how the authorizer finds them.
+func MigrateLegacyToken(apiTokens data.ApiTokenDataService, legacy data.ApiTokenDTO) (data.ApiTokenDTO, error) {
+	secretHash := HashSecret(legacy.SK)
+	migrated, err := apiTokens.CreateWithItemId("Global", data.ApiTokenInputDTO{
+		Name:       &legacy.Name,
+		Scopes:     &legacy.Scopes,
+		Claims:     &legacy.Claims,
+		AccountId:  &legacy.AccountId,
+		ExpiresIn:  legacy.ExpiresIn,
+		SecretHash: &secretHash,
+	}, secretHash)
+	if err != nil {
+		if _, ok := err.(*exceptions.ConflictError); !ok {
+			return migrated, err
+		}
+		if migrated, err = apiTokens.Get("Global", secretHash); err != nil {
+			return migrated, err
+		}
+	}
+	return migrated, apiTokens.Delete("Global", legacy.SK)
+}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"philcali.me/recipes/internal/data"
+)
+
+func TestSecrets(t *testing.T) {
+	tokenId, secret, err := GenerateToken()
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+	parsedId, parsedSecret, ok := ParseToken(FormatToken(tokenId, secret))
+	if !ok || parsedId != tokenId || parsedSecret != secret {
+		t.Fatalf("Expected %s.%s, got %s.%s", tokenId, secret, parsedId, parsedSecret)
+	}
+	if _, _, ok := ParseToken(secret); ok {
+		t.Fatal("Expected a bare secret to not parse")
+	}
+	if !VerifySecret(HashSecret(secret), secret) {
+		t.Fatal("Expected the secret to verify against its hash")
+	}
+	if VerifySecret(HashSecret(secret), tokenId) || VerifySecret("", "") {
+		t.Fatal("Expected a mismatched secret to fail verification")
+	}
+}
+
+func TestVerifyTokenSecret(t *testing.T) {
+	now := time.Now()
+	tokenDTO := data.ApiTokenDTO{
+		SecretHash:              HashSecret("current"),
+		PreviousSecretHash:      HashSecret("previous"),
+		PreviousSecretExpiresIn: aws.Int(int(now.Add(time.Hour).UnixMilli())),
+	}
+	if !VerifyTokenSecret(tokenDTO, "current", now) || !VerifyTokenSecret(tokenDTO, "previous", now) {
+		t.Fatal("Expected both secrets to verify during the grace period")
+	}
+	if VerifyTokenSecret(tokenDTO, "previous", now.Add(2*time.Hour)) {
+		t.Fatal("Expected the previous secret to fail after the grace period")
+	}
+	if !VerifyTokenSecret(tokenDTO, "current", now.Add(2*time.Hour)) {
+		t.Fatal("Expected the current secret to outlive the grace period")
+	}
+}
+
+func TestMigrateLegacyToken(t *testing.T) {
+	apiTokens := NewApiTokenRepository(t)
+	legacySecret, err := _randomHex(TOKEN_SECRET_BYTES)
+	if err != nil {
+		t.Fatalf("Failed to generate secret: %v", err)
+	}
+	legacy, err := apiTokens.CreateWithItemId("Global", data.ApiTokenInputDTO{
+		Name:      aws.String("Legacy"),
+		Scopes:    &[]data.Scope{data.RECIPE_READ},
+		Claims:    &map[string]string{"username": "nobody"},
+		AccountId: aws.String("nobody"),
+	}, legacySecret)
+	if err != nil {
+		t.Fatalf("Failed to create legacy token: %v", err)
+	}
+	migrated, err := MigrateLegacyToken(apiTokens, legacy)
+	if err != nil {
+		t.Fatalf("Failed to migrate token: %v", err)
+	}
+	if migrated.SK == legacySecret || !VerifySecret(migrated.SecretHash, legacySecret) {
+		t.Fatalf("Expected the migrated token to hold only a hash: %v", migrated)
+	}
+	if migrated.Name != "Legacy" || migrated.AccountId != "nobody" {
+		t.Fatalf("Expected the token details to carry over: %v", migrated)
+	}
+	if _, err := apiTokens.Get("Global", legacySecret); err == nil {
+		t.Fatal("Expected the plaintext token to be removed")
+	}
+}
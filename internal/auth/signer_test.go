@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"philcali.me/recipes/internal/data"
+)
+
+func TestSigner(t *testing.T) {
+	signer := NewSigner([]byte("test-key"))
+	now := time.Now()
+	claims := ApiTokenClaims{
+		Id:        "abc-123",
+		AccountId: "nobody",
+		Scopes:    []data.Scope{data.RECIPE_READ},
+		Claims:    map[string]string{"username": "nobody"},
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(time.Hour).Unix(),
+	}
+	token, err := signer.Sign(claims)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+
+	t.Run("Verify", func(t *testing.T) {
+		verified, err := signer.Verify(token, now)
+		if err != nil {
+			t.Fatalf("Failed to verify token: %v", err)
+		}
+		if verified.Id != "abc-123" || verified.Claims["username"] != "nobody" || verified.Scopes[0] != data.RECIPE_READ {
+			t.Fatalf("Unexpected claims %v", verified)
+		}
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		if _, err := signer.Verify(token, now.Add(2*time.Hour)); err != ErrExpiredToken {
+			t.Fatalf("Expected an expired token, got %v", err)
+		}
+	})
+
+	t.Run("Tampered", func(t *testing.T) {
+		if _, err := NewSigner([]byte("other-key")).Verify(token, now); err != ErrInvalidToken {
+			t.Fatalf("Expected an invalid signature, got %v", err)
+		}
+		parts := strings.Split(token, ".")
+		forged, _ := signer.Sign(ApiTokenClaims{Id: "abc-123", Scopes: []data.Scope{data.TOKENS_WRITE}})
+		parts[1] = strings.Split(forged, ".")[1]
+		if _, err := signer.Verify(strings.Join(parts, "."), now); err != ErrInvalidToken {
+			t.Fatalf("Expected a swapped payload to fail, got %v", err)
+		}
+	})
+
+	t.Run("Malformed", func(t *testing.T) {
+		if _, err := signer.Verify("0123456789abcdef", now); err != ErrMalformedToken {
+			t.Fatalf("Expected a legacy token to be malformed, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"philcali.me/recipes/internal/data"
+)
+
+// A token's usage is written at most once per interval from a warm authorizer
+const USAGE_FLUSH_INTERVAL = 5 * time.Minute
+
+// Uses between flushes are held in memory, so counts are approximate when an
+// authorizer is recycled before it flushes
+type UsageRecorder struct {
+	ApiTokens     data.ApiTokenDataService
+	FlushInterval time.Duration
+	Now           func() time.Time
+
+	mutex   sync.Mutex
+	pending map[string]int
+	flushed map[string]time.Time
+}
+
+func NewUsageRecorder(apiTokens data.ApiTokenDataService) *UsageRecorder {
+	return &UsageRecorder{
+		ApiTokens:     apiTokens,
+		FlushInterval: USAGE_FLUSH_INTERVAL,
+		Now:           time.Now,
+		pending:       make(map[string]int),
+		flushed:       make(map[string]time.Time),
+	}
+}
+
+func (ur *UsageRecorder) Record(tokenId string) error {
+	ur.mutex.Lock()
+	defer ur.mutex.Unlock()
+	now := ur.Now()
+	ur.pending[tokenId]++
+	if lastFlush, ok := ur.flushed[tokenId]; ok && now.Sub(lastFlush) < ur.FlushInterval {
+		return nil
+	}
+	_, err := ur.ApiTokens.Update("Global", tokenId, data.ApiTokenInputDTO{
+		LastUsedTime: aws.Time(now),
+		UsageCount:   aws.Int(ur.pending[tokenId]),
+	})
+	if err != nil {
+		return err
+	}
+	delete(ur.pending, tokenId)
+	ur.flushed[tokenId] = now
+	return nil
+}
+
+func IsExpired(expiresIn *int, now time.Time) bool {
+	return expiresIn != nil && now.UnixMilli() >= int64(*expiresIn)
+}
@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/dynamodb/apitokens"
+	"philcali.me/recipes/internal/dynamodb/token"
+	"philcali.me/recipes/internal/test"
+)
+
+func NewApiTokenRepository(t *testing.T) data.ApiTokenDataService {
+	localServer := test.StartLocalServer(test.LOCAL_DDB_PORT+3, t)
+	client, err := localServer.CreateLocalClient()
+	if err != nil {
+		t.Fatalf("Failed to create DDB client: %s", err)
+	}
+	tableName, err := test.CreateTable(client)
+	if err != nil {
+		t.Fatalf("Failed to create DDB table: %s", err)
+	}
+	return apitokens.NewApiTokenService(tableName, *client, token.NewGCM())
+}
+
+func TestUsageRecorder(t *testing.T) {
+	apiTokens := NewApiTokenRepository(t)
+	created, err := apiTokens.CreateWithItemId("Global", data.ApiTokenInputDTO{
+		Name:      aws.String("Kitchen"),
+		Scopes:    &[]data.Scope{data.LIST_READ},
+		Claims:    &map[string]string{"username": "nobody"},
+		AccountId: aws.String("nobody"),
+	}, "abc-123")
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+
+	now := time.Now()
+	recorder := NewUsageRecorder(apiTokens)
+	recorder.Now = func() time.Time { return now }
+	for i := 0; i < 3; i++ {
+		if err := recorder.Record(created.SK); err != nil {
+			t.Fatalf("Failed to record usage: %v", err)
+		}
+	}
+	stored, _ := apiTokens.Get("Global", created.SK)
+	if stored.UsageCount != 1 || stored.LastUsedTime == nil {
+		t.Fatalf("Expected only the first use to be written, got %v", stored)
+	}
+
+	now = now.Add(USAGE_FLUSH_INTERVAL)
+	if err := recorder.Record(created.SK); err != nil {
+		t.Fatalf("Failed to record usage: %v", err)
+	}
+	stored, _ = apiTokens.Get("Global", created.SK)
+	if stored.UsageCount != 4 || !stored.LastUsedTime.Equal(now) {
+		t.Fatalf("Expected the held uses to be flushed, got %v", stored)
+	}
+}
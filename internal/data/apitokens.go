@@ -21,6 +21,12 @@ const (
 	TOKENS_WRITE        Scope = "tokens"
 	PROVIDER_READ       Scope = "providers.readonly"
 	PROVIDER_WRITE      Scope = "providers"
+	OAUTH_READ          Scope = "oauth.readonly"
+	OAUTH_WRITE         Scope = "oauth"
+	HOUSEHOLDS_READ     Scope = "households.readonly"
+	HOUSEHOLDS_WRITE    Scope = "households"
+	TEMPLATES_READ      Scope = "templates.readonly"
+	TEMPLATES_WRITE     Scope = "templates"
 )
 
 type ApiTokenDTO struct {
@@ -31,17 +37,31 @@ type ApiTokenDTO struct {
 	Name       string            `dynamodbav:"name"`
 	Claims     map[string]string `dynamodbav:"claims"`
 	Scopes     []Scope           `dynamodbav:"scopes"`
-	ExpiresIn  *int              `dynamodbav:"expiresIn"`
-	CreateTime time.Time         `dynamodbav:"createTime"`
-	UpdateTime time.Time         `dynamodbav:"updateTime"`
+	SecretHash string            `dynamodbav:"secretHash"`
+	// A rotated secret stays valid until its grace period expires
+	PreviousSecretHash      string     `dynamodbav:"previousSecretHash"`
+	PreviousSecretExpiresIn *int       `dynamodbav:"previousSecretExpiresIn"`
+	Generation              int        `dynamodbav:"generation"`
+	ExpiresIn               *int       `dynamodbav:"expiresIn"`
+	LastUsedTime            *time.Time `dynamodbav:"lastUsedTime"`
+	UsageCount              int        `dynamodbav:"usageCount"`
+	CreateTime              time.Time  `dynamodbav:"createTime"`
+	UpdateTime              time.Time  `dynamodbav:"updateTime"`
 }
 
 type ApiTokenInputDTO struct {
-	Name      *string            `dynamodbav:"name"`
-	Scopes    *[]Scope           `dynamodbav:"scopes"`
-	Claims    *map[string]string `dynamodbav:"map"`
-	AccountId *string            `dynamodbav:"accountId"`
-	ExpiresIn *int               `dynamodbav:"expiresIn"`
+	Name                    *string            `dynamodbav:"name"`
+	Scopes                  *[]Scope           `dynamodbav:"scopes"`
+	Claims                  *map[string]string `dynamodbav:"map"`
+	AccountId               *string            `dynamodbav:"accountId"`
+	ExpiresIn               *int               `dynamodbav:"expiresIn"`
+	SecretHash              *string            `dynamodbav:"secretHash"`
+	PreviousSecretHash      *string            `dynamodbav:"previousSecretHash"`
+	PreviousSecretExpiresIn *int               `dynamodbav:"previousSecretExpiresIn"`
+	Generation              *int               `dynamodbav:"generation"`
+	// Usage is accumulated, so the count is added to the stored total
+	LastUsedTime *time.Time `dynamodbav:"lastUsedTime"`
+	UsageCount   *int       `dynamodbav:"usageCount"`
 }
 
 type ApiTokenDataService interface {
@@ -0,0 +1,25 @@
+package data
+
+import "time"
+
+type BackfillDTO struct {
+	PK           string    `dynamodbav:"PK"`
+	SK           string    `dynamodbav:"SK"`
+	ResourceType string    `dynamodbav:"resourceType"`
+	PartnerId    string    `dynamodbav:"partnerId"`
+	NextToken    *string   `dynamodbav:"nextToken"`
+	Completed    bool      `dynamodbav:"completed"`
+	CreateTime   time.Time `dynamodbav:"createTime"`
+	UpdateTime   time.Time `dynamodbav:"updateTime"`
+}
+
+type BackfillInputDTO struct {
+	ResourceType *string `dynamodbav:"resourceType"`
+	PartnerId    *string `dynamodbav:"partnerId"`
+	NextToken    *string `dynamodbav:"nextToken"`
+	Completed    *bool   `dynamodbav:"completed"`
+}
+
+type BackfillRepository interface {
+	Repository[BackfillDTO, BackfillInputDTO]
+}
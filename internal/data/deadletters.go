@@ -0,0 +1,27 @@
+package data
+
+import "time"
+
+type DeadLetterDTO struct {
+	PK          string    `dynamodbav:"PK"`
+	SK          string    `dynamodbav:"SK"`
+	HandlerName string    `dynamodbav:"handlerName"`
+	EventId     string    `dynamodbav:"eventId"`
+	Record      string    `dynamodbav:"record"`
+	Error       string    `dynamodbav:"error"`
+	Attempts    int       `dynamodbav:"attempts"`
+	CreateTime  time.Time `dynamodbav:"createTime"`
+	UpdateTime  time.Time `dynamodbav:"updateTime"`
+}
+
+type DeadLetterInputDTO struct {
+	HandlerName *string `dynamodbav:"handlerName"`
+	EventId     *string `dynamodbav:"eventId"`
+	Record      *string `dynamodbav:"record"`
+	Error       *string `dynamodbav:"error"`
+	Attempts    *int    `dynamodbav:"attempts"`
+}
+
+type DeadLetterRepository interface {
+	Repository[DeadLetterDTO, DeadLetterInputDTO]
+}
@@ -0,0 +1,52 @@
+package data
+
+import "time"
+
+type DeliveryStatus string
+
+const (
+	DELIVERY_PENDING     DeliveryStatus = "PENDING"
+	DELIVERY_SUCCEEDED   DeliveryStatus = "SUCCEEDED"
+	DELIVERY_DEAD_LETTER DeliveryStatus = "DEAD_LETTER"
+)
+
+type DeliveryAttemptDTO struct {
+	StatusCode  int       `dynamodbav:"statusCode"`
+	Error       *string   `dynamodbav:"error"`
+	AttemptTime time.Time `dynamodbav:"attemptTime"`
+}
+
+type DeliveryDTO struct {
+	PK           string               `dynamodbav:"PK"`
+	SK           string               `dynamodbav:"SK"`
+	FirstIndex   string               `dynamodbav:"GS1-PK"`
+	SubscriberId string               `dynamodbav:"subscriberId"`
+	Endpoint     string               `dynamodbav:"endpoint"`
+	EventId      string               `dynamodbav:"eventId"`
+	EventType    string               `dynamodbav:"eventType"`
+	Payload      string               `dynamodbav:"payload"`
+	Status       DeliveryStatus       `dynamodbav:"status"`
+	Attempts     []DeliveryAttemptDTO `dynamodbav:"attempts"`
+	// Unix seconds of the next attempt of a pending delivery
+	NextAttemptTime *int      `dynamodbav:"nextAttemptTime"`
+	ExpiresIn       *int      `dynamodbav:"expiresIn"`
+	CreateTime      time.Time `dynamodbav:"createTime"`
+	UpdateTime      time.Time `dynamodbav:"updateTime"`
+}
+
+type DeliveryInputDTO struct {
+	AccountId       *string               `dynamodbav:"accountId"`
+	SubscriberId    *string               `dynamodbav:"subscriberId"`
+	Endpoint        *string               `dynamodbav:"endpoint"`
+	EventId         *string               `dynamodbav:"eventId"`
+	EventType       *string               `dynamodbav:"eventType"`
+	Payload         *string               `dynamodbav:"payload"`
+	Status          *DeliveryStatus       `dynamodbav:"status"`
+	Attempts        *[]DeliveryAttemptDTO `dynamodbav:"attempts"`
+	NextAttemptTime *int                  `dynamodbav:"nextAttemptTime"`
+	ExpiresIn       *int                  `dynamodbav:"expiresIn"`
+}
+
+type DeliveryRepository interface {
+	Repository[DeliveryDTO, DeliveryInputDTO]
+}
@@ -0,0 +1,82 @@
+package data
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+type Role string
+
+const (
+	ROLE_OWNER  Role = "OWNER"
+	ROLE_EDITOR Role = "EDITOR"
+	ROLE_VIEWER Role = "VIEWER"
+)
+
+type MembershipStatus string
+
+const (
+	MEMBERSHIP_INVITED MembershipStatus = "INVITED"
+	MEMBERSHIP_ACTIVE  MembershipStatus = "ACTIVE"
+)
+
+// Resources only an owner can reach, even to read
+var OWNER_RESOURCES = []string{"tokens", "oauth", "subscriptions", "shares", "settings", "providers"}
+
+// Resources an editor can change
+var EDITOR_RESOURCES = []string{"recipes", "lists", "templates"}
+
+// Members are listed by everyone in the household but managed by the owner.
+// Every other household path concerns the caller's own memberships.
+func _grantsHousehold(role Role, method string, path string) bool {
+	if !_hasPathPrefix(path, "/households/members") {
+		return true
+	}
+	return role == ROLE_OWNER || ReadMethod(method)
+}
+
+// Roles are enforced alongside scopes, a request must be granted by both
+func (r Role) Grants(method string, path string) bool {
+	resource, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	if resource == "households" {
+		return _grantsHousehold(r, method, path)
+	}
+	if r == ROLE_OWNER {
+		return true
+	}
+	if slices.Contains(OWNER_RESOURCES, resource) {
+		return false
+	}
+	if ReadMethod(method) {
+		return true
+	}
+	return r == ROLE_EDITOR && slices.Contains(EDITOR_RESOURCES, resource)
+}
+
+// Households are the owner's account. Memberships are keyed by the invited
+// email and bound to the member's username on acceptance.
+type MembershipDTO struct {
+	PK          string           `dynamodbav:"PK"`
+	SK          string           `dynamodbav:"SK"`
+	FirstIndex  string           `dynamodbav:"GS1-PK"`
+	HouseholdId string           `dynamodbav:"householdId"`
+	MemberId    *string          `dynamodbav:"memberId"`
+	Role        Role             `dynamodbav:"role"`
+	Status      MembershipStatus `dynamodbav:"status"`
+	InvitedBy   string           `dynamodbav:"invitedBy"`
+	CreateTime  time.Time        `dynamodbav:"createTime"`
+	UpdateTime  time.Time        `dynamodbav:"updateTime"`
+}
+
+type MembershipInputDTO struct {
+	HouseholdId *string           `dynamodbav:"householdId"`
+	MemberId    *string           `dynamodbav:"memberId"`
+	Role        *Role             `dynamodbav:"role"`
+	Status      *MembershipStatus `dynamodbav:"status"`
+	InvitedBy   *string           `dynamodbav:"invitedBy"`
+}
+
+type MembershipRepository interface {
+	Repository[MembershipDTO, MembershipInputDTO]
+}
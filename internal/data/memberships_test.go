@@ -0,0 +1,28 @@
+package data
+
+import "testing"
+
+func TestRoleGrants(t *testing.T) {
+	for _, test := range []struct {
+		role     Role
+		method   string
+		path     string
+		expected bool
+	}{
+		{ROLE_OWNER, "DELETE", "/tokens/abc", true},
+		{ROLE_EDITOR, "PUT", "/recipes/abc", true},
+		{ROLE_EDITOR, "POST", "/lists", true},
+		{ROLE_EDITOR, "PUT", "/audits/abc", false},
+		{ROLE_EDITOR, "GET", "/tokens", false},
+		{ROLE_VIEWER, "GET", "/lists/abc", true},
+		{ROLE_VIEWER, "PUT", "/lists/abc", false},
+		{ROLE_VIEWER, "GET", "/households/members", true},
+		{ROLE_EDITOR, "POST", "/households/members", false},
+		{ROLE_VIEWER, "POST", "/households/abc/accept", true},
+		{ROLE_VIEWER, "DELETE", "/households/abc", true},
+	} {
+		if test.role.Grants(test.method, test.path) != test.expected {
+			t.Fatalf("Expected %s %s %s to be %v", test.role, test.method, test.path, test.expected)
+		}
+	}
+}
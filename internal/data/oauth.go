@@ -0,0 +1,115 @@
+package data
+
+import "time"
+
+type OAuthClientDTO struct {
+	PK           string            `dynamodbav:"PK"`
+	SK           string            `dynamodbav:"SK"`
+	FirstIndex   string            `dynamodbav:"GS1-PK"`
+	AccountId    string            `dynamodbav:"accountId"`
+	Name         string            `dynamodbav:"name"`
+	RedirectUris []string          `dynamodbav:"redirectUris"`
+	Scopes       []Scope           `dynamodbav:"scopes"`
+	Claims       map[string]string `dynamodbav:"claims"`
+	Confidential bool              `dynamodbav:"confidential"`
+	SecretHash   string            `dynamodbav:"secretHash"`
+	CreateTime   time.Time         `dynamodbav:"createTime"`
+	UpdateTime   time.Time         `dynamodbav:"updateTime"`
+}
+
+type OAuthClientInputDTO struct {
+	AccountId    *string            `dynamodbav:"accountId"`
+	Name         *string            `dynamodbav:"name"`
+	RedirectUris *[]string          `dynamodbav:"redirectUris"`
+	Scopes       *[]Scope           `dynamodbav:"scopes"`
+	Claims       *map[string]string `dynamodbav:"claims"`
+	Confidential *bool              `dynamodbav:"confidential"`
+	SecretHash   *string            `dynamodbav:"secretHash"`
+}
+
+type OAuthClientDataService interface {
+	Repository[OAuthClientDTO, OAuthClientInputDTO]
+}
+
+// Consent is kept per client, and every token issued under it carries the grant id
+type OAuthConsentDTO struct {
+	PK         string    `dynamodbav:"PK"`
+	SK         string    `dynamodbav:"SK"`
+	FirstIndex string    `dynamodbav:"GS1-PK"`
+	GrantId    string    `dynamodbav:"grantId"`
+	ClientName string    `dynamodbav:"clientName"`
+	Scopes     []Scope   `dynamodbav:"scopes"`
+	CreateTime time.Time `dynamodbav:"createTime"`
+	UpdateTime time.Time `dynamodbav:"updateTime"`
+}
+
+type OAuthConsentInputDTO struct {
+	GrantId    *string  `dynamodbav:"grantId"`
+	ClientName *string  `dynamodbav:"clientName"`
+	Scopes     *[]Scope `dynamodbav:"scopes"`
+}
+
+type OAuthConsentDataService interface {
+	Repository[OAuthConsentDTO, OAuthConsentInputDTO]
+}
+
+// Codes are stored by the hash of their value and can be exchanged once
+type AuthorizationCodeDTO struct {
+	PK                  string            `dynamodbav:"PK"`
+	SK                  string            `dynamodbav:"SK"`
+	ClientId            string            `dynamodbav:"clientId"`
+	AccountId           string            `dynamodbav:"accountId"`
+	GrantId             string            `dynamodbav:"grantId"`
+	RedirectUri         string            `dynamodbav:"redirectUri"`
+	Scopes              []Scope           `dynamodbav:"scopes"`
+	Claims              map[string]string `dynamodbav:"claims"`
+	CodeChallenge       string            `dynamodbav:"codeChallenge"`
+	CodeChallengeMethod string            `dynamodbav:"codeChallengeMethod"`
+	ExpiresIn           int               `dynamodbav:"expiresIn"`
+	CreateTime          time.Time         `dynamodbav:"createTime"`
+	UpdateTime          time.Time         `dynamodbav:"updateTime"`
+}
+
+type AuthorizationCodeInputDTO struct {
+	ClientId            *string            `dynamodbav:"clientId"`
+	AccountId           *string            `dynamodbav:"accountId"`
+	GrantId             *string            `dynamodbav:"grantId"`
+	RedirectUri         *string            `dynamodbav:"redirectUri"`
+	Scopes              *[]Scope           `dynamodbav:"scopes"`
+	Claims              *map[string]string `dynamodbav:"claims"`
+	CodeChallenge       *string            `dynamodbav:"codeChallenge"`
+	CodeChallengeMethod *string            `dynamodbav:"codeChallengeMethod"`
+	ExpiresIn           *int               `dynamodbav:"expiresIn"`
+}
+
+type AuthorizationCodeDataService interface {
+	Repository[AuthorizationCodeDTO, AuthorizationCodeInputDTO]
+}
+
+type RefreshTokenDTO struct {
+	PK         string            `dynamodbav:"PK"`
+	SK         string            `dynamodbav:"SK"`
+	ClientId   string            `dynamodbav:"clientId"`
+	AccountId  string            `dynamodbav:"accountId"`
+	GrantId    string            `dynamodbav:"grantId"`
+	Scopes     []Scope           `dynamodbav:"scopes"`
+	Claims     map[string]string `dynamodbav:"claims"`
+	SecretHash string            `dynamodbav:"secretHash"`
+	ExpiresIn  int               `dynamodbav:"expiresIn"`
+	CreateTime time.Time         `dynamodbav:"createTime"`
+	UpdateTime time.Time         `dynamodbav:"updateTime"`
+}
+
+type RefreshTokenInputDTO struct {
+	ClientId   *string            `dynamodbav:"clientId"`
+	AccountId  *string            `dynamodbav:"accountId"`
+	GrantId    *string            `dynamodbav:"grantId"`
+	Scopes     *[]Scope           `dynamodbav:"scopes"`
+	Claims     *map[string]string `dynamodbav:"claims"`
+	SecretHash *string            `dynamodbav:"secretHash"`
+	ExpiresIn  *int               `dynamodbav:"expiresIn"`
+}
+
+type RefreshTokenDataService interface {
+	Repository[RefreshTokenDTO, RefreshTokenInputDTO]
+}
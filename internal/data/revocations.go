@@ -0,0 +1,27 @@
+package data
+
+import "time"
+
+// Revokes every generation of a token below Generation from EffectiveTime on
+type RevokedTokenDTO struct {
+	PK            string     `dynamodbav:"PK"`
+	SK            string     `dynamodbav:"SK"`
+	AccountId     string     `dynamodbav:"accountId"`
+	Generation    int        `dynamodbav:"generation"`
+	EffectiveTime *time.Time `dynamodbav:"effectiveTime"`
+	// Revocations only need to outlive the token, so they share its expiry
+	ExpiresIn  *int      `dynamodbav:"expiresIn"`
+	CreateTime time.Time `dynamodbav:"createTime"`
+	UpdateTime time.Time `dynamodbav:"updateTime"`
+}
+
+type RevokedTokenInputDTO struct {
+	AccountId     *string    `dynamodbav:"accountId"`
+	Generation    *int       `dynamodbav:"generation"`
+	EffectiveTime *time.Time `dynamodbav:"effectiveTime"`
+	ExpiresIn     *int       `dynamodbav:"expiresIn"`
+}
+
+type RevokedTokenDataService interface {
+	Repository[RevokedTokenDTO, RevokedTokenInputDTO]
+}
@@ -0,0 +1,115 @@
+package data
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	SCOPE_READONLY_SUFFIX = ".readonly"
+	SCOPE_RESOURCE_SEP    = ":"
+)
+
+// Scopes are "{resource}[:{resourceId}][.readonly]", ie: lists:abc-123.readonly
+func (s Scope) _parse() (string, string, bool) {
+	return strings.Cut(strings.TrimSuffix(string(s), SCOPE_READONLY_SUFFIX), SCOPE_RESOURCE_SEP)
+}
+
+func (s Scope) Resource() string {
+	resource, _, _ := s._parse()
+	return resource
+}
+
+func (s Scope) ResourceId() string {
+	_, resourceId, _ := s._parse()
+	return resourceId
+}
+
+func (s Scope) ReadOnly() bool {
+	return strings.HasSuffix(string(s), SCOPE_READONLY_SUFFIX)
+}
+
+func NewResourceScope(resource Scope, resourceId string, readOnly bool) Scope {
+	scope := resource.Resource() + SCOPE_RESOURCE_SEP + resourceId
+	if readOnly {
+		scope += SCOPE_READONLY_SUFFIX
+	}
+	return Scope(scope)
+}
+
+func _hasPathPrefix(path string, prefix string) bool {
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// HEAD requests are answered by GET routes, so are granted alike
+func ReadMethod(method string) bool {
+	return method == "GET" || method == "HEAD"
+}
+
+// A resource qualified scope grants the resource and everything below it, but
+// never the collection it belongs to
+func (s Scope) Grants(method string, path string) bool {
+	prefix := "/" + s.Resource()
+	if resourceId := s.ResourceId(); resourceId != "" {
+		prefix += "/" + resourceId
+	}
+	if !_hasPathPrefix(path, prefix) {
+		return false
+	}
+	return !s.ReadOnly() || ReadMethod(method)
+}
+
+// Covers is true when s grants at least everything other does
+func (s Scope) Covers(other Scope) bool {
+	if s.Resource() != other.Resource() {
+		return false
+	}
+	if s.ResourceId() != "" && s.ResourceId() != other.ResourceId() {
+		return false
+	}
+	return !s.ReadOnly() || other.ReadOnly()
+}
+
+// Finds the first requested scope that no granted scope covers
+func UncoveredScope(granted []Scope, requested []Scope) (Scope, bool) {
+	for _, scope := range requested {
+		covered := false
+		for _, grant := range granted {
+			if grant.Covers(scope) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return scope, true
+		}
+	}
+	return "", false
+}
+
+var SCOPE_RESOURCES = []Scope{
+	RECIPE_WRITE,
+	LIST_WRITE,
+	SETTINGS_WRITE,
+	SHARE_WRITE,
+	AUDIT_WRITE,
+	SUBSCRIPTIONS_WRITE,
+	TOKENS_WRITE,
+	PROVIDER_WRITE,
+	OAUTH_WRITE,
+	HOUSEHOLDS_WRITE,
+	TEMPLATES_WRITE,
+}
+
+func (s Scope) Validate() error {
+	resource, resourceId, qualified := s._parse()
+	if qualified && (resourceId == "" || strings.ContainsAny(resourceId, "/:")) {
+		return fmt.Errorf("scope %s has an invalid resource id", s)
+	}
+	for _, known := range SCOPE_RESOURCES {
+		if string(known) == resource {
+			return nil
+		}
+	}
+	return fmt.Errorf("scope %s is not a known resource", s)
+}
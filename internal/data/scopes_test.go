@@ -0,0 +1,70 @@
+package data
+
+import "testing"
+
+func TestScopes(t *testing.T) {
+	tablet := NewResourceScope(LIST_WRITE, "abc-123", true)
+	if tablet != "lists:abc-123.readonly" {
+		t.Fatalf("Unexpected scope %s", tablet)
+	}
+	if tablet.Resource() != "lists" || tablet.ResourceId() != "abc-123" || !tablet.ReadOnly() {
+		t.Fatalf("Failed to parse %s", tablet)
+	}
+
+	t.Run("Grants", func(t *testing.T) {
+		for _, test := range []struct {
+			scope  Scope
+			method string
+			path   string
+			grant  bool
+		}{
+			{LIST_WRITE, "POST", "/lists", true},
+			{LIST_READ, "GET", "/lists/abc-123", true},
+			{LIST_READ, "PUT", "/lists/abc-123", false},
+			{LIST_WRITE, "GET", "/listsabc", false},
+			{tablet, "GET", "/lists/abc-123", true},
+			{tablet, "GET", "/lists/abc-123/items", true},
+			{tablet, "PUT", "/lists/abc-123", false},
+			{tablet, "GET", "/lists", false},
+			{tablet, "GET", "/lists/abc-1234", false},
+			{"lists:abc-123", "PUT", "/lists/abc-123", true},
+		} {
+			if test.scope.Grants(test.method, test.path) != test.grant {
+				t.Fatalf("Expected %s on %s %s to be %v", test.scope, test.method, test.path, test.grant)
+			}
+		}
+	})
+
+	t.Run("Covers", func(t *testing.T) {
+		for _, test := range []struct {
+			scope  Scope
+			other  Scope
+			covers bool
+		}{
+			{LIST_WRITE, tablet, true},
+			{LIST_READ, tablet, true},
+			{LIST_READ, LIST_WRITE, false},
+			{tablet, LIST_READ, false},
+			{tablet, "lists:abc-123", false},
+			{"lists:abc-123", tablet, true},
+			{RECIPE_WRITE, tablet, false},
+		} {
+			if test.scope.Covers(test.other) != test.covers {
+				t.Fatalf("Expected %s covering %s to be %v", test.scope, test.other, test.covers)
+			}
+		}
+	})
+
+	t.Run("Validate", func(t *testing.T) {
+		for _, scope := range []Scope{LIST_READ, tablet, TOKENS_WRITE} {
+			if err := scope.Validate(); err != nil {
+				t.Fatalf("Expected %s to be valid: %v", scope, err)
+			}
+		}
+		for _, scope := range []Scope{"pantry", "lists:", "lists:.readonly", "lists:a/b"} {
+			if err := scope.Validate(); err == nil {
+				t.Fatalf("Expected %s to be invalid", scope)
+			}
+		}
+	})
+}
@@ -3,17 +3,23 @@ package data
 import "time"
 
 type SettingsDTO struct {
-	AutoShareLists   bool      `dynamodbav:"autoShareLists"`
-	AutoShareRecipes bool      `dynamodbav:"autoShareRecipes"`
-	PK               string    `dynamodbav:"PK"`
-	SK               string    `dynamodbav:"SK"`
-	CreateTime       time.Time `dynamodbav:"createTime"`
-	UpdateTime       time.Time `dynamodbav:"updateTime"`
+	AutoShareLists   bool `dynamodbav:"autoShareLists"`
+	AutoShareRecipes bool `dynamodbav:"autoShareRecipes"`
+	// Item names to the aisles they are found in, ahead of the built in ones
+	Categories map[string]string `dynamodbav:"categories,omitempty"`
+	// Aisles in the order they are walked
+	StoreLayout []string  `dynamodbav:"storeLayout,omitempty"`
+	PK          string    `dynamodbav:"PK"`
+	SK          string    `dynamodbav:"SK"`
+	CreateTime  time.Time `dynamodbav:"createTime"`
+	UpdateTime  time.Time `dynamodbav:"updateTime"`
 }
 
 type SettingsInputDTO struct {
-	AutoShareLists   *bool `dynamodbav:"autoShareLists"`
-	AutoShareRecipes *bool `dynamodbav:"autoShareRecipes"`
+	AutoShareLists   *bool              `dynamodbav:"autoShareLists"`
+	AutoShareRecipes *bool              `dynamodbav:"autoShareRecipes"`
+	Categories       *map[string]string `dynamodbav:"categories"`
+	StoreLayout      *[]string          `dynamodbav:"storeLayout"`
 }
 
 type SettingsRepository interface {
@@ -10,6 +10,9 @@ const (
 	REJECTED  ApprovalStatus = "REJECTED"
 )
 
+// Share requests left unanswered expire after a week
+const SHARE_REQUEST_EXPIRY = 7 * 24 * time.Hour
+
 type ShareRequestDTO struct {
 	PK             string         `dynamodbav:"PK"`
 	SK             string         `dynamodbav:"SK"`
@@ -1,12 +1,21 @@
 package data
 
-import "time"
+import (
+	"fmt"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
+)
+
+// Items are stored under their JSON names, so patches can address their fields
 type ShoppingListItemDTO struct {
+	Id          string  `dynamodbav:"itemId"`
 	Name        string  `dynamodbav:"name"`
 	Measurement string  `dynamodbav:"measurement"`
 	Amount      float32 `dynamodbav:"amount"`
 	Completed   bool    `dynamodbav:"completed"`
+	Category    *string `dynamodbav:"category,omitempty"`
 }
 
 type ShoppingListDTO struct {
@@ -17,6 +26,7 @@ type ShoppingListDTO struct {
 	UpdateToken *string               `dynamodbav:"updateToken"`
 	Shared      *bool                 `dynamodbav:"shared"`
 	Items       []ShoppingListItemDTO `dynamodbav:"items"`
+	SyncTokens  []string              `dynamodbav:"syncTokens,omitempty"`
 	ExpiresIn   *int                  `dynamodbav:"expiresIn"`
 	CreateTime  time.Time             `dynamodbav:"createTime"`
 	UpdateTime  time.Time             `dynamodbav:"updateTime"`
@@ -33,3 +43,21 @@ type ShoppingListInputDTO struct {
 type ShoppingListDataService interface {
 	Repository[ShoppingListDTO, ShoppingListInputDTO]
 }
+
+// Items written before items had ids are given one derived from the list and
+// their name, so every copy of a shared list derives the same one. Returns
+// whether any item was given an id.
+func FillItemIds(listId string, items []ShoppingListItemDTO) bool {
+	filled := false
+	occurrences := make(map[string]int)
+	for i, item := range items {
+		if item.Id != "" {
+			continue
+		}
+		name := strings.ToLower(strings.TrimSpace(item.Name))
+		occurrences[name]++
+		items[i].Id = uuid.NewSHA1(uuid.NameSpaceOID, []byte(fmt.Sprintf("%s/%s/%d", listId, name, occurrences[name]))).String()
+		filled = true
+	}
+	return filled
+}
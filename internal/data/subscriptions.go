@@ -2,20 +2,38 @@ package data
 
 import "time"
 
+// Subscriptions delivered by the webhook subsystem rather than SNS
+const WEBHOOK_PROTOCOL = "webhook"
+
+type ConfirmationStatus string
+
+const (
+	CONFIRMATION_PENDING   ConfirmationStatus = "PENDING"
+	CONFIRMATION_CONFIRMED ConfirmationStatus = "CONFIRMED"
+)
+
 type SubscriptionDTO struct {
-	PK            string    `dynamodbav:"PK"`
-	SK            string    `dynamodbav:"SK"`
-	Endpoint      string    `dynamodbav:"endpoint"`
-	Protocol      string    `dynamodbav:"protocol"`
-	SubscriberArn string    `dynamodbav:"subscriberArn"`
-	CreateTime    time.Time `dynamodbav:"createTime"`
-	UpdateTime    time.Time `dynamodbav:"updateTime"`
+	PK                 string             `dynamodbav:"PK"`
+	SK                 string             `dynamodbav:"SK"`
+	Endpoint           string             `dynamodbav:"endpoint"`
+	Protocol           string             `dynamodbav:"protocol"`
+	SubscriberArn      string             `dynamodbav:"subscriberArn"`
+	Secret             *string            `dynamodbav:"secret"`
+	EventTypes         []string           `dynamodbav:"eventTypes,omitempty"`
+	ResourceTypes      []string           `dynamodbav:"resourceTypes,omitempty"`
+	ConfirmationStatus ConfirmationStatus `dynamodbav:"confirmationStatus"`
+	CreateTime         time.Time          `dynamodbav:"createTime"`
+	UpdateTime         time.Time          `dynamodbav:"updateTime"`
 }
 
 type SubscriptionInputDTO struct {
-	Endpoint      *string `dynamodbav:"endpoint"`
-	Protocol      *string `dynamodbav:"protocol"`
-	SubscriberArn *string `dynamodbav:"subscriberArn"`
+	Endpoint           *string             `dynamodbav:"endpoint"`
+	Protocol           *string             `dynamodbav:"protocol"`
+	SubscriberArn      *string             `dynamodbav:"subscriberArn"`
+	Secret             *string             `dynamodbav:"secret"`
+	EventTypes         []string            `dynamodbav:"eventTypes"`
+	ResourceTypes      []string            `dynamodbav:"resourceTypes"`
+	ConfirmationStatus *ConfirmationStatus `dynamodbav:"confirmationStatus"`
 }
 
 type SubscriptionDataService interface {
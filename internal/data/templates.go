@@ -0,0 +1,56 @@
+package data
+
+import "time"
+
+type Frequency string
+
+const (
+	FREQUENCY_DAILY   Frequency = "DAILY"
+	FREQUENCY_WEEKLY  Frequency = "WEEKLY"
+	FREQUENCY_MONTHLY Frequency = "MONTHLY"
+)
+
+// Runs at the hour of the day in the time zone, every interval of the frequency
+type RecurrenceDTO struct {
+	Frequency Frequency `dynamodbav:"frequency"`
+	Interval  int       `dynamodbav:"interval"`
+	// The name of the day of weekly runs, ie: Sunday
+	DayOfWeek *string `dynamodbav:"dayOfWeek,omitempty"`
+	// The day of monthly runs, up to the 28th so every month has one
+	DayOfMonth *int   `dynamodbav:"dayOfMonth,omitempty"`
+	Hour       int    `dynamodbav:"hour"`
+	TimeZone   string `dynamodbav:"timeZone"`
+}
+
+// Templates are indexed together, so the scheduler can find those that are due
+type ShoppingListTemplateDTO struct {
+	PK             string                `dynamodbav:"PK"`
+	SK             string                `dynamodbav:"SK"`
+	FirstIndex     string                `dynamodbav:"GS1-PK"`
+	Name           string                `dynamodbav:"name"`
+	Owner          *string               `dynamodbav:"owner"`
+	Items          []ShoppingListItemDTO `dynamodbav:"items"`
+	Recurrence     *RecurrenceDTO        `dynamodbav:"recurrence"`
+	MergeUnchecked bool                  `dynamodbav:"mergeUnchecked"`
+	// Lists made from the template expire this many days after they are made
+	ExpiresAfterDays *int       `dynamodbav:"expiresAfterDays"`
+	NextRunTime      *time.Time `dynamodbav:"nextRunTime"`
+	LastListId       *string    `dynamodbav:"lastListId"`
+	CreateTime       time.Time  `dynamodbav:"createTime"`
+	UpdateTime       time.Time  `dynamodbav:"updateTime"`
+}
+
+type ShoppingListTemplateInputDTO struct {
+	Name             *string                `dynamodbav:"name"`
+	Owner            *string                `dynamodbav:"owner"`
+	Items            *[]ShoppingListItemDTO `dynamodbav:"items"`
+	Recurrence       *RecurrenceDTO         `dynamodbav:"recurrence"`
+	MergeUnchecked   *bool                  `dynamodbav:"mergeUnchecked"`
+	ExpiresAfterDays *int                   `dynamodbav:"expiresAfterDays"`
+	NextRunTime      *time.Time             `dynamodbav:"nextRunTime"`
+	LastListId       *string                `dynamodbav:"lastListId"`
+}
+
+type ShoppingListTemplateRepository interface {
+	Repository[ShoppingListTemplateDTO, ShoppingListTemplateInputDTO]
+}
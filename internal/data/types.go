@@ -24,12 +24,32 @@ type QueryResults[T interface{}] struct {
 
 type NextToken map[string]map[string]string
 
+// Changes one attribute path of an item, ie: items[2].completed
+type PatchChange struct {
+	Path  string
+	Value any
+	// Removes the path instead of setting it
+	Remove bool
+	// Appends Value, a list, to the list at the path
+	Append bool
+}
+
+// Targeted changes applied in the same update as the set fields of an input
+type Patch struct {
+	Changes []PatchChange
+	// Expected values of paths, the update conflicts when any of them changed
+	Conditions map[string]any
+}
+
 type Repository[T interface{}, I interface{}] interface {
 	Get(accountId string, itemId string) (T, error)
 	Create(accountId string, input I) (T, error)
 	CreateWithItemId(accountId string, input I, itemId string) (T, error)
 	Update(accountId string, itemId string, input I) (T, error)
+	Patch(accountId string, itemId string, input I, patch Patch) (T, error)
 	List(accountId string, params QueryParams) (QueryResults[T], error)
 	ListByIndex(accountId string, indexName string, params QueryParams) (QueryResults[T], error)
 	Delete(accountId string, itemId string) error
+	// Deletes and returns the item, so only one of concurrent callers gets it
+	Take(accountId string, itemId string) (T, error)
 }
@@ -0,0 +1,40 @@
+package deadletters
+
+import (
+	"encoding/json"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/uuid"
+	"philcali.me/recipes/internal/data"
+)
+
+// Dead letters are kept under one account, rather than the account of the record
+const (
+	GLOBAL_ACCOUNT = "Global"
+	NAME           = "DeadLetter"
+)
+
+// Keeps the stream records handlers failed to apply, so they can be replayed
+type Store interface {
+	// Counts another attempt when the handler already failed the record
+	Put(handlerName string, record events.DynamoDBEventRecord, failure error) (data.DeadLetterDTO, error)
+	Get(id string) (data.DeadLetterDTO, error)
+	List(params data.QueryParams) (data.QueryResults[data.DeadLetterDTO], error)
+	Delete(id string) error
+}
+
+// The same handler failing the same record is the same letter
+func LetterId(handlerName string, record events.DynamoDBEventRecord) string {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(handlerName+"@"+record.EventID)).String()
+}
+
+func EncodeRecord(record events.DynamoDBEventRecord) (string, error) {
+	encoded, err := json.Marshal(record)
+	return string(encoded), err
+}
+
+func DecodeRecord(letter data.DeadLetterDTO) (events.DynamoDBEventRecord, error) {
+	var record events.DynamoDBEventRecord
+	err := json.Unmarshal([]byte(letter.Record), &record)
+	return record, err
+}
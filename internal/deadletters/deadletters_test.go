@@ -0,0 +1,88 @@
+package deadletters
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/exceptions"
+)
+
+func _record(eventId string) events.DynamoDBEventRecord {
+	return events.DynamoDBEventRecord{
+		EventID:   eventId,
+		EventName: "INSERT",
+		Change: events.DynamoDBStreamRecord{
+			SequenceNumber: eventId,
+			Keys: map[string]events.DynamoDBAttributeValue{
+				"PK": events.NewStringAttribute("owner:Recipe"),
+				"SK": events.NewStringAttribute("abc-123"),
+			},
+		},
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	store := NewFileStore(filepath.Join(t.TempDir(), "deadletters.json"))
+	empty, err := store.List(data.QueryParams{})
+	if err != nil || len(empty.Items) != 0 {
+		t.Fatalf("Expected a missing file to have no letters, got %v: %v", empty, err)
+	}
+	first, err := store.Put("audits", _record("1"), errors.New("throttled"))
+	if err != nil {
+		t.Fatalf("Failed to put: %v", err)
+	}
+	again, err := store.Put("audits", _record("1"), errors.New("still throttled"))
+	if err != nil {
+		t.Fatalf("Failed to put: %v", err)
+	}
+	if again.SK != first.SK || again.Attempts != 2 || again.Error != "still throttled" {
+		t.Fatalf("Expected another attempt of the same letter, got %v", again)
+	}
+	if _, err := store.Put("publish", _record("1"), errors.New("failed")); err != nil {
+		t.Fatalf("Failed to put: %v", err)
+	}
+	if _, err := store.Put("audits", _record("2"), errors.New("failed")); err != nil {
+		t.Fatalf("Failed to put: %v", err)
+	}
+
+	// Reopened, as the replay tool would
+	store = NewFileStore(store.Location)
+	letter, err := store.Get(first.SK)
+	if err != nil {
+		t.Fatalf("Failed to get %s: %v", first.SK, err)
+	}
+	record, err := DecodeRecord(letter)
+	if err != nil || record.Change.Keys["PK"].String() != "owner:Recipe" {
+		t.Fatalf("Expected the record to be kept, got %v: %v", record, err)
+	}
+	var ids []string
+	params := data.QueryParams{Limit: 2}
+	for {
+		page, err := store.List(params)
+		if err != nil {
+			t.Fatalf("Failed to list: %v", err)
+		}
+		for _, letter := range page.Items {
+			ids = append(ids, letter.SK)
+		}
+		if page.NextToken == nil {
+			break
+		}
+		// Deleting a listed letter leaves the next page in place
+		if err := store.Delete(page.Items[len(page.Items)-1].SK); err != nil {
+			t.Fatalf("Failed to delete: %v", err)
+		}
+		params.NextToken = page.NextToken
+	}
+	if len(ids) != 3 {
+		t.Fatalf("Expected three letters, got %v", ids)
+	}
+	if _, err := store.Get(ids[1]); err == nil {
+		t.Fatalf("Expected %s to be deleted", ids[1])
+	} else if _, ok := err.(*exceptions.NotFoundError); !ok {
+		t.Fatalf("Expected a deleted letter to be not found, got %v", err)
+	}
+}
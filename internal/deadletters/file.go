@@ -0,0 +1,137 @@
+package deadletters
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/exceptions"
+)
+
+// Keeps dead letters in a JSON file for local use. Letters are listed by id,
+// as they are in the table, and the next token is the last id listed.
+type FileStore struct {
+	Location string
+	mutex    sync.Mutex
+}
+
+func NewFileStore(location string) *FileStore {
+	return &FileStore{
+		Location: location,
+	}
+}
+
+func (fs *FileStore) _read() (map[string]data.DeadLetterDTO, error) {
+	letters := make(map[string]data.DeadLetterDTO)
+	content, err := os.ReadFile(fs.Location)
+	if errors.Is(err, os.ErrNotExist) {
+		return letters, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(content, &letters)
+	return letters, err
+}
+
+func (fs *FileStore) _write(letters map[string]data.DeadLetterDTO) error {
+	content, err := json.MarshalIndent(letters, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fs.Location, content, 0600)
+}
+
+func (fs *FileStore) Put(handlerName string, record events.DynamoDBEventRecord, failure error) (data.DeadLetterDTO, error) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	letters, err := fs._read()
+	if err != nil {
+		return data.DeadLetterDTO{}, err
+	}
+	now := time.Now()
+	id := LetterId(handlerName, record)
+	letter, ok := letters[id]
+	if !ok {
+		encoded, err := EncodeRecord(record)
+		if err != nil {
+			return letter, err
+		}
+		letter = data.DeadLetterDTO{
+			PK:          fmt.Sprintf("%s:%s", GLOBAL_ACCOUNT, NAME),
+			SK:          id,
+			HandlerName: handlerName,
+			EventId:     record.EventID,
+			Record:      encoded,
+			CreateTime:  now,
+		}
+	}
+	letter.Error = failure.Error()
+	letter.Attempts++
+	letter.UpdateTime = now
+	letters[id] = letter
+	return letter, fs._write(letters)
+}
+
+func (fs *FileStore) Get(id string) (data.DeadLetterDTO, error) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	letters, err := fs._read()
+	if err != nil {
+		return data.DeadLetterDTO{}, err
+	}
+	letter, ok := letters[id]
+	if !ok {
+		return letter, exceptions.NotFound("deadletter", id)
+	}
+	return letter, nil
+}
+
+func (fs *FileStore) List(params data.QueryParams) (data.QueryResults[data.DeadLetterDTO], error) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	letters, err := fs._read()
+	if err != nil {
+		return data.QueryResults[data.DeadLetterDTO]{}, err
+	}
+	items := make([]data.DeadLetterDTO, 0, len(letters))
+	for _, letter := range letters {
+		items = append(items, letter)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].SK < items[j].SK
+	})
+	if params.NextToken != nil {
+		// Letters deleted since the last page leave the rest in order
+		start := sort.Search(len(items), func(i int) bool {
+			return items[i].SK > *params.NextToken
+		})
+		items = items[start:]
+	}
+	results := data.QueryResults[data.DeadLetterDTO]{Items: items}
+	if params.Limit > 0 && len(items) > params.Limit {
+		results.Items = items[:params.Limit]
+		results.NextToken = &results.Items[params.Limit-1].SK
+	}
+	return results, nil
+}
+
+func (fs *FileStore) Delete(id string) error {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	letters, err := fs._read()
+	if err != nil {
+		return err
+	}
+	if _, ok := letters[id]; !ok {
+		return nil
+	}
+	delete(letters, id)
+	return fs._write(letters)
+}
@@ -21,7 +21,7 @@ func NewApiTokenService(tableName string, client dynamodb.Client, marshaler toke
 			return data.ApiTokenDTO{PK: pk, SK: sk}
 		},
 		OnCreate: func(atid data.ApiTokenInputDTO, t time.Time, pk, sk string) data.ApiTokenDTO {
-			return data.ApiTokenDTO{
+			tokenDTO := data.ApiTokenDTO{
 				PK:         pk,
 				SK:         sk,
 				FirstIndex: fmt.Sprintf("%s:ApiToken", *atid.AccountId),
@@ -33,6 +33,10 @@ func NewApiTokenService(tableName string, client dynamodb.Client, marshaler toke
 				CreateTime: t,
 				UpdateTime: t,
 			}
+			if atid.SecretHash != nil {
+				tokenDTO.SecretHash = *atid.SecretHash
+			}
+			return tokenDTO
 		},
 		OnUpdate: func(atid data.ApiTokenInputDTO, ub expression.UpdateBuilder) {
 			if atid.Name != nil {
@@ -41,6 +45,24 @@ func NewApiTokenService(tableName string, client dynamodb.Client, marshaler toke
 			if atid.ExpiresIn != nil {
 				ub.Set(expression.Name("expiresIn"), expression.Value(atid.ExpiresIn))
 			}
+			if atid.LastUsedTime != nil {
+				ub.Set(expression.Name("lastUsedTime"), expression.Value(atid.LastUsedTime))
+			}
+			if atid.SecretHash != nil {
+				ub.Set(expression.Name("secretHash"), expression.Value(atid.SecretHash))
+			}
+			if atid.PreviousSecretHash != nil {
+				ub.Set(expression.Name("previousSecretHash"), expression.Value(atid.PreviousSecretHash))
+			}
+			if atid.PreviousSecretExpiresIn != nil {
+				ub.Set(expression.Name("previousSecretExpiresIn"), expression.Value(atid.PreviousSecretExpiresIn))
+			}
+			if atid.Generation != nil {
+				ub.Set(expression.Name("generation"), expression.Value(atid.Generation))
+			}
+			if atid.UsageCount != nil {
+				ub.Add(expression.Name("usageCount"), expression.Value(atid.UsageCount))
+			}
 		},
 	}
 }
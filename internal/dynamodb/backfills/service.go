@@ -0,0 +1,50 @@
+package backfills
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/dynamodb/services"
+	"philcali.me/recipes/internal/dynamodb/token"
+)
+
+func NewBackfillService(tableName string, client dynamodb.Client, marshaler token.TokenMarshaler) data.Repository[data.BackfillDTO, data.BackfillInputDTO] {
+	return &services.RepositoryDynamoDBService[data.BackfillDTO, data.BackfillInputDTO]{
+		DynamoDB:       client,
+		TableName:      tableName,
+		TokenMarshaler: marshaler,
+		Name:           "Backfill",
+		Shim: func(pk, sk string) data.BackfillDTO {
+			return data.BackfillDTO{PK: pk, SK: sk}
+		},
+		OnCreate: func(bid data.BackfillInputDTO, t time.Time, pk, sk string) data.BackfillDTO {
+			completed := false
+			if bid.Completed != nil {
+				completed = *bid.Completed
+			}
+			return data.BackfillDTO{
+				PK:           pk,
+				SK:           sk,
+				ResourceType: *bid.ResourceType,
+				PartnerId:    *bid.PartnerId,
+				NextToken:    bid.NextToken,
+				Completed:    completed,
+				CreateTime:   t,
+				UpdateTime:   t,
+			}
+		},
+		OnUpdate: func(bid data.BackfillInputDTO, ub expression.UpdateBuilder) {
+			if bid.NextToken != nil {
+				ub.Set(expression.Name("nextToken"), expression.Value(bid.NextToken))
+			}
+			if bid.Completed != nil {
+				ub.Set(expression.Name("completed"), expression.Value(bid.Completed))
+				if *bid.Completed {
+					ub.Remove(expression.Name("nextToken"))
+				}
+			}
+		},
+	}
+}
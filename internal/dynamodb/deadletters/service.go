@@ -0,0 +1,114 @@
+package deadletters
+
+import (
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/deadletters"
+	"philcali.me/recipes/internal/dynamodb/services"
+	"philcali.me/recipes/internal/dynamodb/token"
+	"philcali.me/recipes/internal/exceptions"
+)
+
+// Concurrent failures of a letter are retried when counting attempts
+const PUT_ATTEMPTS = 3
+
+func NewDeadLetterRepository(tableName string, client dynamodb.Client, marshaler token.TokenMarshaler) data.DeadLetterRepository {
+	return &services.RepositoryDynamoDBService[data.DeadLetterDTO, data.DeadLetterInputDTO]{
+		DynamoDB:       client,
+		TableName:      tableName,
+		TokenMarshaler: marshaler,
+		Name:           deadletters.NAME,
+		Shim: func(pk, sk string) data.DeadLetterDTO {
+			return data.DeadLetterDTO{PK: pk, SK: sk}
+		},
+		OnCreate: func(input data.DeadLetterInputDTO, t time.Time, pk, sk string) data.DeadLetterDTO {
+			return data.DeadLetterDTO{
+				PK:          pk,
+				SK:          sk,
+				HandlerName: *input.HandlerName,
+				EventId:     *input.EventId,
+				Record:      *input.Record,
+				Error:       *input.Error,
+				Attempts:    services.ValueOf(input.Attempts),
+				CreateTime:  t,
+				UpdateTime:  t,
+			}
+		},
+		OnUpdate: func(input data.DeadLetterInputDTO, ub expression.UpdateBuilder) {
+			if input.Error != nil {
+				ub.Set(expression.Name("error"), expression.Value(input.Error))
+			}
+			if input.Attempts != nil {
+				ub.Set(expression.Name("attempts"), expression.Value(input.Attempts))
+			}
+		},
+	}
+}
+
+// Keeps dead letters in their own partition of the table
+type DeadLetterDynamoDBService struct {
+	Letters data.DeadLetterRepository
+}
+
+func NewDeadLetterService(tableName string, client dynamodb.Client, marshaler token.TokenMarshaler) *DeadLetterDynamoDBService {
+	return &DeadLetterDynamoDBService{
+		Letters: NewDeadLetterRepository(tableName, client, marshaler),
+	}
+}
+
+func (ds *DeadLetterDynamoDBService) Put(handlerName string, record events.DynamoDBEventRecord, failure error) (data.DeadLetterDTO, error) {
+	id := deadletters.LetterId(handlerName, record)
+	encoded, err := deadletters.EncodeRecord(record)
+	if err != nil {
+		return data.DeadLetterDTO{}, err
+	}
+	message := failure.Error()
+	for attempt := 0; attempt < PUT_ATTEMPTS; attempt++ {
+		letter, err := ds.Letters.CreateWithItemId(deadletters.GLOBAL_ACCOUNT, data.DeadLetterInputDTO{
+			HandlerName: aws.String(handlerName),
+			EventId:     aws.String(record.EventID),
+			Record:      aws.String(encoded),
+			Error:       aws.String(message),
+			Attempts:    aws.Int(1),
+		}, id)
+		if _, ok := err.(*exceptions.ConflictError); !ok {
+			return letter, err
+		}
+		existing, err := ds.Letters.Get(deadletters.GLOBAL_ACCOUNT, id)
+		if _, ok := err.(*exceptions.NotFoundError); ok {
+			continue
+		}
+		if err != nil {
+			return existing, err
+		}
+		letter, err = ds.Letters.Patch(deadletters.GLOBAL_ACCOUNT, id, data.DeadLetterInputDTO{
+			Error:    aws.String(message),
+			Attempts: aws.Int(existing.Attempts + 1),
+		}, data.Patch{
+			Conditions: map[string]any{"attempts": existing.Attempts},
+		})
+		switch err.(type) {
+		case *exceptions.ConflictError, *exceptions.NotFoundError:
+			continue
+		}
+		return letter, err
+	}
+	return data.DeadLetterDTO{}, exceptions.Conflict("deadletter", id)
+}
+
+func (ds *DeadLetterDynamoDBService) Get(id string) (data.DeadLetterDTO, error) {
+	return ds.Letters.Get(deadletters.GLOBAL_ACCOUNT, id)
+}
+
+func (ds *DeadLetterDynamoDBService) List(params data.QueryParams) (data.QueryResults[data.DeadLetterDTO], error) {
+	return ds.Letters.List(deadletters.GLOBAL_ACCOUNT, params)
+}
+
+func (ds *DeadLetterDynamoDBService) Delete(id string) error {
+	return ds.Letters.Delete(deadletters.GLOBAL_ACCOUNT, id)
+}
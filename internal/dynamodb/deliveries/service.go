@@ -0,0 +1,57 @@
+package deliveries
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/dynamodb/services"
+	"philcali.me/recipes/internal/dynamodb/token"
+)
+
+func NewDeliveryService(tableName string, client dynamodb.Client, marshaler token.TokenMarshaler) data.Repository[data.DeliveryDTO, data.DeliveryInputDTO] {
+	return &services.RepositoryDynamoDBService[data.DeliveryDTO, data.DeliveryInputDTO]{
+		DynamoDB:       client,
+		TableName:      tableName,
+		TokenMarshaler: marshaler,
+		Name:           "Delivery",
+		Shim: func(pk, sk string) data.DeliveryDTO {
+			return data.DeliveryDTO{PK: pk, SK: sk}
+		},
+		OnCreate: func(did data.DeliveryInputDTO, t time.Time, pk, sk string) data.DeliveryDTO {
+			delivery := data.DeliveryDTO{
+				PK:              pk,
+				SK:              sk,
+				FirstIndex:      fmt.Sprintf("%s:%s:Delivery", *did.AccountId, *did.SubscriberId),
+				SubscriberId:    *did.SubscriberId,
+				Endpoint:        *did.Endpoint,
+				EventId:         *did.EventId,
+				EventType:       *did.EventType,
+				Payload:         *did.Payload,
+				Status:          *did.Status,
+				Attempts:        []data.DeliveryAttemptDTO{},
+				NextAttemptTime: did.NextAttemptTime,
+				ExpiresIn:       did.ExpiresIn,
+				CreateTime:      t,
+				UpdateTime:      t,
+			}
+			if did.Attempts != nil {
+				delivery.Attempts = *did.Attempts
+			}
+			return delivery
+		},
+		OnUpdate: func(did data.DeliveryInputDTO, ub expression.UpdateBuilder) {
+			if did.Status != nil {
+				ub.Set(expression.Name("status"), expression.Value(did.Status))
+			}
+			if did.Attempts != nil {
+				ub.Set(expression.Name("attempts"), expression.Value(did.Attempts))
+			}
+			if did.NextAttemptTime != nil {
+				ub.Set(expression.Name("nextAttemptTime"), expression.Value(did.NextAttemptTime))
+			}
+		},
+	}
+}
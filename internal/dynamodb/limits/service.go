@@ -0,0 +1,222 @@
+package limits
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"philcali.me/recipes/internal/limits"
+)
+
+// Contended buckets are retried, then the request is let through by the filter
+const RATE_LIMIT_ATTEMPTS = 3
+
+type _bucketItem struct {
+	PK         string    `dynamodbav:"PK"`
+	SK         string    `dynamodbav:"SK"`
+	Tokens     float64   `dynamodbav:"tokens"`
+	RefillTime time.Time `dynamodbav:"refillTime"`
+	ExpiresIn  int       `dynamodbav:"expiresIn"`
+}
+
+type _quotaItem struct {
+	PK    string `dynamodbav:"PK"`
+	SK    string `dynamodbav:"SK"`
+	Count int    `dynamodbav:"count"`
+	Limit *int   `dynamodbav:"limit"`
+}
+
+func _key(pk string, sk string) (map[string]types.AttributeValue, error) {
+	return attributevalue.MarshalMap(map[string]string{"PK": pk, "SK": sk})
+}
+
+func _isConditionalFailure(err error) bool {
+	var conditionFailed *types.ConditionalCheckFailedException
+	return errors.As(err, &conditionFailed)
+}
+
+type RateLimitDynamoDBService struct {
+	DynamoDB  dynamodb.Client
+	TableName string
+}
+
+func NewRateLimitService(tableName string, client dynamodb.Client) *RateLimitDynamoDBService {
+	return &RateLimitDynamoDBService{
+		DynamoDB:  client,
+		TableName: tableName,
+	}
+}
+
+func (rl *RateLimitDynamoDBService) _get(key map[string]types.AttributeValue) (*_bucketItem, error) {
+	response, err := rl.DynamoDB.GetItem(context.TODO(), &dynamodb.GetItemInput{
+		TableName:      aws.String(rl.TableName),
+		Key:            key,
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil || response.Item == nil {
+		return nil, err
+	}
+	var item _bucketItem
+	err = attributevalue.UnmarshalMap(response.Item, &item)
+	return &item, err
+}
+
+// Buckets are written optimistically, conditioned on the refill time read
+func (rl *RateLimitDynamoDBService) Take(key string, limit limits.Limit, now time.Time) (bool, time.Duration, error) {
+	pk := fmt.Sprintf("%s:RateLimit", key)
+	itemKey, err := _key(pk, "bucket")
+	if err != nil {
+		return false, 0, err
+	}
+	for attempt := 0; attempt < RATE_LIMIT_ATTEMPTS; attempt++ {
+		item, err := rl._get(itemKey)
+		if err != nil {
+			return false, 0, err
+		}
+		condition := expression.Name("PK").AttributeNotExists()
+		var current *limits.Bucket
+		if item != nil {
+			current = &limits.Bucket{Tokens: item.Tokens, RefillTime: item.RefillTime}
+			condition = expression.Name("refillTime").Equal(expression.Value(item.RefillTime))
+		}
+		bucket, allowed, retryAfter := limit.Take(current, now)
+		// Denials change nothing the next refill would not recompute
+		if !allowed {
+			return false, retryAfter, nil
+		}
+		updated, err := attributevalue.MarshalMap(_bucketItem{
+			PK:         pk,
+			SK:         "bucket",
+			Tokens:     bucket.Tokens,
+			RefillTime: bucket.RefillTime,
+			ExpiresIn:  int(now.Add(limit.FullAfter(bucket) + time.Hour).Unix()),
+		})
+		if err != nil {
+			return false, 0, err
+		}
+		expr, err := expression.NewBuilder().WithCondition(condition).Build()
+		if err != nil {
+			return false, 0, err
+		}
+		_, err = rl.DynamoDB.PutItem(context.TODO(), &dynamodb.PutItemInput{
+			TableName:                 aws.String(rl.TableName),
+			Item:                      updated,
+			ConditionExpression:       expr.Condition(),
+			ExpressionAttributeNames:  expr.Names(),
+			ExpressionAttributeValues: expr.Values(),
+		})
+		if _isConditionalFailure(err) {
+			continue
+		}
+		return err == nil, 0, err
+	}
+	return false, 0, fmt.Errorf("rate limit bucket %s is contended", key)
+}
+
+type QuotaDynamoDBService struct {
+	DynamoDB  dynamodb.Client
+	TableName string
+}
+
+func NewQuotaService(tableName string, client dynamodb.Client) *QuotaDynamoDBService {
+	return &QuotaDynamoDBService{
+		DynamoDB:  client,
+		TableName: tableName,
+	}
+}
+
+func (qs *QuotaDynamoDBService) _update(accountId string, resource string, builder expression.Builder) error {
+	key, err := _key(fmt.Sprintf("%s:Quota", accountId), resource)
+	if err != nil {
+		return err
+	}
+	expr, err := builder.Build()
+	if err != nil {
+		return err
+	}
+	_, err = qs.DynamoDB.UpdateItem(context.TODO(), &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(qs.TableName),
+		Key:                       key,
+		UpdateExpression:          expr.Update(),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	return err
+}
+
+// Removals of items counted before the count was seeded would take it below
+// zero, those are dropped instead
+func (qs *QuotaDynamoDBService) Add(accountId string, resource string, delta int) error {
+	builder := expression.NewBuilder().WithUpdate(expression.Add(expression.Name("count"), expression.Value(delta)))
+	if delta < 0 {
+		builder = builder.WithCondition(expression.Name("count").GreaterThanEqual(expression.Value(-delta)))
+	}
+	if err := qs._update(accountId, resource, builder); err != nil && !_isConditionalFailure(err) {
+		return err
+	}
+	return nil
+}
+
+// Records of the items counted by a quota share their partition, so they are
+// applied in order and any record at or before the last applied is a retry
+func (qs *QuotaDynamoDBService) Remove(accountId string, resource string, sequenceNumber string) error {
+	sequence := limits.SequenceOrder(sequenceNumber)
+	count, last := expression.Name("count"), expression.Name("sequence")
+	builder := expression.NewBuilder().
+		WithUpdate(expression.Add(count, expression.Value(-1)).Set(last, expression.Value(sequence))).
+		WithCondition(count.GreaterThanEqual(expression.Value(1)).
+			And(last.AttributeNotExists().Or(last.LessThan(expression.Value(sequence)))))
+	if err := qs._update(accountId, resource, builder); err != nil && !_isConditionalFailure(err) {
+		return err
+	}
+	return nil
+}
+
+func (qs *QuotaDynamoDBService) Reserve(accountId string, resource string, quota int) (bool, error) {
+	count, limit := expression.Name("count"), expression.Name("limit")
+	underQuota := count.LessThan(expression.Value(quota))
+	if quota > 0 {
+		underQuota = underQuota.Or(count.AttributeNotExists())
+	}
+	underLimit := count.LessThan(limit).Or(count.AttributeNotExists().And(limit.GreaterThan(expression.Value(0))))
+	condition := limit.AttributeNotExists().And(underQuota).Or(underLimit)
+	builder := expression.NewBuilder().
+		WithUpdate(expression.Add(count, expression.Value(1))).
+		WithCondition(condition)
+	err := qs._update(accountId, resource, builder)
+	if _isConditionalFailure(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (qs *QuotaDynamoDBService) Set(accountId string, resource string, count int) error {
+	return qs._update(accountId, resource, expression.NewBuilder().WithUpdate(expression.Set(expression.Name("count"), expression.Value(count))))
+}
+
+// Accounts can carry a limit on their quota item to override the configured quota
+func (qs *QuotaDynamoDBService) Usage(accountId string, resource string) (int, *int, error) {
+	key, err := _key(fmt.Sprintf("%s:Quota", accountId), resource)
+	if err != nil {
+		return 0, nil, err
+	}
+	response, err := qs.DynamoDB.GetItem(context.TODO(), &dynamodb.GetItemInput{
+		TableName: aws.String(qs.TableName),
+		Key:       key,
+	})
+	if err != nil || response.Item == nil {
+		return 0, nil, err
+	}
+	var item _quotaItem
+	if err := attributevalue.UnmarshalMap(response.Item, &item); err != nil {
+		return 0, nil, err
+	}
+	return item.Count, item.Limit, nil
+}
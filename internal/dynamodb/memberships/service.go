@@ -0,0 +1,50 @@
+package memberships
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/dynamodb/services"
+	"philcali.me/recipes/internal/dynamodb/token"
+)
+
+func NewMembershipService(tableName string, client dynamodb.Client, marshaler token.TokenMarshaler) data.Repository[data.MembershipDTO, data.MembershipInputDTO] {
+	return &services.RepositoryDynamoDBService[data.MembershipDTO, data.MembershipInputDTO]{
+		DynamoDB:       client,
+		TableName:      tableName,
+		TokenMarshaler: marshaler,
+		Name:           "Membership",
+		Shim: func(pk, sk string) data.MembershipDTO {
+			return data.MembershipDTO{PK: pk, SK: sk}
+		},
+		// Indexed by the invited email, so invitees can find their households
+		OnCreate: func(mid data.MembershipInputDTO, t time.Time, pk, sk string) data.MembershipDTO {
+			return data.MembershipDTO{
+				PK:          pk,
+				SK:          sk,
+				FirstIndex:  fmt.Sprintf("%s:Membership", sk),
+				HouseholdId: *mid.HouseholdId,
+				MemberId:    mid.MemberId,
+				Role:        *mid.Role,
+				Status:      *mid.Status,
+				InvitedBy:   *mid.InvitedBy,
+				CreateTime:  t,
+				UpdateTime:  t,
+			}
+		},
+		OnUpdate: func(mid data.MembershipInputDTO, ub expression.UpdateBuilder) {
+			if mid.MemberId != nil {
+				ub.Set(expression.Name("memberId"), expression.Value(mid.MemberId))
+			}
+			if mid.Role != nil {
+				ub.Set(expression.Name("role"), expression.Value(mid.Role))
+			}
+			if mid.Status != nil {
+				ub.Set(expression.Name("status"), expression.Value(mid.Status))
+			}
+		},
+	}
+}
@@ -0,0 +1,75 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"philcali.me/recipes/internal/migrations"
+)
+
+type _migrationItem struct {
+	PK           string    `dynamodbav:"PK"`
+	SK           string    `dynamodbav:"SK"`
+	CompleteTime time.Time `dynamodbav:"completeTime"`
+}
+
+// Completed migrations never revert, so they are remembered once read
+type MigrationDynamoDBService struct {
+	DynamoDB  dynamodb.Client
+	TableName string
+	completed map[string]bool
+}
+
+func NewMigrationService(tableName string, client dynamodb.Client) *MigrationDynamoDBService {
+	return &MigrationDynamoDBService{
+		DynamoDB:  client,
+		TableName: tableName,
+		completed: make(map[string]bool),
+	}
+}
+
+func _pk() string {
+	return fmt.Sprintf("%s:%s", migrations.GLOBAL_ACCOUNT, migrations.NAME)
+}
+
+func (ms *MigrationDynamoDBService) IsComplete(name string) (bool, error) {
+	if ms.completed[name] {
+		return true, nil
+	}
+	key, err := attributevalue.MarshalMap(map[string]string{"PK": _pk(), "SK": name})
+	if err != nil {
+		return false, err
+	}
+	response, err := ms.DynamoDB.GetItem(context.TODO(), &dynamodb.GetItemInput{
+		TableName: aws.String(ms.TableName),
+		Key:       key,
+	})
+	if err != nil {
+		return false, err
+	}
+	ms.completed[name] = len(response.Item) > 0
+	return ms.completed[name], nil
+}
+
+func (ms *MigrationDynamoDBService) Complete(name string) error {
+	item, err := attributevalue.MarshalMap(_migrationItem{
+		PK:           _pk(),
+		SK:           name,
+		CompleteTime: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = ms.DynamoDB.PutItem(context.TODO(), &dynamodb.PutItemInput{
+		TableName: aws.String(ms.TableName),
+		Item:      item,
+	})
+	if err == nil {
+		ms.completed[name] = true
+	}
+	return err
+}
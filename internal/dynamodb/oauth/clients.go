@@ -0,0 +1,53 @@
+package oauth
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/dynamodb/services"
+	"philcali.me/recipes/internal/dynamodb/token"
+)
+
+func NewClientService(tableName string, client dynamodb.Client, marshaler token.TokenMarshaler) data.Repository[data.OAuthClientDTO, data.OAuthClientInputDTO] {
+	return &services.RepositoryDynamoDBService[data.OAuthClientDTO, data.OAuthClientInputDTO]{
+		DynamoDB:       client,
+		TableName:      tableName,
+		TokenMarshaler: marshaler,
+		Name:           "OAuthClient",
+		Shim: func(pk, sk string) data.OAuthClientDTO {
+			return data.OAuthClientDTO{PK: pk, SK: sk}
+		},
+		OnCreate: func(ocid data.OAuthClientInputDTO, t time.Time, pk, sk string) data.OAuthClientDTO {
+			client := data.OAuthClientDTO{
+				PK:           pk,
+				SK:           sk,
+				FirstIndex:   fmt.Sprintf("%s:OAuthClient", *ocid.AccountId),
+				AccountId:    *ocid.AccountId,
+				Name:         *ocid.Name,
+				RedirectUris: *ocid.RedirectUris,
+				Scopes:       *ocid.Scopes,
+				Claims:       *ocid.Claims,
+				CreateTime:   t,
+				UpdateTime:   t,
+			}
+			if ocid.Confidential != nil {
+				client.Confidential = *ocid.Confidential
+			}
+			if ocid.SecretHash != nil {
+				client.SecretHash = *ocid.SecretHash
+			}
+			return client
+		},
+		OnUpdate: func(ocid data.OAuthClientInputDTO, ub expression.UpdateBuilder) {
+			if ocid.Name != nil {
+				ub.Set(expression.Name("name"), expression.Value(ocid.Name))
+			}
+			if ocid.RedirectUris != nil {
+				ub.Set(expression.Name("redirectUris"), expression.Value(ocid.RedirectUris))
+			}
+		},
+	}
+}
@@ -0,0 +1,39 @@
+package oauth
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/dynamodb/services"
+	"philcali.me/recipes/internal/dynamodb/token"
+)
+
+func NewAuthorizationCodeService(tableName string, client dynamodb.Client, marshaler token.TokenMarshaler) data.Repository[data.AuthorizationCodeDTO, data.AuthorizationCodeInputDTO] {
+	return &services.RepositoryDynamoDBService[data.AuthorizationCodeDTO, data.AuthorizationCodeInputDTO]{
+		DynamoDB:       client,
+		TableName:      tableName,
+		TokenMarshaler: marshaler,
+		Name:           "AuthorizationCode",
+		Shim: func(pk, sk string) data.AuthorizationCodeDTO {
+			return data.AuthorizationCodeDTO{PK: pk, SK: sk}
+		},
+		OnCreate: func(acid data.AuthorizationCodeInputDTO, t time.Time, pk, sk string) data.AuthorizationCodeDTO {
+			return data.AuthorizationCodeDTO{
+				PK:                  pk,
+				SK:                  sk,
+				ClientId:            *acid.ClientId,
+				AccountId:           *acid.AccountId,
+				GrantId:             *acid.GrantId,
+				RedirectUri:         *acid.RedirectUri,
+				Scopes:              *acid.Scopes,
+				Claims:              *acid.Claims,
+				CodeChallenge:       *acid.CodeChallenge,
+				CodeChallengeMethod: *acid.CodeChallengeMethod,
+				ExpiresIn:           *acid.ExpiresIn,
+				CreateTime:          t,
+				UpdateTime:          t,
+			}
+		},
+	}
+}
@@ -0,0 +1,44 @@
+package oauth
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/dynamodb/services"
+	"philcali.me/recipes/internal/dynamodb/token"
+)
+
+func NewConsentService(tableName string, client dynamodb.Client, marshaler token.TokenMarshaler) data.Repository[data.OAuthConsentDTO, data.OAuthConsentInputDTO] {
+	return &services.RepositoryDynamoDBService[data.OAuthConsentDTO, data.OAuthConsentInputDTO]{
+		DynamoDB:       client,
+		TableName:      tableName,
+		TokenMarshaler: marshaler,
+		Name:           "OAuthConsent",
+		Shim: func(pk, sk string) data.OAuthConsentDTO {
+			return data.OAuthConsentDTO{PK: pk, SK: sk}
+		},
+		OnCreate: func(ocid data.OAuthConsentInputDTO, t time.Time, pk, sk string) data.OAuthConsentDTO {
+			return data.OAuthConsentDTO{
+				PK:         pk,
+				SK:         sk,
+				FirstIndex: fmt.Sprintf("%s:OAuthConsent", sk),
+				GrantId:    *ocid.GrantId,
+				ClientName: *ocid.ClientName,
+				Scopes:     *ocid.Scopes,
+				CreateTime: t,
+				UpdateTime: t,
+			}
+		},
+		OnUpdate: func(ocid data.OAuthConsentInputDTO, ub expression.UpdateBuilder) {
+			if ocid.ClientName != nil {
+				ub.Set(expression.Name("clientName"), expression.Value(ocid.ClientName))
+			}
+			if ocid.Scopes != nil {
+				ub.Set(expression.Name("scopes"), expression.Value(ocid.Scopes))
+			}
+		},
+	}
+}
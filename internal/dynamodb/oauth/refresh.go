@@ -0,0 +1,37 @@
+package oauth
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/dynamodb/services"
+	"philcali.me/recipes/internal/dynamodb/token"
+)
+
+func NewRefreshTokenService(tableName string, client dynamodb.Client, marshaler token.TokenMarshaler) data.Repository[data.RefreshTokenDTO, data.RefreshTokenInputDTO] {
+	return &services.RepositoryDynamoDBService[data.RefreshTokenDTO, data.RefreshTokenInputDTO]{
+		DynamoDB:       client,
+		TableName:      tableName,
+		TokenMarshaler: marshaler,
+		Name:           "RefreshToken",
+		Shim: func(pk, sk string) data.RefreshTokenDTO {
+			return data.RefreshTokenDTO{PK: pk, SK: sk}
+		},
+		OnCreate: func(rtid data.RefreshTokenInputDTO, t time.Time, pk, sk string) data.RefreshTokenDTO {
+			return data.RefreshTokenDTO{
+				PK:         pk,
+				SK:         sk,
+				ClientId:   *rtid.ClientId,
+				AccountId:  *rtid.AccountId,
+				GrantId:    *rtid.GrantId,
+				Scopes:     *rtid.Scopes,
+				Claims:     *rtid.Claims,
+				SecretHash: *rtid.SecretHash,
+				ExpiresIn:  *rtid.ExpiresIn,
+				CreateTime: t,
+				UpdateTime: t,
+			}
+		},
+	}
+}
@@ -25,9 +25,9 @@ func NewRecipeService(tableName string, client dynamodb.Client, marshaler token.
 				PK:                 pk,
 				SK:                 sk,
 				Name:               *input.Name,
-				Instructions:       *input.Instructions,
-				Ingredients:        *input.Ingredients,
-				Nutrients:          *input.Nutrients,
+				Instructions:       aws.ToString(input.Instructions),
+				Ingredients:        services.ValueOf(input.Ingredients),
+				Nutrients:          services.ValueOf(input.Nutrients),
 				Thumbnail:          input.Thumbnail,
 				UpdateToken:        input.UpdateToken,
 				Type:               input.Type,
@@ -0,0 +1,49 @@
+package revocations
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/dynamodb/services"
+	"philcali.me/recipes/internal/dynamodb/token"
+)
+
+func NewRevokedTokenService(tableName string, client dynamodb.Client, marshaler token.TokenMarshaler) data.Repository[data.RevokedTokenDTO, data.RevokedTokenInputDTO] {
+	return &services.RepositoryDynamoDBService[data.RevokedTokenDTO, data.RevokedTokenInputDTO]{
+		DynamoDB:       client,
+		TableName:      tableName,
+		TokenMarshaler: marshaler,
+		Name:           "RevokedToken",
+		Shim: func(pk, sk string) data.RevokedTokenDTO {
+			return data.RevokedTokenDTO{PK: pk, SK: sk}
+		},
+		OnCreate: func(rtid data.RevokedTokenInputDTO, t time.Time, pk, sk string) data.RevokedTokenDTO {
+			revoked := data.RevokedTokenDTO{
+				PK:            pk,
+				SK:            sk,
+				AccountId:     *rtid.AccountId,
+				EffectiveTime: rtid.EffectiveTime,
+				ExpiresIn:     rtid.ExpiresIn,
+				CreateTime:    t,
+				UpdateTime:    t,
+			}
+			if rtid.Generation != nil {
+				revoked.Generation = *rtid.Generation
+			}
+			return revoked
+		},
+		OnUpdate: func(rtid data.RevokedTokenInputDTO, ub expression.UpdateBuilder) {
+			if rtid.Generation != nil {
+				ub.Set(expression.Name("generation"), expression.Value(rtid.Generation))
+			}
+			if rtid.EffectiveTime != nil {
+				ub.Set(expression.Name("effectiveTime"), expression.Value(rtid.EffectiveTime))
+			}
+			if rtid.ExpiresIn != nil {
+				ub.Set(expression.Name("expiresIn"), expression.Value(rtid.ExpiresIn))
+			}
+		},
+	}
+}
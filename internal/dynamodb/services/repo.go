@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -27,6 +28,15 @@ type RepositoryDynamoDBService[T interface{}, I interface{}] struct {
 	OnUpdate       func(I, expression.UpdateBuilder)
 }
 
+// Optional inputs are stored as their zero value on create
+func ValueOf[T interface{}](value *T) T {
+	var zero T
+	if value == nil {
+		return zero
+	}
+	return *value
+}
+
 func _getPrimaryKey(accountId string, name string) string {
 	return fmt.Sprintf("%s:%s", accountId, name)
 }
@@ -128,6 +138,12 @@ func (rs *RepositoryDynamoDBService[T, I]) CreateWithItemId(accountId string, in
 }
 
 func (rs *RepositoryDynamoDBService[T, I]) Update(accountId string, itemId string, input I) (T, error) {
+	return rs.Patch(accountId, itemId, input, data.Patch{})
+}
+
+// Paths address list elements and their fields, ie: items[2].completed, so
+// concurrent changes to different elements leave each other alone
+func (rs *RepositoryDynamoDBService[T, I]) Patch(accountId string, itemId string, input I, patch data.Patch) (T, error) {
 	pk := _getPrimaryKey(accountId, rs.Name)
 	shim := rs.Shim(pk, itemId)
 	key, err := _getKey(pk, itemId)
@@ -138,6 +154,20 @@ func (rs *RepositoryDynamoDBService[T, I]) Update(accountId string, itemId strin
 	update := expression.Set(expression.Name("updateTime"), expression.Value(updateTime))
 	condition := expression.Name("PK").AttributeExists().And(expression.Name("SK").AttributeExists())
 	rs.OnUpdate(input, update)
+	for _, change := range patch.Changes {
+		name := expression.Name(change.Path)
+		switch {
+		case change.Remove:
+			update.Remove(name)
+		case change.Append:
+			update.Set(name, expression.ListAppend(name, expression.Value(change.Value)))
+		default:
+			update.Set(name, expression.Value(change.Value))
+		}
+	}
+	for path, expected := range patch.Conditions {
+		condition = condition.And(expression.Name(path).Equal(expression.Value(expected)))
+	}
 	expr, err := expression.NewBuilder().WithCondition(condition).WithUpdate(update).Build()
 	if err != nil {
 		return shim, err
@@ -153,6 +183,9 @@ func (rs *RepositoryDynamoDBService[T, I]) Update(accountId string, itemId strin
 	})
 	if err != nil {
 		if strings.Contains(err.Error(), "ConditionalCheckFailedException") {
+			if len(patch.Conditions) > 0 {
+				return shim, exceptions.Conflict(strings.ToLower(rs.Name), itemId)
+			}
 			return shim, exceptions.NotFound(strings.ToLower(rs.Name), itemId)
 		}
 		return shim, err
@@ -194,3 +227,32 @@ func (rs *RepositoryDynamoDBService[T, I]) Delete(accountId string, itemId strin
 	})
 	return err
 }
+
+func (rs *RepositoryDynamoDBService[T, I]) Take(accountId string, itemId string) (T, error) {
+	pk := _getPrimaryKey(accountId, rs.Name)
+	shim := rs.Shim(pk, itemId)
+	key, err := _getKey(pk, itemId)
+	if err != nil {
+		return shim, err
+	}
+	expr, err := expression.NewBuilder().WithCondition(expression.Name("SK").AttributeExists()).Build()
+	if err != nil {
+		return shim, err
+	}
+	response, err := rs.DynamoDB.DeleteItem(context.TODO(), &dynamodb.DeleteItemInput{
+		Key:                      key,
+		TableName:                aws.String(rs.TableName),
+		ConditionExpression:      expr.Condition(),
+		ExpressionAttributeNames: expr.Names(),
+		ReturnValues:             types.ReturnValueAllOld,
+	})
+	if err != nil {
+		var conditionFailed *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionFailed) {
+			return shim, exceptions.NotFound(strings.ToLower(rs.Name), itemId)
+		}
+		return shim, err
+	}
+	err = attributevalue.UnmarshalMap(response.Attributes, &shim)
+	return shim, err
+}
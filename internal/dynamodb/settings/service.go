@@ -3,6 +3,7 @@ package settings
 import (
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"philcali.me/recipes/internal/data"
@@ -23,8 +24,10 @@ func NewSettingService(tableName string, client dynamodb.Client, marshaler token
 			return data.SettingsDTO{
 				PK:               pk,
 				SK:               sk,
-				AutoShareLists:   *sid.AutoShareLists,
-				AutoShareRecipes: *sid.AutoShareRecipes,
+				AutoShareLists:   aws.ToBool(sid.AutoShareLists),
+				AutoShareRecipes: aws.ToBool(sid.AutoShareRecipes),
+				Categories:       services.ValueOf(sid.Categories),
+				StoreLayout:      services.ValueOf(sid.StoreLayout),
 				CreateTime:       t,
 				UpdateTime:       t,
 			}
@@ -36,6 +39,12 @@ func NewSettingService(tableName string, client dynamodb.Client, marshaler token
 			if sid.AutoShareRecipes != nil {
 				ub.Set(expression.Name("autoShareRecipes"), expression.Value(sid.AutoShareRecipes))
 			}
+			if sid.Categories != nil {
+				ub.Set(expression.Name("categories"), expression.Value(sid.Categories))
+			}
+			if sid.StoreLayout != nil {
+				ub.Set(expression.Name("storeLayout"), expression.Value(sid.StoreLayout))
+			}
 		},
 	}
 }
@@ -26,7 +26,7 @@ func NewShoppingListService(tableName string, client dynamodb.Client, marshaler
 				Owner:       slid.Owner,
 				Shared:      aws.Bool(false),
 				Name:        *slid.Name,
-				Items:       *slid.Items,
+				Items:       services.ValueOf(slid.Items),
 				ExpiresIn:   slid.ExpiresIn,
 				UpdateToken: slid.UpdateToken,
 			}
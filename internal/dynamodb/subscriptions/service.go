@@ -3,6 +3,7 @@ package subscriptions
 import (
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"philcali.me/recipes/internal/data"
 	"philcali.me/recipes/internal/dynamodb/services"
@@ -19,14 +20,32 @@ func NewSubscriptionService(tableName string, client dynamodb.Client, marshaler
 			return data.SubscriptionDTO{PK: pk, SK: sk}
 		},
 		OnCreate: func(sid data.SubscriptionInputDTO, createTime time.Time, pk, sk string) data.SubscriptionDTO {
-			return data.SubscriptionDTO{
-				PK:            pk,
-				SK:            sk,
-				CreateTime:    createTime,
-				UpdateTime:    createTime,
-				Endpoint:      *sid.Endpoint,
-				Protocol:      *sid.Protocol,
-				SubscriberArn: *sid.SubscriberArn,
+			subscription := data.SubscriptionDTO{
+				PK:                 pk,
+				SK:                 sk,
+				CreateTime:         createTime,
+				UpdateTime:         createTime,
+				Endpoint:           *sid.Endpoint,
+				Protocol:           *sid.Protocol,
+				Secret:             sid.Secret,
+				EventTypes:         sid.EventTypes,
+				ResourceTypes:      sid.ResourceTypes,
+				ConfirmationStatus: data.CONFIRMATION_PENDING,
+			}
+			if sid.SubscriberArn != nil {
+				subscription.SubscriberArn = *sid.SubscriberArn
+			}
+			if sid.ConfirmationStatus != nil {
+				subscription.ConfirmationStatus = *sid.ConfirmationStatus
+			}
+			return subscription
+		},
+		OnUpdate: func(sid data.SubscriptionInputDTO, ub expression.UpdateBuilder) {
+			if sid.SubscriberArn != nil {
+				ub.Set(expression.Name("subscriberArn"), expression.Value(sid.SubscriberArn))
+			}
+			if sid.ConfirmationStatus != nil {
+				ub.Set(expression.Name("confirmationStatus"), expression.Value(sid.ConfirmationStatus))
 			}
 		},
 	}
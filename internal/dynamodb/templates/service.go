@@ -0,0 +1,70 @@
+package templates
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/dynamodb/services"
+	"philcali.me/recipes/internal/dynamodb/token"
+)
+
+const (
+	// Every template is indexed under the site wide account
+	GLOBAL_ACCOUNT = "Global"
+	NAME           = "ShoppingListTemplate"
+)
+
+func NewShoppingListTemplateService(tableName string, client dynamodb.Client, marshaler token.TokenMarshaler) data.Repository[data.ShoppingListTemplateDTO, data.ShoppingListTemplateInputDTO] {
+	return &services.RepositoryDynamoDBService[data.ShoppingListTemplateDTO, data.ShoppingListTemplateInputDTO]{
+		DynamoDB:       client,
+		TableName:      tableName,
+		TokenMarshaler: marshaler,
+		Name:           NAME,
+		Shim: func(pk, sk string) data.ShoppingListTemplateDTO {
+			return data.ShoppingListTemplateDTO{PK: pk, SK: sk}
+		},
+		OnCreate: func(tid data.ShoppingListTemplateInputDTO, createTime time.Time, pk, sk string) data.ShoppingListTemplateDTO {
+			return data.ShoppingListTemplateDTO{
+				PK:               pk,
+				SK:               sk,
+				FirstIndex:       fmt.Sprintf("%s:%s", GLOBAL_ACCOUNT, NAME),
+				Name:             *tid.Name,
+				Owner:            tid.Owner,
+				Items:            services.ValueOf(tid.Items),
+				Recurrence:       tid.Recurrence,
+				MergeUnchecked:   services.ValueOf(tid.MergeUnchecked),
+				ExpiresAfterDays: tid.ExpiresAfterDays,
+				NextRunTime:      tid.NextRunTime,
+				LastListId:       tid.LastListId,
+				CreateTime:       createTime,
+				UpdateTime:       createTime,
+			}
+		},
+		OnUpdate: func(tid data.ShoppingListTemplateInputDTO, ub expression.UpdateBuilder) {
+			if tid.Name != nil {
+				ub.Set(expression.Name("name"), expression.Value(tid.Name))
+			}
+			if tid.Items != nil {
+				ub.Set(expression.Name("items"), expression.Value(tid.Items))
+			}
+			if tid.Recurrence != nil {
+				ub.Set(expression.Name("recurrence"), expression.Value(tid.Recurrence))
+			}
+			if tid.MergeUnchecked != nil {
+				ub.Set(expression.Name("mergeUnchecked"), expression.Value(tid.MergeUnchecked))
+			}
+			if tid.ExpiresAfterDays != nil {
+				ub.Set(expression.Name("expiresAfterDays"), expression.Value(tid.ExpiresAfterDays))
+			}
+			if tid.NextRunTime != nil {
+				ub.Set(expression.Name("nextRunTime"), expression.Value(tid.NextRunTime))
+			}
+			if tid.LastListId != nil {
+				ub.Set(expression.Name("lastListId"), expression.Value(tid.LastListId))
+			}
+		},
+	}
+}
@@ -1,6 +1,7 @@
 package events
 
 import (
+	"reflect"
 	"strings"
 	"time"
 
@@ -73,9 +74,39 @@ type CreateAuditEntryHandler struct {
 	ResourceTypes []string
 }
 
+// Fields the authorizer touches on use, which are not worth an audit entry
+var _usageFields = map[string]bool{
+	"lastUsedTime": true,
+	"usageCount":   true,
+	"updateTime":   true,
+}
+
+func _isUsageOnlyChange(record events.DynamoDBEventRecord) bool {
+	if record.EventName != "MODIFY" {
+		return false
+	}
+	oldValues := _flattenResourceProperties(record.Change.OldImage)
+	newValues := _flattenResourceProperties(record.Change.NewImage)
+	if oldValues == nil || newValues == nil || len(*oldValues) > len(*newValues) {
+		return false
+	}
+	for field, value := range *newValues {
+		if _usageFields[field] {
+			continue
+		}
+		if !reflect.DeepEqual(value, (*oldValues)[field]) {
+			return false
+		}
+	}
+	return true
+}
+
 func (ch *CreateAuditEntryHandler) Filter(record events.DynamoDBEventRecord) bool {
 	pk := _getRecordImage(record)["PK"]
 	parts := strings.Split(pk.String(), ":")
+	if parts[1] == "ApiToken" && _isUsageOnlyChange(record) {
+		return false
+	}
 	for _, t := range ch.ResourceTypes {
 		if t == parts[1] {
 			return true
@@ -197,6 +197,24 @@ func TestAudits(t *testing.T) {
 					t.Fatalf("Expected no error, but got %v", err)
 				}
 			}
+
+			used := events.DynamoDBEventRecord{
+				EventName: "MODIFY",
+				Change: events.DynamoDBStreamRecord{
+					OldImage: modify.Change.NewImage,
+					NewImage: map[string]events.DynamoDBAttributeValue{
+						"name":         events.NewStringAttribute("A Very Tasty Treat"),
+						"SK":           events.NewStringAttribute(id),
+						"PK":           events.NewStringAttribute("Global:ApiToken"),
+						"GS1-PK":       events.NewStringAttribute(pk),
+						"lastUsedTime": events.NewStringAttribute("2024-01-01T00:00:00Z"),
+						"usageCount":   events.NewNumberAttribute("3"),
+					},
+				},
+			}
+			if handler.Filter(used) {
+				t.Fatalf("Expected usage updates to not be audited %v", used)
+			}
 		})
 	})
 }
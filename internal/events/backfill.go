@@ -0,0 +1,157 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/exceptions"
+	"philcali.me/recipes/internal/limits"
+)
+
+func _copyBackfillItem[T interface{}](tableName string, partnerId string, resource T, ddb *dynamodb.Client, quotas *limits.Quotas) error {
+	item, err := attributevalue.MarshalMap(resource)
+	if err != nil {
+		return err
+	}
+	// Resources shared with the owner are not the owner's to share
+	if shared, ok := item["shared"].(*types.AttributeValueMemberBOOL); ok && shared.Value {
+		return nil
+	}
+	pk, ok := item["PK"].(*types.AttributeValueMemberS)
+	if !ok {
+		return fmt.Errorf("backfill resource is missing a PK: %v", item)
+	}
+	parts := strings.Split(pk.Value, ":")
+	item["PK"] = &types.AttributeValueMemberS{
+		Value: fmt.Sprintf("%s:%s", partnerId, parts[1]),
+	}
+	item["shared"] = &types.AttributeValueMemberBOOL{
+		Value: true,
+	}
+	release, err := quotas.Reserve(partnerId, limits.QUOTA_RESOURCE_TYPES[parts[1]])
+	if err != nil {
+		fmt.Printf("Skipping the backfill of a %s for %s: %v\n", parts[1], partnerId, err)
+		return nil
+	}
+	_, err = ddb.PutItem(context.TODO(), &dynamodb.PutItemInput{
+		Item:                item,
+		TableName:           aws.String(tableName),
+		ConditionExpression: aws.String("attribute_not_exists(PK) and attribute_not_exists(SK)"),
+	})
+	if err != nil {
+		release()
+		var conditionFailed *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionFailed) {
+			return nil
+		}
+	}
+	return err
+}
+
+func _backfillResources[T interface{}, I interface{}](bh *BackfillSharedResourceHandler, repo data.Repository[T, I], ownerId string, partnerId string, shareId string, resourceType string) error {
+	checkpointId := fmt.Sprintf("%s:%s", shareId, resourceType)
+	checkpoint, err := bh.Backfill.Get(ownerId, checkpointId)
+	if err != nil {
+		if _, ok := err.(*exceptions.NotFoundError); !ok {
+			return err
+		}
+		checkpoint, err = bh.Backfill.CreateWithItemId(ownerId, data.BackfillInputDTO{
+			ResourceType: aws.String(resourceType),
+			PartnerId:    aws.String(partnerId),
+		}, checkpointId)
+		if err != nil {
+			return err
+		}
+	}
+	// Every page is recorded on the checkpoint, so a timed out invocation
+	// picks up from the last page that was fully copied
+	nextToken := checkpoint.NextToken
+	for !checkpoint.Completed {
+		results, err := repo.List(ownerId, data.QueryParams{
+			Limit:     100,
+			NextToken: nextToken,
+		})
+		if err != nil {
+			return err
+		}
+		for _, item := range results.Items {
+			if err := _copyBackfillItem(bh.TableName, partnerId, item, bh.DynamoDB, bh.Quotas); err != nil {
+				return err
+			}
+		}
+		nextToken = results.NextToken
+		checkpoint, err = bh.Backfill.Update(ownerId, checkpointId, data.BackfillInputDTO{
+			NextToken: nextToken,
+			Completed: aws.Bool(nextToken == nil),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func _isShareApproval(record events.DynamoDBEventRecord) bool {
+	pk := record.Change.Keys["PK"]
+	parts := strings.Split(pk.String(), ":")
+	return record.EventName == "MODIFY" &&
+		parts[1] == "ShareRequest" &&
+		!record.Change.NewImage["approverId"].IsNull() &&
+		record.Change.NewImage["approvalStatus"].String() == "APPROVED"
+}
+
+type BackfillSharedResourceHandler struct {
+	Setting   data.SettingsRepository
+	Backfill  data.BackfillRepository
+	Recipes   data.RecipeDataService
+	Lists     data.ShoppingListDataService
+	Quotas    *limits.Quotas
+	DynamoDB  *dynamodb.Client
+	TableName string
+}
+
+func (bh *BackfillSharedResourceHandler) Filter(record events.DynamoDBEventRecord) bool {
+	return _isShareApproval(record) &&
+		record.Change.OldImage["approvalStatus"].String() != "APPROVED"
+}
+
+func (bh *BackfillSharedResourceHandler) Apply(record events.DynamoDBEventRecord) error {
+	pk := record.Change.Keys["PK"]
+	parts := strings.Split(pk.String(), ":")
+	requesterId := parts[0]
+	approverId := record.Change.NewImage["approverId"].String()
+	shareId := record.Change.NewImage["SK"].String()
+	partners := [][2]string{
+		{requesterId, approverId},
+		{approverId, requesterId},
+	}
+	for _, partner := range partners {
+		ownerId := partner[0]
+		s, err := bh.Setting.Get(ownerId, "Global")
+		if err != nil {
+			if _, ok := err.(*exceptions.NotFoundError); ok {
+				continue
+			}
+			return err
+		}
+		if s.AutoShareRecipes {
+			if err := _backfillResources(bh, bh.Recipes, ownerId, partner[1], shareId, "Recipe"); err != nil {
+				return err
+			}
+		}
+		if s.AutoShareLists {
+			if err := _backfillResources(bh, bh.Lists, ownerId, partner[1], shareId, "ShoppingList"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
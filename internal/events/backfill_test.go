@@ -0,0 +1,150 @@
+package events
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/google/uuid"
+	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/dynamodb/backfills"
+	"philcali.me/recipes/internal/dynamodb/recipes"
+	"philcali.me/recipes/internal/dynamodb/settings"
+	"philcali.me/recipes/internal/dynamodb/shopping"
+	"philcali.me/recipes/internal/dynamodb/token"
+	"philcali.me/recipes/internal/test"
+)
+
+func TestBackfillResources(t *testing.T) {
+	localServer := test.StartLocalServer(test.LOCAL_DDB_PORT+2, t)
+	client, err := localServer.CreateLocalClient()
+	if err != nil {
+		t.Fatalf("Failed to create DDB client: %s", err)
+	}
+	tableName, err := test.CreateTable(client)
+	if err != nil {
+		t.Fatalf("Failed to create DDB table: %s", err)
+	}
+	t.Logf("Successfully created local resources running on %d", test.LOCAL_DDB_PORT)
+	marshaler := token.NewGCM()
+
+	t.Run("BackfillHandler", func(t *testing.T) {
+		settingData := settings.NewSettingService(tableName, *client, marshaler)
+		backfillData := backfills.NewBackfillService(tableName, *client, marshaler)
+		recipeData := recipes.NewRecipeService(tableName, *client, marshaler)
+		listData := shopping.NewShoppingListService(tableName, *client, marshaler)
+
+		handler := &BackfillSharedResourceHandler{
+			Setting:   settingData,
+			Backfill:  backfillData,
+			Recipes:   recipeData,
+			Lists:     listData,
+			DynamoDB:  client,
+			TableName: tableName,
+		}
+
+		requesterId := uuid.NewString()
+		approverId := uuid.NewString()
+		shareId := uuid.NewString()
+
+		_, err := settingData.CreateWithItemId(requesterId, data.SettingsInputDTO{
+			AutoShareLists:   aws.Bool(true),
+			AutoShareRecipes: aws.Bool(false),
+		}, "Global")
+		if err != nil {
+			t.Fatalf("Failed to create settings: %v", err)
+		}
+
+		for i := 0; i < 3; i++ {
+			_, err := listData.Create(requesterId, data.ShoppingListInputDTO{
+				Name:  aws.String(fmt.Sprintf("List %d", i)),
+				Owner: aws.String("nobody@email.com"),
+				Items: &[]data.ShoppingListItemDTO{
+					{
+						Name: "Milk",
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create list: %v", err)
+			}
+		}
+
+		_, err = recipeData.Create(requesterId, data.RecipeInputDTO{
+			Name:         aws.String("Soup"),
+			Instructions: aws.String("Boil water"),
+			Ingredients:  &[]data.IngredientDTO{},
+			Nutrients:    &[]data.NutrientDTO{},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create recipe: %v", err)
+		}
+
+		approved := events.DynamoDBEventRecord{
+			EventName: "MODIFY",
+			Change: events.DynamoDBStreamRecord{
+				Keys: map[string]events.DynamoDBAttributeValue{
+					"PK": events.NewStringAttribute(fmt.Sprintf("%s:ShareRequest", requesterId)),
+					"SK": events.NewStringAttribute(shareId),
+				},
+				OldImage: map[string]events.DynamoDBAttributeValue{
+					"PK":             events.NewStringAttribute(fmt.Sprintf("%s:ShareRequest", requesterId)),
+					"SK":             events.NewStringAttribute(shareId),
+					"approvalStatus": events.NewStringAttribute("REQUESTED"),
+				},
+				NewImage: map[string]events.DynamoDBAttributeValue{
+					"PK":             events.NewStringAttribute(fmt.Sprintf("%s:ShareRequest", requesterId)),
+					"SK":             events.NewStringAttribute(shareId),
+					"approverId":     events.NewStringAttribute(approverId),
+					"approvalStatus": events.NewStringAttribute("APPROVED"),
+				},
+			},
+		}
+
+		if !handler.Filter(approved) {
+			t.Fatalf("Expected the approval to be filtered %v", approved)
+		}
+
+		reapproved := approved
+		reapproved.Change.OldImage = approved.Change.NewImage
+		if handler.Filter(reapproved) {
+			t.Fatalf("Expected an already approved request to skip %v", reapproved)
+		}
+
+		if err := handler.Apply(approved); err != nil {
+			t.Fatalf("Failed to backfill resources: %v", err)
+		}
+
+		copiedLists, err := listData.List(approverId, data.QueryParams{})
+		if err != nil {
+			t.Fatalf("Failed to list copied lists: %v", err)
+		}
+		if len(copiedLists.Items) != 3 {
+			t.Fatalf("Expected 3 copied lists, got %d", len(copiedLists.Items))
+		}
+		if copiedLists.Items[0].Shared == nil || !*copiedLists.Items[0].Shared {
+			t.Fatalf("Expected the copied list to be shared: %v", copiedLists.Items[0])
+		}
+
+		copiedRecipes, err := recipeData.List(approverId, data.QueryParams{})
+		if err != nil {
+			t.Fatalf("Failed to list copied recipes: %v", err)
+		}
+		if len(copiedRecipes.Items) != 0 {
+			t.Fatalf("Expected recipes to not be shared, got %d", len(copiedRecipes.Items))
+		}
+
+		checkpoint, err := backfillData.Get(requesterId, fmt.Sprintf("%s:ShoppingList", shareId))
+		if err != nil {
+			t.Fatalf("Failed to get the backfill checkpoint: %v", err)
+		}
+		if !checkpoint.Completed || checkpoint.NextToken != nil {
+			t.Fatalf("Expected the checkpoint to be completed: %v", checkpoint)
+		}
+
+		if err := handler.Apply(approved); err != nil {
+			t.Fatalf("Expected a completed backfill to be idempotent: %v", err)
+		}
+	})
+}
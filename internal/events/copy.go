@@ -2,14 +2,17 @@ package events
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/limits"
 )
 
 func _convertStreamAttribute(attr events.DynamoDBAttributeValue) types.AttributeValue {
@@ -85,7 +88,13 @@ func _sharedResourceFilter(t string) bool {
 	return t == "Recipe" || t == "ShoppingList"
 }
 
-func _copyShareResource(tableName string, ownerId string, condition string, record events.DynamoDBEventRecord, ddb *dynamodb.Client, shareRepo data.ShareRequestRepository) error {
+// Copies that create an item reserve the quota of the partner, updates pass no quotas
+func _copyShareResource(tableName string, ownerId string, condition expression.ConditionBuilder, record events.DynamoDBEventRecord, ddb *dynamodb.Client, shareRepo data.ShareRequestRepository, quotas *limits.Quotas) error {
+	resource := limits.QUOTA_RESOURCE_TYPES[ResourceType(record)]
+	expr, err := expression.NewBuilder().WithCondition(condition).Build()
+	if err != nil {
+		return err
+	}
 	var nextToken *string
 	truncated := true
 	for truncated {
@@ -108,15 +117,24 @@ func _copyShareResource(tableName string, ownerId string, condition string, reco
 				otherAccountId = item.ApproverId
 			}
 
+			release, err := quotas.Reserve(*otherAccountId, resource)
+			if err != nil {
+				fmt.Printf("Skipping the copy of %s for %s: %v\n", record.Change.Keys["SK"].String(), *otherAccountId, err)
+				continue
+			}
 			converted := _convertStreamImageToItem(*otherAccountId, record.Change.NewImage)
-			_, err := ddb.PutItem(context.TODO(), &dynamodb.PutItemInput{
-				Item:                converted,
-				TableName:           aws.String(tableName),
-				ConditionExpression: aws.String(condition),
+			_, err = ddb.PutItem(context.TODO(), &dynamodb.PutItemInput{
+				Item:                      converted,
+				TableName:                 aws.String(tableName),
+				ConditionExpression:       expr.Condition(),
+				ExpressionAttributeNames:  expr.Names(),
+				ExpressionAttributeValues: expr.Values(),
 			})
 
 			if err != nil {
-				if _, ok := err.(*types.ConditionalCheckFailedException); ok {
+				release()
+				var conditionFailed *types.ConditionalCheckFailedException
+				if errors.As(err, &conditionFailed) {
 					continue
 				}
 				return err
@@ -148,19 +166,33 @@ func (uh *UpdateSharedResourceHandler) Apply(record events.DynamoDBEventRecord)
 	pk := record.Change.Keys["PK"]
 	parts := strings.Split(pk.String(), ":")
 	ownerId := parts[0]
+	// Lists are collaborative: edits on any copy are merged into the others
+	if _, ok := record.Change.NewImage["updateToken"]; ok && parts[1] == "ShoppingList" {
+		return _syncSharedList(uh.TableName, ownerId, record, uh.DynamoDB, uh.Sharing)
+	}
+	// Retried or replayed records never overwrite a copy that has moved past them
+	condition := expression.Name("PK").AttributeExists().And(expression.Name("SK").AttributeExists())
+	if updateTime, ok := record.Change.NewImage["updateTime"]; ok && updateTime.DataType() == events.DataTypeString {
+		condition = condition.And(expression.Or(
+			expression.Name("updateTime").AttributeNotExists(),
+			expression.Name("updateTime").LessThanEqual(expression.Value(updateTime.String())),
+		))
+	}
 	return _copyShareResource(
 		uh.TableName,
 		ownerId,
-		"attribute_exists(PK) and attribute_exists(SK)",
+		condition,
 		record,
 		uh.DynamoDB,
 		uh.Sharing,
+		nil,
 	)
 }
 
 type CopySharingResourceHandler struct {
 	Setting   data.SettingsRepository
 	Sharing   data.ShareRequestRepository
+	Quotas    *limits.Quotas
 	DynamoDB  *dynamodb.Client
 	TableName string
 }
@@ -191,9 +223,10 @@ func (ch *CopySharingResourceHandler) Apply(record events.DynamoDBEventRecord) e
 	return _copyShareResource(
 		ch.TableName,
 		ownerId,
-		"attribute_not_exists(PK) and attribute_not_exists(SK)",
+		expression.Name("PK").AttributeNotExists().And(expression.Name("SK").AttributeNotExists()),
 		record,
 		ch.DynamoDB,
 		ch.Sharing,
+		ch.Quotas,
 	)
 }
@@ -10,6 +10,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/google/uuid"
 	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/dynamodb/recipes"
 	"philcali.me/recipes/internal/dynamodb/settings"
 	"philcali.me/recipes/internal/dynamodb/shares"
 	"philcali.me/recipes/internal/dynamodb/shopping"
@@ -170,6 +171,69 @@ func TestCopyResources(t *testing.T) {
 		}
 	})
 
+	t.Run("StaleUpdate", func(t *testing.T) {
+		sharingData := shares.NewShareService(tableName, *client, marshaler)
+		recipeData := recipes.NewRecipeService(tableName, *client, marshaler)
+		updateHandler := &UpdateSharedResourceHandler{
+			Sharing:   sharingData,
+			DynamoDB:  client,
+			TableName: tableName,
+		}
+
+		accountId := uuid.NewString()
+		partnerId := uuid.NewString()
+		approvalStatus := data.APPROVED
+		if _, err := sharingData.Create(accountId, data.ShareRequestInputDTO{
+			Requester:      aws.String("owner@example.com"),
+			RequesterId:    aws.String(accountId),
+			Approver:       aws.String("partner@example.com"),
+			ApproverId:     aws.String(partnerId),
+			ApprovalStatus: &approvalStatus,
+		}); err != nil {
+			t.Fatalf("Expected to create a share entry, got %v", err)
+		}
+		itemId := uuid.NewString()
+		copied, err := recipeData.CreateWithItemId(partnerId, data.RecipeInputDTO{
+			Name: aws.String("Current"),
+		}, itemId)
+		if err != nil {
+			t.Fatalf("Failed to create the copy: %v", err)
+		}
+
+		modified := func(name string, updateTime time.Time) events.DynamoDBEventRecord {
+			content, _ := updateTime.MarshalText()
+			return events.DynamoDBEventRecord{
+				EventName: "MODIFY",
+				Change: events.DynamoDBStreamRecord{
+					Keys: map[string]events.DynamoDBAttributeValue{
+						"PK": events.NewStringAttribute(fmt.Sprintf("%s:Recipe", accountId)),
+						"SK": events.NewStringAttribute(itemId),
+					},
+					NewImage: map[string]events.DynamoDBAttributeValue{
+						"PK":         events.NewStringAttribute(fmt.Sprintf("%s:Recipe", accountId)),
+						"SK":         events.NewStringAttribute(itemId),
+						"name":       events.NewStringAttribute(name),
+						"updateTime": events.NewStringAttribute(string(content)),
+					},
+				},
+			}
+		}
+
+		// A replayed record the copy has moved past is skipped
+		if err := updateHandler.Apply(modified("Stale", copied.UpdateTime.Add(-time.Hour))); err != nil {
+			t.Fatalf("Expected a stale record to be skipped, got %v", err)
+		}
+		if recipe, err := recipeData.Get(partnerId, itemId); err != nil || recipe.Name != "Current" {
+			t.Fatalf("Expected the copy to be kept, got %v: %v", recipe, err)
+		}
+		if err := updateHandler.Apply(modified("Newer", copied.UpdateTime.Add(time.Hour))); err != nil {
+			t.Fatalf("Expected a newer record to be copied, got %v", err)
+		}
+		if recipe, err := recipeData.Get(partnerId, itemId); err != nil || recipe.Name != "Newer" {
+			t.Fatalf("Expected the copy to be updated, got %v: %v", recipe, err)
+		}
+	})
+
 	t.Run("CopyHandler", func(t *testing.T) {
 		settingData := settings.NewSettingService(tableName, *client, marshaler)
 		sharingData := shares.NewShareService(tableName, *client, marshaler)
@@ -0,0 +1,179 @@
+package events
+
+import (
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"philcali.me/recipes/internal/deadletters"
+	"philcali.me/recipes/internal/dynamodb/audits"
+	"philcali.me/recipes/internal/dynamodb/backfills"
+	dynamoDeadLetters "philcali.me/recipes/internal/dynamodb/deadletters"
+	"philcali.me/recipes/internal/dynamodb/deliveries"
+	limitData "philcali.me/recipes/internal/dynamodb/limits"
+	"philcali.me/recipes/internal/dynamodb/migrations"
+	"philcali.me/recipes/internal/dynamodb/recipes"
+	"philcali.me/recipes/internal/dynamodb/settings"
+	"philcali.me/recipes/internal/dynamodb/shares"
+	"philcali.me/recipes/internal/dynamodb/shopping"
+	"philcali.me/recipes/internal/dynamodb/subscriptions"
+	"philcali.me/recipes/internal/dynamodb/token"
+	"philcali.me/recipes/internal/dynamodb/users"
+	"philcali.me/recipes/internal/limits"
+	"philcali.me/recipes/internal/notifications"
+	"philcali.me/recipes/internal/sns/services"
+)
+
+func NewMessageChannel(cfg aws.Config) notifications.MessageChannel {
+	if topicArn, ok := os.LookupEnv("MESSAGE_TOPIC_ARN"); ok {
+		return &services.MessageSNSChannel{
+			Sns:      *sns.NewFromConfig(cfg),
+			TopicArn: topicArn,
+		}
+	}
+	return &notifications.LoggingMessageChannel{}
+}
+
+// Dead letters are kept in the table, or in DEAD_LETTER_FILE when run locally
+func DefaultDeadLetters(cfg aws.Config, tableName string) deadletters.Store {
+	if location, ok := os.LookupEnv("DEAD_LETTER_FILE"); ok {
+		return deadletters.NewFileStore(location)
+	}
+	client := dynamodb.NewFromConfig(cfg)
+	return dynamoDeadLetters.NewDeadLetterService(tableName, *client, token.NewGCM())
+}
+
+func DefaultRouter(cfg aws.Config, tableName string) (*Router, error) {
+	router := NewRouter()
+	router.DeadLetters = DefaultDeadLetters(cfg, tableName)
+	routes, err := DefaultRoutes(cfg, tableName)
+	if err != nil {
+		return nil, err
+	}
+	for _, route := range routes {
+		if err := router.Register(route); err != nil {
+			return nil, err
+		}
+	}
+	return router, nil
+}
+
+// The handlers of the table stream, named for their dead letters
+func DefaultRoutes(cfg aws.Config, tableName string) ([]Route, error) {
+	client := dynamodb.NewFromConfig(cfg)
+	marshaler := token.NewGCM()
+	userData := users.NewUserService(tableName, *client, marshaler)
+	auditData := audits.NewAuditService(tableName, *client, marshaler)
+	shareData := shares.NewShareService(tableName, *client, marshaler)
+	settingData := settings.NewSettingService(tableName, *client, marshaler)
+	backfillData := backfills.NewBackfillService(tableName, *client, marshaler)
+	recipeData := recipes.NewRecipeService(tableName, *client, marshaler)
+	listData := shopping.NewShoppingListService(tableName, *client, marshaler)
+	subscriptionData := subscriptions.NewSubscriptionService(tableName, *client, marshaler)
+	deliveryData := deliveries.NewDeliveryService(tableName, *client, marshaler)
+	quotaCounter := limitData.NewQuotaService(tableName, *client)
+	quotas, err := limits.QuotasFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	channel := NewMessageChannel(cfg)
+	publisher := &services.NotificationSNSService{
+		Sns:      *sns.NewFromConfig(cfg),
+		TopicArn: os.Getenv("TOPIC_ARN"),
+	}
+	auditHandler := DefaultAuditHandler(auditData)
+	resources := []string{"Recipe", "ShoppingList", "ShareRequest"}
+	shared := []string{"Recipe", "ShoppingList"}
+	shareRequests := []string{"ShareRequest"}
+
+	return []Route{
+		{
+			Name:          "users",
+			ResourceTypes: []string{"Subscription"},
+			EventNames:    []string{"INSERT", "REMOVE"},
+			Handler:       DefaultUserHandler(userData),
+		},
+		{
+			Name:          "audits",
+			ResourceTypes: auditHandler.ResourceTypes,
+			Handler:       auditHandler,
+		},
+		{
+			Name:          "publish",
+			ResourceTypes: resources,
+			Handler:       DefaultPublishHandler(publisher, migrations.NewMigrationService(tableName, *client)),
+		},
+		{
+			Name:          "webhooks",
+			ResourceTypes: resources,
+			Handler:       DefaultWebhookHandler(subscriptionData, deliveryData),
+		},
+		{
+			Name:          "delete-associated-shares",
+			ResourceTypes: shareRequests,
+			EventNames:    []string{"REMOVE"},
+			Handler:       DefaultDeleteAssociatedHandler(shareData),
+		},
+		{
+			Name:          "copy-approved-shares",
+			ResourceTypes: shareRequests,
+			EventNames:    []string{"MODIFY"},
+			Handler:       DefaultCopyApprovedRequestHandler(shareData),
+		},
+		{
+			Name:          "share-invitations",
+			ResourceTypes: shareRequests,
+			EventNames:    []string{"INSERT"},
+			Handler:       DefaultShareInvitationHandler(channel),
+		},
+		{
+			Name:          "share-responses",
+			ResourceTypes: shareRequests,
+			EventNames:    []string{"MODIFY"},
+			Handler:       DefaultShareResponseHandler(channel),
+		},
+		{
+			Name:          "quotas",
+			ResourceTypes: shared,
+			EventNames:    []string{"REMOVE"},
+			Handler:       DefaultQuotaCounterHandler(quotaCounter),
+		},
+		{
+			Name:          "backfill-shared-resources",
+			ResourceTypes: shareRequests,
+			EventNames:    []string{"MODIFY"},
+			Handler: &BackfillSharedResourceHandler{
+				Setting:   settingData,
+				Backfill:  backfillData,
+				Recipes:   recipeData,
+				Lists:     listData,
+				Quotas:    limits.NewQuotas(quotaCounter, quotas),
+				DynamoDB:  client,
+				TableName: tableName,
+			},
+		},
+		{
+			Name:          "copy-sharing-resources",
+			ResourceTypes: shared,
+			EventNames:    []string{"INSERT"},
+			Handler: &CopySharingResourceHandler{
+				Sharing:   shareData,
+				Setting:   settingData,
+				Quotas:    limits.NewQuotas(quotaCounter, quotas),
+				DynamoDB:  client,
+				TableName: tableName,
+			},
+		},
+		{
+			Name:          "update-shared-resources",
+			ResourceTypes: shared,
+			EventNames:    []string{"MODIFY"},
+			Handler: &UpdateSharedResourceHandler{
+				Sharing:   shareData,
+				DynamoDB:  client,
+				TableName: tableName,
+			},
+		},
+	}, nil
+}
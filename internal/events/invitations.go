@@ -0,0 +1,84 @@
+package events
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/notifications"
+)
+
+type ShareInvitationHandler struct {
+	Channel notifications.MessageChannel
+}
+
+func (sh *ShareInvitationHandler) Filter(record events.DynamoDBEventRecord) bool {
+	pk := record.Change.Keys["PK"]
+	parts := strings.Split(pk.String(), ":")
+	return record.EventName == "INSERT" &&
+		parts[1] == "ShareRequest" &&
+		!record.Change.NewImage["approver"].IsNull() &&
+		record.Change.NewImage["approvalStatus"].String() == string(data.REQUESTED)
+}
+
+func (sh *ShareInvitationHandler) Apply(record events.DynamoDBEventRecord) error {
+	requester := record.Change.NewImage["requester"].String()
+	return sh.Channel.Send(notifications.MessageInput{
+		Recipient: record.Change.NewImage["approver"].String(),
+		Type:      notifications.SHARE_REQUESTED,
+		Subject:   fmt.Sprintf("%s wants to share with you", requester),
+		Body: fmt.Sprintf(
+			"%s would like to share recipes and shopping lists with you. Review share request %s to approve or reject it.",
+			requester,
+			record.Change.NewImage["SK"].String(),
+		),
+	})
+}
+
+type ShareResponseHandler struct {
+	Channel notifications.MessageChannel
+}
+
+func (sh *ShareResponseHandler) Filter(record events.DynamoDBEventRecord) bool {
+	pk := record.Change.Keys["PK"]
+	parts := strings.Split(pk.String(), ":")
+	status := record.Change.NewImage["approvalStatus"].String()
+	return record.EventName == "MODIFY" &&
+		parts[1] == "ShareRequest" &&
+		record.Change.OldImage["approvalStatus"].String() == string(data.REQUESTED) &&
+		(status == string(data.APPROVED) || status == string(data.REJECTED))
+}
+
+func (sh *ShareResponseHandler) Apply(record events.DynamoDBEventRecord) error {
+	approver := record.Change.NewImage["approver"].String()
+	messageType := notifications.SHARE_REJECTED
+	verb := "rejected"
+	if record.Change.NewImage["approvalStatus"].String() == string(data.APPROVED) {
+		messageType = notifications.SHARE_APPROVED
+		verb = "approved"
+	}
+	return sh.Channel.Send(notifications.MessageInput{
+		Recipient: record.Change.NewImage["requester"].String(),
+		Type:      messageType,
+		Subject:   fmt.Sprintf("%s %s your share request", approver, verb),
+		Body: fmt.Sprintf(
+			"%s %s share request %s.",
+			approver,
+			verb,
+			record.Change.NewImage["SK"].String(),
+		),
+	})
+}
+
+func DefaultShareInvitationHandler(channel notifications.MessageChannel) *ShareInvitationHandler {
+	return &ShareInvitationHandler{
+		Channel: channel,
+	}
+}
+
+func DefaultShareResponseHandler(channel notifications.MessageChannel) *ShareResponseHandler {
+	return &ShareResponseHandler{
+		Channel: channel,
+	}
+}
@@ -0,0 +1,88 @@
+package events
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"philcali.me/recipes/internal/notifications"
+)
+
+type RecordingChannel struct {
+	Messages []notifications.MessageInput
+}
+
+func (rc *RecordingChannel) Send(input notifications.MessageInput) error {
+	rc.Messages = append(rc.Messages, input)
+	return nil
+}
+
+func TestShareInvitations(t *testing.T) {
+	pk := events.NewStringAttribute(fmt.Sprintf("%s:ShareRequest", "nobody"))
+	requested := map[string]events.DynamoDBAttributeValue{
+		"PK":             pk,
+		"SK":             events.NewStringAttribute("abc-123"),
+		"requester":      events.NewStringAttribute("nobody@email.com"),
+		"approver":       events.NewStringAttribute("nobody2@email.com"),
+		"approvalStatus": events.NewStringAttribute("REQUESTED"),
+	}
+
+	t.Run("InvitationHandler", func(t *testing.T) {
+		channel := &RecordingChannel{}
+		handler := DefaultShareInvitationHandler(channel)
+		insert := events.DynamoDBEventRecord{
+			EventName: "INSERT",
+			Change: events.DynamoDBStreamRecord{
+				Keys:     map[string]events.DynamoDBAttributeValue{"PK": pk},
+				NewImage: requested,
+			},
+		}
+		if !handler.Filter(insert) {
+			t.Fatalf("Expected the share request to be filtered %v", insert)
+		}
+		if err := handler.Apply(insert); err != nil {
+			t.Fatalf("Failed to send invitation: %v", err)
+		}
+		if len(channel.Messages) != 1 || channel.Messages[0].Recipient != "nobody2@email.com" {
+			t.Fatalf("Expected an invitation to the approver, got %v", channel.Messages)
+		}
+		if channel.Messages[0].Type != notifications.SHARE_REQUESTED {
+			t.Fatalf("Expected %s, got %s", notifications.SHARE_REQUESTED, channel.Messages[0].Type)
+		}
+	})
+
+	t.Run("ResponseHandler", func(t *testing.T) {
+		channel := &RecordingChannel{}
+		handler := DefaultShareResponseHandler(channel)
+		for _, status := range []string{"APPROVED", "REJECTED"} {
+			responded := make(map[string]events.DynamoDBAttributeValue, len(requested))
+			for k, v := range requested {
+				responded[k] = v
+			}
+			responded["approvalStatus"] = events.NewStringAttribute(status)
+			modify := events.DynamoDBEventRecord{
+				EventName: "MODIFY",
+				Change: events.DynamoDBStreamRecord{
+					Keys:     map[string]events.DynamoDBAttributeValue{"PK": pk},
+					OldImage: requested,
+					NewImage: responded,
+				},
+			}
+			if !handler.Filter(modify) {
+				t.Fatalf("Expected the %s response to be filtered %v", status, modify)
+			}
+			if err := handler.Apply(modify); err != nil {
+				t.Fatalf("Failed to send response: %v", err)
+			}
+		}
+		if len(channel.Messages) != 2 {
+			t.Fatalf("Expected 2 messages, got %v", channel.Messages)
+		}
+		if channel.Messages[0].Type != notifications.SHARE_APPROVED || channel.Messages[1].Type != notifications.SHARE_REJECTED {
+			t.Fatalf("Unexpected message types %v", channel.Messages)
+		}
+		if channel.Messages[0].Recipient != "nobody@email.com" {
+			t.Fatalf("Expected the requester to be notified, got %s", channel.Messages[0].Recipient)
+		}
+	})
+}
@@ -0,0 +1,142 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"philcali.me/recipes/internal/migrations"
+	"philcali.me/recipes/internal/notifications"
+)
+
+type ResourceEvent struct {
+	Id           string                  `json:"id"`
+	Type         string                  `json:"type"`
+	AccountId    string                  `json:"accountId"`
+	ResourceType string                  `json:"resourceType"`
+	ResourceId   string                  `json:"resourceId"`
+	NewValues    *map[string]interface{} `json:"newValues,omitempty"`
+	OldValues    *map[string]interface{} `json:"oldValues,omitempty"`
+	Time         time.Time               `json:"time"`
+}
+
+type PublishResourceEventHandler struct {
+	Notifications notifications.NotificationService
+	// Maps the resource type of the PK to an event prefix, ie: Recipe -> recipe
+	ResourceTypes map[string]string
+	// Events are held back until subscriptions are filtered by account, when set
+	Migrations migrations.Store
+}
+
+func _eventAction(eventName string) string {
+	switch eventName {
+	case "INSERT":
+		return "created"
+	case "MODIFY":
+		return "updated"
+	case "REMOVE":
+		return "deleted"
+	}
+	return strings.ToLower(eventName)
+}
+
+// Share requests are described by their approval status, ie: share.approved
+func _shareEventType(record events.DynamoDBEventRecord) string {
+	status := strings.ToLower(record.Change.NewImage["approvalStatus"].String())
+	switch record.EventName {
+	case "INSERT":
+		if status != "" {
+			return "share." + status
+		}
+	case "MODIFY":
+		if status != "" && status != strings.ToLower(record.Change.OldImage["approvalStatus"].String()) {
+			return "share." + status
+		}
+	}
+	return fmt.Sprintf("share.%s", _eventAction(record.EventName))
+}
+
+func _resourceEventType(resourceTypes map[string]string, record events.DynamoDBEventRecord, resourceType string) string {
+	if resourceType == "ShareRequest" {
+		return _shareEventType(record)
+	}
+	return fmt.Sprintf("%s.%s", resourceTypes[resourceType], _eventAction(record.EventName))
+}
+
+func _filterResourceEvent(resourceTypes map[string]string, record events.DynamoDBEventRecord) bool {
+	pk := record.Change.Keys["PK"]
+	parts := strings.Split(pk.String(), ":")
+	if len(parts) < 2 {
+		return false
+	}
+	_, ok := resourceTypes[parts[1]]
+	return ok
+}
+
+func _newResourceEvent(resourceTypes map[string]string, record events.DynamoDBEventRecord) ResourceEvent {
+	pk := record.Change.Keys["PK"]
+	parts := strings.Split(pk.String(), ":")
+	eventTime := time.Now()
+	if !record.Change.ApproximateCreationDateTime.IsZero() {
+		eventTime = record.Change.ApproximateCreationDateTime.Time
+	}
+	return ResourceEvent{
+		Id:           record.EventID,
+		Type:         _resourceEventType(resourceTypes, record, parts[1]),
+		AccountId:    parts[0],
+		ResourceType: parts[1],
+		ResourceId:   record.Change.Keys["SK"].String(),
+		NewValues:    _flattenResourceProperties(record.Change.NewImage),
+		OldValues:    _flattenResourceProperties(record.Change.OldImage),
+		Time:         eventTime,
+	}
+}
+
+func (ph *PublishResourceEventHandler) Filter(record events.DynamoDBEventRecord) bool {
+	return _filterResourceEvent(ph.ResourceTypes, record)
+}
+
+func (ph *PublishResourceEventHandler) Apply(record events.DynamoDBEventRecord) error {
+	if ph.Migrations != nil {
+		filtered, err := ph.Migrations.IsComplete(migrations.FILTER_SUBSCRIPTIONS)
+		if err != nil {
+			return err
+		}
+		// Unfiltered subscriptions would receive the events of every account
+		if !filtered {
+			fmt.Printf("Holding back %s until subscriptions are filtered by account\n", record.EventID)
+			return nil
+		}
+	}
+	event := _newResourceEvent(ph.ResourceTypes, record)
+	message, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = ph.Notifications.Publish(notifications.PublishInput{
+		AccountId:    event.AccountId,
+		EventType:    event.Type,
+		ResourceType: event.ResourceType,
+		ResourceId:   event.ResourceId,
+		Message:      string(message),
+	})
+	return err
+}
+
+func _defaultEventResourceTypes() map[string]string {
+	return map[string]string{
+		"Recipe":       "recipe",
+		"ShoppingList": "list",
+		"ShareRequest": "share",
+	}
+}
+
+func DefaultPublishHandler(service notifications.NotificationService, store migrations.Store) *PublishResourceEventHandler {
+	return &PublishResourceEventHandler{
+		Notifications: service,
+		ResourceTypes: _defaultEventResourceTypes(),
+		Migrations:    store,
+	}
+}
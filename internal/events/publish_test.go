@@ -0,0 +1,124 @@
+package events
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"philcali.me/recipes/internal/migrations"
+	"philcali.me/recipes/internal/notifications"
+)
+
+type RecordingNotifications struct {
+	Published []notifications.PublishInput
+}
+
+func (rn *RecordingNotifications) Subscribe(input notifications.SubscribeInput) (*notifications.SubscribeOutput, error) {
+	return &notifications.SubscribeOutput{SubscriberId: *input.Endpoint}, nil
+}
+
+func (rn *RecordingNotifications) Unsubscribe(subscriberId string) error {
+	return nil
+}
+
+func (rn *RecordingNotifications) IsPendingConfirmation(subscriberId string) (bool, error) {
+	return false, nil
+}
+
+func (rn *RecordingNotifications) SetFilter(subscriberId string, input notifications.SubscribeInput) error {
+	return nil
+}
+
+func (rn *RecordingNotifications) Publish(input notifications.PublishInput) (*notifications.PublishOutput, error) {
+	rn.Published = append(rn.Published, input)
+	return &notifications.PublishOutput{MessageId: input.ResourceId}, nil
+}
+
+func TestPublishEvents(t *testing.T) {
+	t.Run("PublishHandler", func(t *testing.T) {
+		recorder := &RecordingNotifications{}
+		store := migrations.NewMemoryStore()
+		handler := DefaultPublishHandler(recorder, store)
+
+		settings := events.DynamoDBEventRecord{
+			EventName: "INSERT",
+			Change: events.DynamoDBStreamRecord{
+				Keys: map[string]events.DynamoDBAttributeValue{
+					"PK": events.NewStringAttribute("012345678912:Settings"),
+					"SK": events.NewStringAttribute("Global"),
+				},
+			},
+		}
+		if handler.Filter(settings) {
+			t.Fatalf("Expected settings to not be published %v", settings)
+		}
+
+		modify := events.DynamoDBEventRecord{
+			EventID:   "event-1",
+			EventName: "MODIFY",
+			Change: events.DynamoDBStreamRecord{
+				Keys: map[string]events.DynamoDBAttributeValue{
+					"PK": events.NewStringAttribute("012345678912:Recipe"),
+					"SK": events.NewStringAttribute("abc-123"),
+				},
+				OldImage: map[string]events.DynamoDBAttributeValue{
+					"name": events.NewStringAttribute("Soup"),
+				},
+				NewImage: map[string]events.DynamoDBAttributeValue{
+					"name": events.NewStringAttribute("Stew"),
+				},
+			},
+		}
+		if !handler.Filter(modify) {
+			t.Fatalf("Expected the recipe to be published %v", modify)
+		}
+		if err := handler.Apply(modify); err != nil || len(recorder.Published) != 0 {
+			t.Fatalf("Expected events to be held back until subscriptions are filtered, got %v: %v", recorder.Published, err)
+		}
+		store.Complete(migrations.FILTER_SUBSCRIPTIONS)
+		if err := handler.Apply(modify); err != nil {
+			t.Fatalf("Failed to publish: %v", err)
+		}
+		if len(recorder.Published) != 1 {
+			t.Fatalf("Expected 1 published event, got %d", len(recorder.Published))
+		}
+		published := recorder.Published[0]
+		if published.AccountId != "012345678912" || published.EventType != "recipe.updated" {
+			t.Fatalf("Unexpected published attributes %v", published)
+		}
+		var event ResourceEvent
+		if err := json.Unmarshal([]byte(published.Message), &event); err != nil {
+			t.Fatalf("Failed to parse published message: %v", err)
+		}
+		if event.ResourceId != "abc-123" || (*event.NewValues)["name"] != "Stew" {
+			t.Fatalf("Unexpected published event %v", event)
+		}
+	})
+
+	t.Run("ShareEventTypes", func(t *testing.T) {
+		keys := map[string]events.DynamoDBAttributeValue{
+			"PK": events.NewStringAttribute("012345678912:ShareRequest"),
+			"SK": events.NewStringAttribute("abc-123"),
+		}
+		requested := map[string]events.DynamoDBAttributeValue{
+			"approvalStatus": events.NewStringAttribute("REQUESTED"),
+		}
+		approved := map[string]events.DynamoDBAttributeValue{
+			"approvalStatus": events.NewStringAttribute("APPROVED"),
+		}
+		for expected, record := range map[string]events.DynamoDBEventRecord{
+			notifications.SHARE_REQUESTED: {EventName: "INSERT", Change: events.DynamoDBStreamRecord{Keys: keys, NewImage: requested}},
+			notifications.SHARE_APPROVED:  {EventName: "MODIFY", Change: events.DynamoDBStreamRecord{Keys: keys, OldImage: requested, NewImage: approved}},
+			notifications.SHARE_UPDATED:   {EventName: "MODIFY", Change: events.DynamoDBStreamRecord{Keys: keys, OldImage: approved, NewImage: approved}},
+			notifications.SHARE_DELETED:   {EventName: "REMOVE", Change: events.DynamoDBStreamRecord{Keys: keys, OldImage: approved}},
+		} {
+			event := _newResourceEvent(_defaultEventResourceTypes(), record)
+			if event.Type != expected {
+				t.Fatalf("Expected %s, got %s", expected, event.Type)
+			}
+			if !notifications.ValidEventType(event.Type) {
+				t.Fatalf("Expected %s to be a valid event type", event.Type)
+			}
+		}
+	})
+}
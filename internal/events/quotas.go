@@ -0,0 +1,33 @@
+package events
+
+import (
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"philcali.me/recipes/internal/limits"
+)
+
+// Releases the quota of removed items, creates reserve theirs before the
+// item is written, see limits.Quotas
+type QuotaCounterHandler struct {
+	Counter limits.QuotaCounter
+	// Maps the resource type of the PK to a quota resource, ie: Recipe -> recipes
+	ResourceTypes map[string]string
+}
+
+func (qh *QuotaCounterHandler) Filter(record events.DynamoDBEventRecord) bool {
+	return record.EventName == "REMOVE" && _filterResourceEvent(qh.ResourceTypes, record)
+}
+
+func (qh *QuotaCounterHandler) Apply(record events.DynamoDBEventRecord) error {
+	parts := strings.Split(record.Change.Keys["PK"].String(), ":")
+	// Records are redelivered when another handler fails them
+	return qh.Counter.Remove(parts[0], qh.ResourceTypes[parts[1]], record.Change.SequenceNumber)
+}
+
+func DefaultQuotaCounterHandler(counter limits.QuotaCounter) *QuotaCounterHandler {
+	return &QuotaCounterHandler{
+		Counter:       counter,
+		ResourceTypes: limits.QUOTA_RESOURCE_TYPES,
+	}
+}
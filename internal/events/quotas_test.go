@@ -0,0 +1,64 @@
+package events
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"philcali.me/recipes/internal/limits"
+)
+
+func TestQuotaCounterHandler(t *testing.T) {
+	counter := limits.NewMemoryQuotaCounter()
+	handler := DefaultQuotaCounterHandler(counter)
+	sequenceNumber := 100
+	record := func(eventName string, pk string) events.DynamoDBEventRecord {
+		sequenceNumber++
+		return events.DynamoDBEventRecord{
+			EventName: eventName,
+			Change: events.DynamoDBStreamRecord{
+				SequenceNumber: fmt.Sprint(sequenceNumber),
+				Keys: map[string]events.DynamoDBAttributeValue{
+					"PK": events.NewStringAttribute(pk),
+					"SK": events.NewStringAttribute("abc-123"),
+				},
+			},
+		}
+	}
+
+	if handler.Filter(record("MODIFY", "012345678912:Recipe")) {
+		t.Fatal("Expected updates to not change the count")
+	}
+	if handler.Filter(record("INSERT", "012345678912:Recipe")) {
+		t.Fatal("Expected creates to be counted by their reservation")
+	}
+	if handler.Filter(record("REMOVE", "012345678912:Settings")) {
+		t.Fatal("Expected settings to not be counted")
+	}
+	counter.Set("012345678912", "recipes", 3)
+	removed := record("REMOVE", "012345678912:Recipe")
+	// Redelivered records, ie: when another handler failed them, count once
+	for _, r := range []events.DynamoDBEventRecord{
+		removed,
+		record("REMOVE", "012345678912:ShoppingList"),
+		removed,
+		record("REMOVE", "012345678912:Recipe"),
+		removed,
+	} {
+		if !handler.Filter(r) {
+			t.Fatalf("Expected %s to be counted", r.Change.Keys["PK"].String())
+		}
+		if err := handler.Apply(r); err != nil {
+			t.Fatalf("Failed to apply %s: %v", r.EventName, err)
+		}
+	}
+	for resource, expected := range map[string]int{"recipes": 1, "lists": 0} {
+		count, _, err := counter.Usage("012345678912", resource)
+		if err != nil {
+			t.Fatalf("Failed to read usage: %v", err)
+		}
+		if count != expected {
+			t.Fatalf("Expected %d %s, got %d", expected, resource, count)
+		}
+	}
+}
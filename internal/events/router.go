@@ -0,0 +1,227 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/deadletters"
+)
+
+// Dispatches a handler by resource type and event name, where none matches any
+type Route struct {
+	Name          string
+	ResourceTypes []string
+	EventNames    []string
+	Handler       EventFilter
+}
+
+func (r Route) _matches(resourceType string, eventName string) bool {
+	return _matchesAny(r.ResourceTypes, resourceType) && _matchesAny(r.EventNames, eventName)
+}
+
+func _matchesAny(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+type HandlerError struct {
+	Name   string
+	Record events.DynamoDBEventRecord
+	Err    error
+}
+
+func (e *HandlerError) Error() string {
+	return fmt.Sprintf("handler %s failed on %s %s: %v", e.Name, e.Record.EventName, e.Record.EventID, e.Err)
+}
+
+func (e *HandlerError) Unwrap() error {
+	return e.Err
+}
+
+// Records are retried by the stream before a failure is left to be replayed
+const DEFAULT_MAX_ATTEMPTS = 3
+
+type Router struct {
+	Routes []Route
+	// Failures kept here are replayed rather than retried by the stream, once
+	// the stream has attempted them MaxAttempts times
+	DeadLetters deadletters.Store
+	MaxAttempts int
+}
+
+func NewRouter() *Router {
+	return &Router{
+		MaxAttempts: DEFAULT_MAX_ATTEMPTS,
+	}
+}
+
+func (r *Router) _route(name string) (Route, bool) {
+	for _, route := range r.Routes {
+		if route.Name == name {
+			return route, true
+		}
+	}
+	return Route{}, false
+}
+
+func (r *Router) Register(route Route) error {
+	if route.Handler == nil {
+		return fmt.Errorf("route %s has no handler", route.Name)
+	}
+	if _, ok := r._route(route.Name); ok {
+		return fmt.Errorf("route %s is already registered", route.Name)
+	}
+	r.Routes = append(r.Routes, route)
+	return nil
+}
+
+// The resource type of a record, ie: Recipe from 012345678912:Recipe
+func ResourceType(record events.DynamoDBEventRecord) string {
+	pk := record.Change.Keys["PK"]
+	if pk.DataType() != events.DataTypeString {
+		return ""
+	}
+	parts := strings.Split(pk.String(), ":")
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[1]
+}
+
+// Reports whether the handler applied to the record, rather than filtering it
+func _apply(route Route, record events.DynamoDBEventRecord) (applied bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			applied, err = true, fmt.Errorf("panic: %v", r)
+		}
+	}()
+	if !route.Handler.Filter(record) {
+		return false, nil
+	}
+	return true, route.Handler.Apply(record)
+}
+
+func (r *Router) _dispatch(record events.DynamoDBEventRecord) ([]string, []*HandlerError) {
+	var succeeded []string
+	var failures []*HandlerError
+	resourceType := ResourceType(record)
+	if resourceType == "" {
+		return succeeded, failures
+	}
+	for _, route := range r.Routes {
+		if !route._matches(resourceType, record.EventName) {
+			continue
+		}
+		applied, err := _apply(route, record)
+		if err != nil {
+			failures = append(failures, &HandlerError{
+				Name:   route.Name,
+				Record: record,
+				Err:    err,
+			})
+		} else if applied {
+			succeeded = append(succeeded, route.Name)
+		}
+	}
+	return succeeded, failures
+}
+
+// Applies every matching handler to the record, so one failing handler does
+// not keep the others from running
+func (r *Router) Dispatch(record events.DynamoDBEventRecord) []*HandlerError {
+	_, failures := r._dispatch(record)
+	return failures
+}
+
+// Counts the failure as an attempt of its dead letter when there is a store
+// for them, and leaves it to the stream to retry until the last attempt
+func (r *Router) _deadLetter(failure *HandlerError) error {
+	if r.DeadLetters == nil {
+		return failure
+	}
+	letter, err := r.DeadLetters.Put(failure.Name, failure.Record, failure.Err)
+	if err != nil {
+		return fmt.Errorf("%v, and could not be dead-lettered: %w", failure, err)
+	}
+	if letter.Attempts < r.MaxAttempts {
+		return fmt.Errorf("%w, retrying after attempt %d of %d", failure, letter.Attempts, r.MaxAttempts)
+	}
+	fmt.Printf("Dead-lettered %s after %d attempts: %v\n", letter.SK, letter.Attempts, failure)
+	return nil
+}
+
+// Removes any letter left by an earlier attempt of the handlers that applied
+// the record, as the attempt may have failed on any instance
+func (r *Router) _recovered(record events.DynamoDBEventRecord, succeeded []string) {
+	if r.DeadLetters == nil {
+		return
+	}
+	for _, name := range succeeded {
+		id := deadletters.LetterId(name, record)
+		if err := r.DeadLetters.Delete(id); err != nil {
+			fmt.Printf("Failed to remove the recovered letter %s: %v\n", id, err)
+		}
+	}
+}
+
+// Reports records with a failure that was not dead-lettered, so the stream
+// retries from the first of them
+func (r *Router) HandleEvent(ctx context.Context, event events.DynamoDBEvent) (events.DynamoDBEventResponse, error) {
+	response := events.DynamoDBEventResponse{
+		BatchItemFailures: []events.DynamoDBBatchItemFailure{},
+	}
+	for _, record := range event.Records {
+		failed := false
+		succeeded, failures := r._dispatch(record)
+		r._recovered(record, succeeded)
+		for _, failure := range failures {
+			if err := r._deadLetter(failure); err != nil {
+				fmt.Printf("ERROR: %v\n", err)
+				failed = true
+			}
+		}
+		if failed {
+			response.BatchItemFailures = append(response.BatchItemFailures, events.DynamoDBBatchItemFailure{
+				ItemIdentifier: record.Change.SequenceNumber,
+			})
+		}
+	}
+	return response, nil
+}
+
+// Applies only the handler that failed the record, so the handlers that
+// succeeded are not repeated. Handlers condition their writes on the record,
+// so an old record never overwrites a target that has moved past it. The
+// letter is removed once the handler succeeds, otherwise another attempt is
+// counted.
+func (r *Router) Replay(letter data.DeadLetterDTO) error {
+	route, ok := r._route(letter.HandlerName)
+	if !ok {
+		return fmt.Errorf("dead letter %s has no handler %s", letter.SK, letter.HandlerName)
+	}
+	record, err := deadletters.DecodeRecord(letter)
+	if err != nil {
+		return err
+	}
+	if _, err := _apply(route, record); err != nil {
+		failure := &HandlerError{Name: route.Name, Record: record, Err: err}
+		if err := r._deadLetter(failure); err != nil {
+			return err
+		}
+		return failure
+	}
+	if r.DeadLetters == nil {
+		return nil
+	}
+	return r.DeadLetters.Delete(letter.SK)
+}
@@ -0,0 +1,179 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/deadletters"
+)
+
+type _handler struct {
+	err     error
+	panics  bool
+	applied []string
+}
+
+func (h *_handler) Filter(record events.DynamoDBEventRecord) bool {
+	return record.Change.Keys["SK"].String() != "filtered"
+}
+
+func (h *_handler) Apply(record events.DynamoDBEventRecord) error {
+	if h.panics {
+		panic("handler panicked")
+	}
+	h.applied = append(h.applied, record.Change.SequenceNumber)
+	return h.err
+}
+
+func _routedRecord(eventName string, pk string, sk string, sequenceNumber string) events.DynamoDBEventRecord {
+	return events.DynamoDBEventRecord{
+		EventName: eventName,
+		Change: events.DynamoDBStreamRecord{
+			SequenceNumber: sequenceNumber,
+			Keys: map[string]events.DynamoDBAttributeValue{
+				"PK": events.NewStringAttribute(pk),
+				"SK": events.NewStringAttribute(sk),
+			},
+		},
+	}
+}
+
+func TestRouter(t *testing.T) {
+	recipes := &_handler{}
+	inserts := &_handler{}
+	failing := &_handler{err: errors.New("failed")}
+	panicking := &_handler{panics: true}
+	router := NewRouter()
+	for _, route := range []Route{
+		{Name: "panicking", ResourceTypes: []string{"Settings"}, Handler: panicking},
+		{Name: "recipes", ResourceTypes: []string{"Recipe"}, Handler: recipes},
+		{Name: "inserts", EventNames: []string{"INSERT"}, Handler: inserts},
+		{Name: "failing", ResourceTypes: []string{"ShoppingList"}, EventNames: []string{"MODIFY"}, Handler: failing},
+	} {
+		if err := router.Register(route); err != nil {
+			t.Fatalf("Failed to register %s: %v", route.Name, err)
+		}
+	}
+	if err := router.Register(Route{Name: "recipes", Handler: recipes}); err == nil {
+		t.Fatal("Expected a route to be registered once")
+	}
+
+	response, err := router.HandleEvent(context.TODO(), events.DynamoDBEvent{
+		Records: []events.DynamoDBEventRecord{
+			_routedRecord("INSERT", "owner:Recipe", "abc", "1"),
+			_routedRecord("MODIFY", "owner:Recipe", "filtered", "2"),
+			_routedRecord("MODIFY", "owner:ShoppingList", "abc", "3"),
+			_routedRecord("INSERT", "owner:Settings", "abc", "4"),
+			_routedRecord("INSERT", "malformed", "abc", "5"),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to handle event: %v", err)
+	}
+	if len(recipes.applied) != 1 || recipes.applied[0] != "1" {
+		t.Fatalf("Expected recipe handler to apply to 1, got %v", recipes.applied)
+	}
+	if len(inserts.applied) != 2 || inserts.applied[1] != "4" {
+		t.Fatalf("Expected insert handler to apply after a panic, got %v", inserts.applied)
+	}
+	if len(failing.applied) != 1 || failing.applied[0] != "3" {
+		t.Fatalf("Expected list modifications to be applied, got %v", failing.applied)
+	}
+	if len(response.BatchItemFailures) != 2 {
+		t.Fatalf("Expected two failed records, got %v", response.BatchItemFailures)
+	}
+	for i, expected := range []string{"3", "4"} {
+		if response.BatchItemFailures[i].ItemIdentifier != expected {
+			t.Fatalf("Expected %s to fail, got %v", expected, response.BatchItemFailures[i])
+		}
+	}
+
+	failures := router.Dispatch(_routedRecord("MODIFY", "owner:ShoppingList", "abc", "6"))
+	if len(failures) != 1 || failures[0].Name != "failing" || !errors.Is(failures[0], failing.err) {
+		t.Fatalf("Expected the failing handler to be reported, got %v", failures)
+	}
+}
+
+func TestRouterDeadLetters(t *testing.T) {
+	failing := &_handler{err: errors.New("failed")}
+	succeeding := &_handler{}
+	router := NewRouter()
+	location := filepath.Join(t.TempDir(), "deadletters.json")
+	router.DeadLetters = deadletters.NewFileStore(location)
+	for _, route := range []Route{
+		{Name: "failing", Handler: failing},
+		{Name: "succeeding", Handler: succeeding},
+	} {
+		if err := router.Register(route); err != nil {
+			t.Fatalf("Failed to register %s: %v", route.Name, err)
+		}
+	}
+	record := _routedRecord("INSERT", "owner:Recipe", "abc", "1")
+	record.EventID = "event-1"
+	// The stream retries the record until its last attempt
+	for attempt := 1; attempt <= DEFAULT_MAX_ATTEMPTS; attempt++ {
+		response, err := router.HandleEvent(context.TODO(), events.DynamoDBEvent{
+			Records: []events.DynamoDBEventRecord{record},
+		})
+		retried := attempt < DEFAULT_MAX_ATTEMPTS
+		if err != nil || (len(response.BatchItemFailures) == 1) != retried {
+			t.Fatalf("Expected attempt %d to be retried %v, got %v: %v", attempt, retried, response, err)
+		}
+	}
+	letters, err := router.DeadLetters.List(data.QueryParams{})
+	if err != nil || len(letters.Items) != 1 {
+		t.Fatalf("Expected one dead letter, got %v: %v", letters, err)
+	}
+	letter := letters.Items[0]
+	if letter.HandlerName != "failing" || letter.Error != "failed" || letter.Attempts != DEFAULT_MAX_ATTEMPTS {
+		t.Fatalf("Expected the failing handler to be dead-lettered, got %v", letter)
+	}
+
+	if err := router.Replay(letter); !errors.Is(err, failing.err) {
+		t.Fatalf("Expected the replay to fail, got %v", err)
+	}
+	if letter, err = router.DeadLetters.Get(letter.SK); err != nil || letter.Attempts != DEFAULT_MAX_ATTEMPTS+1 {
+		t.Fatalf("Expected another attempt to be counted, got %v: %v", letter, err)
+	}
+	failing.err = nil
+	if err := router.Replay(letter); err != nil {
+		t.Fatalf("Failed to replay: %v", err)
+	}
+	if len(failing.applied) != DEFAULT_MAX_ATTEMPTS+2 || len(succeeding.applied) != DEFAULT_MAX_ATTEMPTS {
+		t.Fatalf("Expected only the failed handler to be replayed, got %v and %v", failing.applied, succeeding.applied)
+	}
+	if _, err := router.DeadLetters.Get(letter.SK); err == nil {
+		t.Fatal("Expected a replayed letter to be removed")
+	}
+	if err := router.Replay(data.DeadLetterDTO{SK: "unknown", HandlerName: "removed"}); err == nil {
+		t.Fatal("Expected a letter without a handler to fail")
+	}
+
+	// A retry that succeeds removes the letter of its first attempt, even on
+	// another instance
+	failing.err = errors.New("throttled")
+	record = _routedRecord("INSERT", "owner:Recipe", "abc", "2")
+	record.EventID = "event-2"
+	event := events.DynamoDBEvent{Records: []events.DynamoDBEventRecord{record}}
+	if response, err := router.HandleEvent(context.TODO(), event); err != nil || len(response.BatchItemFailures) != 1 {
+		t.Fatalf("Expected the record to be retried, got %v: %v", response, err)
+	}
+	failing.err = nil
+	retried := NewRouter()
+	retried.DeadLetters = deadletters.NewFileStore(location)
+	for _, route := range router.Routes {
+		if err := retried.Register(route); err != nil {
+			t.Fatalf("Failed to register %s: %v", route.Name, err)
+		}
+	}
+	if response, err := retried.HandleEvent(context.TODO(), event); err != nil || len(response.BatchItemFailures) != 0 {
+		t.Fatalf("Expected the retry to succeed, got %v: %v", response, err)
+	}
+	if letters, err := router.DeadLetters.List(data.QueryParams{}); err != nil || len(letters.Items) != 0 {
+		t.Fatalf("Expected no letters after a successful retry, got %v: %v", letters, err)
+	}
+}
@@ -41,12 +41,7 @@ type CopyApprovedShareRequestHandler struct {
 }
 
 func (ch *CopyApprovedShareRequestHandler) Filter(record events.DynamoDBEventRecord) bool {
-	pk := record.Change.Keys["PK"]
-	parts := strings.Split(pk.String(), ":")
-	return record.EventName == "MODIFY" &&
-		parts[1] == "ShareRequest" &&
-		!record.Change.NewImage["approverId"].IsNull() &&
-		record.Change.NewImage["approvalStatus"].String() == "APPROVED"
+	return _isShareApproval(record)
 }
 
 func (ch *CopyApprovedShareRequestHandler) Apply(record events.DynamoDBEventRecord) error {
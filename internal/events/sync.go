@@ -0,0 +1,227 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"philcali.me/recipes/internal/data"
+)
+
+// Number of applied update tokens remembered on each copy
+const MAX_SYNC_TOKENS = 10
+
+// Number of times a copy is re-read when a concurrent edit wins the write
+const MAX_SYNC_ATTEMPTS = 3
+
+type _itemChange struct {
+	Added   bool
+	Removed bool
+	Item    data.ShoppingListItemDTO
+}
+
+// Items are matched by id, so renaming an item modifies it, only items
+// without an id are matched by name
+func _listItemKey(item data.ShoppingListItemDTO) string {
+	if item.Id != "" {
+		return item.Id
+	}
+	return strings.ToLower(strings.TrimSpace(item.Name))
+}
+
+func _convertStreamItems(image map[string]events.DynamoDBAttributeValue) ([]data.ShoppingListItemDTO, error) {
+	var items []data.ShoppingListItemDTO
+	value, ok := image["items"]
+	if !ok || value.IsNull() {
+		return items, nil
+	}
+	err := attributevalue.Unmarshal(_convertStreamAttribute(value), &items)
+	return items, err
+}
+
+func _diffListItems(oldItems []data.ShoppingListItemDTO, newItems []data.ShoppingListItemDTO) ([]string, map[string]_itemChange) {
+	var keys []string
+	changes := make(map[string]_itemChange, len(newItems))
+	previous := make(map[string]data.ShoppingListItemDTO, len(oldItems))
+	for _, item := range oldItems {
+		previous[_listItemKey(item)] = item
+	}
+	for _, item := range newItems {
+		key := _listItemKey(item)
+		old, existed := previous[key]
+		delete(previous, key)
+		// Categories are pointers, so items are compared by value
+		if existed && reflect.DeepEqual(old, item) {
+			continue
+		}
+		keys = append(keys, key)
+		changes[key] = _itemChange{
+			Added: !existed,
+			Item:  item,
+		}
+	}
+	for _, item := range oldItems {
+		key := _listItemKey(item)
+		if _, removed := previous[key]; removed {
+			keys = append(keys, key)
+			changes[key] = _itemChange{
+				Removed: true,
+				Item:    item,
+			}
+		}
+	}
+	return keys, changes
+}
+
+func _mergeListItems(items []data.ShoppingListItemDTO, keys []string, changes map[string]_itemChange) []data.ShoppingListItemDTO {
+	merged := make([]data.ShoppingListItemDTO, 0, len(items))
+	present := make(map[string]bool, len(items))
+	for _, item := range items {
+		key := _listItemKey(item)
+		present[key] = true
+		change, ok := changes[key]
+		if !ok {
+			merged = append(merged, item)
+		} else if !change.Removed {
+			merged = append(merged, change.Item)
+		}
+	}
+	// Modifications to items removed from this copy are dropped: removal wins
+	for _, key := range keys {
+		if change := changes[key]; change.Added && !present[key] {
+			merged = append(merged, change.Item)
+		}
+	}
+	return merged
+}
+
+func _containsToken(tokens []string, token string) bool {
+	for _, t := range tokens {
+		if t == token {
+			return true
+		}
+	}
+	return false
+}
+
+func _syncListCopy(tableName string, accountId string, record events.DynamoDBEventRecord, keys []string, changes map[string]_itemChange, ddb *dynamodb.Client) error {
+	updateToken := record.Change.NewImage["updateToken"].String()
+	key := map[string]types.AttributeValue{
+		"PK": &types.AttributeValueMemberS{Value: fmt.Sprintf("%s:ShoppingList", accountId)},
+		"SK": &types.AttributeValueMemberS{Value: record.Change.Keys["SK"].String()},
+	}
+	for attempt := 0; attempt < MAX_SYNC_ATTEMPTS; attempt++ {
+		output, err := ddb.GetItem(context.TODO(), &dynamodb.GetItemInput{
+			TableName:      aws.String(tableName),
+			Key:            key,
+			ConsistentRead: aws.Bool(true),
+		})
+		if err != nil {
+			return err
+		}
+		if output.Item == nil {
+			return nil
+		}
+		var copied data.ShoppingListDTO
+		if err := attributevalue.UnmarshalMap(output.Item, &copied); err != nil {
+			return err
+		}
+		data.FillItemIds(copied.SK, copied.Items)
+		// Every copy applies a change once, which is what stops the echo
+		if copied.UpdateToken != nil && *copied.UpdateToken == updateToken || _containsToken(copied.SyncTokens, updateToken) {
+			return nil
+		}
+		syncTokens := append(copied.SyncTokens, updateToken)
+		if len(syncTokens) > MAX_SYNC_TOKENS {
+			syncTokens = syncTokens[len(syncTokens)-MAX_SYNC_TOKENS:]
+		}
+		update := expression.Set(expression.Name("updateTime"), expression.Value(time.Now())).
+			Set(expression.Name("items"), expression.Value(_mergeListItems(copied.Items, keys, changes))).
+			Set(expression.Name("updateToken"), expression.Value(updateToken)).
+			Set(expression.Name("syncTokens"), expression.Value(syncTokens))
+		oldName := record.Change.OldImage["name"]
+		newName := record.Change.NewImage["name"]
+		if !newName.IsNull() && oldName.String() != newName.String() {
+			update.Set(expression.Name("name"), expression.Value(newName.String()))
+		}
+		condition := expression.Name("PK").AttributeExists().And(expression.Name("SK").AttributeExists())
+		if copied.UpdateToken != nil {
+			condition = condition.And(expression.Name("updateToken").Equal(expression.Value(*copied.UpdateToken)))
+		} else {
+			condition = condition.And(expression.Or(
+				expression.Name("updateToken").AttributeNotExists(),
+				expression.Name("updateToken").AttributeType(expression.Null),
+			))
+		}
+		expr, err := expression.NewBuilder().WithCondition(condition).WithUpdate(update).Build()
+		if err != nil {
+			return err
+		}
+		_, err = ddb.UpdateItem(context.TODO(), &dynamodb.UpdateItemInput{
+			TableName:                 aws.String(tableName),
+			Key:                       key,
+			ConditionExpression:       expr.Condition(),
+			UpdateExpression:          expr.Update(),
+			ExpressionAttributeNames:  expr.Names(),
+			ExpressionAttributeValues: expr.Values(),
+		})
+		if err == nil {
+			return nil
+		}
+		var conditionFailed *types.ConditionalCheckFailedException
+		if !errors.As(err, &conditionFailed) {
+			return err
+		}
+	}
+	return fmt.Errorf("failed to sync list %s for %s after %d attempts", record.Change.Keys["SK"].String(), accountId, MAX_SYNC_ATTEMPTS)
+}
+
+func _syncSharedList(tableName string, ownerId string, record events.DynamoDBEventRecord, ddb *dynamodb.Client, shareRepo data.ShareRequestRepository) error {
+	oldItems, err := _convertStreamItems(record.Change.OldImage)
+	if err != nil {
+		return err
+	}
+	newItems, err := _convertStreamItems(record.Change.NewImage)
+	if err != nil {
+		return err
+	}
+	listId := record.Change.Keys["SK"].String()
+	data.FillItemIds(listId, oldItems)
+	data.FillItemIds(listId, newItems)
+	keys, changes := _diffListItems(oldItems, newItems)
+	var nextToken *string
+	truncated := true
+	for truncated {
+		sharing, err := shareRepo.List(ownerId, data.QueryParams{
+			Limit:     100,
+			NextToken: nextToken,
+		})
+		if err != nil {
+			return err
+		}
+		for _, item := range sharing.Items {
+			if item.ApprovalStatus != data.APPROVED {
+				continue
+			}
+			otherAccountId := item.RequesterId
+			if strings.EqualFold(*item.RequesterId, ownerId) {
+				otherAccountId = item.ApproverId
+			}
+			if err := _syncListCopy(tableName, *otherAccountId, record, keys, changes, ddb); err != nil {
+				return err
+			}
+		}
+		nextToken = sharing.NextToken
+		truncated = nextToken != nil
+	}
+	return nil
+}
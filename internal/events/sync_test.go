@@ -0,0 +1,225 @@
+package events
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/google/uuid"
+	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/dynamodb/shares"
+	"philcali.me/recipes/internal/dynamodb/shopping"
+	"philcali.me/recipes/internal/dynamodb/token"
+	"philcali.me/recipes/internal/test"
+)
+
+func TestMergeListItems(t *testing.T) {
+	oldItems := []data.ShoppingListItemDTO{
+		{Name: "Milk"},
+		{Name: "Eggs"},
+		{Name: "Bread"},
+	}
+	newItems := []data.ShoppingListItemDTO{
+		{Name: "Milk"},
+		{Name: "Eggs", Completed: true},
+		{Name: "Butter"},
+	}
+	keys, changes := _diffListItems(oldItems, newItems)
+	if len(keys) != 3 {
+		t.Fatalf("Expected 3 changes, got %v", keys)
+	}
+	if !changes["butter"].Added || !changes["bread"].Removed || changes["eggs"].Added {
+		t.Fatalf("Unexpected changes %v", changes)
+	}
+
+	t.Run("ConcurrentEdit", func(t *testing.T) {
+		copied := []data.ShoppingListItemDTO{
+			{Name: "Milk", Completed: true},
+			{Name: "Eggs"},
+			{Name: "Bread"},
+		}
+		merged := _mergeListItems(copied, keys, changes)
+		expected := []data.ShoppingListItemDTO{
+			{Name: "Milk", Completed: true},
+			{Name: "Eggs", Completed: true},
+			{Name: "Butter"},
+		}
+		if fmt.Sprint(merged) != fmt.Sprint(expected) {
+			t.Fatalf("Expected %v, got %v", expected, merged)
+		}
+	})
+
+	t.Run("RemovalWins", func(t *testing.T) {
+		copied := []data.ShoppingListItemDTO{
+			{Name: "Milk"},
+			{Name: "Bread"},
+		}
+		merged := _mergeListItems(copied, keys, changes)
+		expected := []data.ShoppingListItemDTO{
+			{Name: "Milk"},
+			{Name: "Butter"},
+		}
+		if fmt.Sprint(merged) != fmt.Sprint(expected) {
+			t.Fatalf("Expected %v, got %v", expected, merged)
+		}
+	})
+
+	t.Run("ItemIds", func(t *testing.T) {
+		oldItems := []data.ShoppingListItemDTO{
+			{Id: "milk", Name: "Milk", Category: aws.String("Dairy")},
+			{Id: "eggs", Name: "Eggs"},
+		}
+		newItems := []data.ShoppingListItemDTO{
+			{Id: "milk", Name: "Milk", Category: aws.String("Dairy")},
+			{Id: "eggs", Name: "Brown Eggs"},
+		}
+		keys, changes := _diffListItems(oldItems, newItems)
+		if len(keys) != 1 || changes["eggs"].Added || changes["eggs"].Item.Name != "Brown Eggs" {
+			t.Fatalf("Expected only the renamed item to change, got %v", changes)
+		}
+		copied := []data.ShoppingListItemDTO{
+			{Id: "milk", Name: "Milk", Completed: true},
+			{Id: "eggs", Name: "Eggs"},
+		}
+		merged := _mergeListItems(copied, keys, changes)
+		if len(merged) != 2 || merged[1].Name != "Brown Eggs" || !merged[0].Completed {
+			t.Fatalf("Expected the rename to be merged by id, got %v", merged)
+		}
+	})
+
+	t.Run("FillItemIds", func(t *testing.T) {
+		items := []data.ShoppingListItemDTO{{Name: "Milk"}, {Id: "eggs", Name: "Eggs"}, {Name: "milk "}}
+		copied := []data.ShoppingListItemDTO{{Name: "Milk"}, {Name: "Milk"}}
+		if !data.FillItemIds("list", items) || !data.FillItemIds("list", copied) {
+			t.Fatal("Expected items without ids to be filled")
+		}
+		if items[1].Id != "eggs" || items[0].Id == items[2].Id {
+			t.Fatalf("Expected only missing ids to be filled, got %v", items)
+		}
+		if items[0].Id != copied[0].Id || items[2].Id != copied[1].Id {
+			t.Fatalf("Expected copies to derive the same ids, got %v and %v", items, copied)
+		}
+		if data.FillItemIds("list", items) {
+			t.Fatal("Expected filled items to be left alone")
+		}
+	})
+}
+
+func TestSyncSharedLists(t *testing.T) {
+	localServer := test.StartLocalServer(test.LOCAL_DDB_PORT+2, t)
+	client, err := localServer.CreateLocalClient()
+	if err != nil {
+		t.Fatalf("Failed to create DDB client: %s", err)
+	}
+	tableName, err := test.CreateTable(client)
+	if err != nil {
+		t.Fatalf("Failed to create DDB table: %s", err)
+	}
+	t.Logf("Successfully created local resources running on %d", test.LOCAL_DDB_PORT)
+	marshaler := token.NewGCM()
+
+	t.Run("PartnerEdit", func(t *testing.T) {
+		sharingData := shares.NewShareService(tableName, *client, marshaler)
+		listData := shopping.NewShoppingListService(tableName, *client, marshaler)
+		updateHandler := &UpdateSharedResourceHandler{
+			Sharing:   sharingData,
+			DynamoDB:  client,
+			TableName: tableName,
+		}
+
+		ownerId := uuid.NewString()
+		partnerId := uuid.NewString()
+		shareId := uuid.NewString()
+		itemId := uuid.NewString()
+		status := data.APPROVED
+		for _, accountId := range []string{ownerId, partnerId} {
+			_, err := sharingData.CreateWithItemId(accountId, data.ShareRequestInputDTO{
+				Requester:      aws.String("owner@email.com"),
+				RequesterId:    aws.String(ownerId),
+				Approver:       aws.String("partner@email.com"),
+				ApproverId:     aws.String(partnerId),
+				ApprovalStatus: &status,
+			}, shareId)
+			if err != nil {
+				t.Fatalf("Failed to create share for %s: %v", accountId, err)
+			}
+		}
+
+		// The owner checked off milk while the partner checks off eggs
+		_, err := listData.CreateWithItemId(ownerId, data.ShoppingListInputDTO{
+			Name:        aws.String("Giant"),
+			Owner:       aws.String("owner@email.com"),
+			UpdateToken: aws.String("owner-edit"),
+			Items: &[]data.ShoppingListItemDTO{
+				{Name: "Milk", Completed: true},
+				{Name: "Eggs"},
+			},
+		}, itemId)
+		if err != nil {
+			t.Fatalf("Failed to create owner list: %v", err)
+		}
+
+		image := func(accountId string, token string, eggs bool) map[string]events.DynamoDBAttributeValue {
+			return map[string]events.DynamoDBAttributeValue{
+				"PK":   events.NewStringAttribute(fmt.Sprintf("%s:ShoppingList", accountId)),
+				"SK":   events.NewStringAttribute(itemId),
+				"name": events.NewStringAttribute("Giant"),
+				"items": events.NewListAttribute([]events.DynamoDBAttributeValue{
+					events.NewMapAttribute(map[string]events.DynamoDBAttributeValue{
+						"name":      events.NewStringAttribute("Milk"),
+						"completed": events.NewBooleanAttribute(false),
+					}),
+					events.NewMapAttribute(map[string]events.DynamoDBAttributeValue{
+						"name":      events.NewStringAttribute("Eggs"),
+						"completed": events.NewBooleanAttribute(eggs),
+					}),
+				}),
+				"updateToken": events.NewStringAttribute(token),
+			}
+		}
+
+		partnerEdit := events.DynamoDBEventRecord{
+			EventName: "MODIFY",
+			Change: events.DynamoDBStreamRecord{
+				Keys: map[string]events.DynamoDBAttributeValue{
+					"PK": events.NewStringAttribute(fmt.Sprintf("%s:ShoppingList", partnerId)),
+					"SK": events.NewStringAttribute(itemId),
+				},
+				OldImage: image(partnerId, "original", false),
+				NewImage: image(partnerId, "partner-edit", true),
+			},
+		}
+
+		if !updateHandler.Filter(partnerEdit) {
+			t.Fatalf("Expected the partner edit to be filtered %v", partnerEdit)
+		}
+
+		if err := updateHandler.Apply(partnerEdit); err != nil {
+			t.Fatalf("Failed to sync the partner edit: %v", err)
+		}
+
+		synced, err := listData.Get(ownerId, itemId)
+		if err != nil {
+			t.Fatalf("Failed to get the owner list: %v", err)
+		}
+		if !synced.Items[0].Completed || !synced.Items[1].Completed {
+			t.Fatalf("Expected both items to be completed: %v", synced.Items)
+		}
+		if *synced.UpdateToken != "partner-edit" {
+			t.Fatalf("Expected the partner token to be applied, got %s", *synced.UpdateToken)
+		}
+
+		// Replaying the change is a no-op, which is what breaks the cycle
+		if err := updateHandler.Apply(partnerEdit); err != nil {
+			t.Fatalf("Failed to replay the partner edit: %v", err)
+		}
+		replayed, err := listData.Get(ownerId, itemId)
+		if err != nil {
+			t.Fatalf("Failed to get the owner list: %v", err)
+		}
+		if !replayed.UpdateTime.Equal(synced.UpdateTime) {
+			t.Fatalf("Expected the replay to skip, but the list was written: %v", replayed)
+		}
+	})
+}
@@ -0,0 +1,175 @@
+package events
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/google/uuid"
+	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/exceptions"
+	"philcali.me/recipes/internal/notifications"
+	"philcali.me/recipes/internal/webhooks"
+)
+
+const (
+	// Deliveries are kept for thirty days
+	EXPIRY_DELIVERY = 30 * 24 * time.Hour
+	// Retries leave a delivery being attempted alone until the attempt would
+	// have timed out
+	DELIVERY_LEASE = time.Minute
+)
+
+type WebhookDeliveryHandler struct {
+	Subscriptions data.SubscriptionDataService
+	Deliveries    data.DeliveryRepository
+	Deliverer     *webhooks.Deliverer
+	ResourceTypes map[string]string
+}
+
+func (wh *WebhookDeliveryHandler) Filter(record events.DynamoDBEventRecord) bool {
+	return _filterResourceEvent(wh.ResourceTypes, record)
+}
+
+// An event is delivered to a subscriber once, however often the record is
+// handled
+func DeliveryId(eventId string, subscriberId string) string {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(eventId+"@"+subscriberId)).String()
+}
+
+func (wh *WebhookDeliveryHandler) deliver(subscription data.SubscriptionDTO, event ResourceEvent, payload []byte) error {
+	now := time.Now()
+	status := data.DELIVERY_PENDING
+	delivery, err := wh.Deliveries.CreateWithItemId(event.AccountId, data.DeliveryInputDTO{
+		AccountId:       aws.String(event.AccountId),
+		SubscriberId:    aws.String(subscription.SK),
+		Endpoint:        aws.String(subscription.Endpoint),
+		EventId:         aws.String(event.Id),
+		EventType:       aws.String(event.Type),
+		Payload:         aws.String(string(payload)),
+		Status:          &status,
+		NextAttemptTime: aws.Int(int(now.Add(DELIVERY_LEASE).Unix())),
+		ExpiresIn:       aws.Int(int(now.Add(EXPIRY_DELIVERY).Unix())),
+	}, DeliveryId(event.Id, subscription.SK))
+	if _, ok := err.(*exceptions.ConflictError); ok {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return wh.Attempt(event.AccountId, delivery, subscription)
+}
+
+// Makes the next attempt of a pending delivery and records it. A failed
+// delivery stays pending until its next attempt, unless it is exhausted.
+func (wh *WebhookDeliveryHandler) Attempt(accountId string, delivery data.DeliveryDTO, subscription data.SubscriptionDTO) error {
+	var secret string
+	if subscription.Secret != nil {
+		secret = *subscription.Secret
+	}
+	attempt, next := wh.Deliverer.Deliver(webhooks.DeliveryRequest{
+		Id:        delivery.SK,
+		Endpoint:  delivery.Endpoint,
+		Secret:    secret,
+		EventType: delivery.EventType,
+		Payload:   []byte(delivery.Payload),
+	}, len(delivery.Attempts))
+	recorded := data.DeliveryAttemptDTO{
+		StatusCode:  attempt.StatusCode,
+		AttemptTime: attempt.AttemptTime,
+	}
+	if attempt.Error != nil {
+		recorded.Error = aws.String(attempt.Error.Error())
+	}
+	// Exhausted deliveries stay in the log as the dead-letter record
+	status := data.DELIVERY_DEAD_LETTER
+	nextAttempt := data.PatchChange{Path: "nextAttemptTime", Remove: true}
+	switch {
+	case attempt.Error == nil:
+		status = data.DELIVERY_SUCCEEDED
+	case next != nil:
+		status = data.DELIVERY_PENDING
+		nextAttempt = data.PatchChange{Path: "nextAttemptTime", Value: int(next.Unix())}
+	}
+	_, err := wh.Deliveries.Patch(accountId, delivery.SK, data.DeliveryInputDTO{Status: &status}, data.Patch{
+		Changes: []data.PatchChange{
+			{Path: "attempts", Value: []data.DeliveryAttemptDTO{recorded}, Append: true},
+			nextAttempt,
+		},
+	})
+	return err
+}
+
+// Claims a pending delivery that is due, so concurrent retries attempt it once
+func (wh *WebhookDeliveryHandler) Retry(accountId string, delivery data.DeliveryDTO, now time.Time) error {
+	if delivery.Status != data.DELIVERY_PENDING || delivery.NextAttemptTime == nil || int64(*delivery.NextAttemptTime) > now.Unix() {
+		return nil
+	}
+	claimed, err := wh.Deliveries.Patch(accountId, delivery.SK, data.DeliveryInputDTO{
+		NextAttemptTime: aws.Int(int(now.Add(DELIVERY_LEASE).Unix())),
+	}, data.Patch{
+		Conditions: map[string]any{"nextAttemptTime": *delivery.NextAttemptTime},
+	})
+	if _, ok := err.(*exceptions.ConflictError); ok {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	subscription, err := wh.Subscriptions.Get(accountId, claimed.SubscriberId)
+	if _, ok := err.(*exceptions.NotFoundError); ok {
+		// Nobody is left to deliver to
+		status := data.DELIVERY_DEAD_LETTER
+		_, err = wh.Deliveries.Patch(accountId, claimed.SK, data.DeliveryInputDTO{Status: &status}, data.Patch{
+			Changes: []data.PatchChange{{Path: "nextAttemptTime", Remove: true}},
+		})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	return wh.Attempt(accountId, claimed, subscription)
+}
+
+func (wh *WebhookDeliveryHandler) Apply(record events.DynamoDBEventRecord) error {
+	event := _newResourceEvent(wh.ResourceTypes, record)
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	var nextToken *string
+	truncated := true
+	for truncated {
+		subscriptions, err := wh.Subscriptions.List(event.AccountId, data.QueryParams{
+			Limit:     100,
+			NextToken: nextToken,
+		})
+		if err != nil {
+			return err
+		}
+		for _, subscription := range subscriptions.Items {
+			if subscription.Protocol != data.WEBHOOK_PROTOCOL {
+				continue
+			}
+			if !notifications.MatchesFilters(subscription.EventTypes, subscription.ResourceTypes, event.Type, event.ResourceType) {
+				continue
+			}
+			if err := wh.deliver(subscription, event, payload); err != nil {
+				return err
+			}
+		}
+		nextToken = subscriptions.NextToken
+		truncated = nextToken != nil
+	}
+	return nil
+}
+
+func DefaultWebhookHandler(subscriptions data.SubscriptionDataService, deliveries data.DeliveryRepository) *WebhookDeliveryHandler {
+	return &WebhookDeliveryHandler{
+		Subscriptions: subscriptions,
+		Deliveries:    deliveries,
+		Deliverer:     webhooks.NewDeliverer(),
+		ResourceTypes: _defaultEventResourceTypes(),
+	}
+}
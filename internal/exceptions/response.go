@@ -0,0 +1,39 @@
+package exceptions
+
+import "errors"
+
+// The body of every error response, regardless of where it was raised
+type ErrorResponse struct {
+	Code      string       `json:"code"`
+	Message   string       `json:"message"`
+	Details   []FieldError `json:"details,omitempty"`
+	RequestId string       `json:"requestId,omitempty"`
+}
+
+// Errors that are not request or service errors are internal errors
+func NewErrorResponse(err error, requestId string) (int, ErrorResponse) {
+	serviceError := &ServiceError{
+		StatusCode: 500,
+		Code:       CODE_INTERNAL_ERROR,
+		Cause:      err,
+	}
+	if re, ok := err.(RequestError); ok {
+		serviceError = re.ToServiceError()
+	}
+	if se, ok := err.(*ServiceError); ok {
+		serviceError = se
+	}
+	response := ErrorResponse{
+		Code:      serviceError.Code,
+		Message:   err.Error(),
+		RequestId: requestId,
+	}
+	if response.Code == "" {
+		response.Code = CODE_INTERNAL_ERROR
+	}
+	var ve *ValidationError
+	if errors.As(err, &ve) {
+		response.Details = ve.Fields
+	}
+	return serviceError.StatusCode, response
+}
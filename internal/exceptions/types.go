@@ -3,10 +3,26 @@ package exceptions
 import (
 	"errors"
 	"fmt"
+	"strings"
+)
+
+// Machine readable codes, so clients need not parse messages
+const (
+	CODE_INVALID_INPUT          = "INVALID_INPUT"
+	CODE_VALIDATION_FAILED      = "VALIDATION_FAILED"
+	CODE_UNAUTHORIZED           = "UNAUTHORIZED"
+	CODE_FORBIDDEN              = "FORBIDDEN"
+	CODE_NOT_FOUND              = "NOT_FOUND"
+	CODE_METHOD_NOT_ALLOWED     = "METHOD_NOT_ALLOWED"
+	CODE_CONFLICT               = "CONFLICT"
+	CODE_UNSUPPORTED_MEDIA_TYPE = "UNSUPPORTED_MEDIA_TYPE"
+	CODE_TOO_MANY_REQUESTS      = "TOO_MANY_REQUESTS"
+	CODE_INTERNAL_ERROR         = "INTERNAL_ERROR"
 )
 
 type ServiceError struct {
 	StatusCode int
+	Code       string
 	Cause      error
 }
 
@@ -31,6 +47,7 @@ func (ce *ConflictError) Error() string {
 func (ce *ConflictError) ToServiceError() *ServiceError {
 	return &ServiceError{
 		StatusCode: 409,
+		Code:       CODE_CONFLICT,
 		Cause:      ce,
 	}
 }
@@ -54,6 +71,7 @@ func (nfe *NotFoundError) Error() string {
 func (nfe *NotFoundError) ToServiceError() *ServiceError {
 	return &ServiceError{
 		StatusCode: 404,
+		Code:       CODE_NOT_FOUND,
 		Cause:      nfe,
 	}
 }
@@ -76,6 +94,7 @@ func (ie *InvalidInputError) Error() string {
 func (ie *InvalidInputError) ToServiceError() *ServiceError {
 	return &ServiceError{
 		StatusCode: 400,
+		Code:       CODE_INVALID_INPUT,
 		Cause:      ie,
 	}
 }
@@ -86,9 +105,96 @@ func InvalidInput(message string) *InvalidInputError {
 	}
 }
 
+type ForbiddenError struct {
+	Message string
+}
+
+func (fe *ForbiddenError) Error() string {
+	return fe.Message
+}
+
+func (fe *ForbiddenError) ToServiceError() *ServiceError {
+	return &ServiceError{
+		StatusCode: 403,
+		Code:       CODE_FORBIDDEN,
+		Cause:      fe,
+	}
+}
+
+func Forbidden(message string) *ForbiddenError {
+	return &ForbiddenError{
+		Message: message,
+	}
+}
+
 func InternalServer(message string) *ServiceError {
 	return &ServiceError{
 		StatusCode: 500,
+		Code:       CODE_INTERNAL_ERROR,
+		Cause:      errors.New(message),
+	}
+}
+
+func Unauthorized(message string) *ServiceError {
+	return &ServiceError{
+		StatusCode: 401,
+		Code:       CODE_UNAUTHORIZED,
+		Cause:      errors.New(message),
+	}
+}
+
+func MethodNotAllowed(method string, path string) *ServiceError {
+	return &ServiceError{
+		StatusCode: 405,
+		Code:       CODE_METHOD_NOT_ALLOWED,
+		Cause:      fmt.Errorf("Method %s is not allowed on %s", method, path),
+	}
+}
+
+func UnsupportedMediaType(contentType string) *ServiceError {
+	return &ServiceError{
+		StatusCode: 415,
+		Code:       CODE_UNSUPPORTED_MEDIA_TYPE,
+		Cause:      fmt.Errorf("Content type %s is not supported", contentType),
+	}
+}
+
+func TooManyRequests(message string) *ServiceError {
+	return &ServiceError{
+		StatusCode: 429,
+		Code:       CODE_TOO_MANY_REQUESTS,
 		Cause:      errors.New(message),
 	}
 }
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (ve *ValidationError) Error() string {
+	messages := make([]string, len(ve.Fields))
+	for i, field := range ve.Fields {
+		messages[i] = field.Message
+	}
+	return "Invalid input: " + strings.Join(messages, ", ")
+}
+
+func (ve *ValidationError) ToServiceError() *ServiceError {
+	return &ServiceError{
+		StatusCode: 400,
+		Code:       CODE_VALIDATION_FAILED,
+		Cause:      ve,
+	}
+}
+
+func Validation(fields ...FieldError) *ValidationError {
+	return &ValidationError{
+		Fields: fields,
+	}
+}
@@ -0,0 +1,110 @@
+package jobs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Bounds of the minute, hour, day of month, month and day of week fields
+var _bounds = [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 6}}
+
+// Schedules are cron expressions of five fields: minute, hour, day of month,
+// month and day of week. Fields are lists of *, values, ranges and steps, ie:
+// "*/15 8-17 * * 1-5". Schedules are matched in UTC.
+type Schedule struct {
+	Expression string
+	fields     [5]map[int]bool
+	// Either day restricts the day when the other is *, otherwise either matches
+	restricted [5]bool
+}
+
+func _parseValue(value string, field int) (int, error) {
+	number, err := strconv.Atoi(value)
+	if field == 4 && number == 7 {
+		// Sunday is either end of the week
+		number = 0
+	}
+	if err != nil || number < _bounds[field][0] || number > _bounds[field][1] {
+		return 0, fmt.Errorf("%s is not between %d and %d", value, _bounds[field][0], _bounds[field][1])
+	}
+	return number, nil
+}
+
+func _parseField(expression string, field int) (map[int]bool, error) {
+	values := make(map[int]bool)
+	for _, part := range strings.Split(expression, ",") {
+		span, stepValue, stepped := strings.Cut(part, "/")
+		step := 1
+		if stepped {
+			var err error
+			if step, err = strconv.Atoi(stepValue); err != nil || step < 1 {
+				return nil, fmt.Errorf("%s is not a step", stepValue)
+			}
+		}
+		low, high := _bounds[field][0], _bounds[field][1]
+		if span != "*" {
+			from, to, ranged := strings.Cut(span, "-")
+			var err error
+			if low, err = _parseValue(from, field); err != nil {
+				return nil, err
+			}
+			high = low
+			if ranged {
+				if high, err = _parseValue(to, field); err != nil {
+					return nil, err
+				}
+			} else if stepped {
+				high = _bounds[field][1]
+			}
+			if high < low {
+				return nil, fmt.Errorf("%s is not an ascending range", span)
+			}
+		}
+		for value := low; value <= high; value += step {
+			values[value] = true
+		}
+	}
+	return values, nil
+}
+
+func ParseSchedule(expression string) (Schedule, error) {
+	schedule := Schedule{Expression: expression}
+	fields := strings.Fields(expression)
+	if len(fields) != len(_bounds) {
+		return schedule, fmt.Errorf("schedule %q must have %d fields", expression, len(_bounds))
+	}
+	for i, field := range fields {
+		values, err := _parseField(field, i)
+		if err != nil {
+			return schedule, fmt.Errorf("schedule %q: %w", expression, err)
+		}
+		schedule.fields[i] = values
+		schedule.restricted[i] = field != "*"
+	}
+	return schedule, nil
+}
+
+// True when the minute of the time is one of the schedule's
+func (s Schedule) Matches(t time.Time) bool {
+	t = t.UTC()
+	dayOfMonth := s.fields[2][t.Day()]
+	dayOfWeek := s.fields[4][int(t.Weekday())]
+	day := dayOfMonth && dayOfWeek
+	if s.restricted[2] && s.restricted[4] {
+		day = dayOfMonth || dayOfWeek
+	}
+	return s.fields[0][t.Minute()] && s.fields[1][t.Hour()] && s.fields[3][int(t.Month())] && day
+}
+
+// True when any minute after since, up to and including until, matches, so a
+// late or missed tick still runs what was scheduled since the previous one
+func (s Schedule) MatchesBetween(since time.Time, until time.Time) bool {
+	for minute := since.Truncate(time.Minute).Add(time.Minute); !minute.After(until); minute = minute.Add(time.Minute) {
+		if s.Matches(minute) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/migrations"
+	"philcali.me/recipes/internal/notifications"
+)
+
+// SNS subscriptions created before events were published for every account
+// have no account filter. Resource events are held back until every one is
+// filtered, so this runs until it completes once.
+type FilterSubscriptionsJob struct {
+	Notifications notifications.NotificationService
+	Migrations    migrations.Store
+	DynamoDB      *dynamodb.Client
+	TableName     string
+}
+
+func (fj *FilterSubscriptionsJob) Name() string {
+	return migrations.FILTER_SUBSCRIPTIONS
+}
+
+type _filteredSubscription struct {
+	_key
+	SubscriberArn string   `dynamodbav:"subscriberArn"`
+	EventTypes    []string `dynamodbav:"eventTypes"`
+	ResourceTypes []string `dynamodbav:"resourceTypes"`
+}
+
+func (fj *FilterSubscriptionsJob) Run(ctx context.Context, now time.Time) error {
+	complete, err := fj.Migrations.IsComplete(migrations.FILTER_SUBSCRIPTIONS)
+	if err != nil || complete {
+		return err
+	}
+	filtered := 0
+	var errs []error
+	filter := expression.Name("protocol").NotEqual(expression.Value(data.WEBHOOK_PROTOCOL)).
+		And(expression.Name("subscriberArn").BeginsWith("arn:"))
+	err = _scan(ctx, fj.DynamoDB, fj.TableName, filter, func(item _filteredSubscription) error {
+		if item.ResourceType() != "Subscription" {
+			return nil
+		}
+		accountId := item.AccountId()
+		err := fj.Notifications.SetFilter(item.SubscriberArn, notifications.SubscribeInput{
+			AccountId:     &accountId,
+			EventTypes:    item.EventTypes,
+			ResourceTypes: item.ResourceTypes,
+		})
+		// Subscriptions removed from SNS receive nothing, and are cleaned up
+		if errors.Is(err, notifications.ErrSubscriptionNotFound) {
+			return nil
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to filter %s: %w", item.SK, err))
+			return nil
+		}
+		filtered++
+		return nil
+	})
+	fmt.Printf("Filtered %d subscriptions by account\n", filtered)
+	if err := errors.Join(append(errs, err)...); err != nil {
+		return err
+	}
+	return fj.Migrations.Complete(migrations.FILTER_SUBSCRIPTIONS)
+}
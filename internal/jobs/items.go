@@ -0,0 +1,54 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/exceptions"
+)
+
+// Lists are given item ids on their first item change, which leaves the
+// lists that are never changed without them
+type FillListItemIdsJob struct {
+	Lists     data.ShoppingListDataService
+	DynamoDB  *dynamodb.Client
+	TableName string
+}
+
+func (fj *FillListItemIdsJob) Name() string {
+	return "fill-list-item-ids"
+}
+
+func (fj *FillListItemIdsJob) Run(ctx context.Context, now time.Time) error {
+	filled := 0
+	lists := expression.Name("PK").Contains(":ShoppingList")
+	err := _scan(ctx, fj.DynamoDB, fj.TableName, lists, func(list data.ShoppingListDTO) error {
+		key := _key{PK: list.PK, SK: list.SK}
+		// Templates are kept under ShoppingListTemplate
+		if key.ResourceType() != "ShoppingList" {
+			return nil
+		}
+		items := slices.Clone(list.Items)
+		if !data.FillItemIds(list.SK, items) {
+			return nil
+		}
+		// Lists changed since the scan are given ids by the change or the next run
+		_, err := fj.Lists.Patch(key.AccountId(), list.SK, data.ShoppingListInputDTO{Items: &items}, data.Patch{
+			Conditions: map[string]any{"updateTime": list.UpdateTime},
+		})
+		if _, ok := err.(*exceptions.ConflictError); ok {
+			return nil
+		}
+		if err == nil {
+			filled++
+		}
+		return err
+	})
+	fmt.Printf("Filled the item ids of %d lists\n", filled)
+	return err
+}
@@ -0,0 +1,436 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	lambdaEvents "github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"philcali.me/recipes/internal/auth"
+	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/dynamodb/apitokens"
+	"philcali.me/recipes/internal/dynamodb/deliveries"
+	"philcali.me/recipes/internal/dynamodb/limits"
+	"philcali.me/recipes/internal/dynamodb/shares"
+	"philcali.me/recipes/internal/dynamodb/shopping"
+	"philcali.me/recipes/internal/dynamodb/subscriptions"
+	"philcali.me/recipes/internal/dynamodb/token"
+	"philcali.me/recipes/internal/events"
+	"philcali.me/recipes/internal/migrations"
+	"philcali.me/recipes/internal/notifications"
+	"philcali.me/recipes/internal/test"
+)
+
+func _time(t *testing.T, value string) time.Time {
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t.Fatalf("Failed to parse %s: %v", value, err)
+	}
+	return parsed
+}
+
+func TestSchedule(t *testing.T) {
+	for _, test := range []struct {
+		expression string
+		time       string
+		expected   bool
+	}{
+		{"* * * * *", "2024-05-01T12:34:00Z", true},
+		{"0 * * * *", "2024-05-01T12:00:00Z", true},
+		{"0 * * * *", "2024-05-01T12:01:00Z", false},
+		{"*/15 8-17 * * 1-5", "2024-05-01T08:45:00Z", true},
+		{"*/15 8-17 * * 1-5", "2024-05-04T08:45:00Z", false},
+		{"0 5 * * 7", "2024-05-05T05:00:00Z", true},
+		{"30 2 1,15 * *", "2024-05-15T02:30:00Z", true},
+		// Restricting both days matches either
+		{"0 0 1 * 1", "2024-05-06T00:00:00Z", true},
+		{"0 0 1 * 1", "2024-05-07T00:00:00Z", false},
+		{"0 0 * 6 *", "2024-05-01T00:00:00Z", false},
+	} {
+		schedule, err := ParseSchedule(test.expression)
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", test.expression, err)
+		}
+		if schedule.Matches(_time(t, test.time)) != test.expected {
+			t.Fatalf("Expected %s at %s to be %v", test.expression, test.time, test.expected)
+		}
+	}
+	for _, expression := range []string{"* * * *", "60 * * * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		if _, err := ParseSchedule(expression); err == nil {
+			t.Fatalf("Expected %s to fail", expression)
+		}
+	}
+}
+
+type _job struct {
+	name string
+	err  error
+	runs int
+}
+
+func (j *_job) Name() string {
+	return j.name
+}
+
+func (j *_job) Run(ctx context.Context, now time.Time) error {
+	j.runs++
+	return j.err
+}
+
+func TestRegistry(t *testing.T) {
+	hourly := &_job{name: "hourly", err: errors.New("failed")}
+	nightly := &_job{name: "nightly"}
+	registry := NewRegistry()
+	if err := registry.Register("0 * * * *", hourly); err != nil {
+		t.Fatalf("Failed to register: %v", err)
+	}
+	if err := registry.Register("0 3 * * *", nightly); err != nil {
+		t.Fatalf("Failed to register: %v", err)
+	}
+	if err := registry.Register("0 3 * * *", &_job{name: "nightly"}); err == nil {
+		t.Fatal("Expected a job to be registered once")
+	}
+	// A late tick still runs the jobs scheduled since the previous one
+	due := registry.Due(_time(t, "2024-05-01T02:00:00Z"), _time(t, "2024-05-01T03:02:00Z"))
+	if len(due) != 2 {
+		t.Fatalf("Expected both jobs to be due, got %v", due)
+	}
+	if err := Run(context.Background(), time.Now(), due); err == nil || hourly.runs != 1 || nightly.runs != 1 {
+		t.Fatalf("Expected a failing job to leave the others to run, got %v", err)
+	}
+	if due := registry.Due(_time(t, "2024-05-01T03:02:00Z"), _time(t, "2024-05-01T04:00:00Z")); len(due) != 1 || due[0] != hourly {
+		t.Fatalf("Expected only the hourly job to be due, got %v", due)
+	}
+	if due := registry.Due(_time(t, "2024-05-01T03:00:00Z"), _time(t, "2024-05-01T03:59:00Z")); len(due) != 0 {
+		t.Fatalf("Expected a job run by the previous tick to not be due, got %v", due)
+	}
+	if _, err := registry.Named("nightly", "weekly"); err == nil {
+		t.Fatal("Expected an unknown job to fail")
+	}
+}
+
+type _notifications struct {
+	notifications.NotificationService
+	Removed  map[string]bool
+	Filtered map[string]notifications.SubscribeInput
+}
+
+func (n *_notifications) IsPendingConfirmation(subscriberId string) (bool, error) {
+	if n.Removed[subscriberId] {
+		return false, notifications.ErrSubscriptionNotFound
+	}
+	return false, nil
+}
+
+func (n *_notifications) SetFilter(subscriberId string, input notifications.SubscribeInput) error {
+	if n.Removed[subscriberId] {
+		return notifications.ErrSubscriptionNotFound
+	}
+	n.Filtered[subscriberId] = input
+	return nil
+}
+
+func _putItem(t *testing.T, client *dynamodb.Client, tableName string, item any) {
+	av, err := attributevalue.MarshalMap(item)
+	if err != nil {
+		t.Fatalf("Failed to marshal %v: %v", item, err)
+	}
+	if _, err := client.PutItem(context.TODO(), &dynamodb.PutItemInput{TableName: aws.String(tableName), Item: av}); err != nil {
+		t.Fatalf("Failed to put %v: %v", item, err)
+	}
+}
+
+func _exists(t *testing.T, client *dynamodb.Client, tableName string, pk string, sk string) bool {
+	job := &ReconcileSharedCopiesJob{DynamoDB: client, TableName: tableName}
+	exists, err := job._exists(context.TODO(), pk, sk)
+	if err != nil {
+		t.Fatalf("Failed to get %s %s: %v", pk, sk, err)
+	}
+	return exists
+}
+
+func TestJobs(t *testing.T) {
+	localServer := test.StartLocalServer(test.LOCAL_DDB_PORT+4, t)
+	client, err := localServer.CreateLocalClient()
+	if err != nil {
+		t.Fatalf("Failed to create DDB client: %s", err)
+	}
+	tableName, err := test.CreateTable(client)
+	if err != nil {
+		t.Fatalf("Failed to create DDB table: %s", err)
+	}
+	marshaler := token.NewGCM()
+	now := time.Now()
+
+	t.Run("PurgeExpired", func(t *testing.T) {
+		_putItem(t, client, tableName, data.ShoppingListDTO{PK: "owner:ShoppingList", SK: "expired", ExpiresIn: aws.Int(int(now.Add(-time.Hour).Unix()))})
+		_putItem(t, client, tableName, data.ShoppingListDTO{PK: "owner:ShoppingList", SK: "expiring", ExpiresIn: aws.Int(int(now.Add(time.Hour).Unix()))})
+		_putItem(t, client, tableName, data.ShoppingListDTO{PK: "owner:ShoppingList", SK: "forever"})
+		// Tokens and share requests expire in milliseconds
+		_putItem(t, client, tableName, data.ApiTokenDTO{PK: "Global:ApiToken", SK: "expired", SecretHash: "expired", ExpiresIn: aws.Int(int(now.Add(-time.Hour).UnixMilli()))})
+		_putItem(t, client, tableName, data.ApiTokenDTO{PK: "Global:ApiToken", SK: "expiring", SecretHash: "expiring", ExpiresIn: aws.Int(int(now.Add(time.Hour).UnixMilli()))})
+		_putItem(t, client, tableName, data.ShareRequestDTO{PK: "owner:ShareRequest", SK: "expired", ExpiresIn: aws.Int(int(now.Add(-time.Hour).UnixMilli()))})
+		_putItem(t, client, tableName, data.ShareRequestDTO{PK: "owner:ShareRequest", SK: "expiring", ExpiresIn: aws.Int(int(now.Add(time.Hour).UnixMilli()))})
+		job := &PurgeExpiredJob{DynamoDB: client, TableName: tableName}
+		if err := job.Run(context.TODO(), now); err != nil {
+			t.Fatalf("Failed to purge: %v", err)
+		}
+		if _exists(t, client, tableName, "owner:ShoppingList", "expired") {
+			t.Fatal("Expected the expired list to be purged")
+		}
+		if !_exists(t, client, tableName, "owner:ShoppingList", "expiring") || !_exists(t, client, tableName, "owner:ShoppingList", "forever") {
+			t.Fatal("Expected the unexpired items to be left alone")
+		}
+		if _exists(t, client, tableName, "Global:ApiToken", "expired") || _exists(t, client, tableName, "owner:ShareRequest", "expired") {
+			t.Fatal("Expected the expired token and share request to be purged")
+		}
+		if !_exists(t, client, tableName, "Global:ApiToken", "expiring") || !_exists(t, client, tableName, "owner:ShareRequest", "expiring") {
+			t.Fatal("Expected the unexpired token and share request to be left alone")
+		}
+
+		// Share requests are answered within their expiry, not within a night
+		_putItem(t, client, tableName, data.ShareRequestDTO{PK: "owner:ShareRequest", SK: "fresh", ExpiresIn: aws.Int(int(now.Add(data.SHARE_REQUEST_EXPIRY).UnixMilli()))})
+		if err := job.Run(context.TODO(), now.Add(24*time.Hour)); err != nil {
+			t.Fatalf("Failed to purge: %v", err)
+		}
+		if !_exists(t, client, tableName, "owner:ShareRequest", "fresh") {
+			t.Fatal("Expected a freshly created share request to survive the next purge")
+		}
+	})
+
+	t.Run("ReconcileSharedCopies", func(t *testing.T) {
+		sharing := shares.NewShareService(tableName, *client, marshaler)
+		approved := data.APPROVED
+		_, err := sharing.CreateWithItemId("friend", data.ShareRequestInputDTO{
+			Requester:      aws.String("owner@email.com"),
+			RequesterId:    aws.String("owner"),
+			ApproverId:     aws.String("friend"),
+			ApprovalStatus: &approved,
+		}, "share")
+		if err != nil {
+			t.Fatalf("Failed to share: %v", err)
+		}
+		_putItem(t, client, tableName, data.ShoppingListDTO{PK: "owner:ShoppingList", SK: "kept", Shared: aws.Bool(false)})
+		_putItem(t, client, tableName, data.ShoppingListDTO{PK: "friend:ShoppingList", SK: "kept", Shared: aws.Bool(true)})
+		_putItem(t, client, tableName, data.ShoppingListDTO{PK: "friend:ShoppingList", SK: "orphaned", Shared: aws.Bool(true)})
+		_putItem(t, client, tableName, data.ShoppingListDTO{PK: "stranger:ShoppingList", SK: "kept", Shared: aws.Bool(true)})
+		job := &ReconcileSharedCopiesJob{Sharing: sharing, DynamoDB: client, TableName: tableName}
+		if err := job.Run(context.TODO(), now); err != nil {
+			t.Fatalf("Failed to reconcile: %v", err)
+		}
+		for pk, expected := range map[string]bool{"owner:ShoppingList": true, "friend:ShoppingList": true, "stranger:ShoppingList": false} {
+			if exists := _exists(t, client, tableName, pk, "kept"); exists != expected {
+				t.Fatalf("Expected %s to exist %v, got %v", pk, expected, exists)
+			}
+		}
+		if _exists(t, client, tableName, "friend:ShoppingList", "orphaned") {
+			t.Fatal("Expected the copy of a deleted list to be removed")
+		}
+	})
+
+	t.Run("CleanupSubscriptions", func(t *testing.T) {
+		subscriptionData := subscriptions.NewSubscriptionService(tableName, *client, marshaler)
+		create := func(protocol string, arn string) string {
+			created, err := subscriptionData.Create("owner", data.SubscriptionInputDTO{
+				Endpoint:      aws.String(fmt.Sprintf("%s://endpoint", protocol)),
+				Protocol:      aws.String(protocol),
+				SubscriberArn: aws.String(arn),
+			})
+			if err != nil {
+				t.Fatalf("Failed to subscribe: %v", err)
+			}
+			return created.SK
+		}
+		removed := create("https", "arn:aws:sns:removed")
+		active := create("https", "arn:aws:sns:active")
+		webhook := create(data.WEBHOOK_PROTOCOL, "arn:aws:sns:removed")
+		job := &CleanupSubscriptionsJob{
+			Subscriptions: subscriptionData,
+			Notifications: &_notifications{Removed: map[string]bool{"arn:aws:sns:removed": true}},
+			DynamoDB:      client,
+			TableName:     tableName,
+		}
+		if err := job.Run(context.TODO(), now); err != nil {
+			t.Fatalf("Failed to clean up: %v", err)
+		}
+		if _exists(t, client, tableName, "owner:Subscription", removed) {
+			t.Fatal("Expected the subscription removed from SNS to be deleted")
+		}
+		if !_exists(t, client, tableName, "owner:Subscription", active) || !_exists(t, client, tableName, "owner:Subscription", webhook) {
+			t.Fatal("Expected the other subscriptions to be left alone")
+		}
+	})
+	t.Run("FilterSubscriptions", func(t *testing.T) {
+		subscriptionData := subscriptions.NewSubscriptionService(tableName, *client, marshaler)
+		if _, err := subscriptionData.Create("filtered", data.SubscriptionInputDTO{
+			Endpoint:      aws.String("https://endpoint"),
+			Protocol:      aws.String("https"),
+			SubscriberArn: aws.String("arn:aws:sns:filtered"),
+			EventTypes:    []string{"recipe.created"},
+		}); err != nil {
+			t.Fatalf("Failed to subscribe: %v", err)
+		}
+		published := &_notifications{
+			Removed:  map[string]bool{"arn:aws:sns:removed": true},
+			Filtered: make(map[string]notifications.SubscribeInput),
+		}
+		store := migrations.NewMemoryStore()
+		job := &FilterSubscriptionsJob{
+			Notifications: published,
+			Migrations:    store,
+			DynamoDB:      client,
+			TableName:     tableName,
+		}
+		if err := job.Run(context.TODO(), now); err != nil {
+			t.Fatalf("Failed to filter: %v", err)
+		}
+		filtered, ok := published.Filtered["arn:aws:sns:filtered"]
+		if !ok || *filtered.AccountId != "filtered" || len(filtered.EventTypes) != 1 {
+			t.Fatalf("Expected the subscription to be filtered by its account, got %v", published.Filtered)
+		}
+		if complete, err := store.IsComplete(migrations.FILTER_SUBSCRIPTIONS); err != nil || !complete {
+			t.Fatalf("Expected the migration to complete, got %v: %v", complete, err)
+		}
+		published.Filtered = make(map[string]notifications.SubscribeInput)
+		if err := job.Run(context.TODO(), now); err != nil || len(published.Filtered) != 0 {
+			t.Fatalf("Expected a completed migration to not run again, got %v: %v", published.Filtered, err)
+		}
+	})
+	t.Run("RetryWebhooks", func(t *testing.T) {
+		calls := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if calls == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+		subscriptionData := subscriptions.NewSubscriptionService(tableName, *client, marshaler)
+		deliveryData := deliveries.NewDeliveryService(tableName, *client, marshaler)
+		handler := events.DefaultWebhookHandler(subscriptionData, deliveryData)
+		// Test servers listen on loopback, which the default client refuses
+		handler.Deliverer.Client = server.Client()
+		subscription, err := subscriptionData.Create("hooks", data.SubscriptionInputDTO{
+			Endpoint: aws.String(server.URL),
+			Protocol: aws.String(data.WEBHOOK_PROTOCOL),
+			Secret:   aws.String("secret"),
+		})
+		if err != nil {
+			t.Fatalf("Failed to subscribe: %v", err)
+		}
+		record := lambdaEvents.DynamoDBEventRecord{
+			EventID:   "event-1",
+			EventName: "INSERT",
+			Change: lambdaEvents.DynamoDBStreamRecord{
+				Keys: map[string]lambdaEvents.DynamoDBAttributeValue{
+					"PK": lambdaEvents.NewStringAttribute("hooks:Recipe"),
+					"SK": lambdaEvents.NewStringAttribute("recipe"),
+				},
+			},
+		}
+		// Handling the record again does not deliver it again
+		for i := 0; i < 2; i++ {
+			if err := handler.Apply(record); err != nil {
+				t.Fatalf("Failed to deliver: %v", err)
+			}
+		}
+		deliveryId := events.DeliveryId("event-1", subscription.SK)
+		delivery, err := deliveryData.Get("hooks", deliveryId)
+		if err != nil {
+			t.Fatalf("Failed to get delivery: %v", err)
+		}
+		if calls != 1 || delivery.Status != data.DELIVERY_PENDING || len(delivery.Attempts) != 1 || delivery.NextAttemptTime == nil {
+			t.Fatalf("Expected one attempt pending a retry, got %d calls and %v", calls, delivery)
+		}
+		job := &RetryWebhooksJob{Webhooks: handler, DynamoDB: client, TableName: tableName}
+		if err := job.Run(context.TODO(), now); err != nil || calls != 1 {
+			t.Fatalf("Expected the retry to wait for its backoff, got %d calls: %v", calls, err)
+		}
+		if err := job.Run(context.TODO(), now.Add(time.Hour)); err != nil {
+			t.Fatalf("Failed to retry: %v", err)
+		}
+		delivery, err = deliveryData.Get("hooks", deliveryId)
+		if err != nil {
+			t.Fatalf("Failed to get delivery: %v", err)
+		}
+		if calls != 2 || delivery.Status != data.DELIVERY_SUCCEEDED || len(delivery.Attempts) != 2 || delivery.NextAttemptTime != nil {
+			t.Fatalf("Expected the retry to succeed, got %d calls and %v", calls, delivery)
+		}
+	})
+	t.Run("MigrateLegacyTokens", func(t *testing.T) {
+		apiTokens := apitokens.NewApiTokenService(tableName, *client, marshaler)
+		_, err := apiTokens.CreateWithItemId("Global", data.ApiTokenInputDTO{
+			Name:      aws.String("Legacy"),
+			Scopes:    &[]data.Scope{data.RECIPE_READ},
+			AccountId: aws.String("owner"),
+		}, "legacy-secret")
+		if err != nil {
+			t.Fatalf("Failed to create a legacy token: %v", err)
+		}
+		job := &MigrateLegacyTokensJob{ApiTokens: apiTokens, DynamoDB: client, TableName: tableName}
+		if err := job.Run(context.TODO(), now); err != nil {
+			t.Fatalf("Failed to migrate: %v", err)
+		}
+		if _exists(t, client, tableName, "Global:ApiToken", "legacy-secret") {
+			t.Fatal("Expected the plaintext secret to be removed")
+		}
+		migrated, err := apiTokens.Get("Global", auth.HashSecret("legacy-secret"))
+		if err != nil || migrated.SecretHash == "" || migrated.AccountId != "owner" {
+			t.Fatalf("Expected the token to be kept under its hash, got %v: %v", migrated, err)
+		}
+	})
+	t.Run("FillListItemIds", func(t *testing.T) {
+		lists := shopping.NewShoppingListService(tableName, *client, marshaler)
+		_putItem(t, client, tableName, data.ShoppingListDTO{
+			PK:    "filled:ShoppingList",
+			SK:    "legacy",
+			Items: []data.ShoppingListItemDTO{{Name: "Milk"}, {Id: "eggs", Name: "Eggs"}},
+		})
+		job := &FillListItemIdsJob{Lists: lists, DynamoDB: client, TableName: tableName}
+		if err := job.Run(context.TODO(), now); err != nil {
+			t.Fatalf("Failed to fill item ids: %v", err)
+		}
+		list, err := lists.Get("filled", "legacy")
+		if err != nil || len(list.Items) != 2 || list.Items[0].Id == "" || list.Items[1].Id != "eggs" {
+			t.Fatalf("Expected the missing item id to be filled, got %v: %v", list, err)
+		}
+	})
+	t.Run("CountQuotas", func(t *testing.T) {
+		counter := limits.NewQuotaService(tableName, *client)
+		_putItem(t, client, tableName, data.RecipeDTO{PK: "counted:Recipe", SK: "first"})
+		_putItem(t, client, tableName, data.RecipeDTO{PK: "counted:Recipe", SK: "second"})
+		if err := counter.Set("counted", "lists", 3); err != nil {
+			t.Fatalf("Failed to set a drifted count: %v", err)
+		}
+		job := &CountQuotasJob{Counter: counter, DynamoDB: client, TableName: tableName}
+		if err := job.Run(context.TODO(), now); err != nil {
+			t.Fatalf("Failed to count quotas: %v", err)
+		}
+		for resource, expected := range map[string]int{"recipes": 2, "lists": 0} {
+			if count, _, err := counter.Usage("counted", resource); err != nil || count != expected {
+				t.Fatalf("Expected %d %s, got %d: %v", expected, resource, count, err)
+			}
+		}
+		if reserved, err := counter.Reserve("counted", "recipes", 2); err != nil || reserved {
+			t.Fatalf("Expected the seeded count to reach the quota: %v", err)
+		}
+		if reserved, err := counter.Reserve("counted", "lists", 1); err != nil || !reserved {
+			t.Fatalf("Expected a reservation under the quota: %v", err)
+		}
+		if err := counter.Add("counted", "lists", -2); err != nil {
+			t.Fatalf("Failed to release: %v", err)
+		}
+		if count, _, err := counter.Usage("counted", "lists"); err != nil || count != 1 {
+			t.Fatalf("Expected a release to not go below zero, got %d: %v", count, err)
+		}
+	})
+}
@@ -0,0 +1,60 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+// Resource types whose expiresIn is in milliseconds rather than seconds
+var EXPIRY_MILLISECONDS = map[string]bool{
+	"ApiToken":     true,
+	"ShareRequest": true,
+	"Audit":        true,
+}
+
+type _expiring struct {
+	PK        string `dynamodbav:"PK"`
+	SK        string `dynamodbav:"SK"`
+	ExpiresIn int64  `dynamodbav:"expiresIn"`
+}
+
+// DynamoDB TTL can take days to delete items past their expiresIn, so expired
+// share requests, audits, tokens and lists are purged as they expire
+type PurgeExpiredJob struct {
+	DynamoDB  *dynamodb.Client
+	TableName string
+}
+
+func (pj *PurgeExpiredJob) Name() string {
+	return "purge-expired"
+}
+
+func (pj *PurgeExpiredJob) Run(ctx context.Context, now time.Time) error {
+	// Expiries in seconds are all before now in milliseconds, so the scan
+	// finds every candidate and each is checked in its own unit
+	candidates := expression.Name("expiresIn").LessThan(expression.Value(now.UnixMilli()))
+	purged := 0
+	err := _scan(ctx, pj.DynamoDB, pj.TableName, candidates, func(item _expiring) error {
+		key := _key{PK: item.PK, SK: item.SK}
+		expiry := now.Unix()
+		if EXPIRY_MILLISECONDS[key.ResourceType()] {
+			expiry = now.UnixMilli()
+		}
+		if item.ExpiresIn >= expiry {
+			return nil
+		}
+		// Items extended since they were scanned are left alone
+		expired := expression.Name("expiresIn").LessThan(expression.Value(expiry))
+		deleted, err := _deleteIf(ctx, pj.DynamoDB, pj.TableName, item.PK, item.SK, expired)
+		if deleted {
+			purged++
+		}
+		return err
+	})
+	fmt.Printf("Purged %d expired items\n", purged)
+	return err
+}
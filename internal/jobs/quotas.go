@@ -0,0 +1,62 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"philcali.me/recipes/internal/limits"
+)
+
+// Creates only reserve against a count, so counts are seeded from the items
+// in the table and corrected where they drifted. Creates racing the count
+// are corrected on the next run.
+type CountQuotasJob struct {
+	Counter   limits.QuotaCounter
+	DynamoDB  *dynamodb.Client
+	TableName string
+}
+
+func (cj *CountQuotasJob) Name() string {
+	return "count-quotas"
+}
+
+func (cj *CountQuotasJob) Run(ctx context.Context, now time.Time) error {
+	// Counts are kept by account, then by the quota resource
+	counts := make(map[string]map[string]int)
+	count := func(accountId string, resource string, delta int) {
+		if _, ok := counts[accountId]; !ok {
+			counts[accountId] = make(map[string]int)
+		}
+		counts[accountId][resource] += delta
+	}
+	filter := expression.Name("PK").Contains(":Quota")
+	for resourceType := range limits.QUOTA_RESOURCE_TYPES {
+		filter = filter.Or(expression.Name("PK").Contains(":" + resourceType))
+	}
+	err := _scan(ctx, cj.DynamoDB, cj.TableName, filter, func(item _key) error {
+		// Existing counts are kept in the results, so ones without items are reset
+		if item.ResourceType() == "Quota" {
+			count(item.AccountId(), item.SK, 0)
+		} else if resource, ok := limits.QUOTA_RESOURCE_TYPES[item.ResourceType()]; ok {
+			count(item.AccountId(), resource, 1)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for accountId, resources := range counts {
+		for resource, total := range resources {
+			if err := cj.Counter.Set(accountId, resource, total); err != nil {
+				errs = append(errs, fmt.Errorf("failed to count %s of %s: %w", resource, accountId, err))
+			}
+		}
+	}
+	fmt.Printf("Counted quotas of %d accounts\n", len(counts))
+	return errors.Join(errs...)
+}
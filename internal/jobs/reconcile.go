@@ -0,0 +1,98 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"philcali.me/recipes/internal/data"
+)
+
+// Copies of shared recipes and lists are left behind when their original is
+// deleted or the share between the accounts is removed. A copy is orphaned
+// when no account it shares with holds the resource, so copies kept alive by
+// one another are left alone rather than risk deleting an original.
+type ReconcileSharedCopiesJob struct {
+	Sharing   data.ShareRequestRepository
+	DynamoDB  *dynamodb.Client
+	TableName string
+}
+
+func (rj *ReconcileSharedCopiesJob) Name() string {
+	return "reconcile-shared-copies"
+}
+
+// Accounts with an approved share with the account
+func (rj *ReconcileSharedCopiesJob) _partners(accountId string) ([]string, error) {
+	var partners []string
+	params := data.QueryParams{Limit: 100}
+	for {
+		sharing, err := rj.Sharing.List(accountId, params)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range sharing.Items {
+			if item.ApprovalStatus != data.APPROVED || item.RequesterId == nil || item.ApproverId == nil {
+				continue
+			}
+			partner := *item.RequesterId
+			if strings.EqualFold(partner, accountId) {
+				partner = *item.ApproverId
+			}
+			partners = append(partners, partner)
+		}
+		if sharing.NextToken == nil {
+			return partners, nil
+		}
+		params.NextToken = sharing.NextToken
+	}
+}
+
+func (rj *ReconcileSharedCopiesJob) _exists(ctx context.Context, pk string, sk string) (bool, error) {
+	output, err := rj.DynamoDB.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(rj.TableName),
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: pk},
+			"SK": &types.AttributeValueMemberS{Value: sk},
+		},
+		ProjectionExpression: aws.String("PK"),
+	})
+	if err != nil {
+		return false, err
+	}
+	return output.Item != nil, nil
+}
+
+func (rj *ReconcileSharedCopiesJob) Run(ctx context.Context, now time.Time) error {
+	partners := make(map[string][]string)
+	removed := 0
+	shared := expression.Name("shared").Equal(expression.Value(true))
+	err := _scan(ctx, rj.DynamoDB, rj.TableName, shared, func(item _key) error {
+		accountId := item.AccountId()
+		if _, ok := partners[accountId]; !ok {
+			found, err := rj._partners(accountId)
+			if err != nil {
+				return err
+			}
+			partners[accountId] = found
+		}
+		for _, partner := range partners[accountId] {
+			exists, err := rj._exists(ctx, fmt.Sprintf("%s:%s", partner, item.ResourceType()), item.SK)
+			if err != nil || exists {
+				return err
+			}
+		}
+		deleted, err := _deleteIf(ctx, rj.DynamoDB, rj.TableName, item.PK, item.SK, shared)
+		if deleted {
+			removed++
+		}
+		return err
+	})
+	fmt.Printf("Removed %d orphaned shared copies\n", removed)
+	return err
+}
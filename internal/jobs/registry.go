@@ -0,0 +1,102 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Maintenance tasks, run on a schedule rather than in response to a request
+type Job interface {
+	Name() string
+	Run(ctx context.Context, now time.Time) error
+}
+
+type Entry struct {
+	Job      Job
+	Schedule Schedule
+}
+
+// Jobs by name, in the order they are registered
+type Registry struct {
+	Entries []Entry
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) Register(schedule string, job Job) error {
+	parsed, err := ParseSchedule(schedule)
+	if err != nil {
+		return err
+	}
+	for _, entry := range r.Entries {
+		if entry.Job.Name() == job.Name() {
+			return fmt.Errorf("job %s is already registered", job.Name())
+		}
+	}
+	r.Entries = append(r.Entries, Entry{Job: job, Schedule: parsed})
+	return nil
+}
+
+// Jobs whose schedule matches a minute since the previous tick, up to and
+// including now
+func (r *Registry) Due(previous time.Time, now time.Time) []Job {
+	var due []Job
+	for _, entry := range r.Entries {
+		if entry.Schedule.MatchesBetween(previous, now) {
+			due = append(due, entry.Job)
+		}
+	}
+	return due
+}
+
+func (r *Registry) Named(names ...string) ([]Job, error) {
+	jobs := make([]Job, 0, len(names))
+	for _, name := range names {
+		found := false
+		for _, entry := range r.Entries {
+			if entry.Job.Name() == name {
+				jobs = append(jobs, entry.Job)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("job %s is not registered", name)
+		}
+	}
+	return jobs, nil
+}
+
+// Runs every job, a job failing leaves the others to run
+func Run(ctx context.Context, now time.Time, jobs []Job) error {
+	var errs []error
+	for _, job := range jobs {
+		start := time.Now()
+		if err := job.Run(ctx, now); err != nil {
+			fmt.Printf("ERROR: job %s failed after %s: %v\n", job.Name(), time.Since(start), err)
+			errs = append(errs, fmt.Errorf("job %s: %w", job.Name(), err))
+			continue
+		}
+		fmt.Printf("Job %s finished in %s\n", job.Name(), time.Since(start))
+	}
+	return errors.Join(errs...)
+}
+
+// Runs the due jobs at the start of every minute until the context is done,
+// standing in for the EventBridge schedule when run locally
+func (r *Registry) RunLocal(ctx context.Context) error {
+	for {
+		now := time.Now()
+		next := now.Truncate(time.Minute).Add(time.Minute)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(next.Sub(now)):
+			Run(ctx, next, r.Due(next.Add(-time.Minute), next))
+		}
+	}
+}
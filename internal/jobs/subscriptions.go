@@ -0,0 +1,54 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/notifications"
+)
+
+// Endpoints can unsubscribe themselves from SNS, which leaves their
+// subscription behind
+type CleanupSubscriptionsJob struct {
+	Subscriptions data.SubscriptionDataService
+	Notifications notifications.NotificationService
+	DynamoDB      *dynamodb.Client
+	TableName     string
+}
+
+func (cj *CleanupSubscriptionsJob) Name() string {
+	return "cleanup-subscriptions"
+}
+
+type _subscription struct {
+	_key
+	SubscriberArn string `dynamodbav:"subscriberArn"`
+}
+
+func (cj *CleanupSubscriptionsJob) Run(ctx context.Context, now time.Time) error {
+	removed := 0
+	// Webhooks are delivered without SNS, and pending SNS subscriptions have no ARN yet
+	filter := expression.Name("protocol").NotEqual(expression.Value(data.WEBHOOK_PROTOCOL)).
+		And(expression.Name("subscriberArn").BeginsWith("arn:"))
+	err := _scan(ctx, cj.DynamoDB, cj.TableName, filter, func(item _subscription) error {
+		if item.ResourceType() != "Subscription" {
+			return nil
+		}
+		_, err := cj.Notifications.IsPendingConfirmation(item.SubscriberArn)
+		if !errors.Is(err, notifications.ErrSubscriptionNotFound) {
+			return err
+		}
+		if err := cj.Subscriptions.Delete(item.AccountId(), item.SK); err != nil {
+			return err
+		}
+		removed++
+		return nil
+	})
+	fmt.Printf("Removed %d subscriptions missing from SNS\n", removed)
+	return err
+}
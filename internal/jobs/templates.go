@@ -0,0 +1,24 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"philcali.me/recipes/internal/templates"
+)
+
+// Makes lists of the templates that are due
+type InstantiateTemplatesJob struct {
+	Scheduler *templates.Scheduler
+}
+
+func (ij *InstantiateTemplatesJob) Name() string {
+	return "instantiate-templates"
+}
+
+func (ij *InstantiateTemplatesJob) Run(ctx context.Context, now time.Time) error {
+	lists, err := ij.Scheduler.Run(now)
+	fmt.Printf("Made %d lists from templates due by %s\n", len(lists), now.Format(time.RFC3339))
+	return err
+}
@@ -0,0 +1,39 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"philcali.me/recipes/internal/auth"
+	"philcali.me/recipes/internal/data"
+)
+
+// The authorizer only migrates legacy tokens as they are presented, which
+// leaves the plaintext secrets of unused tokens in the table
+type MigrateLegacyTokensJob struct {
+	ApiTokens data.ApiTokenDataService
+	DynamoDB  *dynamodb.Client
+	TableName string
+}
+
+func (mj *MigrateLegacyTokensJob) Name() string {
+	return "migrate-legacy-tokens"
+}
+
+func (mj *MigrateLegacyTokensJob) Run(ctx context.Context, now time.Time) error {
+	migrated := 0
+	legacy := expression.Name("PK").Equal(expression.Value("Global:ApiToken")).
+		And(expression.Name("secretHash").AttributeNotExists().Or(expression.Name("secretHash").Equal(expression.Value(""))))
+	err := _scan(ctx, mj.DynamoDB, mj.TableName, legacy, func(item data.ApiTokenDTO) error {
+		if _, err := auth.MigrateLegacyToken(mj.ApiTokens, item); err != nil {
+			return err
+		}
+		migrated++
+		return nil
+	})
+	fmt.Printf("Migrated %d legacy tokens\n", migrated)
+	return err
+}
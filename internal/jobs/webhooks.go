@@ -0,0 +1,47 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/events"
+)
+
+// Webhooks are attempted once as their event is handled, and retried here
+// once their next attempt is due
+type RetryWebhooksJob struct {
+	Webhooks  *events.WebhookDeliveryHandler
+	DynamoDB  *dynamodb.Client
+	TableName string
+}
+
+func (rj *RetryWebhooksJob) Name() string {
+	return "retry-webhooks"
+}
+
+func (rj *RetryWebhooksJob) Run(ctx context.Context, now time.Time) error {
+	var failures []error
+	retried := 0
+	due := expression.Name("status").Equal(expression.Value(data.DELIVERY_PENDING)).
+		And(expression.Name("nextAttemptTime").LessThanEqual(expression.Value(now.Unix())))
+	err := _scan(ctx, rj.DynamoDB, rj.TableName, due, func(delivery data.DeliveryDTO) error {
+		key := _key{PK: delivery.PK, SK: delivery.SK}
+		if key.ResourceType() != "Delivery" {
+			return nil
+		}
+		// One endpoint failing leaves the other deliveries to be retried
+		if err := rj.Webhooks.Retry(key.AccountId(), delivery, now); err != nil {
+			failures = append(failures, fmt.Errorf("failed to retry delivery %s: %w", delivery.SK, err))
+			return nil
+		}
+		retried++
+		return nil
+	})
+	fmt.Printf("Retried %d webhook deliveries\n", retried)
+	return errors.Join(append(failures, err)...)
+}
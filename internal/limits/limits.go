@@ -0,0 +1,220 @@
+package limits
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	RATE_LIMIT_CAPACITY_ENV = "RATE_LIMIT_CAPACITY"
+	RATE_LIMIT_REFILL_ENV   = "RATE_LIMIT_REFILL_PER_SECOND"
+	// Quotas are "{resource}={count}" pairs, ie: recipes=5000,lists=500
+	ACCOUNT_QUOTAS_ENV = "ACCOUNT_QUOTAS"
+)
+
+// Bursts of a minute of requests, sustained at one request a second
+var DEFAULT_RATE_LIMIT = Limit{
+	Capacity:        60,
+	RefillPerSecond: 1,
+}
+
+var DEFAULT_QUOTAS = map[string]int{
+	"recipes": 5000,
+	"lists":   500,
+}
+
+type Limit struct {
+	Capacity        int
+	RefillPerSecond float64
+}
+
+type Bucket struct {
+	Tokens     float64
+	RefillTime time.Time
+}
+
+// Refills the bucket up to now and takes a token, returning how long until
+// a token is available when the bucket is empty
+func (l Limit) Take(bucket *Bucket, now time.Time) (Bucket, bool, time.Duration) {
+	tokens := float64(l.Capacity)
+	if bucket != nil {
+		elapsed := math.Max(0, now.Sub(bucket.RefillTime).Seconds())
+		tokens = math.Min(tokens, bucket.Tokens+elapsed*l.RefillPerSecond)
+	}
+	if tokens < 1 {
+		wait := time.Duration((1 - tokens) / l.RefillPerSecond * float64(time.Second))
+		return Bucket{Tokens: tokens, RefillTime: now}, false, wait
+	}
+	return Bucket{Tokens: tokens - 1, RefillTime: now}, true, 0
+}
+
+// How long an idle bucket takes to refill completely
+func (l Limit) FullAfter(bucket Bucket) time.Duration {
+	missing := float64(l.Capacity) - bucket.Tokens
+	return time.Duration(missing / l.RefillPerSecond * float64(time.Second))
+}
+
+type RateLimiter interface {
+	Take(key string, limit Limit, now time.Time) (bool, time.Duration, error)
+}
+
+type QuotaCounter interface {
+	// Adds the delta, never taking the count below zero
+	Add(accountId string, resource string, delta int) error
+	// Subtracts the removed item once, skipping the stream records at or
+	// before the last one applied, as retried records are
+	Remove(accountId string, resource string, sequenceNumber string) error
+	// Adds one when the count is under the quota, or under any quota overriding it
+	Reserve(accountId string, resource string, quota int) (bool, error)
+	// Replaces the count with the number of items counted by a backfill
+	Set(accountId string, resource string, count int) error
+	// Returns the count and any quota overriding the configured one
+	Usage(accountId string, resource string) (int, *int, error)
+}
+
+type MemoryRateLimiter struct {
+	mutex   sync.Mutex
+	buckets map[string]Bucket
+}
+
+func NewMemoryRateLimiter() *MemoryRateLimiter {
+	return &MemoryRateLimiter{
+		buckets: make(map[string]Bucket),
+	}
+}
+
+func (ml *MemoryRateLimiter) Take(key string, limit Limit, now time.Time) (bool, time.Duration, error) {
+	ml.mutex.Lock()
+	defer ml.mutex.Unlock()
+	var current *Bucket
+	if bucket, ok := ml.buckets[key]; ok {
+		current = &bucket
+	}
+	bucket, allowed, retryAfter := limit.Take(current, now)
+	ml.buckets[key] = bucket
+	return allowed, retryAfter, nil
+}
+
+// Stream sequence numbers have up to 40 digits, so they are padded to be
+// ordered as strings
+func SequenceOrder(sequenceNumber string) string {
+	return fmt.Sprintf("%040s", sequenceNumber)
+}
+
+type MemoryQuotaCounter struct {
+	mutex     sync.Mutex
+	counts    map[string]int
+	sequences map[string]string
+	Overrides map[string]int
+}
+
+func NewMemoryQuotaCounter() *MemoryQuotaCounter {
+	return &MemoryQuotaCounter{
+		counts:    make(map[string]int),
+		sequences: make(map[string]string),
+		Overrides: make(map[string]int),
+	}
+}
+
+func _quotaKey(accountId string, resource string) string {
+	return fmt.Sprintf("%s:%s", accountId, resource)
+}
+
+func (mq *MemoryQuotaCounter) Add(accountId string, resource string, delta int) error {
+	mq.mutex.Lock()
+	defer mq.mutex.Unlock()
+	key := _quotaKey(accountId, resource)
+	mq.counts[key] = max(0, mq.counts[key]+delta)
+	return nil
+}
+
+func (mq *MemoryQuotaCounter) Remove(accountId string, resource string, sequenceNumber string) error {
+	mq.mutex.Lock()
+	defer mq.mutex.Unlock()
+	key := _quotaKey(accountId, resource)
+	sequence := SequenceOrder(sequenceNumber)
+	if last, ok := mq.sequences[key]; ok && sequence <= last {
+		return nil
+	}
+	mq.sequences[key] = sequence
+	mq.counts[key] = max(0, mq.counts[key]-1)
+	return nil
+}
+
+func (mq *MemoryQuotaCounter) Reserve(accountId string, resource string, quota int) (bool, error) {
+	mq.mutex.Lock()
+	defer mq.mutex.Unlock()
+	key := _quotaKey(accountId, resource)
+	if override, ok := mq.Overrides[key]; ok {
+		quota = override
+	}
+	if mq.counts[key] >= quota {
+		return false, nil
+	}
+	mq.counts[key] += 1
+	return true, nil
+}
+
+func (mq *MemoryQuotaCounter) Set(accountId string, resource string, count int) error {
+	mq.mutex.Lock()
+	defer mq.mutex.Unlock()
+	mq.counts[_quotaKey(accountId, resource)] = count
+	return nil
+}
+
+func (mq *MemoryQuotaCounter) Usage(accountId string, resource string) (int, *int, error) {
+	mq.mutex.Lock()
+	defer mq.mutex.Unlock()
+	key := _quotaKey(accountId, resource)
+	if override, ok := mq.Overrides[key]; ok {
+		return mq.counts[key], &override, nil
+	}
+	return mq.counts[key], nil, nil
+}
+
+func NewLimitFromEnv() (Limit, error) {
+	limit := DEFAULT_RATE_LIMIT
+	if value, ok := os.LookupEnv(RATE_LIMIT_CAPACITY_ENV); ok {
+		capacity, err := strconv.Atoi(value)
+		if err != nil || capacity < 1 {
+			return limit, fmt.Errorf("%s must be a positive integer: %s", RATE_LIMIT_CAPACITY_ENV, value)
+		}
+		limit.Capacity = capacity
+	}
+	if value, ok := os.LookupEnv(RATE_LIMIT_REFILL_ENV); ok {
+		refill, err := strconv.ParseFloat(value, 64)
+		if err != nil || refill <= 0 {
+			return limit, fmt.Errorf("%s must be a positive number: %s", RATE_LIMIT_REFILL_ENV, value)
+		}
+		limit.RefillPerSecond = refill
+	}
+	return limit, nil
+}
+
+func ParseQuotas(value string) (map[string]int, error) {
+	quotas := make(map[string]int)
+	for _, pair := range strings.Split(value, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		resource, count, ok := strings.Cut(pair, "=")
+		quota, err := strconv.Atoi(count)
+		if !ok || err != nil || quota < 0 {
+			return nil, fmt.Errorf("quota %s must be {resource}={count}", pair)
+		}
+		quotas[strings.TrimSpace(resource)] = quota
+	}
+	return quotas, nil
+}
+
+func QuotasFromEnv() (map[string]int, error) {
+	if value, ok := os.LookupEnv(ACCOUNT_QUOTAS_ENV); ok {
+		return ParseQuotas(value)
+	}
+	return DEFAULT_QUOTAS, nil
+}
@@ -0,0 +1,96 @@
+package limits
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/exceptions"
+)
+
+func TestLimitTake(t *testing.T) {
+	limit := Limit{Capacity: 2, RefillPerSecond: 0.5}
+	now := time.Now()
+	limiter := NewMemoryRateLimiter()
+	for i := 0; i < 2; i++ {
+		if allowed, _, _ := limiter.Take("account/nobody", limit, now); !allowed {
+			t.Fatalf("Expected request %d to be allowed", i)
+		}
+	}
+	allowed, retryAfter, _ := limiter.Take("account/nobody", limit, now)
+	if allowed {
+		t.Fatal("Expected an empty bucket to deny the request")
+	}
+	if retryAfter != 2*time.Second {
+		t.Fatalf("Expected to retry after 2s, got %v", retryAfter)
+	}
+	if allowed, _, _ := limiter.Take("token/abc", limit, now); !allowed {
+		t.Fatal("Expected buckets to be kept per key")
+	}
+	if allowed, _, _ := limiter.Take("account/nobody", limit, now.Add(retryAfter)); !allowed {
+		t.Fatal("Expected the bucket to refill")
+	}
+	bucket, _, _ := limit.Take(nil, now)
+	if limit.FullAfter(bucket) != 2*time.Second {
+		t.Fatalf("Expected a full bucket after 2s, got %v", limit.FullAfter(bucket))
+	}
+}
+
+func TestParseQuotas(t *testing.T) {
+	quotas, err := ParseQuotas("recipes=10, lists=0")
+	if err != nil {
+		t.Fatalf("Failed to parse quotas: %v", err)
+	}
+	if quotas["recipes"] != 10 || quotas["lists"] != 0 || len(quotas) != 2 {
+		t.Fatalf("Unexpected quotas %v", quotas)
+	}
+	for _, value := range []string{"recipes", "recipes=many", "lists=-1"} {
+		if _, err := ParseQuotas(value); err == nil {
+			t.Fatalf("Expected %s to be invalid", value)
+		}
+	}
+}
+
+type _repository struct {
+	data.Repository[string, string]
+	err error
+}
+
+func (r *_repository) Create(accountId string, input string) (string, error) {
+	return input, r.err
+}
+
+func TestQuotas(t *testing.T) {
+	counter := NewMemoryQuotaCounter()
+	repository := &_repository{}
+	recipes := NewQuotaRepository(repository, NewQuotas(counter, map[string]int{"recipes": 1}), "recipes")
+
+	if _, err := recipes.Create("nobody", "first"); err != nil {
+		t.Fatalf("Expected creates under the quota to pass: %v", err)
+	}
+	_, err := recipes.Create("nobody", "second")
+	if serviceErr, ok := err.(*exceptions.ServiceError); !ok || serviceErr.StatusCode != 429 {
+		t.Fatalf("Expected the quota to be enforced, got %v", err)
+	}
+	counter.Overrides["nobody:recipes"] = 2
+	repository.err = errors.New("failed")
+	if _, err := recipes.Create("nobody", "second"); !errors.Is(err, repository.err) {
+		t.Fatalf("Expected the create to fail, got %v", err)
+	}
+	if count, _, _ := counter.Usage("nobody", "recipes"); count != 1 {
+		t.Fatalf("Expected a failed create to release its reservation, got %d", count)
+	}
+	repository.err = nil
+	if _, err := recipes.Create("nobody", "second"); err != nil {
+		t.Fatalf("Expected an account override to raise the quota: %v", err)
+	}
+	lists := NewQuotaRepository(repository, NewQuotas(counter, map[string]int{"recipes": 1}), "lists")
+	if _, err := lists.Create("nobody", "list"); err != nil {
+		t.Fatalf("Expected resources without a quota to pass: %v", err)
+	}
+	counter.Add("nobody", "recipes", -5)
+	if count, _, _ := counter.Usage("nobody", "recipes"); count != 0 {
+		t.Fatalf("Expected the count to not go below zero, got %d", count)
+	}
+}
@@ -0,0 +1,96 @@
+package limits
+
+import (
+	"fmt"
+
+	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/exceptions"
+)
+
+// Maps the resource type of an item to the quota it counts against, ie: Recipe -> recipes
+var QUOTA_RESOURCE_TYPES = map[string]string{
+	"Recipe":       "recipes",
+	"ShoppingList": "lists",
+}
+
+// Counts are reserved before an item is created and released when the
+// create fails, so concurrent creates can never exceed the quota
+type Quotas struct {
+	Counter QuotaCounter
+	Quotas  map[string]int
+}
+
+func NewQuotas(counter QuotaCounter, quotas map[string]int) *Quotas {
+	return &Quotas{
+		Counter: counter,
+		Quotas:  quotas,
+	}
+}
+
+func _released() {}
+
+// Returns the release of the reservation, which does nothing when the
+// resource has no quota to reserve
+func (q *Quotas) Reserve(accountId string, resource string) (func(), error) {
+	if q == nil {
+		return _released, nil
+	}
+	quota, ok := q.Quotas[resource]
+	if !ok || accountId == "" {
+		return _released, nil
+	}
+	reserved, err := q.Counter.Reserve(accountId, resource, quota)
+	// Limits protect the service, failing to count never fails a create
+	if err != nil {
+		fmt.Printf("Failed to reserve the %s quota of %s: %v\n", resource, accountId, err)
+		return _released, nil
+	}
+	if !reserved {
+		return _released, exceptions.TooManyRequests(fmt.Sprintf("Quota of %s reached", resource))
+	}
+	return func() {
+		if err := q.Counter.Add(accountId, resource, -1); err != nil {
+			fmt.Printf("Failed to release the %s quota of %s: %v\n", resource, accountId, err)
+		}
+	}, nil
+}
+
+// Reserves the quota of every item created through the repository
+type QuotaRepository[T interface{}, I interface{}] struct {
+	data.Repository[T, I]
+	Quotas   *Quotas
+	Resource string
+}
+
+func NewQuotaRepository[T interface{}, I interface{}](repository data.Repository[T, I], quotas *Quotas, resource string) *QuotaRepository[T, I] {
+	return &QuotaRepository[T, I]{
+		Repository: repository,
+		Quotas:     quotas,
+		Resource:   resource,
+	}
+}
+
+func (qr *QuotaRepository[T, I]) _reserved(accountId string, create func() (T, error)) (T, error) {
+	release, err := qr.Quotas.Reserve(accountId, qr.Resource)
+	if err != nil {
+		var empty T
+		return empty, err
+	}
+	item, err := create()
+	if err != nil {
+		release()
+	}
+	return item, err
+}
+
+func (qr *QuotaRepository[T, I]) Create(accountId string, input I) (T, error) {
+	return qr._reserved(accountId, func() (T, error) {
+		return qr.Repository.Create(accountId, input)
+	})
+}
+
+func (qr *QuotaRepository[T, I]) CreateWithItemId(accountId string, input I, itemId string) (T, error) {
+	return qr._reserved(accountId, func() (T, error) {
+		return qr.Repository.CreateWithItemId(accountId, input, itemId)
+	})
+}
@@ -0,0 +1,44 @@
+package migrations
+
+import "sync"
+
+// Migrations are kept under one account, by their name
+const (
+	GLOBAL_ACCOUNT = "Global"
+	NAME           = "Migration"
+)
+
+// Subscriptions created before events carried their account have no account
+// filter, so resource events are only published once every one is filtered
+const FILTER_SUBSCRIPTIONS = "filter-subscriptions"
+
+// Records the one-off migrations that have completed, so the changes that
+// depend on them can wait
+type Store interface {
+	IsComplete(name string) (bool, error)
+	Complete(name string) error
+}
+
+type MemoryStore struct {
+	mutex     sync.Mutex
+	completed map[string]bool
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		completed: make(map[string]bool),
+	}
+}
+
+func (ms *MemoryStore) IsComplete(name string) (bool, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+	return ms.completed[name], nil
+}
+
+func (ms *MemoryStore) Complete(name string) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+	ms.completed[name] = true
+	return nil
+}
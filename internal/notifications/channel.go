@@ -0,0 +1,22 @@
+package notifications
+
+import "fmt"
+
+type MessageInput struct {
+	Recipient string
+	Subject   string
+	Body      string
+	Type      string
+}
+
+type MessageChannel interface {
+	Send(input MessageInput) error
+}
+
+type LoggingMessageChannel struct {
+}
+
+func (lc *LoggingMessageChannel) Send(input MessageInput) error {
+	fmt.Printf("Sending %s to %s: %s - %s\n", input.Type, input.Recipient, input.Subject, input.Body)
+	return nil
+}
@@ -0,0 +1,58 @@
+package notifications
+
+const (
+	RECIPE_CREATED  = "recipe.created"
+	RECIPE_UPDATED  = "recipe.updated"
+	RECIPE_DELETED  = "recipe.deleted"
+	LIST_CREATED    = "list.created"
+	LIST_UPDATED    = "list.updated"
+	LIST_DELETED    = "list.deleted"
+	SHARE_REQUESTED = "share.requested"
+	SHARE_APPROVED  = "share.approved"
+	SHARE_REJECTED  = "share.rejected"
+	SHARE_UPDATED   = "share.updated"
+	SHARE_DELETED   = "share.deleted"
+)
+
+var EVENT_TYPES = []string{
+	RECIPE_CREATED,
+	RECIPE_UPDATED,
+	RECIPE_DELETED,
+	LIST_CREATED,
+	LIST_UPDATED,
+	LIST_DELETED,
+	SHARE_REQUESTED,
+	SHARE_APPROVED,
+	SHARE_REJECTED,
+	SHARE_UPDATED,
+	SHARE_DELETED,
+}
+
+var RESOURCE_TYPES = []string{
+	"Recipe",
+	"ShoppingList",
+	"ShareRequest",
+}
+
+func _contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func ValidEventType(eventType string) bool {
+	return _contains(EVENT_TYPES, eventType)
+}
+
+func ValidResourceType(resourceType string) bool {
+	return _contains(RESOURCE_TYPES, resourceType)
+}
+
+// Empty filters match everything, mirroring an absent SNS filter policy key
+func MatchesFilters(eventTypes []string, resourceTypes []string, eventType string, resourceType string) bool {
+	return (len(eventTypes) == 0 || _contains(eventTypes, eventType)) &&
+		(len(resourceTypes) == 0 || _contains(resourceTypes, resourceType))
+}
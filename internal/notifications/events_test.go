@@ -0,0 +1,18 @@
+package notifications
+
+import "testing"
+
+func TestMatchesFilters(t *testing.T) {
+	if !MatchesFilters(nil, nil, RECIPE_UPDATED, "Recipe") {
+		t.Fatal("Expected empty filters to match everything")
+	}
+	if !MatchesFilters([]string{RECIPE_UPDATED, SHARE_REQUESTED}, nil, SHARE_REQUESTED, "ShareRequest") {
+		t.Fatal("Expected the event type to match")
+	}
+	if MatchesFilters([]string{RECIPE_UPDATED}, nil, RECIPE_DELETED, "Recipe") {
+		t.Fatal("Expected a different event type to not match")
+	}
+	if MatchesFilters(nil, []string{"ShoppingList"}, RECIPE_UPDATED, "Recipe") {
+		t.Fatal("Expected a different resource type to not match")
+	}
+}
@@ -1,15 +1,41 @@
 package notifications
 
+import "errors"
+
+// Subscriptions removed from the notification service, ie: by their endpoint
+var ErrSubscriptionNotFound = errors.New("subscription does not exist")
+
 type SubscribeInput struct {
-	Endpoint *string
-	Protocol *string
+	AccountId     *string
+	Endpoint      *string
+	Protocol      *string
+	EventTypes    []string
+	ResourceTypes []string
 }
 
 type SubscribeOutput struct {
-	SubscriberId string
+	SubscriberId        string
+	PendingConfirmation bool
+}
+
+type PublishInput struct {
+	AccountId    string
+	EventType    string
+	ResourceType string
+	ResourceId   string
+	Message      string
+}
+
+type PublishOutput struct {
+	MessageId string
 }
 
 type NotificationService interface {
 	Subscribe(input SubscribeInput) (*SubscribeOutput, error)
 	Unsubscribe(subscriberId string) error
+	Publish(input PublishInput) (*PublishOutput, error)
+	IsPendingConfirmation(subscriberId string) (bool, error)
+	// Replaces the filter of an existing subscription, ie: one created before
+	// subscriptions were filtered by account
+	SetFilter(subscriberId string, input SubscribeInput) error
 }
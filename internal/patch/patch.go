@@ -0,0 +1,281 @@
+package patch
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"philcali.me/recipes/internal/exceptions"
+)
+
+const (
+	MERGE_PATCH_CONTENT_TYPE = "application/merge-patch+json"
+	JSON_PATCH_CONTENT_TYPE  = "application/json-patch+json"
+)
+
+const (
+	OP_ADD     = "add"
+	OP_REMOVE  = "remove"
+	OP_REPLACE = "replace"
+	OP_MOVE    = "move"
+	OP_COPY    = "copy"
+	OP_TEST    = "test"
+)
+
+// A single RFC 6902 operation, paths and from are JSON pointers
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+func (o *Operation) _value() (any, error) {
+	if len(o.Value) == 0 {
+		return nil, exceptions.InvalidInput(fmt.Sprintf("Operation %s on %s requires a value", o.Op, o.Path))
+	}
+	var value any
+	err := json.Unmarshal(o.Value, &value)
+	return value, err
+}
+
+// Splits a JSON pointer into its unescaped tokens, the root has none
+func Tokens(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, exceptions.InvalidInput(fmt.Sprintf("Path %s is not a JSON pointer", pointer))
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// Applies an RFC 7396 merge patch, nulls remove members and anything other
+// than an object replaces the target
+func Merge(target any, patch any) any {
+	patchObject, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	merged := make(map[string]any)
+	if targetObject, ok := target.(map[string]any); ok {
+		for key, value := range targetObject {
+			merged[key] = value
+		}
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(merged, key)
+		} else {
+			merged[key] = Merge(merged[key], value)
+		}
+	}
+	return merged
+}
+
+func _clone(document any) (any, error) {
+	content, err := json.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+	var clone any
+	err = json.Unmarshal(content, &clone)
+	return clone, err
+}
+
+func _notFound(tokens []string) error {
+	return exceptions.InvalidInput(fmt.Sprintf("Path /%s does not exist", strings.Join(tokens, "/")))
+}
+
+// Indexes must name an element, or the end of the list when appending
+func _index(token string, length int, appending bool) (int, bool) {
+	if appending && token == "-" {
+		return length, true
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || (token != "0" && strings.HasPrefix(token, "0")) {
+		return 0, false
+	}
+	if appending {
+		return index, index <= length
+	}
+	return index, index < length
+}
+
+func Get(document any, tokens []string) (any, bool) {
+	node := document
+	for _, token := range tokens {
+		switch container := node.(type) {
+		case map[string]any:
+			child, ok := container[token]
+			if !ok {
+				return nil, false
+			}
+			node = child
+		case []any:
+			index, ok := _index(token, len(container), false)
+			if !ok {
+				return nil, false
+			}
+			node = container[index]
+		default:
+			return nil, false
+		}
+	}
+	return node, true
+}
+
+// Walks to the parent of the last token, returning the node with its parent updated
+func _update(node any, tokens []string, leaf func(parent any, token string) (any, error)) (any, error) {
+	if len(tokens) == 1 {
+		return leaf(node, tokens[0])
+	}
+	switch container := node.(type) {
+	case map[string]any:
+		child, ok := container[tokens[0]]
+		if !ok {
+			return nil, _notFound(tokens)
+		}
+		updated, err := _update(child, tokens[1:], leaf)
+		if err != nil {
+			return nil, err
+		}
+		container[tokens[0]] = updated
+		return container, nil
+	case []any:
+		index, ok := _index(tokens[0], len(container), false)
+		if !ok {
+			return nil, _notFound(tokens)
+		}
+		updated, err := _update(container[index], tokens[1:], leaf)
+		if err != nil {
+			return nil, err
+		}
+		container[index] = updated
+		return container, nil
+	}
+	return nil, _notFound(tokens)
+}
+
+func _add(document any, tokens []string, value any) (any, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	return _update(document, tokens, func(parent any, token string) (any, error) {
+		switch container := parent.(type) {
+		case map[string]any:
+			container[token] = value
+			return container, nil
+		case []any:
+			index, ok := _index(token, len(container), true)
+			if !ok {
+				return nil, _notFound(tokens)
+			}
+			return append(container[:index], append([]any{value}, container[index:]...)...), nil
+		}
+		return nil, _notFound(tokens)
+	})
+}
+
+func _remove(document any, tokens []string) (any, error) {
+	if len(tokens) == 0 {
+		return nil, exceptions.InvalidInput("The whole document cannot be removed")
+	}
+	return _update(document, tokens, func(parent any, token string) (any, error) {
+		switch container := parent.(type) {
+		case map[string]any:
+			if _, ok := container[token]; !ok {
+				return nil, _notFound(tokens)
+			}
+			delete(container, token)
+			return container, nil
+		case []any:
+			index, ok := _index(token, len(container), false)
+			if !ok {
+				return nil, _notFound(tokens)
+			}
+			return append(container[:index], container[index+1:]...), nil
+		}
+		return nil, _notFound(tokens)
+	})
+}
+
+func _apply(document any, operation Operation) (any, error) {
+	tokens, err := Tokens(operation.Path)
+	if err != nil {
+		return nil, err
+	}
+	switch operation.Op {
+	case OP_ADD:
+		value, err := operation._value()
+		if err != nil {
+			return nil, err
+		}
+		return _add(document, tokens, value)
+	case OP_REMOVE:
+		return _remove(document, tokens)
+	case OP_REPLACE:
+		value, err := operation._value()
+		if err != nil {
+			return nil, err
+		}
+		if len(tokens) == 0 {
+			return value, nil
+		}
+		if document, err = _remove(document, tokens); err != nil {
+			return nil, err
+		}
+		return _add(document, tokens, value)
+	case OP_MOVE, OP_COPY:
+		from, err := Tokens(operation.From)
+		if err != nil {
+			return nil, err
+		}
+		value, ok := Get(document, from)
+		if !ok {
+			return nil, _notFound(from)
+		}
+		if operation.Op == OP_MOVE {
+			if operation.Path != operation.From && strings.HasPrefix(operation.Path, operation.From+"/") {
+				return nil, exceptions.InvalidInput(fmt.Sprintf("Cannot move %s into itself", operation.From))
+			}
+			if document, err = _remove(document, from); err != nil {
+				return nil, err
+			}
+		} else if value, err = _clone(value); err != nil {
+			return nil, err
+		}
+		return _add(document, tokens, value)
+	case OP_TEST:
+		expected, err := operation._value()
+		if err != nil {
+			return nil, err
+		}
+		if actual, ok := Get(document, tokens); !ok || !reflect.DeepEqual(actual, expected) {
+			return nil, exceptions.InvalidInput(fmt.Sprintf("Test failed for %s", operation.Path))
+		}
+		return document, nil
+	}
+	return nil, exceptions.InvalidInput(fmt.Sprintf("Unsupported operation %s", operation.Op))
+}
+
+// Applies RFC 6902 operations in order to a copy of the document, failing
+// as a whole when any of them fails
+func Apply(document any, operations []Operation) (any, error) {
+	patched, err := _clone(document)
+	if err != nil {
+		return nil, err
+	}
+	for _, operation := range operations {
+		if patched, err = _apply(patched, operation); err != nil {
+			return nil, err
+		}
+	}
+	return patched, nil
+}
@@ -0,0 +1,139 @@
+package patch
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func _json(t *testing.T, content string) map[string]any {
+	var document map[string]any
+	if err := json.Unmarshal([]byte(content), &document); err != nil {
+		t.Fatalf("Failed to parse %s: %v", content, err)
+	}
+	return document
+}
+
+func _operations(t *testing.T, content string) []Operation {
+	var operations []Operation
+	if err := json.Unmarshal([]byte(content), &operations); err != nil {
+		t.Fatalf("Failed to parse %s: %v", content, err)
+	}
+	return operations
+}
+
+const _list = `{
+	"name": "Groceries",
+	"items": [
+		{"name": "bread", "completed": false},
+		{"name": "milk", "completed": false}
+	]
+}`
+
+func TestMerge(t *testing.T) {
+	original := _json(t, `{"name": "Soup", "thumbnail": "soup.png", "tags": {"a": 1, "b": 2}}`)
+	merged := Merge(original, _json(t, `{"name": "Stew", "thumbnail": null, "tags": {"a": null}}`))
+	expected := _json(t, `{"name": "Stew", "tags": {"b": 2}}`)
+	if !reflect.DeepEqual(merged, expected) {
+		t.Fatalf("Expected %v, got %v", expected, merged)
+	}
+	if original["name"] != "Soup" {
+		t.Fatal("Expected the original to be left alone")
+	}
+	plan := MergePlan(original, merged.(map[string]any))
+	if !reflect.DeepEqual(plan.Set, []string{"name", "tags"}) || !reflect.DeepEqual(plan.Remove, []string{"thumbnail"}) || plan.Guarded {
+		t.Fatalf("Unexpected plan %v", plan)
+	}
+}
+
+func TestApply(t *testing.T) {
+	original := _json(t, _list)
+	patched, err := Apply(original, _operations(t, `[
+		{"op": "test", "path": "/items/0/name", "value": "bread"},
+		{"op": "replace", "path": "/items/0/completed", "value": true},
+		{"op": "add", "path": "/items/1", "value": {"name": "eggs", "completed": false}},
+		{"op": "add", "path": "/items/-", "value": {"name": "jam", "completed": false}},
+		{"op": "remove", "path": "/items/2"},
+		{"op": "copy", "from": "/name", "path": "/title"},
+		{"op": "move", "from": "/title", "path": "/heading"}
+	]`))
+	if err != nil {
+		t.Fatalf("Failed to apply: %v", err)
+	}
+	expected := _json(t, `{
+		"name": "Groceries",
+		"heading": "Groceries",
+		"items": [
+			{"name": "bread", "completed": true},
+			{"name": "eggs", "completed": false},
+			{"name": "jam", "completed": false}
+		]
+	}`)
+	if !reflect.DeepEqual(patched, expected) {
+		t.Fatalf("Expected %v, got %v", expected, patched)
+	}
+	failures := []string{
+		`[{"op": "test", "path": "/name", "value": "Other"}]`,
+		`[{"op": "remove", "path": "/missing"}]`,
+		`[{"op": "replace", "path": "/items/5/name", "value": "x"}]`,
+		`[{"op": "add", "path": "/items/01", "value": {}}]`,
+		`[{"op": "add", "path": "/name"}]`,
+		`[{"op": "move", "from": "/items", "path": "/items/0"}]`,
+		`[{"op": "jump", "path": "/name"}]`,
+	}
+	for _, failure := range failures {
+		if _, err := Apply(original, _operations(t, failure)); err == nil {
+			t.Fatalf("Expected %s to fail", failure)
+		}
+	}
+	if original["items"].([]any)[0].(map[string]any)["completed"] != false {
+		t.Fatal("Expected the original to be left alone")
+	}
+}
+
+func TestJSONPlan(t *testing.T) {
+	original := _json(t, _list)
+	plan := func(content string) Plan {
+		operations := _operations(t, content)
+		patched, err := Apply(original, operations)
+		if err != nil {
+			t.Fatalf("Failed to apply %s: %v", content, err)
+		}
+		return JSONPlan(original, patched.(map[string]any), operations, List{Field: "items", Key: "name"})
+	}
+
+	t.Run("Targeted", func(t *testing.T) {
+		targeted := plan(`[
+			{"op": "test", "path": "/items/1/completed", "value": false},
+			{"op": "replace", "path": "/items/1/completed", "value": true},
+			{"op": "replace", "path": "/name", "value": "Weekly"}
+		]`)
+		if len(targeted.Changes) != 1 || targeted.Changes[0].Path != "items[1].completed" || targeted.Changes[0].Value != true {
+			t.Fatalf("Expected a change of the element, got %v", targeted.Changes)
+		}
+		expected := map[string]any{"items[1].name": "milk", "items[1].completed": false}
+		if !reflect.DeepEqual(targeted.Conditions, expected) || targeted.Guarded {
+			t.Fatalf("Expected conditions %v, got %v", expected, targeted.Conditions)
+		}
+		if !reflect.DeepEqual(targeted.Set, []string{"name"}) {
+			t.Fatalf("Expected the name to be set, got %v", targeted.Set)
+		}
+	})
+
+	t.Run("Append", func(t *testing.T) {
+		appended := plan(`[{"op": "add", "path": "/items/-", "value": {"name": "jam"}}]`)
+		if len(appended.Changes) != 1 || !appended.Changes[0].Append || appended.Changes[0].Path != "items" {
+			t.Fatalf("Expected an append, got %v", appended.Changes)
+		}
+	})
+
+	t.Run("Whole", func(t *testing.T) {
+		whole := plan(`[
+			{"op": "remove", "path": "/items/0"},
+			{"op": "replace", "path": "/items/0/completed", "value": true}
+		]`)
+		if len(whole.Changes) != 0 || !reflect.DeepEqual(whole.Set, []string{"items"}) || !whole.Guarded {
+			t.Fatalf("Expected shifting elements to write the list whole, got %v", whole)
+		}
+	})
+}
@@ -0,0 +1,191 @@
+package patch
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+
+	"philcali.me/recipes/internal/data"
+)
+
+// A list whose elements are changed in place. Element fields must be stored
+// under their JSON names, and the key guards against elements shifting.
+type List struct {
+	Field string
+	Key   string
+	// Completes elements added whole before they are written, ie: assigns their ids
+	Prepare func(element map[string]any)
+}
+
+func (l *List) _prepare(value any) {
+	if element, ok := value.(map[string]any); ok && l.Prepare != nil {
+		l.Prepare(element)
+	}
+}
+
+// How a patched document is written, fields are named by their JSON names
+type Plan struct {
+	// Top level fields set from the patched document
+	Set []string
+	// Top level fields missing from the patched document
+	Remove []string
+	// Targeted changes of list elements
+	Changes    []data.PatchChange
+	Conditions map[string]any
+	// Some fields were written whole after reading them, so the update must
+	// conflict when the document changed since
+	Guarded bool
+}
+
+func (p *Plan) _field(original map[string]any, patched map[string]any, field string) {
+	value, ok := patched[field]
+	if !ok {
+		if _, existed := original[field]; existed {
+			p.Remove = append(p.Remove, field)
+		}
+		return
+	}
+	if !reflect.DeepEqual(original[field], value) {
+		p.Set = append(p.Set, field)
+	}
+}
+
+// Merge patches replace lists whole, as the client sent them
+func MergePlan(original map[string]any, patched map[string]any) Plan {
+	plan := Plan{Conditions: make(map[string]any)}
+	var fields []string
+	for field := range original {
+		fields = append(fields, field)
+	}
+	for field := range patched {
+		if _, ok := original[field]; !ok {
+			fields = append(fields, field)
+		}
+	}
+	slices.Sort(fields)
+	for _, field := range fields {
+		plan._field(original, patched, field)
+	}
+	return plan
+}
+
+// Operations on existing elements of a list become changes of their paths,
+// guarded by the key of the element. Appends become a list append. Anything
+// else on the list writes it whole.
+func _targeted(list List, elements []any, patched map[string]any, operations []Operation) ([]data.PatchChange, map[string]any, bool) {
+	var changes []data.PatchChange
+	var appends []any
+	conditions := make(map[string]any)
+	changed := make(map[string]bool)
+	for _, operation := range operations {
+		tokens, err := Tokens(operation.Path)
+		if err != nil || len(tokens) < 2 || len(tokens) > 3 || operation.Op == OP_MOVE || operation.Op == OP_COPY {
+			return nil, nil, false
+		}
+		if operation.Op == OP_ADD && len(tokens) == 2 && tokens[1] == "-" {
+			value, err := operation._value()
+			if err != nil {
+				return nil, nil, false
+			}
+			list._prepare(value)
+			appends = append(appends, value)
+			continue
+		}
+		index, ok := _index(tokens[1], len(elements), false)
+		if !ok {
+			return nil, nil, false
+		}
+		element, ok := elements[index].(map[string]any)
+		if !ok || element[list.Key] == nil {
+			return nil, nil, false
+		}
+		conditions[fmt.Sprintf("%s[%d].%s", list.Field, index, list.Key)] = element[list.Key]
+		path := fmt.Sprintf("%s[%d]", list.Field, index)
+		if len(tokens) == 3 {
+			path += "." + tokens[2]
+		}
+		switch {
+		case operation.Op == OP_TEST && !changed[path]:
+			value, err := operation._value()
+			if err != nil {
+				return nil, nil, false
+			}
+			conditions[path] = value
+		case operation.Op == OP_REPLACE, operation.Op == OP_ADD && len(tokens) == 3:
+			value, _ := Get(patched, tokens)
+			if len(tokens) == 2 {
+				list._prepare(value)
+			}
+			changes = append(changes, data.PatchChange{Path: path, Value: value})
+			changed[path] = true
+		case operation.Op == OP_REMOVE && len(tokens) == 3:
+			changes = append(changes, data.PatchChange{Path: path, Remove: true})
+			changed[path] = true
+		case operation.Op == OP_REMOVE && len(operations) == 1:
+			// Removing an element shifts the ones after it
+			changes = append(changes, data.PatchChange{Path: path, Remove: true})
+		default:
+			return nil, nil, false
+		}
+	}
+	if len(appends) > 0 {
+		// A list cannot be appended to and changed by the same update
+		if len(changes) > 0 || len(elements) == 0 {
+			return nil, nil, false
+		}
+		changes = append(changes, data.PatchChange{Path: list.Field, Value: appends, Append: true})
+	}
+	return changes, conditions, true
+}
+
+// Plans the write of the operations patching original into patched
+func JSONPlan(original map[string]any, patched map[string]any, operations []Operation, lists ...List) Plan {
+	plan := Plan{Conditions: make(map[string]any)}
+	var fields []string
+	byField := make(map[string][]Operation)
+	for _, operation := range operations {
+		for _, pointer := range []string{operation.Path, operation.From} {
+			tokens, err := Tokens(pointer)
+			if err != nil || len(tokens) == 0 {
+				continue
+			}
+			if _, ok := byField[tokens[0]]; !ok {
+				fields = append(fields, tokens[0])
+			}
+			byField[tokens[0]] = append(byField[tokens[0]], operation)
+		}
+		// Replacing the whole document writes every field of it
+		if operation.Path == "" {
+			plan = MergePlan(original, patched)
+			plan.Guarded = true
+			return plan
+		}
+		// Moves and copies carry values as they were read
+		if operation.Op == OP_MOVE || operation.Op == OP_COPY {
+			plan.Guarded = true
+		}
+	}
+	for _, field := range fields {
+		listIndex := slices.IndexFunc(lists, func(l List) bool { return l.Field == field })
+		if listIndex >= 0 {
+			elements, _ := original[field].([]any)
+			changes, conditions, ok := _targeted(lists[listIndex], elements, patched, byField[field])
+			if ok {
+				plan.Changes = append(plan.Changes, changes...)
+				for path, value := range conditions {
+					plan.Conditions[path] = value
+				}
+				continue
+			}
+			plan.Guarded = true
+		}
+		// Tests of anything but list elements are only honored by reading first
+		for _, operation := range byField[field] {
+			if operation.Op == OP_TEST {
+				plan.Guarded = true
+			}
+		}
+		plan._field(original, patched, field)
+	}
+	return plan
+}
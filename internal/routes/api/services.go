@@ -0,0 +1,58 @@
+package api
+
+import (
+	"philcali.me/recipes/internal/auth"
+	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/notifications"
+	"philcali.me/recipes/internal/routes"
+	"philcali.me/recipes/internal/routes/apitokens"
+	"philcali.me/recipes/internal/routes/audits"
+	"philcali.me/recipes/internal/routes/external"
+	"philcali.me/recipes/internal/routes/households"
+	"philcali.me/recipes/internal/routes/oauth"
+	"philcali.me/recipes/internal/routes/recipes"
+	"philcali.me/recipes/internal/routes/settings"
+	"philcali.me/recipes/internal/routes/shares"
+	"philcali.me/recipes/internal/routes/shopping"
+	"philcali.me/recipes/internal/routes/subscriptions"
+	"philcali.me/recipes/internal/routes/templates"
+)
+
+// The data the services of the API are routed over, routes never touch it
+// while they are only described
+type Data struct {
+	Recipes            data.RecipeDataService
+	Lists              data.ShoppingListDataService
+	Templates          data.ShoppingListTemplateRepository
+	Settings           data.SettingsRepository
+	ApiTokens          data.ApiTokenDataService
+	Revocations        data.RevokedTokenDataService
+	OAuthClients       data.OAuthClientDataService
+	OAuthConsents      data.OAuthConsentDataService
+	AuthorizationCodes data.AuthorizationCodeDataService
+	Audits             data.AuditRepository
+	Shares             data.ShareRequestRepository
+	Memberships        data.MembershipRepository
+	Subscriptions      data.SubscriptionDataService
+	Deliveries         data.DeliveryRepository
+	Notifications      notifications.NotificationService
+	Signer             *auth.Signer
+	IndexName          string
+}
+
+// Every service of the API, so the app and its tests route the same ones
+func Services(d Data) []routes.Service {
+	return []routes.Service{
+		external.NewExternalService(),
+		recipes.NewRoute(d.Recipes),
+		shopping.NewRouteWithSettings(d.Lists, d.Settings),
+		templates.NewRoute(d.Templates, d.Lists),
+		apitokens.NewRouteWithIndex(d.ApiTokens, d.Revocations, d.Signer, d.IndexName),
+		oauth.NewRouteWithIndex(d.OAuthClients, d.OAuthConsents, d.AuthorizationCodes, d.Revocations, d.IndexName),
+		audits.NewRouteWithIndex(d.Audits, d.IndexName),
+		settings.NewRoute(d.Settings),
+		shares.NewRouteWithIndex(d.Shares, d.IndexName),
+		households.NewRouteWithIndex(d.Memberships, d.IndexName),
+		subscriptions.NewRouteWithIndex(d.Subscriptions, d.Deliveries, d.Notifications, d.IndexName),
+	}
+}
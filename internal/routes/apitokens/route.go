@@ -2,34 +2,42 @@ package apitokens
 
 import (
 	"context"
-	"crypto/rand"
-	"encoding/hex"
-	"encoding/json"
 	"os"
 	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"philcali.me/recipes/internal/auth"
 	"philcali.me/recipes/internal/data"
 	"philcali.me/recipes/internal/exceptions"
 	"philcali.me/recipes/internal/routes"
 	"philcali.me/recipes/internal/routes/util"
+	"philcali.me/recipes/internal/validation"
+)
+
+const (
+	DEFAULT_ROTATION_GRACE = 24 * time.Hour
+	MAX_ROTATION_GRACE     = 7 * 24 * time.Hour
 )
 
 type ApiTokenService struct {
-	data      data.ApiTokenDataService
-	indexName string
+	data        data.ApiTokenDataService
+	revocations data.RevokedTokenDataService
+	signer      *auth.Signer
+	indexName   string
 }
 
-func NewRouteWithIndex(data data.ApiTokenDataService, indexName string) routes.Service {
+func NewRouteWithIndex(data data.ApiTokenDataService, revocations data.RevokedTokenDataService, signer *auth.Signer, indexName string) routes.Service {
 	return &ApiTokenService{
-		data:      data,
-		indexName: indexName,
+		data:        data,
+		revocations: revocations,
+		signer:      signer,
+		indexName:   indexName,
 	}
 }
 
-func NewRoute(data data.ApiTokenDataService) routes.Service {
-	return NewRouteWithIndex(data, os.Getenv("INDEX_NAME_1"))
+func NewRoute(data data.ApiTokenDataService, revocations data.RevokedTokenDataService) routes.Service {
+	return NewRouteWithIndex(data, revocations, auth.NewSignerFromEnv(), os.Getenv("INDEX_NAME_1"))
 }
 
 func _convertToken(tokenDTO data.ApiTokenDTO) ApiToken {
@@ -37,31 +45,99 @@ func _convertToken(tokenDTO data.ApiTokenDTO) ApiToken {
 	if tokenDTO.ExpiresIn != nil {
 		expiresIn = aws.Time(time.UnixMilli(int64(*tokenDTO.ExpiresIn)))
 	}
-	return ApiToken{
-		Name:       tokenDTO.Name,
-		Value:      tokenDTO.SK,
-		Scopes:     tokenDTO.Scopes,
-		CreateTime: tokenDTO.CreateTime,
-		UpdateTime: tokenDTO.UpdateTime,
-		ExpiresIn:  expiresIn,
+	apiToken := ApiToken{
+		Id:           tokenDTO.SK,
+		Name:         tokenDTO.Name,
+		Scopes:       tokenDTO.Scopes,
+		CreateTime:   tokenDTO.CreateTime,
+		UpdateTime:   tokenDTO.UpdateTime,
+		ExpiresIn:    expiresIn,
+		LastUsedTime: tokenDTO.LastUsedTime,
+		UsageCount:   tokenDTO.UsageCount,
+	}
+	if tokenDTO.PreviousSecretExpiresIn != nil && !auth.IsExpired(tokenDTO.PreviousSecretExpiresIn, time.Now()) {
+		apiToken.PreviousValueExpiresIn = aws.Time(time.UnixMilli(int64(*tokenDTO.PreviousSecretExpiresIn)))
 	}
+	return apiToken
 }
 
-func _generateTokenHash(length int) (string, error) {
-	randomBytes := make([]byte, length)
-	if _, err := rand.Read(randomBytes); err != nil {
-		return "", err
+// Tokens live in a global partition, so ownership is checked on every access
+func (as *ApiTokenService) _getOwnedToken(ctx context.Context) (data.ApiTokenDTO, error) {
+	tokenId := util.RequestParam(ctx, "tokenId")
+	item, err := as.data.Get("Global", tokenId)
+	if err == nil && item.AccountId != util.Username(ctx) {
+		return item, exceptions.NotFound("apitoken", tokenId)
 	}
-	return hex.EncodeToString(randomBytes), nil
+	return item, err
+}
+
+// The plaintext value is only ever revealed on creation. Signed tokens carry
+// everything the authorizer needs to skip the table.
+func (as *ApiTokenService) _revealToken(tokenDTO data.ApiTokenDTO, secret string) (ApiToken, error) {
+	apiToken := _convertToken(tokenDTO)
+	if as.signer == nil {
+		apiToken.Value = auth.FormatToken(tokenDTO.SK, secret)
+		return apiToken, nil
+	}
+	claims := auth.ApiTokenClaims{
+		Id:         tokenDTO.SK,
+		AccountId:  tokenDTO.AccountId,
+		Scopes:     tokenDTO.Scopes,
+		Claims:     tokenDTO.Claims,
+		Generation: tokenDTO.Generation,
+		IssuedAt:   tokenDTO.UpdateTime.Unix(),
+	}
+	if tokenDTO.ExpiresIn != nil {
+		claims.ExpiresAt = time.UnixMilli(int64(*tokenDTO.ExpiresIn)).Unix()
+	}
+	value, err := as.signer.Sign(claims)
+	if err != nil {
+		return apiToken, exceptions.InternalServer(err.Error())
+	}
+	apiToken.Value = value
+	return apiToken, nil
 }
 
 func (as *ApiTokenService) GetRoutes() map[string]routes.Route {
 	return map[string]routes.Route{
-		"GET:/tokens":             util.AuthorizedRoute(as.ListTokens),
-		"GET:/tokens/:tokenId":    util.AuthorizedRoute(as.GetToken),
-		"POST:/tokens":            util.AuthorizedRoute(as.CreateToken),
-		"PUT:/tokens/:tokenId":    util.AuthorizedRoute(as.UpdateToken),
-		"DELETE:/tokens/:tokenId": util.AuthorizedRoute(as.DeleteToken),
+		"GET:/tokens":                  util.AuthorizedRoute(as.ListTokens),
+		"GET:/tokens/:tokenId":         util.AuthorizedRoute(as.GetToken),
+		"POST:/tokens":                 util.AuthorizedRoute(as.CreateToken),
+		"PUT:/tokens/:tokenId":         util.AuthorizedRoute(as.UpdateToken),
+		"POST:/tokens/:tokenId/rotate": util.AuthorizedRoute(as.RotateToken),
+		"DELETE:/tokens/:tokenId":      util.AuthorizedRoute(as.DeleteToken),
+	}
+}
+
+func (as *ApiTokenService) DescribeRoutes() map[string]routes.RouteDescription {
+	return map[string]routes.RouteDescription{
+		"GET:/tokens": {
+			Summary: "Lists API tokens, without their values",
+			Output:  data.QueryResults[ApiToken]{},
+			Query:   routes.LIST_QUERY,
+		},
+		"GET:/tokens/:tokenId": {
+			Summary: "Gets an API token, without its value",
+			Output:  ApiToken{},
+		},
+		"POST:/tokens": {
+			Summary: "Creates an API token, the only response holding its value",
+			Input:   ApiTokenInput{},
+			Output:  ApiToken{},
+		},
+		"PUT:/tokens/:tokenId": {
+			Summary: "Renames an API token or changes its expiry",
+			Input:   ApiTokenInput{},
+			Output:  ApiToken{},
+		},
+		"POST:/tokens/:tokenId/rotate": {
+			Summary: "Issues a new value, the previous value is accepted for the grace period",
+			Input:   RotateTokenInput{},
+			Output:  ApiToken{},
+		},
+		"DELETE:/tokens/:tokenId": {
+			Summary: "Deletes and revokes an API token",
+		},
 	}
 }
 
@@ -70,51 +146,116 @@ func (as *ApiTokenService) ListTokens(event events.APIGatewayV2HTTPRequest, ctx
 }
 
 func (as *ApiTokenService) GetToken(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
-	item, err := as.data.Get("Global", util.RequestParam(ctx, "tokenId"))
+	item, err := as._getOwnedToken(ctx)
 	return util.SerializeResponseOK(_convertToken, item, err)
 }
 
 func (as *ApiTokenService) CreateToken(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
 	input := ApiTokenInput{}
-	if err := json.Unmarshal([]byte(event.Body), &input); err != nil {
-		return events.APIGatewayV2HTTPResponse{}, exceptions.InvalidInput(err.Error())
+	if err := util.DecodeInput(event, &input, validation.CREATE); err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
 	}
 	var expiresIn *int
 	if input.ExpiresIn != nil {
 		expiresIn = aws.Int(int(input.ExpiresIn.UnixMilli()))
 	}
-	tokenHash, err := _generateTokenHash(32)
+	if err := util.ValidateScopes(event, input.Scopes); err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	tokenId, secret, err := auth.GenerateToken()
 	if err != nil {
 		return events.APIGatewayV2HTTPResponse{}, exceptions.InternalServer(err.Error())
 	}
 	claims := util.AuthorizationClaims(event)
 	created, err := as.data.CreateWithItemId("Global", data.ApiTokenInputDTO{
-		Name:      input.Name,
-		Scopes:    &input.Scopes,
-		Claims:    &claims,
-		AccountId: aws.String(util.Username(ctx)),
-		ExpiresIn: expiresIn,
-	}, tokenHash)
-	return util.SerializeResponseOK(_convertToken, created, err)
+		Name:       input.Name,
+		Scopes:     &input.Scopes,
+		Claims:     &claims,
+		AccountId:  aws.String(util.Username(ctx)),
+		ExpiresIn:  expiresIn,
+		SecretHash: aws.String(auth.HashSecret(secret)),
+	}, tokenId)
+	if err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	apiToken, err := as._revealToken(created, secret)
+	return util.SerializeResponseOK(util.IdentityThunk, apiToken, err)
 }
 
 func (as *ApiTokenService) UpdateToken(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
 	input := ApiTokenInput{}
-	if err := json.Unmarshal([]byte(event.Body), &input); err != nil {
-		return events.APIGatewayV2HTTPResponse{}, exceptions.InvalidInput(err.Error())
+	if err := util.DecodeInput(event, &input, validation.UPDATE); err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
 	}
 	var expiresIn *int
 	if input.ExpiresIn != nil {
 		expiresIn = aws.Int(int(input.ExpiresIn.UnixMilli()))
 	}
-	item, err := as.data.Update("Global", util.RequestParam(ctx, "tokenId"), data.ApiTokenInputDTO{
+	owned, err := as._getOwnedToken(ctx)
+	if err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	// Signed tokens carry their expiry, which the authorizer trusts over the table
+	if as.signer != nil && expiresIn != nil && (owned.ExpiresIn == nil || *owned.ExpiresIn != *expiresIn) {
+		return events.APIGatewayV2HTTPResponse{}, exceptions.InvalidInput("The expiry of a signed token cannot change, create a new token instead")
+	}
+	item, err := as.data.Update("Global", owned.SK, data.ApiTokenInputDTO{
 		Name:      input.Name,
 		ExpiresIn: expiresIn,
 	})
 	return util.SerializeResponseOK(_convertToken, item, err)
 }
 
+func (as *ApiTokenService) RotateToken(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
+	input := RotateTokenInput{}
+	if len(event.Body) > 0 {
+		if err := util.DecodeInput(event, &input, validation.CREATE); err != nil {
+			return events.APIGatewayV2HTTPResponse{}, err
+		}
+	}
+	gracePeriod := DEFAULT_ROTATION_GRACE
+	if input.GracePeriodSeconds != nil {
+		gracePeriod = time.Duration(*input.GracePeriodSeconds) * time.Second
+	}
+	item, err := as._getOwnedToken(ctx)
+	if err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	secret, err := auth.GenerateSecret()
+	if err != nil {
+		return events.APIGatewayV2HTTPResponse{}, exceptions.InternalServer(err.Error())
+	}
+	graceEnd := time.Now().Add(gracePeriod)
+	generation := item.Generation + 1
+	// Revoked first, so a failed update can be retried but never leaves
+	// earlier signed tokens valid indefinitely
+	if err := auth.Revoke(as.revocations, item, generation, graceEnd); err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	rotated, err := as.data.Update("Global", item.SK, data.ApiTokenInputDTO{
+		SecretHash:              aws.String(auth.HashSecret(secret)),
+		PreviousSecretHash:      aws.String(item.SecretHash),
+		PreviousSecretExpiresIn: aws.Int(int(graceEnd.UnixMilli())),
+		Generation:              aws.Int(generation),
+	})
+	if err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	apiToken, err := as._revealToken(rotated, secret)
+	return util.SerializeResponseOK(util.IdentityThunk, apiToken, err)
+}
+
 func (as *ApiTokenService) DeleteToken(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
-	err := as.data.Delete("Global", util.RequestParam(ctx, "tokenId"))
-	return util.SerializeResponseNoContent(err)
+	item, err := as._getOwnedToken(ctx)
+	if err != nil {
+		if _, ok := err.(*exceptions.NotFoundError); ok {
+			return util.SerializeResponseNoContent(nil)
+		}
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	// Signed tokens stay verifiable after the delete until they are denied
+	if err := auth.Revoke(as.revocations, item, item.Generation+1, time.Now()); err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	return util.SerializeResponseNoContent(as.data.Delete("Global", item.SK))
 }
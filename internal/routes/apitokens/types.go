@@ -4,15 +4,21 @@ import (
 	"time"
 
 	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/validation"
 )
 
 type ApiToken struct {
-	Name       string       `json:"name"`
-	Value      string       `json:"value"`
-	Scopes     []data.Scope `json:"scopes"`
-	ExpiresIn  *time.Time   `json:"expiresIn"`
-	CreateTime time.Time    `json:"createTime"`
-	UpdateTime time.Time    `json:"updateTime"`
+	Id           string       `json:"tokenId"`
+	Name         string       `json:"name"`
+	Value        string       `json:"value,omitempty"`
+	Scopes       []data.Scope `json:"scopes"`
+	ExpiresIn    *time.Time   `json:"expiresIn"`
+	LastUsedTime *time.Time   `json:"lastUsedTime,omitempty"`
+	// Set while a rotated value is still accepted
+	PreviousValueExpiresIn *time.Time `json:"previousValueExpiresIn,omitempty"`
+	UsageCount             int        `json:"usageCount"`
+	CreateTime             time.Time  `json:"createTime"`
+	UpdateTime             time.Time  `json:"updateTime"`
 }
 
 type ApiTokenInput struct {
@@ -20,3 +26,25 @@ type ApiTokenInput struct {
 	Scopes    []data.Scope `json:"scopes,omitempty"`
 	ExpiresIn *time.Time   `json:"expiresIn,omitempty"`
 }
+
+type RotateTokenInput struct {
+	GracePeriodSeconds *int `json:"gracePeriodSeconds,omitempty"`
+}
+
+const MAX_NAME_LENGTH = 256
+
+func (ti *ApiTokenInput) Validate(op validation.Operation) error {
+	fields := []validation.Field{
+		validation.Required("name", ti.Name, validation.MaxLength(MAX_NAME_LENGTH)),
+	}
+	if op == validation.CREATE {
+		fields = append(fields, validation.Required("scopes", ti.Scopes, validation.MinLength(1)))
+	}
+	return validation.Validate(op, fields...)
+}
+
+func (ri *RotateTokenInput) Validate(op validation.Operation) error {
+	return validation.Validate(op,
+		validation.Optional("gracePeriodSeconds", ri.GracePeriodSeconds, validation.Range(0, MAX_ROTATION_GRACE.Seconds())),
+	)
+}
@@ -78,6 +78,19 @@ func (as *AuditService) GetRoutes() map[string]routes.Route {
 	}
 }
 
+func (as *AuditService) DescribeRoutes() map[string]routes.RouteDescription {
+	return map[string]routes.RouteDescription{
+		"GET:/audits": {
+			Summary: "Lists changes made to resources",
+			Output:  data.QueryResults[Audit]{},
+			Query:   append([]string{"stripFields"}, routes.LIST_QUERY...),
+		},
+		"DELETE:/audits/:auditId": {
+			Summary: "Deletes an audit entry",
+		},
+	}
+}
+
 func (as *AuditService) ListAudits(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
 	return util.SerializeListByIndex(as.data, _stripFields(event), as.indexName, event, ctx)
 }
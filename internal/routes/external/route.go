@@ -4,10 +4,12 @@ import (
 	"context"
 
 	"github.com/aws/aws-lambda-go/events"
+	"philcali.me/recipes/internal/data"
 	"philcali.me/recipes/internal/exceptions"
 	"philcali.me/recipes/internal/mealdb"
 	"philcali.me/recipes/internal/provider"
 	"philcali.me/recipes/internal/routes"
+	"philcali.me/recipes/internal/routes/recipes"
 	"philcali.me/recipes/internal/routes/util"
 )
 
@@ -29,6 +31,24 @@ func (es *ExternalService) GetRoutes() map[string]routes.Route {
 	}
 }
 
+func (es *ExternalService) DescribeRoutes() map[string]routes.RouteDescription {
+	return map[string]routes.RouteDescription{
+		"GET:/providers/mealdb": {
+			Summary: "Searches TheMealDB for recipes",
+			Output:  data.QueryResults[recipes.Recipe]{},
+			Query:   []string{"search"},
+		},
+		"GET:/providers/mealdb/:mealId/recipes": {
+			Summary: "Looks up a meal of TheMealDB as recipes",
+			Output:  data.QueryResults[recipes.Recipe]{},
+		},
+		"GET:/providers/mealdb/random": {
+			Summary: "Gets a random meal of TheMealDB as recipes",
+			Output:  data.QueryResults[recipes.Recipe]{},
+		},
+	}
+}
+
 func (es *ExternalService) Lookup(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
 	lookupId := util.RequestParam(ctx, "mealId")
 	query, err := es.Service.Lookup(lookupId)
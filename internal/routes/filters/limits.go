@@ -0,0 +1,76 @@
+package filters
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"philcali.me/recipes/internal/exceptions"
+	"philcali.me/recipes/internal/limits"
+)
+
+func _tooManyRequests(ctx *FilterContext, message string, retryAfter *time.Duration) (*FilterContext, bool) {
+	var headers map[string]string
+	if retryAfter != nil {
+		headers = map[string]string{
+			"Retry-After": strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))),
+		}
+	}
+	return ErrorResponse(ctx, exceptions.TooManyRequests(message), headers)
+}
+
+func _username(request *events.APIGatewayV2HTTPRequest) string {
+	if claims, ok := request.RequestContext.Authorizer.Lambda["jwt"].(map[string]interface{}); ok {
+		if username, ok := claims["username"]; ok {
+			return fmt.Sprintf("%v", username)
+		}
+	}
+	if jwt := request.RequestContext.Authorizer.JWT; jwt != nil {
+		return jwt.Claims["username"]
+	}
+	return ""
+}
+
+type RateLimitFilter struct {
+	Limiter limits.RateLimiter
+	Limit   limits.Limit
+	Now     func() time.Time
+}
+
+// Tokens are limited on their own, so one integration cannot starve the account
+func (rf *RateLimitFilter) _key(request *events.APIGatewayV2HTTPRequest) string {
+	if tokenId, ok := request.RequestContext.Authorizer.Lambda["tokenId"]; ok {
+		return fmt.Sprintf("token/%v", tokenId)
+	}
+	if username := _username(request); username != "" {
+		return "account/" + username
+	}
+	return ""
+}
+
+func (rf *RateLimitFilter) Filter(ctx *FilterContext) (*FilterContext, bool) {
+	key := rf._key(ctx.Request)
+	if ctx.Request.RequestContext.HTTP.Method == "OPTIONS" || key == "" {
+		return ctx, false
+	}
+	allowed, retryAfter, err := rf.Limiter.Take(key, rf.Limit, rf.Now())
+	// Limits protect the service, failing to count never fails a request
+	if err != nil {
+		fmt.Printf("Failed to rate limit %s: %v\n", key, err)
+		return ctx, false
+	}
+	if !allowed {
+		return _tooManyRequests(ctx, "Too many requests", &retryAfter)
+	}
+	return ctx, false
+}
+
+func NewRateLimitFilter(limiter limits.RateLimiter, limit limits.Limit) *RateLimitFilter {
+	return &RateLimitFilter{
+		Limiter: limiter,
+		Limit:   limit,
+		Now:     time.Now,
+	}
+}
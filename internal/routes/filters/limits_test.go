@@ -0,0 +1,60 @@
+package filters
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"philcali.me/recipes/internal/limits"
+)
+
+func _limitedRequest(method string, path string, lambda map[string]interface{}) *FilterContext {
+	ctx := context.Background()
+	request := &events.APIGatewayV2HTTPRequest{
+		RawPath: path,
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{
+				Method: method,
+			},
+			Authorizer: &events.APIGatewayV2HTTPRequestContextAuthorizerDescription{
+				Lambda: lambda,
+			},
+		},
+	}
+	return &FilterContext{
+		Request:  request,
+		Response: &events.APIGatewayV2HTTPResponse{StatusCode: 200},
+		Context:  &ctx,
+	}
+}
+
+func TestRateLimitFilter(t *testing.T) {
+	now := time.Now()
+	filter := NewRateLimitFilter(limits.NewMemoryRateLimiter(), limits.Limit{Capacity: 1, RefillPerSecond: 0.25})
+	filter.Now = func() time.Time { return now }
+	account := map[string]interface{}{
+		"jwt": map[string]interface{}{"username": "nobody"},
+	}
+	token := map[string]interface{}{
+		"jwt":     map[string]interface{}{"username": "nobody"},
+		"tokenId": "abc-123",
+	}
+
+	if _, done := filter.Filter(_limitedRequest("GET", "/recipes", account)); done {
+		t.Fatal("Expected the first request to pass")
+	}
+	if _, done := filter.Filter(_limitedRequest("OPTIONS", "/recipes", account)); done {
+		t.Fatal("Expected preflight requests to not be limited")
+	}
+	if _, done := filter.Filter(_limitedRequest("GET", "/recipes", token)); done {
+		t.Fatal("Expected tokens to be limited separately from the account")
+	}
+	resp, done := filter.Filter(_limitedRequest("GET", "/recipes", account))
+	if !done || resp.Response.StatusCode != 429 {
+		t.Fatalf("Expected the account to be limited, got %v", resp.Response)
+	}
+	if resp.Response.Headers["Retry-After"] != "4" {
+		t.Fatalf("Expected to retry after 4s, got %v", resp.Response.Headers)
+	}
+}
@@ -2,10 +2,15 @@ package filters
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/aws/aws-lambda-go/events"
+	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/exceptions"
 )
 
 type FilterContext struct {
@@ -18,10 +23,26 @@ type RequestFilter interface {
 	Filter(ctx *FilterContext) (*FilterContext, bool)
 }
 
+// Resolves the methods routed for a path, see routes.Router
+type MethodResolver interface {
+	AllowedMethods(path string) []string
+}
+
 type CorsFilter struct {
-	Methods []string
-	Origins []string
-	Headers []string
+	// Allowed when the resolver is unset or routes nothing on the path
+	Methods  []string
+	Origins  []string
+	Headers  []string
+	Resolver MethodResolver
+}
+
+func (cf *CorsFilter) AllowedMethods(path string) []string {
+	if cf.Resolver != nil {
+		if methods := cf.Resolver.AllowedMethods(path); len(methods) > 0 {
+			return methods
+		}
+	}
+	return cf.Methods
 }
 
 func (cf *CorsFilter) Filter(ctx *FilterContext) (*FilterContext, bool) {
@@ -32,7 +53,7 @@ func (cf *CorsFilter) Filter(ctx *FilterContext) (*FilterContext, bool) {
 		}
 		headers["content-length"] = "0"
 		headers["access-control-allow-headers"] = strings.Join(cf.Headers, ", ")
-		headers["access-control-allow-methods"] = strings.Join(cf.Methods, ", ")
+		headers["access-control-allow-methods"] = strings.Join(cf.AllowedMethods(ctx.Request.RawPath), ", ")
 		headers["access-control-allow-origin"] = strings.Join(cf.Origins, ", ")
 		return &FilterContext{
 			Request: ctx.Request,
@@ -48,6 +69,8 @@ func (cf *CorsFilter) Filter(ctx *FilterContext) (*FilterContext, bool) {
 
 type AuthorizedScopeFilter struct {
 	ScopeField string
+	// Paths any authorized caller may read, regardless of their scopes
+	UnscopedPaths []string
 }
 
 func (cf *AuthorizedScopeFilter) IdentityScopes(ctx *FilterContext) ([]string, bool) {
@@ -65,36 +88,58 @@ func (cf *AuthorizedScopeFilter) IdentityScopes(ctx *FilterContext) ([]string, b
 
 func (cf *AuthorizedScopeFilter) Filter(ctx *FilterContext) (*FilterContext, bool) {
 	if ctx.Request.RequestContext.HTTP.Method != "OPTIONS" {
+		if data.ReadMethod(ctx.Request.RequestContext.HTTP.Method) && slices.Contains(cf.UnscopedPaths, ctx.Request.RawPath) {
+			return ctx, false
+		}
 		jwt := ctx.Request.RequestContext.Authorizer.JWT
 		if jwt != nil && len(jwt.Claims) > 0 {
 			return ctx, false
 		}
 		if scopes, ok := cf.IdentityScopes(ctx); ok {
 			for _, scope := range scopes {
-				auth := strings.Split(scope, ".")
-				if strings.HasPrefix(ctx.Request.RawPath, "/"+auth[0]) {
-					if len(auth) == 1 || len(auth) > 1 && ctx.Request.RequestContext.HTTP.Method == "GET" {
-						return ctx, false
-					}
+				if data.Scope(scope).Grants(ctx.Request.RequestContext.HTTP.Method, ctx.Request.RawPath) {
+					return ctx, false
 				}
 			}
 		}
 	}
-	body := "{\"message\": \"Unauthorized\"}"
+	return ErrorResponse(ctx, exceptions.Unauthorized("Unauthorized"), nil)
+}
+
+// Ends the chain with the same error body routes respond with
+func ErrorResponse(ctx *FilterContext, err error, headers map[string]string) (*FilterContext, bool) {
+	statusCode, response := exceptions.NewErrorResponse(err, ctx.Request.RequestContext.RequestID)
+	body, _ := json.Marshal(response)
+	if headers == nil {
+		headers = make(map[string]string, 2)
+	}
+	headers["Content-Type"] = "application/json"
+	headers["Content-Length"] = strconv.Itoa(len(body))
 	return &FilterContext{
 		Request: ctx.Request,
 		Context: ctx.Context,
 		Response: &events.APIGatewayV2HTTPResponse{
-			Headers: map[string]string{
-				"Content-Type":   "application/json",
-				"Content-Length": string(rune(len(body))),
-			},
-			StatusCode: 401,
-			Body:       body,
+			Headers:    headers,
+			StatusCode: statusCode,
+			Body:       string(body),
 		},
 	}, true
 }
 
+// Makes memberships available to routes resolving the household of a request
+type HouseholdFilter struct {
+	Memberships data.MembershipRepository
+}
+
+func (hf *HouseholdFilter) Filter(ctx *FilterContext) (*FilterContext, bool) {
+	updated := context.WithValue(*ctx.Context, "Memberships", hf.Memberships)
+	return &FilterContext{
+		Request:  ctx.Request,
+		Response: ctx.Response,
+		Context:  &updated,
+	}, false
+}
+
 func DefaultFilterContext(event events.APIGatewayV2HTTPRequest, ctx context.Context) *FilterContext {
 	return &FilterContext{
 		Request: &event,
@@ -106,8 +151,8 @@ func DefaultFilterContext(event events.APIGatewayV2HTTPRequest, ctx context.Cont
 }
 
 func DefaultCorsFilter() *CorsFilter {
-	methods := [4]string{"GET", "PUT", "POST", "DELETE"}
-	headers := [3]string{"Content-Type", "Content-Length", "Authorization"}
+	methods := [6]string{"GET", "HEAD", "PUT", "PATCH", "POST", "DELETE"}
+	headers := [4]string{"Content-Type", "Content-Length", "Authorization", "X-Household-Id"}
 	origins := [1]string{"*"}
 	return &CorsFilter{
 		Methods: methods[:],
@@ -118,6 +163,13 @@ func DefaultCorsFilter() *CorsFilter {
 
 func DefaultAuthorizationFilter() *AuthorizedScopeFilter {
 	return &AuthorizedScopeFilter{
-		ScopeField: "scopes",
+		ScopeField:    "scopes",
+		UnscopedPaths: []string{"/openapi.json"},
+	}
+}
+
+func NewHouseholdFilter(memberships data.MembershipRepository) *HouseholdFilter {
+	return &HouseholdFilter{
+		Memberships: memberships,
 	}
 }
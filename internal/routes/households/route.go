@@ -0,0 +1,151 @@
+package households
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/exceptions"
+	"philcali.me/recipes/internal/routes"
+	"philcali.me/recipes/internal/routes/util"
+	"philcali.me/recipes/internal/validation"
+)
+
+type HouseholdService struct {
+	data      data.MembershipRepository
+	indexName string
+}
+
+func NewRouteWithIndex(data data.MembershipRepository, indexName string) routes.Service {
+	return &HouseholdService{
+		data:      data,
+		indexName: indexName,
+	}
+}
+
+func NewRoute(data data.MembershipRepository) routes.Service {
+	return NewRouteWithIndex(data, os.Getenv("INDEX_NAME_1"))
+}
+
+// Members are managed on the household a request acts on, while the rest
+// concerns the memberships of the caller
+func (hs *HouseholdService) GetRoutes() map[string]routes.Route {
+	return map[string]routes.Route{
+		"GET:/households":                      util.AuthorizedRoute(hs.ListHouseholds),
+		"POST:/households/:householdId/accept": util.AuthorizedRoute(hs.AcceptMembership),
+		"DELETE:/households/:householdId":      util.AuthorizedRoute(hs.LeaveHousehold),
+		"GET:/households/members":              util.AuthorizedRoute(hs.ListMembers),
+		"POST:/households/members":             util.AuthorizedRoute(hs.InviteMember),
+		"PUT:/households/members/:email":       util.AuthorizedRoute(hs.UpdateMember),
+		"DELETE:/households/members/:email":    util.AuthorizedRoute(hs.RemoveMember),
+	}
+}
+
+func (hs *HouseholdService) DescribeRoutes() map[string]routes.RouteDescription {
+	return map[string]routes.RouteDescription{
+		"GET:/households": {
+			Summary: "Lists the households the caller is invited to or a member of",
+			Output:  data.QueryResults[Member]{},
+			Query:   routes.LIST_QUERY,
+		},
+		"POST:/households/:householdId/accept": {
+			Summary: "Accepts an invitation to a household",
+			Output:  Member{},
+		},
+		"DELETE:/households/:householdId": {
+			Summary: "Leaves a household or declines its invitation",
+		},
+		"GET:/households/members": {
+			Summary: "Lists the members of the household",
+			Output:  data.QueryResults[Member]{},
+			Query:   routes.LIST_QUERY,
+		},
+		"POST:/households/members": {
+			Summary: "Invites a member to the household",
+			Input:   MemberInput{},
+			Output:  Member{},
+		},
+		"PUT:/households/members/:email": {
+			Summary: "Changes the role of a member",
+			Input:   MemberInput{},
+			Output:  Member{},
+		},
+		"DELETE:/households/members/:email": {
+			Summary: "Removes a member from the household",
+		},
+	}
+}
+
+func (hs *HouseholdService) ListHouseholds(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
+	return util.SerializeListByIndexAndHash(hs.data, NewMember, hs.indexName, event, util.Email(event))
+}
+
+func (hs *HouseholdService) AcceptMembership(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
+	householdId := util.RequestParam(ctx, "householdId")
+	membership, err := hs.data.Get(householdId, util.Email(event))
+	if err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	if membership.Status != data.MEMBERSHIP_INVITED {
+		return events.APIGatewayV2HTTPResponse{}, exceptions.InvalidInput(fmt.Sprintf("Membership in %s was already accepted", householdId))
+	}
+	status := data.MEMBERSHIP_ACTIVE
+	updated, err := hs.data.Update(householdId, membership.SK, data.MembershipInputDTO{
+		MemberId: aws.String(util.Member(ctx)),
+		Status:   &status,
+	})
+	return util.SerializeResponseOK(NewMember, updated, err)
+}
+
+func (hs *HouseholdService) LeaveHousehold(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
+	return util.SerializeResponseNoContent(hs.data.Delete(util.RequestParam(ctx, "householdId"), util.Email(event)))
+}
+
+func (hs *HouseholdService) ListMembers(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
+	return util.SerializeList(hs.data, NewMember, event, ctx)
+}
+
+func (hs *HouseholdService) InviteMember(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
+	input := MemberInput{}
+	if err := util.DecodeInput(event, &input, validation.CREATE); err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	email := strings.ToLower(*input.Email)
+	if email == util.Email(event) {
+		return events.APIGatewayV2HTTPResponse{}, exceptions.InvalidInput("Cannot invite yourself")
+	}
+	role := data.ROLE_VIEWER
+	if input.Role != nil {
+		role = *input.Role
+	}
+	status := data.MEMBERSHIP_INVITED
+	created, err := hs.data.CreateWithItemId(util.Username(ctx), data.MembershipInputDTO{
+		HouseholdId: aws.String(util.Username(ctx)),
+		Role:        &role,
+		Status:      &status,
+		InvitedBy:   aws.String(util.Email(event)),
+	}, email)
+	return util.SerializeResponseOK(NewMember, created, err)
+}
+
+func (hs *HouseholdService) UpdateMember(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
+	input := MemberInput{}
+	if err := util.DecodeInput(event, &input, validation.UPDATE); err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	if input.Role == nil {
+		return events.APIGatewayV2HTTPResponse{}, exceptions.InvalidInput("Members can only change their role")
+	}
+	updated, err := hs.data.Update(util.Username(ctx), strings.ToLower(util.RequestParam(ctx, "email")), data.MembershipInputDTO{
+		Role: input.Role,
+	})
+	return util.SerializeResponseOK(NewMember, updated, err)
+}
+
+func (hs *HouseholdService) RemoveMember(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
+	return util.SerializeResponseNoContent(hs.data.Delete(util.Username(ctx), strings.ToLower(util.RequestParam(ctx, "email"))))
+}
@@ -0,0 +1,48 @@
+package households
+
+import (
+	"time"
+
+	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/validation"
+)
+
+type Member struct {
+	Email       string                `json:"email"`
+	HouseholdId string                `json:"householdId"`
+	MemberId    *string               `json:"memberId,omitempty"`
+	Role        data.Role             `json:"role"`
+	Status      data.MembershipStatus `json:"status"`
+	InvitedBy   string                `json:"invitedBy"`
+	CreateTime  time.Time             `json:"createTime"`
+	UpdateTime  time.Time             `json:"updateTime"`
+}
+
+type MemberInput struct {
+	Email *string    `json:"email"`
+	Role  *data.Role `json:"role"`
+}
+
+// Households have a single owner, so members are only ever editors or viewers
+func (mi *MemberInput) Validate(op validation.Operation) error {
+	fields := []validation.Field{
+		validation.Optional("role", mi.Role, validation.OneOf(data.ROLE_EDITOR, data.ROLE_VIEWER)),
+	}
+	if op == validation.CREATE {
+		fields = append(fields, validation.Required("email", mi.Email, validation.Email()))
+	}
+	return validation.Validate(op, fields...)
+}
+
+func NewMember(membership data.MembershipDTO) Member {
+	return Member{
+		Email:       membership.SK,
+		HouseholdId: membership.HouseholdId,
+		MemberId:    membership.MemberId,
+		Role:        membership.Role,
+		Status:      membership.Status,
+		InvitedBy:   membership.InvitedBy,
+		CreateTime:  membership.CreateTime,
+		UpdateTime:  membership.UpdateTime,
+	}
+}
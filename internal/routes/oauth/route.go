@@ -0,0 +1,418 @@
+package oauth
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/google/uuid"
+	"philcali.me/recipes/internal/auth"
+	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/exceptions"
+	"philcali.me/recipes/internal/routes"
+	"philcali.me/recipes/internal/routes/util"
+	"philcali.me/recipes/internal/validation"
+)
+
+const (
+	AUTHORIZATION_CODE_LIFETIME = 10 * time.Minute
+	ACCESS_TOKEN_LIFETIME       = time.Hour
+	REFRESH_TOKEN_LIFETIME      = 30 * 24 * time.Hour
+)
+
+type OAuthService struct {
+	clients     data.OAuthClientDataService
+	consents    data.OAuthConsentDataService
+	codes       data.AuthorizationCodeDataService
+	revocations data.RevokedTokenDataService
+	indexName   string
+}
+
+func NewRouteWithIndex(
+	clients data.OAuthClientDataService,
+	consents data.OAuthConsentDataService,
+	codes data.AuthorizationCodeDataService,
+	revocations data.RevokedTokenDataService,
+	indexName string) routes.Service {
+	return &OAuthService{
+		clients:     clients,
+		consents:    consents,
+		codes:       codes,
+		revocations: revocations,
+		indexName:   indexName,
+	}
+}
+
+func NewRoute(
+	clients data.OAuthClientDataService,
+	consents data.OAuthConsentDataService,
+	codes data.AuthorizationCodeDataService,
+	revocations data.RevokedTokenDataService) routes.Service {
+	return NewRouteWithIndex(clients, consents, codes, revocations, os.Getenv("INDEX_NAME_1"))
+}
+
+func (oa *OAuthService) GetRoutes() map[string]routes.Route {
+	return map[string]routes.Route{
+		"GET:/oauth/clients":               util.AuthorizedRoute(oa.ListClients),
+		"GET:/oauth/clients/:clientId":     util.AuthorizedRoute(oa.GetClient),
+		"POST:/oauth/clients":              util.AuthorizedRoute(oa.CreateClient),
+		"DELETE:/oauth/clients/:clientId":  util.AuthorizedRoute(oa.DeleteClient),
+		"GET:/oauth/authorize":             util.AuthorizedRoute(oa.GetAuthorization),
+		"POST:/oauth/authorize":            util.AuthorizedRoute(oa.Authorize),
+		"GET:/oauth/consents":              util.AuthorizedRoute(oa.ListConsents),
+		"DELETE:/oauth/consents/:clientId": util.AuthorizedRoute(oa.DeleteConsent),
+	}
+}
+
+func (oa *OAuthService) DescribeRoutes() map[string]routes.RouteDescription {
+	authorizeQuery := []string{"response_type", "client_id", "redirect_uri", "scope", "state", "code_challenge", "code_challenge_method"}
+	return map[string]routes.RouteDescription{
+		"GET:/oauth/clients": {
+			Summary: "Lists registered OAuth clients",
+			Output:  data.QueryResults[Client]{},
+			Query:   routes.LIST_QUERY,
+		},
+		"GET:/oauth/clients/:clientId": {
+			Summary: "Gets an OAuth client",
+			Output:  Client{},
+		},
+		"POST:/oauth/clients": {
+			Summary: "Registers an OAuth client, the only response holding its secret",
+			Input:   ClientInput{},
+			Output:  Client{},
+		},
+		"DELETE:/oauth/clients/:clientId": {
+			Summary: "Deletes an OAuth client",
+		},
+		"GET:/oauth/authorize": {
+			Summary: "Describes an authorization request for the consent prompt",
+			Output:  AuthorizationPrompt{},
+			Query:   authorizeQuery,
+		},
+		"POST:/oauth/authorize": {
+			Summary: "Consents to an authorization request, returning the redirect with its code",
+			Output:  AuthorizationResponse{},
+			Query:   authorizeQuery,
+		},
+		"GET:/oauth/consents": {
+			Summary: "Lists the clients consented to",
+			Output:  data.QueryResults[Consent]{},
+			Query:   routes.LIST_QUERY,
+		},
+		"DELETE:/oauth/consents/:clientId": {
+			Summary: "Revokes consent and every token issued to a client",
+		},
+	}
+}
+
+// Clients live in a global partition, so ownership is checked on every access
+func (oa *OAuthService) _getOwnedClient(ctx context.Context) (data.OAuthClientDTO, error) {
+	clientId := util.RequestParam(ctx, "clientId")
+	item, err := oa.clients.Get("Global", clientId)
+	if err == nil && item.AccountId != util.Username(ctx) {
+		return item, exceptions.NotFound("client", clientId)
+	}
+	return item, err
+}
+
+// Redirects must be https, plain http is only tolerated for local development
+func _validateRedirectUri(redirectUri string) error {
+	parsed, err := url.Parse(redirectUri)
+	if err != nil || parsed.Host == "" || parsed.Fragment != "" {
+		return exceptions.InvalidInput(fmt.Sprintf("Redirect URI %s must be an absolute URL without a fragment", redirectUri))
+	}
+	hostname := parsed.Hostname()
+	local := hostname == "localhost" || hostname == "127.0.0.1"
+	if parsed.Scheme != "https" && !(parsed.Scheme == "http" && local) {
+		return exceptions.InvalidInput(fmt.Sprintf("Redirect URI %s must use https", redirectUri))
+	}
+	return nil
+}
+
+func (oa *OAuthService) ListClients(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
+	return util.SerializeListByIndex(oa.clients, NewClient, oa.indexName, event, ctx)
+}
+
+func (oa *OAuthService) GetClient(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
+	item, err := oa._getOwnedClient(ctx)
+	return util.SerializeResponseOK(NewClient, item, err)
+}
+
+func (oa *OAuthService) CreateClient(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
+	input := ClientInput{}
+	if err := util.DecodeInput(event, &input, validation.CREATE); err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	for _, redirectUri := range input.RedirectUris {
+		if err := _validateRedirectUri(redirectUri); err != nil {
+			return events.APIGatewayV2HTTPResponse{}, err
+		}
+	}
+	if err := util.ValidateScopes(event, input.Scopes); err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	confidential := input.Confidential == nil || *input.Confidential
+	if !confidential && len(input.RedirectUris) == 0 {
+		return events.APIGatewayV2HTTPResponse{}, exceptions.InvalidInput("Public clients require at least one redirect URI")
+	}
+	clientId, secret, err := auth.GenerateToken()
+	if err != nil {
+		return events.APIGatewayV2HTTPResponse{}, exceptions.InternalServer(err.Error())
+	}
+	var secretHash *string
+	if confidential {
+		secretHash = aws.String(auth.HashSecret(secret))
+	}
+	claims := util.AuthorizationClaims(event)
+	created, err := oa.clients.CreateWithItemId("Global", data.OAuthClientInputDTO{
+		AccountId:    aws.String(util.Username(ctx)),
+		Name:         input.Name,
+		RedirectUris: &input.RedirectUris,
+		Scopes:       &input.Scopes,
+		Claims:       &claims,
+		Confidential: &confidential,
+		SecretHash:   secretHash,
+	}, clientId)
+	if err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	// The secret is only ever revealed on registration
+	client := NewClient(created)
+	if confidential {
+		client.Secret = &secret
+	}
+	return util.SerializeResponseOK(util.IdentityThunk, client, nil)
+}
+
+func (oa *OAuthService) DeleteClient(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
+	item, err := oa._getOwnedClient(ctx)
+	if err != nil {
+		if _, ok := err.(*exceptions.NotFoundError); ok {
+			return util.SerializeResponseNoContent(nil)
+		}
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	// Client credential tokens are issued under the client id as their grant
+	if err := auth.RevokeGrant(oa.revocations, item.SK, item.AccountId, ACCESS_TOKEN_LIFETIME); err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	if err := oa._revokeConsents(item.SK); err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	return util.SerializeResponseNoContent(oa.clients.Delete("Global", item.SK))
+}
+
+// Revokes every grant consented to the client, and removes the consent so its
+// refresh tokens can no longer be exchanged
+func (oa *OAuthService) _revokeConsents(clientId string) error {
+	params := data.QueryParams{}
+	for {
+		consents, err := oa.consents.ListByIndex(clientId, oa.indexName, params)
+		if err != nil {
+			return err
+		}
+		for _, consent := range consents.Items {
+			accountId := strings.TrimSuffix(consent.PK, ":OAuthConsent")
+			if err := auth.RevokeGrant(oa.revocations, consent.GrantId, accountId, ACCESS_TOKEN_LIFETIME); err != nil {
+				return err
+			}
+			if err := oa.consents.Delete(accountId, consent.SK); err != nil {
+				return err
+			}
+		}
+		if consents.NextToken == nil {
+			return nil
+		}
+		params.NextToken = consents.NextToken
+	}
+}
+
+type _authorizationRequest struct {
+	client              data.OAuthClientDTO
+	redirectUri         string
+	scopes              []data.Scope
+	state               string
+	codeChallenge       string
+	codeChallengeMethod string
+}
+
+// Authorization requests use the RFC 6749 query parameters for both the
+// consent prompt and the approval
+func (oa *OAuthService) _parseAuthorizationRequest(event events.APIGatewayV2HTTPRequest) (*_authorizationRequest, error) {
+	params := event.QueryStringParameters
+	if params["response_type"] != "code" {
+		return nil, exceptions.InvalidInput("Only the code response_type is supported")
+	}
+	client, err := oa.clients.Get("Global", params["client_id"])
+	if err != nil {
+		if _, ok := err.(*exceptions.NotFoundError); ok {
+			return nil, exceptions.InvalidInput(fmt.Sprintf("Unknown client %s", params["client_id"]))
+		}
+		return nil, err
+	}
+	request := &_authorizationRequest{
+		client:              client,
+		redirectUri:         params["redirect_uri"],
+		scopes:              ParseScopes(params["scope"]),
+		state:               params["state"],
+		codeChallenge:       params["code_challenge"],
+		codeChallengeMethod: params["code_challenge_method"],
+	}
+	registered := false
+	for _, redirectUri := range client.RedirectUris {
+		registered = registered || redirectUri == request.redirectUri
+	}
+	if !registered {
+		return nil, exceptions.InvalidInput(fmt.Sprintf("Redirect URI %s is not registered for client %s", request.redirectUri, client.SK))
+	}
+	if request.codeChallenge == "" || request.codeChallengeMethod != auth.PKCE_METHOD_S256 {
+		return nil, exceptions.InvalidInput(fmt.Sprintf("Authorization requires a %s code_challenge", auth.PKCE_METHOD_S256))
+	}
+	if len(request.scopes) == 0 {
+		request.scopes = client.Scopes
+	}
+	for _, scope := range request.scopes {
+		if err := scope.Validate(); err != nil {
+			return nil, exceptions.InvalidInput(err.Error())
+		}
+	}
+	if scope, uncovered := data.UncoveredScope(client.Scopes, request.scopes); uncovered {
+		return nil, exceptions.InvalidInput(fmt.Sprintf("Scope %s is not registered for client %s", scope, client.SK))
+	}
+	return request, nil
+}
+
+func (oa *OAuthService) GetAuthorization(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
+	request, err := oa._parseAuthorizationRequest(event)
+	if err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	consented := false
+	consent, err := oa.consents.Get(util.Username(ctx), request.client.SK)
+	if err == nil {
+		_, uncovered := data.UncoveredScope(consent.Scopes, request.scopes)
+		consented = !uncovered
+	} else if _, ok := err.(*exceptions.NotFoundError); !ok {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	return util.SerializeResponseOK(util.IdentityThunk, AuthorizationPrompt{
+		ClientId:   request.client.SK,
+		ClientName: request.client.Name,
+		Scopes:     request.scopes,
+		Consented:  consented,
+	}, nil)
+}
+
+// Records consent for the requested scopes, keeping the grant of any earlier consent
+func (oa *OAuthService) _recordConsent(accountId string, request *_authorizationRequest) (data.OAuthConsentDTO, error) {
+	consent, err := oa.consents.Get(accountId, request.client.SK)
+	if err != nil {
+		if _, ok := err.(*exceptions.NotFoundError); !ok {
+			return consent, err
+		}
+		return oa.consents.CreateWithItemId(accountId, data.OAuthConsentInputDTO{
+			GrantId:    aws.String(uuid.NewString()),
+			ClientName: aws.String(request.client.Name),
+			Scopes:     &request.scopes,
+		}, request.client.SK)
+	}
+	if _, uncovered := data.UncoveredScope(consent.Scopes, request.scopes); !uncovered {
+		return consent, nil
+	}
+	scopes := consent.Scopes
+	for _, scope := range request.scopes {
+		if _, uncovered := data.UncoveredScope(scopes, []data.Scope{scope}); uncovered {
+			scopes = append(scopes, scope)
+		}
+	}
+	return oa.consents.Update(accountId, request.client.SK, data.OAuthConsentInputDTO{
+		ClientName: aws.String(request.client.Name),
+		Scopes:     &scopes,
+	})
+}
+
+func (oa *OAuthService) Authorize(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
+	request, err := oa._parseAuthorizationRequest(event)
+	if err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	if err := util.ValidateScopes(event, request.scopes); err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	consent, err := oa._recordConsent(util.Username(ctx), request)
+	if err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	code, err := auth.GenerateSecret()
+	if err != nil {
+		return events.APIGatewayV2HTTPResponse{}, exceptions.InternalServer(err.Error())
+	}
+	claims := util.AuthorizationClaims(event)
+	_, err = oa.codes.CreateWithItemId("Global", data.AuthorizationCodeInputDTO{
+		ClientId:            aws.String(request.client.SK),
+		AccountId:           aws.String(util.Username(ctx)),
+		GrantId:             aws.String(consent.GrantId),
+		RedirectUri:         aws.String(request.redirectUri),
+		Scopes:              &request.scopes,
+		Claims:              &claims,
+		CodeChallenge:       aws.String(request.codeChallenge),
+		CodeChallengeMethod: aws.String(request.codeChallengeMethod),
+		ExpiresIn:           aws.Int(int(time.Now().Add(AUTHORIZATION_CODE_LIFETIME).Unix())),
+	}, auth.HashSecret(code))
+	if err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	redirect, _ := url.Parse(request.redirectUri)
+	query := redirect.Query()
+	query.Set("code", code)
+	if request.state != "" {
+		query.Set("state", request.state)
+	}
+	redirect.RawQuery = query.Encode()
+	return util.SerializeResponseOK(util.IdentityThunk, AuthorizationResponse{
+		RedirectUri: redirect.String(),
+	}, nil)
+}
+
+func (oa *OAuthService) ListConsents(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
+	return util.SerializeList(oa.consents, NewConsent, event, ctx)
+}
+
+// Withdrawing consent denies outstanding access tokens, and refresh tokens
+// stop working once their grant no longer matches the consent
+func (oa *OAuthService) DeleteConsent(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
+	consent, err := oa.consents.Get(util.Username(ctx), util.RequestParam(ctx, "clientId"))
+	if err != nil {
+		if _, ok := err.(*exceptions.NotFoundError); ok {
+			return util.SerializeResponseNoContent(nil)
+		}
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	if err := auth.RevokeGrant(oa.revocations, consent.GrantId, util.Username(ctx), ACCESS_TOKEN_LIFETIME); err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	return util.SerializeResponseNoContent(oa.consents.Delete(util.Username(ctx), consent.SK))
+}
+
+// Scopes are space delimited, per RFC 6749
+func ParseScopes(value string) []data.Scope {
+	fields := strings.Fields(value)
+	scopes := make([]data.Scope, len(fields))
+	for i, field := range fields {
+		scopes[i] = data.Scope(field)
+	}
+	return scopes
+}
+
+func FormatScopes(scopes []data.Scope) string {
+	values := make([]string, len(scopes))
+	for i, scope := range scopes {
+		values[i] = string(scope)
+	}
+	return strings.Join(values, " ")
+}
@@ -0,0 +1,352 @@
+package oauth
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"philcali.me/recipes/internal/auth"
+	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/exceptions"
+	"philcali.me/recipes/internal/routes"
+)
+
+// Error codes from RFC 6749 section 5.2
+const (
+	INVALID_REQUEST        = "invalid_request"
+	INVALID_CLIENT         = "invalid_client"
+	INVALID_GRANT          = "invalid_grant"
+	INVALID_SCOPE          = "invalid_scope"
+	UNAUTHORIZED_CLIENT    = "unauthorized_client"
+	UNSUPPORTED_GRANT_TYPE = "unsupported_grant_type"
+	SERVER_ERROR           = "server_error"
+)
+
+type TokenService struct {
+	clients       data.OAuthClientDataService
+	consents      data.OAuthConsentDataService
+	codes         data.AuthorizationCodeDataService
+	refreshTokens data.RefreshTokenDataService
+	signer        *auth.Signer
+	Now           func() time.Time
+}
+
+func NewTokenRouteWithSigner(
+	clients data.OAuthClientDataService,
+	consents data.OAuthConsentDataService,
+	codes data.AuthorizationCodeDataService,
+	refreshTokens data.RefreshTokenDataService,
+	signer *auth.Signer) routes.Service {
+	return &TokenService{
+		clients:       clients,
+		consents:      consents,
+		codes:         codes,
+		refreshTokens: refreshTokens,
+		signer:        signer,
+		Now:           time.Now,
+	}
+}
+
+func NewTokenRoute(
+	clients data.OAuthClientDataService,
+	consents data.OAuthConsentDataService,
+	codes data.AuthorizationCodeDataService,
+	refreshTokens data.RefreshTokenDataService) routes.Service {
+	return NewTokenRouteWithSigner(clients, consents, codes, refreshTokens, auth.NewSignerFromEnv())
+}
+
+// The token endpoint authenticates clients itself, it is never behind the authorizer
+func (ts *TokenService) GetRoutes() map[string]routes.Route {
+	return map[string]routes.Route{
+		"POST:/oauth/token": ts.Token,
+	}
+}
+
+func (ts *TokenService) DescribeRoutes() map[string]routes.RouteDescription {
+	return map[string]routes.RouteDescription{
+		"POST:/oauth/token": {
+			Summary:         "Exchanges a grant for tokens, errors are described by RFC 6749",
+			Input:           map[string]string{},
+			Output:          TokenResponse{},
+			ContentType:     "application/x-www-form-urlencoded",
+			Unauthenticated: true,
+		},
+	}
+}
+
+type _tokenError struct {
+	statusCode  int
+	code        string
+	description string
+}
+
+func _newTokenError(code string, description string) *_tokenError {
+	statusCode := http.StatusBadRequest
+	if code == INVALID_CLIENT {
+		statusCode = http.StatusUnauthorized
+	} else if code == SERVER_ERROR {
+		statusCode = http.StatusInternalServerError
+	}
+	return &_tokenError{
+		statusCode:  statusCode,
+		code:        code,
+		description: description,
+	}
+}
+
+// Token responses must never be cached, per RFC 6749 section 5.1
+func _tokenResponse(statusCode int, body any) (events.APIGatewayV2HTTPResponse, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return events.APIGatewayV2HTTPResponse{}, exceptions.InternalServer(err.Error())
+	}
+	return events.APIGatewayV2HTTPResponse{
+		StatusCode: statusCode,
+		Body:       string(payload),
+		Headers: map[string]string{
+			"Content-Type":   "application/json",
+			"Content-Length": strconv.Itoa(len(payload)),
+			"Cache-Control":  "no-store",
+			"Pragma":         "no-cache",
+		},
+	}, nil
+}
+
+func _header(event events.APIGatewayV2HTTPRequest, name string) string {
+	for key, value := range event.Headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
+
+func _parseForm(event events.APIGatewayV2HTTPRequest) (url.Values, error) {
+	body := event.Body
+	if event.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			return nil, err
+		}
+		body = string(decoded)
+	}
+	return url.ParseQuery(body)
+}
+
+// Clients authenticate with HTTP basic or with form parameters
+func (ts *TokenService) _authenticateClient(event events.APIGatewayV2HTTPRequest, form url.Values) (data.OAuthClientDTO, *_tokenError) {
+	clientId := form.Get("client_id")
+	clientSecret := form.Get("client_secret")
+	if basic, ok := strings.CutPrefix(_header(event, "Authorization"), "Basic "); ok {
+		decoded, err := base64.StdEncoding.DecodeString(basic)
+		if err != nil {
+			return data.OAuthClientDTO{}, _newTokenError(INVALID_CLIENT, "Malformed basic credentials")
+		}
+		id, secret, _ := strings.Cut(string(decoded), ":")
+		clientId, _ = url.QueryUnescape(id)
+		clientSecret, _ = url.QueryUnescape(secret)
+	}
+	if clientId == "" {
+		return data.OAuthClientDTO{}, _newTokenError(INVALID_CLIENT, "Client authentication is required")
+	}
+	client, err := ts.clients.Get("Global", clientId)
+	if err != nil {
+		if _, ok := err.(*exceptions.NotFoundError); ok {
+			return client, _newTokenError(INVALID_CLIENT, "Client authentication failed")
+		}
+		return client, _newTokenError(SERVER_ERROR, err.Error())
+	}
+	if client.Confidential && !auth.VerifySecret(client.SecretHash, clientSecret) {
+		return client, _newTokenError(INVALID_CLIENT, "Client authentication failed")
+	}
+	return client, nil
+}
+
+// Grants tied to a user stay valid only while the consent they were issued under exists
+func (ts *TokenService) _verifyConsent(accountId string, clientId string, grantId string) *_tokenError {
+	consent, err := ts.consents.Get(accountId, clientId)
+	if err != nil {
+		if _, ok := err.(*exceptions.NotFoundError); ok {
+			return _newTokenError(INVALID_GRANT, "Consent has been withdrawn")
+		}
+		return _newTokenError(SERVER_ERROR, err.Error())
+	}
+	if consent.GrantId != grantId {
+		return _newTokenError(INVALID_GRANT, "Consent has been withdrawn")
+	}
+	return nil
+}
+
+func (ts *TokenService) _requestedScopes(form url.Values, granted []data.Scope) ([]data.Scope, *_tokenError) {
+	scopes := ParseScopes(form.Get("scope"))
+	if len(scopes) == 0 {
+		return granted, nil
+	}
+	if scope, uncovered := data.UncoveredScope(granted, scopes); uncovered {
+		return nil, _newTokenError(INVALID_SCOPE, "Scope "+string(scope)+" exceeds the grant")
+	}
+	return scopes, nil
+}
+
+func (ts *TokenService) _issueAccessToken(claims auth.ApiTokenClaims) (TokenResponse, *_tokenError) {
+	now := ts.Now()
+	claims.IssuedAt = now.Unix()
+	claims.ExpiresAt = now.Add(ACCESS_TOKEN_LIFETIME).Unix()
+	accessToken, err := ts.signer.Sign(claims)
+	if err != nil {
+		return TokenResponse{}, _newTokenError(SERVER_ERROR, err.Error())
+	}
+	return TokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(ACCESS_TOKEN_LIFETIME.Seconds()),
+		Scope:       FormatScopes(claims.Scopes),
+	}, nil
+}
+
+// Access tokens carry the grant id as their token id, so withdrawing
+// consent denies them through the deny-list
+func (ts *TokenService) _issueTokens(clientId string, accountId string, grantId string, granted []data.Scope, scopes []data.Scope, claims map[string]string) (TokenResponse, *_tokenError) {
+	response, tokenErr := ts._issueAccessToken(auth.ApiTokenClaims{
+		Id:        grantId,
+		AccountId: accountId,
+		Scopes:    scopes,
+		Claims:    claims,
+		ClientId:  clientId,
+	})
+	if tokenErr != nil {
+		return response, tokenErr
+	}
+	tokenId, secret, err := auth.GenerateToken()
+	if err != nil {
+		return response, _newTokenError(SERVER_ERROR, err.Error())
+	}
+	_, err = ts.refreshTokens.CreateWithItemId("Global", data.RefreshTokenInputDTO{
+		ClientId:   aws.String(clientId),
+		AccountId:  aws.String(accountId),
+		GrantId:    aws.String(grantId),
+		Scopes:     &granted,
+		Claims:     &claims,
+		SecretHash: aws.String(auth.HashSecret(secret)),
+		ExpiresIn:  aws.Int(int(ts.Now().Add(REFRESH_TOKEN_LIFETIME).Unix())),
+	}, tokenId)
+	if err != nil {
+		return response, _newTokenError(SERVER_ERROR, err.Error())
+	}
+	response.RefreshToken = aws.String(auth.FormatToken(tokenId, secret))
+	return response, nil
+}
+
+func (ts *TokenService) _authorizationCodeGrant(client data.OAuthClientDTO, form url.Values) (TokenResponse, *_tokenError) {
+	// Codes are single use, even when the exchange fails, and only one of
+	// concurrent exchanges takes the code
+	code, err := ts.codes.Take("Global", auth.HashSecret(form.Get("code")))
+	if err != nil {
+		if _, ok := err.(*exceptions.NotFoundError); ok {
+			return TokenResponse{}, _newTokenError(INVALID_GRANT, "Authorization code is invalid")
+		}
+		return TokenResponse{}, _newTokenError(SERVER_ERROR, err.Error())
+	}
+	if code.ClientId != client.SK || code.RedirectUri != form.Get("redirect_uri") || code.ExpiresIn <= int(ts.Now().Unix()) {
+		return TokenResponse{}, _newTokenError(INVALID_GRANT, "Authorization code is invalid")
+	}
+	if !auth.VerifyCodeChallenge(code.CodeChallengeMethod, code.CodeChallenge, form.Get("code_verifier")) {
+		return TokenResponse{}, _newTokenError(INVALID_GRANT, "Code verifier does not match the challenge")
+	}
+	if tokenErr := ts._verifyConsent(code.AccountId, client.SK, code.GrantId); tokenErr != nil {
+		return TokenResponse{}, tokenErr
+	}
+	return ts._issueTokens(client.SK, code.AccountId, code.GrantId, code.Scopes, code.Scopes, code.Claims)
+}
+
+// Refresh tokens rotate on every use
+func (ts *TokenService) _refreshTokenGrant(client data.OAuthClientDTO, form url.Values) (TokenResponse, *_tokenError) {
+	tokenId, secret, ok := auth.ParseToken(form.Get("refresh_token"))
+	if !ok {
+		return TokenResponse{}, _newTokenError(INVALID_GRANT, "Refresh token is invalid")
+	}
+	refreshToken, err := ts.refreshTokens.Get("Global", tokenId)
+	if err != nil {
+		if _, ok := err.(*exceptions.NotFoundError); ok {
+			return TokenResponse{}, _newTokenError(INVALID_GRANT, "Refresh token is invalid")
+		}
+		return TokenResponse{}, _newTokenError(SERVER_ERROR, err.Error())
+	}
+	if !auth.VerifySecret(refreshToken.SecretHash, secret) || refreshToken.ClientId != client.SK || refreshToken.ExpiresIn <= int(ts.Now().Unix()) {
+		return TokenResponse{}, _newTokenError(INVALID_GRANT, "Refresh token is invalid")
+	}
+	if tokenErr := ts._verifyConsent(refreshToken.AccountId, client.SK, refreshToken.GrantId); tokenErr != nil {
+		return TokenResponse{}, tokenErr
+	}
+	scopes, tokenErr := ts._requestedScopes(form, refreshToken.Scopes)
+	if tokenErr != nil {
+		return TokenResponse{}, tokenErr
+	}
+	// Only one of concurrent refreshes takes the token
+	if _, err := ts.refreshTokens.Take("Global", refreshToken.SK); err != nil {
+		if _, ok := err.(*exceptions.NotFoundError); ok {
+			return TokenResponse{}, _newTokenError(INVALID_GRANT, "Refresh token is invalid")
+		}
+		return TokenResponse{}, _newTokenError(SERVER_ERROR, err.Error())
+	}
+	return ts._issueTokens(client.SK, refreshToken.AccountId, refreshToken.GrantId, refreshToken.Scopes, scopes, refreshToken.Claims)
+}
+
+// Client credentials act as the owner of the client, within the client scopes
+func (ts *TokenService) _clientCredentialsGrant(client data.OAuthClientDTO, form url.Values) (TokenResponse, *_tokenError) {
+	if !client.Confidential {
+		return TokenResponse{}, _newTokenError(UNAUTHORIZED_CLIENT, "Public clients cannot use client credentials")
+	}
+	scopes, tokenErr := ts._requestedScopes(form, client.Scopes)
+	if tokenErr != nil {
+		return TokenResponse{}, tokenErr
+	}
+	return ts._issueAccessToken(auth.ApiTokenClaims{
+		Id:        client.SK,
+		AccountId: client.AccountId,
+		Scopes:    scopes,
+		Claims:    client.Claims,
+		ClientId:  client.SK,
+	})
+}
+
+func (ts *TokenService) _token(event events.APIGatewayV2HTTPRequest) (TokenResponse, *_tokenError) {
+	if ts.signer == nil {
+		return TokenResponse{}, _newTokenError(SERVER_ERROR, "OAuth requires a signing key")
+	}
+	form, err := _parseForm(event)
+	if err != nil {
+		return TokenResponse{}, _newTokenError(INVALID_REQUEST, err.Error())
+	}
+	client, tokenErr := ts._authenticateClient(event, form)
+	if tokenErr != nil {
+		return TokenResponse{}, tokenErr
+	}
+	switch form.Get("grant_type") {
+	case "authorization_code":
+		return ts._authorizationCodeGrant(client, form)
+	case "refresh_token":
+		return ts._refreshTokenGrant(client, form)
+	case "client_credentials":
+		return ts._clientCredentialsGrant(client, form)
+	}
+	return TokenResponse{}, _newTokenError(UNSUPPORTED_GRANT_TYPE, "Unsupported grant_type "+form.Get("grant_type"))
+}
+
+func (ts *TokenService) Token(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
+	response, tokenErr := ts._token(event)
+	if tokenErr != nil {
+		return _tokenResponse(tokenErr.statusCode, TokenError{
+			Error:       tokenErr.code,
+			Description: tokenErr.description,
+		})
+	}
+	return _tokenResponse(http.StatusOK, response)
+}
@@ -0,0 +1,95 @@
+package oauth
+
+import (
+	"time"
+
+	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/validation"
+)
+
+type Client struct {
+	Id           string       `json:"clientId"`
+	Name         string       `json:"name"`
+	RedirectUris []string     `json:"redirectUris"`
+	Scopes       []data.Scope `json:"scopes"`
+	Confidential bool         `json:"confidential"`
+	Secret       *string      `json:"clientSecret,omitempty"`
+	CreateTime   time.Time    `json:"createTime"`
+	UpdateTime   time.Time    `json:"updateTime"`
+}
+
+type ClientInput struct {
+	Name         *string      `json:"name,omitempty"`
+	RedirectUris []string     `json:"redirectUris,omitempty"`
+	Scopes       []data.Scope `json:"scopes,omitempty"`
+	Confidential *bool        `json:"confidential,omitempty"`
+}
+
+const (
+	MAX_NAME_LENGTH   = 256
+	MAX_REDIRECT_URIS = 10
+)
+
+func (ci *ClientInput) Validate(op validation.Operation) error {
+	return validation.Validate(op,
+		validation.Required("name", ci.Name, validation.MaxLength(MAX_NAME_LENGTH)),
+		validation.Required("scopes", ci.Scopes, validation.MinLength(1)),
+		validation.Optional("redirectUris", ci.RedirectUris, validation.MaxLength(MAX_REDIRECT_URIS)),
+	)
+}
+
+type Consent struct {
+	ClientId   string       `json:"clientId"`
+	ClientName string       `json:"clientName"`
+	Scopes     []data.Scope `json:"scopes"`
+	CreateTime time.Time    `json:"createTime"`
+	UpdateTime time.Time    `json:"updateTime"`
+}
+
+// Describes what a client is asking for, so a consent screen can be rendered
+type AuthorizationPrompt struct {
+	ClientId   string       `json:"clientId"`
+	ClientName string       `json:"clientName"`
+	Scopes     []data.Scope `json:"scopes"`
+	Consented  bool         `json:"consented"`
+}
+
+type AuthorizationResponse struct {
+	RedirectUri string `json:"redirectUri"`
+}
+
+// Token endpoint payloads follow RFC 6749 naming rather than the API's
+type TokenResponse struct {
+	AccessToken  string  `json:"access_token"`
+	TokenType    string  `json:"token_type"`
+	ExpiresIn    int     `json:"expires_in"`
+	RefreshToken *string `json:"refresh_token,omitempty"`
+	Scope        string  `json:"scope"`
+}
+
+type TokenError struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func NewClient(entry data.OAuthClientDTO) Client {
+	return Client{
+		Id:           entry.SK,
+		Name:         entry.Name,
+		RedirectUris: entry.RedirectUris,
+		Scopes:       entry.Scopes,
+		Confidential: entry.Confidential,
+		CreateTime:   entry.CreateTime,
+		UpdateTime:   entry.UpdateTime,
+	}
+}
+
+func NewConsent(entry data.OAuthConsentDTO) Consent {
+	return Consent{
+		ClientId:   entry.SK,
+		ClientName: entry.ClientName,
+		Scopes:     entry.Scopes,
+		CreateTime: entry.CreateTime,
+		UpdateTime: entry.UpdateTime,
+	}
+}
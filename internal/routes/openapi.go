@@ -0,0 +1,327 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"philcali.me/recipes/internal/exceptions"
+)
+
+const (
+	OPENAPI_VERSION = "3.0.3"
+	OPENAPI_PATH    = "/openapi.json"
+)
+
+// Query parameters read by every route serializing a list
+var LIST_QUERY = []string{"limit", "nextToken", "sortOrder"}
+
+type OpenAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type OpenAPISchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Nullable             bool                      `json:"nullable,omitempty"`
+	Items                *OpenAPISchema            `json:"items,omitempty"`
+	Properties           map[string]*OpenAPISchema `json:"properties,omitempty"`
+	AdditionalProperties *OpenAPISchema            `json:"additionalProperties,omitempty"`
+	Required             []string                  `json:"required,omitempty"`
+}
+
+type OpenAPIParameter struct {
+	Name     string         `json:"name"`
+	In       string         `json:"in"`
+	Required bool           `json:"required"`
+	Schema   *OpenAPISchema `json:"schema"`
+}
+
+type OpenAPIMediaType struct {
+	Schema *OpenAPISchema `json:"schema"`
+}
+
+type OpenAPIRequestBody struct {
+	Required bool                        `json:"required"`
+	Content  map[string]OpenAPIMediaType `json:"content"`
+}
+
+type OpenAPIResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]OpenAPIMediaType `json:"content,omitempty"`
+}
+
+type OpenAPIOperation struct {
+	OperationId string                     `json:"operationId"`
+	Summary     string                     `json:"summary"`
+	Parameters  []OpenAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]OpenAPIResponse `json:"responses"`
+	Security    *[]map[string][]string     `json:"security,omitempty"`
+}
+
+type OpenAPISecurityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme"`
+}
+
+type OpenAPIComponents struct {
+	Schemas         map[string]*OpenAPISchema        `json:"schemas"`
+	SecuritySchemes map[string]OpenAPISecurityScheme `json:"securitySchemes"`
+}
+
+type OpenAPIDocument struct {
+	OpenAPI    string                                 `json:"openapi"`
+	Info       OpenAPIInfo                            `json:"info"`
+	Paths      map[string]map[string]OpenAPIOperation `json:"paths"`
+	Components OpenAPIComponents                      `json:"components"`
+	Security   []map[string][]string                  `json:"security"`
+}
+
+// Named segments and trailing wildcards are both path parameters
+var _pathParams = regexp.MustCompile("[:*]([^/]+)")
+
+// Generic instances are named after their type arguments, ie: data.QueryResults_recipes.Recipe
+func _schemaName(t reflect.Type) string {
+	name := t.Name()
+	if start := strings.Index(name, "["); start >= 0 {
+		args := strings.Split(name[start+1:len(name)-1], ",")
+		for i, arg := range args {
+			args[i] = path.Base(arg)
+		}
+		name = name[:start] + "_" + strings.Join(args, "_")
+	}
+	return path.Base(t.PkgPath()) + "." + name
+}
+
+func _jsonField(field reflect.StructField) (string, bool, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" || !field.IsExported() {
+		return "", false, false
+	}
+	name, options, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	return name, strings.Contains(options, "omitempty"), true
+}
+
+type _schemaBuilder struct {
+	schemas map[string]*OpenAPISchema
+}
+
+func (sb *_schemaBuilder) _object(t reflect.Type, schema *OpenAPISchema) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			sb._object(field.Type, schema)
+			continue
+		}
+		name, omitEmpty, ok := _jsonField(field)
+		if !ok {
+			continue
+		}
+		schema.Properties[name] = sb.Schema(field.Type)
+		if !omitEmpty && field.Type.Kind() != reflect.Pointer {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+// Structs are shared as components, so recursive types refer to themselves
+func (sb *_schemaBuilder) Schema(t reflect.Type) *OpenAPISchema {
+	if t == reflect.TypeOf(time.Time{}) {
+		return &OpenAPISchema{Type: "string", Format: "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := *sb.Schema(t.Elem())
+		if schema.Ref == "" {
+			schema.Nullable = true
+		}
+		return &schema
+	case reflect.Struct:
+		name := _schemaName(t)
+		if _, ok := sb.schemas[name]; !ok {
+			schema := &OpenAPISchema{Type: "object", Properties: make(map[string]*OpenAPISchema)}
+			sb.schemas[name] = schema
+			sb._object(t, schema)
+		}
+		return &OpenAPISchema{Ref: "#/components/schemas/" + name}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &OpenAPISchema{Type: "string", Format: "byte"}
+		}
+		return &OpenAPISchema{Type: "array", Items: sb.Schema(t.Elem())}
+	case reflect.Map:
+		return &OpenAPISchema{Type: "object", AdditionalProperties: sb.Schema(t.Elem())}
+	case reflect.Bool:
+		return &OpenAPISchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &OpenAPISchema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &OpenAPISchema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &OpenAPISchema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &OpenAPISchema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &OpenAPISchema{Type: "string"}
+	}
+	// Interfaces can hold anything
+	return &OpenAPISchema{}
+}
+
+// Operation ids name the method and resources, ie: GET:/recipes/:recipeId is getRecipesByRecipeId
+func _operationId(method string, routePath string) string {
+	var builder strings.Builder
+	builder.WriteString(strings.ToLower(method))
+	for _, segment := range strings.Split(routePath, "/") {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			builder.WriteString("By")
+			segment = segment[1:]
+		}
+		for _, word := range strings.FieldsFunc(segment, func(r rune) bool {
+			return !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9')
+		}) {
+			builder.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+	return builder.String()
+}
+
+func (sb *_schemaBuilder) _operation(route CachedRoute) OpenAPIOperation {
+	description := route.Description
+	operation := OpenAPIOperation{
+		OperationId: _operationId(route.Method, route.Path),
+		Summary:     description.Summary,
+		Responses: map[string]OpenAPIResponse{
+			"default": {
+				Description: "Error",
+				Content: map[string]OpenAPIMediaType{
+					"application/json": {Schema: sb.Schema(reflect.TypeOf(exceptions.ErrorResponse{}))},
+				},
+			},
+		},
+	}
+	for _, match := range _pathParams.FindAllStringSubmatch(route.Path, -1) {
+		operation.Parameters = append(operation.Parameters, OpenAPIParameter{
+			Name:     match[1],
+			In:       "path",
+			Required: true,
+			Schema:   &OpenAPISchema{Type: "string"},
+		})
+	}
+	for _, name := range description.Query {
+		operation.Parameters = append(operation.Parameters, OpenAPIParameter{
+			Name:   name,
+			In:     "query",
+			Schema: &OpenAPISchema{Type: "string"},
+		})
+	}
+	if description.Input != nil {
+		contentType := description.ContentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		operation.RequestBody = &OpenAPIRequestBody{
+			Required: true,
+			Content: map[string]OpenAPIMediaType{
+				contentType: {Schema: sb.Schema(reflect.TypeOf(description.Input))},
+			},
+		}
+	}
+	if description.Output != nil {
+		operation.Responses["200"] = OpenAPIResponse{
+			Description: "OK",
+			Content: map[string]OpenAPIMediaType{
+				"application/json": {Schema: sb.Schema(reflect.TypeOf(description.Output))},
+			},
+		}
+	} else {
+		operation.Responses["204"] = OpenAPIResponse{Description: "No Content"}
+	}
+	if description.Unauthenticated {
+		operation.Security = &[]map[string][]string{}
+	}
+	return operation
+}
+
+// Documents every described route, routes without a description are left out
+func (r *Router) OpenAPI(info OpenAPIInfo) OpenAPIDocument {
+	builder := &_schemaBuilder{schemas: make(map[string]*OpenAPISchema)}
+	document := OpenAPIDocument{
+		OpenAPI: OPENAPI_VERSION,
+		Info:    info,
+		Paths:   make(map[string]map[string]OpenAPIOperation),
+		Components: OpenAPIComponents{
+			Schemas: builder.schemas,
+			SecuritySchemes: map[string]OpenAPISecurityScheme{
+				"bearer": {Type: "http", Scheme: "bearer"},
+			},
+		},
+		Security: []map[string][]string{{"bearer": {}}},
+	}
+	for _, route := range r.Routes {
+		if route.Description == nil {
+			continue
+		}
+		documentPath := _pathParams.ReplaceAllString(route.Path, "{$1}")
+		if _, ok := document.Paths[documentPath]; !ok {
+			document.Paths[documentPath] = make(map[string]OpenAPIOperation)
+		}
+		document.Paths[documentPath][strings.ToLower(route.Method)] = builder._operation(route)
+	}
+	return document
+}
+
+type OpenAPIService struct {
+	router *Router
+	info   OpenAPIInfo
+}
+
+// Serves the document of the router, including routes registered after it
+func NewOpenAPIService(router *Router, info OpenAPIInfo) *OpenAPIService {
+	return &OpenAPIService{
+		router: router,
+		info:   info,
+	}
+}
+
+func (oas *OpenAPIService) GetRoutes() map[string]Route {
+	return map[string]Route{
+		"GET:" + OPENAPI_PATH: oas.GetDocument,
+	}
+}
+
+func (oas *OpenAPIService) DescribeRoutes() map[string]RouteDescription {
+	return map[string]RouteDescription{
+		"GET:" + OPENAPI_PATH: {
+			Summary: "Describes every route of the API",
+			Output:  map[string]any{},
+		},
+	}
+}
+
+func (oas *OpenAPIService) GetDocument(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
+	body, err := json.Marshal(oas.router.OpenAPI(oas.info))
+	if err != nil {
+		return events.APIGatewayV2HTTPResponse{}, exceptions.InternalServer(err.Error())
+	}
+	return events.APIGatewayV2HTTPResponse{
+		StatusCode: 200,
+		Body:       string(body),
+		Headers: map[string]string{
+			"Content-Type":   "application/json",
+			"Content-Length": strconv.Itoa(len(body)),
+		},
+	}, nil
+}
@@ -0,0 +1,78 @@
+package routes_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"philcali.me/recipes/internal/auth"
+	"philcali.me/recipes/internal/routes"
+	"philcali.me/recipes/internal/routes/api"
+	"philcali.me/recipes/internal/routes/oauth"
+)
+
+// Every service of the API, and the token service routed on its own
+func _describedRouter() *routes.Router {
+	signer := auth.NewSigner([]byte("local-signing-key"))
+	services := api.Services(api.Data{Signer: signer, IndexName: "GS1"})
+	router := routes.NewRouterWithFilters(
+		nil,
+		append(services, oauth.NewTokenRouteWithSigner(nil, nil, nil, nil, signer))...,
+	)
+	return router.Register(routes.NewOpenAPIService(router, routes.OpenAPIInfo{
+		Title:   "Recipes API",
+		Version: "test",
+	}))
+}
+
+func TestOpenAPI(t *testing.T) {
+	router := _describedRouter()
+
+	t.Run("DescribedRoutes", func(t *testing.T) {
+		for _, route := range router.Routes {
+			if route.Description == nil || route.Description.Summary == "" {
+				t.Errorf("Route %s:%s is not described, add it to DescribeRoutes", route.Method, route.Path)
+			}
+		}
+	})
+
+	t.Run("Document", func(t *testing.T) {
+		response := router.Invoke(events.APIGatewayV2HTTPRequest{
+			RawPath: routes.OPENAPI_PATH,
+			RequestContext: events.APIGatewayV2HTTPRequestContext{
+				HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{
+					Method: "GET",
+				},
+			},
+		}, context.TODO())
+		if response.StatusCode != 200 {
+			t.Fatalf("Expected the document, but received %d: %s", response.StatusCode, response.Body)
+		}
+		var document routes.OpenAPIDocument
+		if err := json.Unmarshal([]byte(response.Body), &document); err != nil {
+			t.Fatalf("Failed to deserialize the document: %v", err)
+		}
+		operation, ok := document.Paths["/recipes/{recipeId}"]["put"]
+		if !ok || operation.OperationId != "putRecipesByRecipeId" {
+			t.Fatalf("Expected the recipe update to be documented, got %v", document.Paths["/recipes/{recipeId}"])
+		}
+		if operation.RequestBody == nil || operation.Parameters[0].Name != "recipeId" {
+			t.Fatalf("Expected a body and path parameter, got %v", operation)
+		}
+		if _, ok := operation.Responses["200"]; !ok {
+			t.Fatalf("Expected an OK response, got %v", operation.Responses)
+		}
+		if _, ok := document.Paths["/recipes/{recipeId}"]["delete"].Responses["204"]; !ok {
+			t.Fatal("Expected deletes to respond with no content")
+		}
+		if token := document.Paths["/oauth/token"]["post"]; token.Security == nil || len(*token.Security) != 0 {
+			t.Fatal("Expected the token endpoint to not require authentication")
+		}
+		for _, name := range []string{"recipes.Recipe", "recipes.Ingredient", "data.QueryResults_recipes.Recipe", "exceptions.ErrorResponse"} {
+			if _, ok := document.Components.Schemas[name]; !ok {
+				t.Fatalf("Expected a %s schema, got %v", name, document.Components.Schemas)
+			}
+		}
+	})
+}
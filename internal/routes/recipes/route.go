@@ -2,13 +2,13 @@ package recipes
 
 import (
 	"context"
-	"encoding/json"
 
 	"github.com/aws/aws-lambda-go/events"
 	"philcali.me/recipes/internal/data"
-	"philcali.me/recipes/internal/exceptions"
+	"philcali.me/recipes/internal/patch"
 	"philcali.me/recipes/internal/routes"
 	"philcali.me/recipes/internal/routes/util"
+	"philcali.me/recipes/internal/validation"
 )
 
 type RecipeService struct {
@@ -27,10 +27,45 @@ func (rs *RecipeService) GetRoutes() map[string]routes.Route {
 		"GET:/recipes/:recipeId":    util.AuthorizedRoute(rs.GetRecipe),
 		"POST:/recipes":             util.AuthorizedRoute(rs.CreateRecipe),
 		"PUT:/recipes/:recipeId":    util.AuthorizedRoute(rs.UpdateRecipe),
+		"PATCH:/recipes/:recipeId":  util.AuthorizedRoute(rs.PatchRecipe),
 		"DELETE:/recipes/:recipeId": util.AuthorizedRoute(rs.DeleteRecipe),
 	}
 }
 
+func (rs *RecipeService) DescribeRoutes() map[string]routes.RouteDescription {
+	return map[string]routes.RouteDescription{
+		"GET:/recipes": {
+			Summary: "Lists recipes",
+			Output:  data.QueryResults[Recipe]{},
+			Query:   append([]string{"stripFields"}, routes.LIST_QUERY...),
+		},
+		"GET:/recipes/:recipeId": {
+			Summary: "Gets a recipe",
+			Output:  Recipe{},
+			Query:   []string{"stripFields"},
+		},
+		"POST:/recipes": {
+			Summary: "Creates a recipe",
+			Input:   RecipeInput{},
+			Output:  Recipe{},
+		},
+		"PUT:/recipes/:recipeId": {
+			Summary: "Updates the fields of a recipe that are set",
+			Input:   RecipeInput{},
+			Output:  Recipe{},
+		},
+		"PATCH:/recipes/:recipeId": {
+			Summary:     "Patches a recipe with a merge patch, or a JSON Patch sent as " + patch.JSON_PATCH_CONTENT_TYPE,
+			Input:       RecipeInput{},
+			Output:      Recipe{},
+			ContentType: patch.MERGE_PATCH_CONTENT_TYPE,
+		},
+		"DELETE:/recipes/:recipeId": {
+			Summary: "Deletes a recipe",
+		},
+	}
+}
+
 func (rs *RecipeService) ListRecipes(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
 	return util.SerializeList(rs.data, StripFields(event), event, ctx)
 }
@@ -42,8 +77,8 @@ func (rs *RecipeService) GetRecipe(event events.APIGatewayV2HTTPRequest, ctx con
 
 func (rs *RecipeService) CreateRecipe(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
 	input := RecipeInput{}
-	if err := json.Unmarshal([]byte(event.Body), &input); err != nil {
-		return events.APIGatewayV2HTTPResponse{}, exceptions.InvalidInput(err.Error())
+	if err := util.DecodeInput(event, &input, validation.CREATE); err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
 	}
 	claims := util.AuthorizationClaims(event)
 	created, err := rs.data.Create(util.Username(ctx), input.ToData(claims["email"]))
@@ -52,14 +87,32 @@ func (rs *RecipeService) CreateRecipe(event events.APIGatewayV2HTTPRequest, ctx
 
 func (rs *RecipeService) UpdateRecipe(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
 	input := RecipeInput{}
-	if err := json.Unmarshal([]byte(event.Body), &input); err != nil {
-		return events.APIGatewayV2HTTPResponse{}, exceptions.InvalidInput(err.Error())
+	if err := util.DecodeInput(event, &input, validation.UPDATE); err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
 	}
 	claims := util.AuthorizationClaims(event)
 	item, err := rs.data.Update(util.Username(ctx), util.RequestParam(ctx, "recipeId"), input.ToData(claims["email"]))
 	return util.SerializeResponseOK(StripFields(event), item, err)
 }
 
+// Ingredients and nutrients are patched in place, guarded by their names
+func (rs *RecipeService) PatchRecipe(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
+	return util.PatchResource[data.RecipeDTO, data.RecipeInputDTO, Recipe, RecipeInput](
+		rs.data,
+		event,
+		ctx,
+		util.RequestParam(ctx, "recipeId"),
+		func(recipe data.RecipeDTO) Recipe {
+			return NewRecipe(recipe, false)
+		},
+		func(recipe data.RecipeDTO) *string {
+			return recipe.UpdateToken
+		},
+		patch.List{Field: "ingredients", Key: "name"},
+		patch.List{Field: "nutrients", Key: "name"},
+	)
+}
+
 func (rs *RecipeService) DeleteRecipe(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
 	err := rs.data.Delete(util.Username(ctx), util.RequestParam(ctx, "recipeId"))
 	return util.SerializeResponseNoContent(err)
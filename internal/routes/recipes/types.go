@@ -9,6 +9,7 @@ import (
 	"github.com/google/uuid"
 	"philcali.me/recipes/internal/data"
 	"philcali.me/recipes/internal/routes/util"
+	"philcali.me/recipes/internal/validation"
 )
 
 type Ingredient struct {
@@ -34,6 +35,36 @@ type RecipeInput struct {
 	Nutrients          *[]Nutrient   `json:"nutrients"`
 }
 
+const (
+	MAX_NAME_LENGTH         = 256
+	MAX_INSTRUCTIONS_LENGTH = 20000
+	MAX_INGREDIENTS         = 200
+	// A week, anything longer is not a recipe worth waiting on
+	MAX_PREPARE_TIME_MINUTES = 10080
+)
+
+func (r *RecipeInput) Validate(op validation.Operation) error {
+	fields := []validation.Field{
+		validation.Required("name", r.Name, validation.MaxLength(MAX_NAME_LENGTH)),
+		validation.Optional("instructions", r.Instructions, validation.MaxLength(MAX_INSTRUCTIONS_LENGTH)),
+		validation.Optional("prepareTimeMinutes", r.PrepareTimeMinutes, validation.Range(0, MAX_PREPARE_TIME_MINUTES)),
+		validation.Optional("numberOfServings", r.NumberOfServings, validation.Range(1, 1000)),
+		validation.Optional("ingredients", r.Ingredients, validation.MaxLength(MAX_INGREDIENTS)),
+		validation.Optional("nutrients", r.Nutrients, validation.MaxLength(MAX_INGREDIENTS)),
+	}
+	fields = append(fields, validation.Each("ingredients", r.Ingredients, func(i Ingredient) []validation.Field {
+		return []validation.Field{
+			validation.Required("name", i.Name, validation.MaxLength(MAX_NAME_LENGTH)),
+		}
+	})...)
+	fields = append(fields, validation.Each("nutrients", r.Nutrients, func(n Nutrient) []validation.Field {
+		return []validation.Field{
+			validation.Required("name", n.Name, validation.MaxLength(MAX_NAME_LENGTH)),
+		}
+	})...)
+	return validation.Validate(op, fields...)
+}
+
 func ConvertIngredientToData(in Ingredient) data.IngredientDTO {
 	return data.IngredientDTO{
 		Name:        in.Name,
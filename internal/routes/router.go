@@ -2,7 +2,9 @@ package routes
 
 import (
 	"context"
+	"encoding/json"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -18,6 +20,27 @@ type Service interface {
 	GetRoutes() map[string]Route
 }
 
+// Describes a route for the OpenAPI document, see Router.OpenAPI
+type RouteDescription struct {
+	Summary string
+	// Decoded from the request body, nil when the route reads no body
+	Input any
+	// Encoded as the response body, nil when the route responds with no content
+	Output any
+	// Names of the query parameters the route reads
+	Query []string
+	// Bodies are JSON unless set, ie: form encoded token requests
+	ContentType string
+	// Routes outside the authorizer, like the OAuth token endpoint
+	Unauthenticated bool
+}
+
+// Services describe their routes with the same "METHOD:/path" keys as GetRoutes
+type DescribedService interface {
+	Service
+	DescribeRoutes() map[string]RouteDescription
+}
+
 type CachedMatcher struct {
 	Matcher    *regexp.Regexp
 	ParamNames []string
@@ -25,41 +48,62 @@ type CachedMatcher struct {
 }
 
 type CachedRoute struct {
-	Method  string
-	Path    string
-	Route   Route
-	Matcher *CachedMatcher
+	Method      string
+	Path        string
+	Route       Route
+	Matcher     *CachedMatcher
+	Description *RouteDescription
 }
 
+// Named segments match a single segment, ie: /recipes/:recipeId, while a trailing
+// wildcard matches the remainder of the path, ie: /assets/*path
 func (cr *CachedMatcher) Refresh(path string) *regexp.Regexp {
 	cr.Mutex.Lock()
 	defer cr.Mutex.Unlock()
 	if cr.Matcher == nil {
+		var wildcard string
+		if index := strings.LastIndex(path, "/*"); index >= 0 {
+			path, wildcard = path[:index], path[index+2:]
+		}
 		namex := regexp.MustCompile(":[^/]+")
-		regexPath := namex.ReplaceAllStringFunc(path, func(found string) string {
+		regexPath := namex.ReplaceAllStringFunc(regexp.QuoteMeta(path), func(found string) string {
 			cr.ParamNames = append(cr.ParamNames, found[1:])
 			return "([^/]+)"
 		})
+		if wildcard != "" {
+			cr.ParamNames = append(cr.ParamNames, wildcard)
+			regexPath += "(?:/(.*))?"
+		}
 		cr.Matcher = regexp.MustCompile("^" + regexPath + "$")
 	}
 	return cr.Matcher
 }
 
-func (cr *CachedRoute) MatchEvent(event events.APIGatewayV2HTTPRequest) (map[string]string, bool) {
-	if event.RequestContext.HTTP.Method != cr.Method {
+func (cr *CachedRoute) MatchPath(path string) (map[string]string, bool) {
+	if path == cr.Path {
+		return make(map[string]string), true
+	}
+	values := cr.Matcher.Refresh(cr.Path).FindStringSubmatchIndex(path)
+	if values == nil {
 		return nil, false
 	}
 	params := make(map[string]string, len(cr.Matcher.ParamNames))
-	if event.RawPath == cr.Path {
-		return params, true
-	}
-	values := cr.Matcher.Refresh(cr.Path).FindAllStringSubmatchIndex(event.RawPath, -1)
-	if values != nil {
-		for i, p := range cr.Matcher.ParamNames {
-			params[p] = event.RawPath[values[0][i+2]:values[0][i+3]]
+	for i, p := range cr.Matcher.ParamNames {
+		// An absent wildcard remainder is empty
+		if values[2*i+2] >= 0 {
+			params[p] = path[values[2*i+2]:values[2*i+3]]
+		} else {
+			params[p] = ""
 		}
 	}
-	return params, values != nil
+	return params, true
+}
+
+func (cr *CachedRoute) MatchEvent(event events.APIGatewayV2HTTPRequest) (map[string]string, bool) {
+	if event.RequestContext.HTTP.Method != cr.Method {
+		return nil, false
+	}
+	return cr.MatchPath(event.RawPath)
 }
 
 type Router struct {
@@ -68,9 +112,33 @@ type Router struct {
 }
 
 func NewRouter(services ...Service) *Router {
-	var routes []CachedRoute
-	var fltrs []filters.RequestFilter
+	return NewRouterWithFilters([]filters.RequestFilter{
+		filters.DefaultCorsFilter(),
+		filters.DefaultAuthorizationFilter(),
+	}, services...)
+}
+
+// Routes outside the authorizer, like the OAuth token endpoint, provide their own filters
+func NewRouterWithFilters(fltrs []filters.RequestFilter, services ...Service) *Router {
+	router := &Router{
+		Filters: fltrs,
+	}
+	// Preflights allow the methods routed on the requested path
+	for _, filter := range fltrs {
+		if cors, ok := filter.(*filters.CorsFilter); ok && cors.Resolver == nil {
+			cors.Resolver = router
+		}
+	}
+	return router.Register(services...)
+}
+
+// Appends the routes of services, matched after every route registered before them
+func (r *Router) Register(services ...Service) *Router {
 	for _, service := range services {
+		var descriptions map[string]RouteDescription
+		if described, ok := service.(DescribedService); ok {
+			descriptions = described.DescribeRoutes()
+		}
 		for composite, route := range service.GetRoutes() {
 			parts := strings.SplitN(composite, ":", 2)
 			cachedRoute := CachedRoute{
@@ -81,37 +149,56 @@ func NewRouter(services ...Service) *Router {
 					Mutex: &sync.Mutex{},
 				},
 			}
-			routes = append(routes, cachedRoute)
+			if description, ok := descriptions[composite]; ok {
+				cachedRoute.Description = &description
+			}
+			r.Routes = append(r.Routes, cachedRoute)
 		}
 	}
-	fltrs = append(fltrs, filters.DefaultCorsFilter())
-	fltrs = append(fltrs, filters.DefaultAuthorizationFilter())
-	return &Router{
-		Routes:  routes,
-		Filters: fltrs,
-	}
+	return r
 }
 
-func translateError(err error) events.APIGatewayV2HTTPResponse {
-	statusCode := 500
-	if re, ok := err.(exceptions.RequestError); ok {
-		statusCode = re.ToServiceError().StatusCode
-	}
-	if se, ok := err.(*exceptions.ServiceError); ok {
-		statusCode = se.StatusCode
-	}
-	body := "{\"message\": \"" + err.Error() + "\"}"
+func translateError(err error, requestId string) events.APIGatewayV2HTTPResponse {
+	statusCode, response := exceptions.NewErrorResponse(err, requestId)
+	body, _ := json.Marshal(response)
 	headers := map[string]string{
 		"Content-Type":   "application/json",
 		"Content-Length": strconv.Itoa(len(body)),
 	}
 	return events.APIGatewayV2HTTPResponse{
 		StatusCode: statusCode,
-		Body:       body,
+		Body:       string(body),
 		Headers:    headers,
 	}
 }
 
+// Methods routed on the path, including HEAD wherever GET is routed
+func (r *Router) AllowedMethods(path string) []string {
+	var methods []string
+	for _, route := range r.Routes {
+		if _, ok := route.MatchPath(path); ok {
+			methods = append(methods, route.Method)
+			if route.Method == "GET" {
+				methods = append(methods, "HEAD")
+			}
+		}
+	}
+	slices.Sort(methods)
+	return slices.Compact(methods)
+}
+
+func (r *Router) _match(method string, path string) (*CachedRoute, map[string]string, bool) {
+	for i := range r.Routes {
+		if r.Routes[i].Method != method {
+			continue
+		}
+		if params, ok := r.Routes[i].MatchPath(path); ok {
+			return &r.Routes[i], params, true
+		}
+	}
+	return nil, nil, false
+}
+
 func (r *Router) Invoke(event events.APIGatewayV2HTTPRequest, ctx context.Context) events.APIGatewayV2HTTPResponse {
 	filterContext := filters.DefaultFilterContext(event, ctx)
 	for _, filter := range r.Filters {
@@ -121,14 +208,30 @@ func (r *Router) Invoke(event events.APIGatewayV2HTTPRequest, ctx context.Contex
 		}
 		filterContext = updatedContext
 	}
-	for _, route := range r.Routes {
-		if params, ok := route.MatchEvent(*filterContext.Request); ok {
-			resp, err := route.Route(event, context.WithValue(*filterContext.Context, "Params", params))
-			if err != nil {
-				return translateError(err)
-			}
+	method := filterContext.Request.RequestContext.HTTP.Method
+	path := filterContext.Request.RawPath
+	route, params, ok := r._match(method, path)
+	head := false
+	if !ok && method == "HEAD" {
+		route, params, ok = r._match("GET", path)
+		head = ok
+	}
+	if !ok {
+		if allowed := r.AllowedMethods(path); len(allowed) > 0 {
+			resp := translateError(exceptions.MethodNotAllowed(method, path), event.RequestContext.RequestID)
+			resp.Headers["Allow"] = strings.Join(allowed, ", ")
 			return resp
 		}
+		return translateError(exceptions.NotFound("route", event.RawPath), event.RequestContext.RequestID)
+	}
+	resp, err := route.Route(event, context.WithValue(*filterContext.Context, "Params", params))
+	if err != nil {
+		return translateError(err, event.RequestContext.RequestID)
+	}
+	// Responses to HEAD keep the headers of GET, including the Content-Length
+	if head {
+		resp.Body = ""
+		resp.IsBase64Encoded = false
 	}
-	return translateError(exceptions.NotFound("route", event.RawPath))
+	return resp
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
 	"testing"
@@ -14,25 +15,40 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/google/uuid"
 	"golang.org/x/exp/maps"
+	"philcali.me/recipes/internal/aisles"
+	"philcali.me/recipes/internal/auth"
 	"philcali.me/recipes/internal/data"
 	tokenData "philcali.me/recipes/internal/dynamodb/apitokens"
 	auditData "philcali.me/recipes/internal/dynamodb/audits"
+	deliveryData "philcali.me/recipes/internal/dynamodb/deliveries"
+	membershipData "philcali.me/recipes/internal/dynamodb/memberships"
+	oauthData "philcali.me/recipes/internal/dynamodb/oauth"
 	recipeData "philcali.me/recipes/internal/dynamodb/recipes"
+	revocationData "philcali.me/recipes/internal/dynamodb/revocations"
 	settingsData "philcali.me/recipes/internal/dynamodb/settings"
 	shareData "philcali.me/recipes/internal/dynamodb/shares"
 	shoppingData "philcali.me/recipes/internal/dynamodb/shopping"
 	subscriberData "philcali.me/recipes/internal/dynamodb/subscriptions"
+	templateData "philcali.me/recipes/internal/dynamodb/templates"
 	"philcali.me/recipes/internal/dynamodb/token"
+	"philcali.me/recipes/internal/exceptions"
 	"philcali.me/recipes/internal/notifications"
+	"philcali.me/recipes/internal/patch"
 	"philcali.me/recipes/internal/routes"
+	"philcali.me/recipes/internal/routes/api"
 	"philcali.me/recipes/internal/routes/apitokens"
 	"philcali.me/recipes/internal/routes/audits"
+	"philcali.me/recipes/internal/routes/filters"
+	"philcali.me/recipes/internal/routes/households"
+	"philcali.me/recipes/internal/routes/oauth"
 	"philcali.me/recipes/internal/routes/recipes"
 	"philcali.me/recipes/internal/routes/settings"
 	"philcali.me/recipes/internal/routes/shares"
 	"philcali.me/recipes/internal/routes/shopping"
 	"philcali.me/recipes/internal/routes/subscriptions"
+	"philcali.me/recipes/internal/routes/templates"
 	"philcali.me/recipes/internal/test"
+	"philcali.me/recipes/internal/validation"
 )
 
 func NewLocalServer(t *testing.T) *LocalServer {
@@ -47,19 +63,34 @@ func NewLocalServer(t *testing.T) *LocalServer {
 	}
 	t.Logf("Successfully created local resources running on %d", test.LOCAL_DDB_PORT)
 	marshaler := token.NewGCM()
-	router := routes.NewRouter(
-		recipes.NewRoute(recipeData.NewRecipeService(tableName, *client, marshaler)),
-		shopping.NewRoute(shoppingData.NewShoppingListService(tableName, *client, marshaler)),
-		apitokens.NewRouteWithIndex(tokenData.NewApiTokenService(tableName, *client, marshaler), "GS1"),
-		settings.NewRoute(settingsData.NewSettingService(tableName, *client, marshaler)),
-		audits.NewRouteWithIndex(auditData.NewAuditService(tableName, *client, marshaler), "GS1"),
-		shares.NewRouteWithIndex(shareData.NewShareService(tableName, *client, marshaler), "GS1"),
-		subscriptions.NewRoute(
-			subscriberData.NewSubscriptionService(tableName, *client, marshaler),
-			&LocalNotifications{
+	memberships := membershipData.NewMembershipService(tableName, *client, marshaler)
+	router := routes.NewRouterWithFilters(
+		[]filters.RequestFilter{
+			filters.DefaultCorsFilter(),
+			filters.DefaultAuthorizationFilter(),
+			filters.NewHouseholdFilter(memberships),
+		},
+		api.Services(api.Data{
+			Recipes:            recipeData.NewRecipeService(tableName, *client, marshaler),
+			Lists:              shoppingData.NewShoppingListService(tableName, *client, marshaler),
+			Templates:          templateData.NewShoppingListTemplateService(tableName, *client, marshaler),
+			Settings:           settingsData.NewSettingService(tableName, *client, marshaler),
+			ApiTokens:          tokenData.NewApiTokenService(tableName, *client, marshaler),
+			Revocations:        revocationData.NewRevokedTokenService(tableName, *client, marshaler),
+			OAuthClients:       oauthData.NewClientService(tableName, *client, marshaler),
+			OAuthConsents:      oauthData.NewConsentService(tableName, *client, marshaler),
+			AuthorizationCodes: oauthData.NewAuthorizationCodeService(tableName, *client, marshaler),
+			Audits:             auditData.NewAuditService(tableName, *client, marshaler),
+			Shares:             shareData.NewShareService(tableName, *client, marshaler),
+			Memberships:        memberships,
+			Subscriptions:      subscriberData.NewSubscriptionService(tableName, *client, marshaler),
+			Deliveries:         deliveryData.NewDeliveryService(tableName, *client, marshaler),
+			Notifications: &LocalNotifications{
 				Cache: make(map[string]notifications.SubscribeInput),
 			},
-		),
+			Signer:    auth.NewSigner([]byte("local-signing-key")),
+			IndexName: "GS1",
+		})...,
 	)
 	if err != nil {
 		t.Fatalf("Failed to create a router: %s", err)
@@ -85,15 +116,35 @@ func (ln *LocalNotifications) Subscribe(input notifications.SubscribeInput) (*no
 	}
 	ln.Cache[id.String()] = input
 	return &notifications.SubscribeOutput{
-		SubscriberId: id.String(),
+		SubscriberId:        id.String(),
+		PendingConfirmation: true,
 	}, nil
 }
 
+func (ln *LocalNotifications) IsPendingConfirmation(subscriberId string) (bool, error) {
+	_, ok := ln.Cache[subscriberId]
+	return ok, nil
+}
+
+func (ln *LocalNotifications) SetFilter(subscriberId string, input notifications.SubscribeInput) error {
+	if _, ok := ln.Cache[subscriberId]; !ok {
+		return notifications.ErrSubscriptionNotFound
+	}
+	ln.Cache[subscriberId] = input
+	return nil
+}
+
 func (ln *LocalNotifications) Unsubscribe(subscriberId string) error {
 	delete(ln.Cache, subscriberId)
 	return nil
 }
 
+func (ln *LocalNotifications) Publish(input notifications.PublishInput) (*notifications.PublishOutput, error) {
+	return &notifications.PublishOutput{
+		MessageId: uuid.NewString(),
+	}, nil
+}
+
 type LocalServer struct {
 	Router         *routes.Router
 	DynamoDB       *dynamodb.Client
@@ -101,6 +152,10 @@ type LocalServer struct {
 	TableName      string
 	Username       string
 	Email          string
+	// Overrides the template scopes when set
+	Scopes []string
+	// Acts on this household when set
+	Household string
 }
 
 func (ls *LocalServer) UpdateIdentity(username, email string) {
@@ -109,6 +164,10 @@ func (ls *LocalServer) UpdateIdentity(username, email string) {
 }
 
 func (ls *LocalServer) Request(t *testing.T, method string, path string, body []byte, out any, params map[string]string) events.APIGatewayV2HTTPResponse {
+	return ls.RequestWithHeaders(t, method, path, body, out, params, nil)
+}
+
+func (ls *LocalServer) RequestWithHeaders(t *testing.T, method string, path string, body []byte, out any, params map[string]string, headers map[string]string) events.APIGatewayV2HTTPResponse {
 	request := events.APIGatewayV2HTTPRequest{}
 	fd, err := os.ReadFile(filepath.Join("router_test", "template.json"))
 	if err != nil {
@@ -125,6 +184,20 @@ func (ls *LocalServer) Request(t *testing.T, method string, path string, body []
 		"username": string(ls.Username),
 		"email":    string(ls.Email),
 	}
+	if ls.Scopes != nil {
+		scopes := make([]interface{}, len(ls.Scopes))
+		for i, scope := range ls.Scopes {
+			scopes[i] = scope
+		}
+		request.RequestContext.Authorizer.Lambda["scopes"] = scopes
+	}
+	request.Headers = headers
+	if ls.Household != "" {
+		if request.Headers == nil {
+			request.Headers = make(map[string]string, 1)
+		}
+		request.Headers["x-household-id"] = ls.Household
+	}
 	request.Body = string(body)
 	response := ls.Router.Invoke(request, context.TODO())
 	if out != nil {
@@ -167,6 +240,16 @@ func (ls *LocalServer) Put(t *testing.T, out any, path string, body any) events.
 	return ls.Request(t, "PUT", path, payload, &out, nil)
 }
 
+func (ls *LocalServer) Patch(t *testing.T, out any, path string, contentType string, body any) events.APIGatewayV2HTTPResponse {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("Failed to serialize patch: %s", err)
+	}
+	return ls.RequestWithHeaders(t, "PATCH", path, payload, &out, nil, map[string]string{
+		"content-type": contentType,
+	})
+}
+
 func TestRouter(t *testing.T) {
 	server := NewLocalServer(t)
 	t.Run("RecipeWorkflow", func(t *testing.T) {
@@ -325,6 +408,188 @@ func TestRouter(t *testing.T) {
 		}
 	})
 
+	t.Run("PatchWorkflow", func(t *testing.T) {
+		var createdList shopping.ShoppingList
+		server.Post(t, &createdList, "/lists", &shopping.ShoppingListInput{
+			Name: aws.String("Patched List"),
+			Items: &[]shopping.ShoppingListItem{
+				{Name: "bread", Measurement: "loaf", Amount: 1},
+				{Name: "milk", Measurement: "gallon", Amount: 1},
+			},
+		})
+		path := fmt.Sprintf("/lists/%s", createdList.Id)
+		var merged shopping.ShoppingList
+		mergeResp := server.Patch(t, &merged, path, patch.MERGE_PATCH_CONTENT_TYPE, map[string]any{
+			"name": "Merged List",
+		})
+		if mergeResp.StatusCode != 200 || merged.Name != "Merged List" || len(merged.Items) != 2 {
+			t.Fatalf("Failed to merge patch, got %d: %s", mergeResp.StatusCode, mergeResp.Body)
+		}
+		// Checking off different items concurrently leaves both checked
+		for _, index := range []int{0, 1} {
+			checked := server.Patch(t, nil, path, patch.JSON_PATCH_CONTENT_TYPE, []patch.Operation{
+				{Op: patch.OP_REPLACE, Path: fmt.Sprintf("/items/%d/completed", index), Value: []byte("true")},
+			})
+			if checked.StatusCode != 200 {
+				t.Fatalf("Failed to check item %d, got %d: %s", index, checked.StatusCode, checked.Body)
+			}
+		}
+		var checkedList shopping.ShoppingList
+		server.Get(t, &checkedList, path)
+		if !checkedList.Items[0].Completed || !checkedList.Items[1].Completed {
+			t.Fatalf("Expected both items to be checked, got %v", checkedList.Items)
+		}
+		var failure exceptions.ErrorResponse
+		failed := server.Patch(t, &failure, path, patch.JSON_PATCH_CONTENT_TYPE, []patch.Operation{
+			{Op: patch.OP_REMOVE, Path: "/name"},
+		})
+		if failed.StatusCode != 400 || failure.Code != exceptions.CODE_VALIDATION_FAILED {
+			t.Fatalf("Expected removing the name to fail validation, got %d: %s", failed.StatusCode, failed.Body)
+		}
+		unsupported := server.Patch(t, nil, path, "application/json", map[string]any{})
+		if unsupported.StatusCode != 415 {
+			t.Fatalf("Expected plain JSON to be unsupported, got %d: %s", unsupported.StatusCode, unsupported.Body)
+		}
+		server.Delete(t, path)
+	})
+
+	t.Run("ShoppingListItems", func(t *testing.T) {
+		var createdList shopping.ShoppingList
+		server.Post(t, &createdList, "/lists", &shopping.ShoppingListInput{
+			Name: aws.String("Itemized List"),
+		})
+		path := fmt.Sprintf("/lists/%s", createdList.Id)
+		var bread, milk shopping.ShoppingListItem
+		for _, created := range []struct {
+			item  *shopping.ShoppingListItem
+			input shopping.ShoppingListItemInput
+		}{
+			{&milk, shopping.ShoppingListItemInput{Name: aws.String("milk"), Category: aws.String("Dairy")}},
+			{&bread, shopping.ShoppingListItemInput{Name: aws.String("bread"), Position: aws.Int(0)}},
+		} {
+			resp := server.Post(t, created.item, path+"/items", created.input)
+			if resp.StatusCode != 200 || created.item.Id == "" {
+				t.Fatalf("Failed to add %s, got %d: %s", *created.input.Name, resp.StatusCode, resp.Body)
+			}
+		}
+		var updated shopping.ShoppingListItem
+		updateResp := server.Put(t, &updated, path+"/items/"+milk.Id, &shopping.ShoppingListItemInput{
+			Amount: aws.Float32(2),
+		})
+		if updateResp.StatusCode != 200 || updated.Amount != 2 || updated.Name != "milk" || aws.ToString(updated.Category) != "Dairy" {
+			t.Fatalf("Failed to update the item, got %d: %s", updateResp.StatusCode, updateResp.Body)
+		}
+		var completed shopping.ShoppingList
+		completeResp := server.Post(t, &completed, path+"/completed", &shopping.CompleteItemsInput{
+			ItemIds: []string{bread.Id},
+		})
+		if completeResp.StatusCode != 200 || completed.Items[0].Id != bread.Id || !completed.Items[0].Completed || completed.Items[1].Completed {
+			t.Fatalf("Expected bread to be first and completed, got %d: %s", completeResp.StatusCode, completeResp.Body)
+		}
+		if deleted := server.Delete(t, path+"/items/"+bread.Id); deleted.StatusCode != 204 {
+			t.Fatalf("Failed to remove the item, got %d: %s", deleted.StatusCode, deleted.Body)
+		}
+		if missing := server.Delete(t, path+"/items/"+bread.Id); missing.StatusCode != 404 {
+			t.Fatalf("Expected the removed item to be missing, got %d", missing.StatusCode)
+		}
+		var remaining shopping.ShoppingList
+		server.Get(t, &remaining, path)
+		if len(remaining.Items) != 1 || remaining.Items[0].Id != milk.Id {
+			t.Fatalf("Expected only milk to remain, got %v", remaining.Items)
+		}
+		server.Delete(t, path)
+	})
+
+	t.Run("AisleGrouping", func(t *testing.T) {
+		server.Post(t, nil, "/settings", &settings.SettingsInput{
+			Categories:  &map[string]string{"oat milk": "Plant Based"},
+			StoreLayout: &[]string{"Plant Based", aisles.BAKERY},
+		})
+		var createdList shopping.ShoppingList
+		server.Post(t, &createdList, "/lists", &shopping.ShoppingListInput{
+			Name: aws.String("Aisles"),
+			Items: &[]shopping.ShoppingListItem{
+				{Name: "widgets"},
+				{Name: "bread"},
+				{Name: "oat milk"},
+				{Name: "apples"},
+				{Name: "candles", Category: aws.String(aisles.HOUSEHOLD)},
+			},
+		})
+		path := fmt.Sprintf("/lists/%s", createdList.Id)
+		var grouped shopping.ShoppingList
+		resp := server.GetQuery(t, &grouped, path, map[string]string{"groupBy": "aisle"})
+		if resp.StatusCode != 200 {
+			t.Fatalf("Failed to group the list, got %d: %s", resp.StatusCode, resp.Body)
+		}
+		expected := []string{"Plant Based", aisles.BAKERY, aisles.PRODUCE, aisles.HOUSEHOLD, aisles.OTHER}
+		if len(grouped.Groups) != len(expected) {
+			t.Fatalf("Expected groups %v, got %s", expected, resp.Body)
+		}
+		for i, category := range expected {
+			if grouped.Groups[i].Category != category {
+				t.Fatalf("Expected group %d to be %s, got %s", i, category, grouped.Groups[i].Category)
+			}
+		}
+		if invalid := server.GetQuery(t, nil, path, map[string]string{"groupBy": "price"}); invalid.StatusCode != 400 {
+			t.Fatalf("Expected grouping by price to be invalid, got %d", invalid.StatusCode)
+		}
+		server.Delete(t, path)
+	})
+
+	t.Run("Templates", func(t *testing.T) {
+		weekly := data.FREQUENCY_WEEKLY
+		var template templates.ShoppingListTemplate
+		created := server.Post(t, &template, "/templates", &templates.ShoppingListTemplateInput{
+			Name:           aws.String("Staples"),
+			Items:          &[]shopping.ShoppingListItem{{Name: "milk"}, {Name: "bread"}},
+			MergeUnchecked: aws.Bool(true),
+			Recurrence: &templates.Recurrence{
+				Frequency: &weekly,
+				DayOfWeek: aws.String("Sunday"),
+				Hour:      aws.Int(8),
+			},
+		})
+		if created.StatusCode != 200 || template.NextRunTime == nil || template.NextRunTime.Weekday() != time.Sunday {
+			t.Fatalf("Expected the template to be scheduled on Sunday, got %d: %s", created.StatusCode, created.Body)
+		}
+		invalid := server.Post(t, nil, "/templates", &templates.ShoppingListTemplateInput{
+			Name: aws.String("Weekly"),
+			Recurrence: &templates.Recurrence{
+				Frequency: &weekly,
+			},
+		})
+		if invalid.StatusCode != 400 {
+			t.Fatalf("Expected weekly templates to need a day, got %d", invalid.StatusCode)
+		}
+		path := fmt.Sprintf("/templates/%s", template.Id)
+		var list shopping.ShoppingList
+		made := server.Post(t, &list, path+"/lists", nil)
+		if made.StatusCode != 200 || list.Name != "Staples" || len(list.Items) != 2 {
+			t.Fatalf("Expected a list of the template items, got %d: %s", made.StatusCode, made.Body)
+		}
+		server.Get(t, &template, path)
+		if template.LastListId == nil || *template.LastListId != list.Id {
+			t.Fatalf("Expected the template to remember its list, got %v", template.LastListId)
+		}
+		// The unchecked eggs of the last list carry over
+		server.Put(t, nil, fmt.Sprintf("/lists/%s/items/%s", list.Id, list.Items[1].Id), &shopping.ShoppingListItemInput{
+			Completed: aws.Bool(true),
+		})
+		server.Post(t, nil, fmt.Sprintf("/lists/%s/items", list.Id), &shopping.ShoppingListItemInput{
+			Name: aws.String("eggs"),
+		})
+		var merged shopping.ShoppingList
+		server.Post(t, &merged, path+"/lists", nil)
+		if len(merged.Items) != 3 || merged.Items[2].Name != "eggs" || merged.Items[1].Completed {
+			t.Fatalf("Expected the unchecked eggs to be merged in, got %v", merged.Items)
+		}
+		for _, id := range []string{list.Id, merged.Id} {
+			server.Delete(t, fmt.Sprintf("/lists/%s", id))
+		}
+		server.Delete(t, path)
+	})
+
 	t.Run("ApiToken", func(t *testing.T) {
 		var createdToken apitokens.ApiToken
 		created := server.Post(t, &createdToken, "/tokens", &apitokens.ApiTokenInput{
@@ -337,10 +602,72 @@ func TestRouter(t *testing.T) {
 		if created.StatusCode != 200 {
 			t.Fatalf("Expected 200 on create, but received: %d", created.StatusCode)
 		}
-		get := server.Get(t, nil, fmt.Sprintf("/tokens/%s", createdToken.Value))
+		claims, err := auth.NewSigner([]byte("local-signing-key")).Verify(createdToken.Value, time.Now())
+		if err != nil {
+			t.Fatalf("Expected a signed token on create: %v", err)
+		}
+		if claims.Id != createdToken.Id || len(claims.Scopes) != 2 {
+			t.Fatalf("Unexpected signed claims: %v", claims)
+		}
+		var fetchedToken apitokens.ApiToken
+		get := server.Get(t, &fetchedToken, fmt.Sprintf("/tokens/%s", createdToken.Id))
 		if get.StatusCode != 200 {
 			t.Fatalf("Expected 200 on get, but received: %d", get.StatusCode)
 		}
+		if fetchedToken.Value != "" {
+			t.Fatalf("Expected the token value to only be revealed on create: %v", fetchedToken)
+		}
+		server.UpdateIdentity("nobody2", "nobody2@email.com")
+		other := server.Get(t, nil, fmt.Sprintf("/tokens/%s", createdToken.Id))
+		server.UpdateIdentity("nobody", "nobody@email.com")
+		if other.StatusCode != 404 {
+			t.Fatalf("Expected another account to not see the token, but received: %d", other.StatusCode)
+		}
+		expiresIn := time.Now().Add(time.Hour)
+		extend := server.Put(t, nil, fmt.Sprintf("/tokens/%s", createdToken.Id), &apitokens.ApiTokenInput{
+			Name:      aws.String("Test Token"),
+			ExpiresIn: &expiresIn,
+		})
+		if extend.StatusCode != 400 {
+			t.Fatalf("Expected the expiry of a signed token to not change, but received: %d", extend.StatusCode)
+		}
+		var rotatedToken apitokens.ApiToken
+		rotate := server.Post(t, &rotatedToken, fmt.Sprintf("/tokens/%s/rotate", createdToken.Id), &apitokens.RotateTokenInput{
+			GracePeriodSeconds: aws.Int(3600),
+		})
+		if rotate.StatusCode != 200 {
+			t.Fatalf("Expected 200 on rotate, but received: %d, %s", rotate.StatusCode, rotate.Body)
+		}
+		rotatedClaims, err := auth.NewSigner([]byte("local-signing-key")).Verify(rotatedToken.Value, time.Now())
+		if err != nil {
+			t.Fatalf("Expected a signed token on rotate: %v", err)
+		}
+		if rotatedClaims.Id != createdToken.Id || rotatedClaims.Generation != claims.Generation+1 || len(rotatedClaims.Scopes) != 2 {
+			t.Fatalf("Unexpected rotated claims: %v", rotatedClaims)
+		}
+		if rotatedToken.Name != "Test Token" || rotatedToken.PreviousValueExpiresIn == nil {
+			t.Fatalf("Expected the rotated token to keep its details in a grace period: %v", rotatedToken)
+		}
+		denyList := auth.NewDenyList(revocationData.NewRevokedTokenService(server.TableName, *server.DynamoDB, server.TokenMarshaler))
+		for _, tokenClaims := range []*auth.ApiTokenClaims{claims, rotatedClaims} {
+			if revoked, err := denyList.IsRevoked(*tokenClaims); err != nil || revoked {
+				t.Fatalf("Expected generation %d to be valid during the grace period: %v", tokenClaims.Generation, err)
+			}
+		}
+		denyList.Now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+		denyList.RefreshInterval = 0
+		if revoked, err := denyList.IsRevoked(*claims); err != nil || !revoked {
+			t.Fatalf("Expected the previous generation to be revoked after the grace period: %v", err)
+		}
+		if revoked, err := denyList.IsRevoked(*rotatedClaims); err != nil || revoked {
+			t.Fatalf("Expected the rotated generation to stay valid: %v", err)
+		}
+		invalidGrace := server.Post(t, nil, fmt.Sprintf("/tokens/%s/rotate", createdToken.Id), &apitokens.RotateTokenInput{
+			GracePeriodSeconds: aws.Int(-1),
+		})
+		if invalidGrace.StatusCode != 400 {
+			t.Fatalf("Expected 400 on a negative grace period, but received: %d", invalidGrace.StatusCode)
+		}
 		var results data.QueryResults[apitokens.ApiToken]
 		listTokens := server.Get(t, &results, "/tokens")
 		if listTokens.StatusCode != 200 {
@@ -350,13 +677,343 @@ func TestRouter(t *testing.T) {
 			t.Fatalf("Expected list of tokens to be 1, received: %d", len(results.Items))
 		}
 		apiToken := results.Items[0]
-		if apiToken.Value != createdToken.Value {
+		if apiToken.Id != createdToken.Id {
 			t.Fatalf("Expected list item is not expected: %v", apiToken)
 		}
-		del := server.Delete(t, fmt.Sprintf("/tokens/%s", apiToken.Value))
+		del := server.Delete(t, fmt.Sprintf("/tokens/%s", apiToken.Id))
 		if del.StatusCode != 204 {
 			t.Fatalf("Expected 204 on delete, received: %d", del.StatusCode)
 		}
+		denyList.Now = time.Now
+		if revoked, err := denyList.IsRevoked(*rotatedClaims); err != nil || !revoked {
+			t.Fatalf("Expected the deleted token to be revoked: %v", err)
+		}
+	})
+
+	t.Run("ResourceScopes", func(t *testing.T) {
+		var list shopping.ShoppingList
+		created := server.Post(t, &list, "/lists", &shopping.ShoppingListInput{
+			Name: aws.String("Kitchen"),
+		})
+		if created.StatusCode != 200 {
+			t.Fatalf("Failed to create list: %s", created.Body)
+		}
+		tablet := data.NewResourceScope(data.LIST_WRITE, list.Id, true)
+		for scope, expected := range map[data.Scope]int{
+			tablet:              200,
+			"pantry":            400,
+			data.PROVIDER_WRITE: 403,
+		} {
+			response := server.Post(t, nil, "/tokens", &apitokens.ApiTokenInput{
+				Name:   aws.String("Kitchen Tablet"),
+				Scopes: []data.Scope{scope},
+			})
+			if response.StatusCode != expected {
+				t.Fatalf("Expected %d creating a token with %s, but received: %d", expected, scope, response.StatusCode)
+			}
+		}
+
+		server.Scopes = []string{string(tablet)}
+		defer func() { server.Scopes = nil }()
+		if get := server.Get(t, nil, "/lists/"+list.Id); get.StatusCode != 200 {
+			t.Fatalf("Expected the scoped list to be readable, but received: %d", get.StatusCode)
+		}
+		if listAll := server.Get(t, nil, "/lists"); listAll.StatusCode != 401 {
+			t.Fatalf("Expected other lists to be hidden, but received: %d", listAll.StatusCode)
+		}
+		update := server.Put(t, nil, "/lists/"+list.Id, &shopping.ShoppingListInput{
+			Name: aws.String("Pantry"),
+		})
+		if update.StatusCode != 401 {
+			t.Fatalf("Expected a read only scope to reject updates, but received: %d", update.StatusCode)
+		}
+		escalate := server.Post(t, nil, "/tokens", &apitokens.ApiTokenInput{
+			Name:   aws.String("Escalated"),
+			Scopes: []data.Scope{data.LIST_WRITE},
+		})
+		if escalate.StatusCode != 401 {
+			t.Fatalf("Expected a list scoped caller to not reach tokens, but received: %d", escalate.StatusCode)
+		}
+		server.Scopes = []string{string(data.TOKENS_WRITE), string(tablet)}
+		escalate = server.Post(t, nil, "/tokens", &apitokens.ApiTokenInput{
+			Name:   aws.String("Escalated"),
+			Scopes: []data.Scope{data.LIST_WRITE},
+		})
+		if escalate.StatusCode != 403 {
+			t.Fatalf("Expected a token broader than its creator to be rejected, but received: %d", escalate.StatusCode)
+		}
+	})
+
+	t.Run("OAuthWorkflow", func(t *testing.T) {
+		marshaler := server.TokenMarshaler
+		tokenServer := &LocalServer{
+			Router: routes.NewRouterWithFilters(
+				[]filters.RequestFilter{filters.DefaultCorsFilter()},
+				oauth.NewTokenRouteWithSigner(
+					oauthData.NewClientService(server.TableName, *server.DynamoDB, marshaler),
+					oauthData.NewConsentService(server.TableName, *server.DynamoDB, marshaler),
+					oauthData.NewAuthorizationCodeService(server.TableName, *server.DynamoDB, marshaler),
+					oauthData.NewRefreshTokenService(server.TableName, *server.DynamoDB, marshaler),
+					auth.NewSigner([]byte("local-signing-key")),
+				),
+			),
+		}
+		exchange := func(form url.Values) (oauth.TokenResponse, events.APIGatewayV2HTTPResponse) {
+			var tokens oauth.TokenResponse
+			response := tokenServer.Request(t, "POST", "/oauth/token", []byte(form.Encode()), &tokens, nil)
+			return tokens, response
+		}
+
+		invalidRedirect := server.Post(t, nil, "/oauth/clients", &oauth.ClientInput{
+			Name:         aws.String("Smart Fridge"),
+			RedirectUris: []string{"http://fridge.example.com/callback"},
+			Scopes:       []data.Scope{data.LIST_WRITE},
+		})
+		if invalidRedirect.StatusCode != 400 {
+			t.Fatalf("Expected 400 on a plain http redirect, but received: %d", invalidRedirect.StatusCode)
+		}
+		var client oauth.Client
+		created := server.Post(t, &client, "/oauth/clients", &oauth.ClientInput{
+			Name:         aws.String("Smart Fridge"),
+			RedirectUris: []string{"https://fridge.example.com/callback"},
+			Scopes:       []data.Scope{data.LIST_WRITE, data.RECIPE_READ},
+		})
+		if created.StatusCode != 200 || client.Secret == nil || !client.Confidential {
+			t.Fatalf("Expected a confidential client with a secret, but received: %d, %s", created.StatusCode, created.Body)
+		}
+		var fetched oauth.Client
+		if get := server.Get(t, &fetched, "/oauth/clients/"+client.Id); get.StatusCode != 200 || fetched.Secret != nil {
+			t.Fatalf("Expected the client secret to only be revealed on create: %s", get.Body)
+		}
+
+		verifier := "a-verifier-that-is-long-enough-to-satisfy-rfc-7636"
+		params := map[string]string{
+			"response_type":         "code",
+			"client_id":             client.Id,
+			"redirect_uri":          "https://fridge.example.com/callback",
+			"scope":                 "lists",
+			"state":                 "xyz",
+			"code_challenge":        auth.CodeChallenge(verifier),
+			"code_challenge_method": auth.PKCE_METHOD_S256,
+		}
+		var prompt oauth.AuthorizationPrompt
+		get := server.GetQuery(t, &prompt, "/oauth/authorize", params)
+		if get.StatusCode != 200 || prompt.ClientName != "Smart Fridge" || prompt.Consented {
+			t.Fatalf("Unexpected authorization prompt: %d, %s", get.StatusCode, get.Body)
+		}
+		var authorization oauth.AuthorizationResponse
+		approve := server.Request(t, "POST", "/oauth/authorize", nil, &authorization, params)
+		if approve.StatusCode != 200 {
+			t.Fatalf("Expected 200 on approval, but received: %d, %s", approve.StatusCode, approve.Body)
+		}
+		redirect, err := url.Parse(authorization.RedirectUri)
+		if err != nil || redirect.Query().Get("state") != "xyz" || redirect.Query().Get("code") == "" {
+			t.Fatalf("Unexpected redirect: %s", authorization.RedirectUri)
+		}
+		code := redirect.Query().Get("code")
+
+		_, badVerifier := exchange(url.Values{
+			"grant_type":    {"authorization_code"},
+			"client_id":     {client.Id},
+			"client_secret": {*client.Secret},
+			"code":          {code},
+			"redirect_uri":  {"https://fridge.example.com/callback"},
+			"code_verifier": {"not-the-verifier-that-was-used-for-the-challenge"},
+		})
+		if badVerifier.StatusCode != 400 {
+			t.Fatalf("Expected 400 on a mismatched verifier, but received: %d", badVerifier.StatusCode)
+		}
+		if approve = server.Request(t, "POST", "/oauth/authorize", nil, &authorization, params); approve.StatusCode != 200 {
+			t.Fatalf("Expected 200 on approval, but received: %d", approve.StatusCode)
+		}
+		redirect, _ = url.Parse(authorization.RedirectUri)
+		codeExchange := url.Values{
+			"grant_type":    {"authorization_code"},
+			"client_id":     {client.Id},
+			"client_secret": {*client.Secret},
+			"code":          {redirect.Query().Get("code")},
+			"redirect_uri":  {"https://fridge.example.com/callback"},
+			"code_verifier": {verifier},
+		}
+		tokens, response := exchange(codeExchange)
+		if response.StatusCode != 200 || tokens.RefreshToken == nil || response.Headers["Cache-Control"] != "no-store" {
+			t.Fatalf("Expected tokens on the code exchange, but received: %d, %s", response.StatusCode, response.Body)
+		}
+		claims, err := auth.NewSigner([]byte("local-signing-key")).Verify(tokens.AccessToken, time.Now())
+		if err != nil || claims.ClientId != client.Id || claims.Claims["username"] != "nobody" || len(claims.Scopes) != 1 || claims.Scopes[0] != data.LIST_WRITE {
+			t.Fatalf("Unexpected access token claims: %v, %v", claims, err)
+		}
+		if _, replay := exchange(codeExchange); replay.StatusCode != 400 {
+			t.Fatalf("Expected codes to be single use, but received: %d", replay.StatusCode)
+		}
+
+		refreshed, response := exchange(url.Values{
+			"grant_type":    {"refresh_token"},
+			"client_id":     {client.Id},
+			"client_secret": {*client.Secret},
+			"refresh_token": {*tokens.RefreshToken},
+		})
+		if response.StatusCode != 200 || refreshed.RefreshToken == nil || *refreshed.RefreshToken == *tokens.RefreshToken {
+			t.Fatalf("Expected a rotated refresh token, but received: %d, %s", response.StatusCode, response.Body)
+		}
+		if _, reuse := exchange(url.Values{
+			"grant_type":    {"refresh_token"},
+			"client_id":     {client.Id},
+			"client_secret": {*client.Secret},
+			"refresh_token": {*tokens.RefreshToken},
+		}); reuse.StatusCode != 400 {
+			t.Fatalf("Expected a used refresh token to be rejected, but received: %d", reuse.StatusCode)
+		}
+
+		credentials, response := exchange(url.Values{
+			"grant_type":    {"client_credentials"},
+			"client_id":     {client.Id},
+			"client_secret": {*client.Secret},
+			"scope":         {"recipes.readonly"},
+		})
+		if response.StatusCode != 200 || credentials.RefreshToken != nil || credentials.Scope != "recipes.readonly" {
+			t.Fatalf("Unexpected client credentials response: %d, %s", response.StatusCode, response.Body)
+		}
+		if _, unauthenticated := exchange(url.Values{
+			"grant_type":    {"client_credentials"},
+			"client_id":     {client.Id},
+			"client_secret": {"wrong"},
+		}); unauthenticated.StatusCode != 401 {
+			t.Fatalf("Expected 401 on a bad client secret, but received: %d", unauthenticated.StatusCode)
+		}
+
+		var consents data.QueryResults[oauth.Consent]
+		if list := server.Get(t, &consents, "/oauth/consents"); list.StatusCode != 200 || len(consents.Items) != 1 {
+			t.Fatalf("Expected a single consent, but received: %d, %s", list.StatusCode, list.Body)
+		}
+		if del := server.Delete(t, "/oauth/consents/"+client.Id); del.StatusCode != 204 {
+			t.Fatalf("Expected 204 on withdrawing consent, but received: %d", del.StatusCode)
+		}
+		denyList := auth.NewDenyList(revocationData.NewRevokedTokenService(server.TableName, *server.DynamoDB, server.TokenMarshaler))
+		if revoked, err := denyList.IsRevoked(*claims); err != nil || !revoked {
+			t.Fatalf("Expected access tokens to be revoked with the consent: %v", err)
+		}
+		if _, withdrawn := exchange(url.Values{
+			"grant_type":    {"refresh_token"},
+			"client_id":     {client.Id},
+			"client_secret": {*client.Secret},
+			"refresh_token": {*refreshed.RefreshToken},
+		}); withdrawn.StatusCode != 400 {
+			t.Fatalf("Expected refresh to fail once consent is withdrawn, but received: %d", withdrawn.StatusCode)
+		}
+
+		if approve = server.Request(t, "POST", "/oauth/authorize", nil, &authorization, params); approve.StatusCode != 200 {
+			t.Fatalf("Expected 200 on approval, but received: %d", approve.StatusCode)
+		}
+		redirect, _ = url.Parse(authorization.RedirectUri)
+		codeExchange.Set("code", redirect.Query().Get("code"))
+		if tokens, response = exchange(codeExchange); response.StatusCode != 200 {
+			t.Fatalf("Expected tokens on the code exchange, but received: %d, %s", response.StatusCode, response.Body)
+		}
+		if claims, err = auth.NewSigner([]byte("local-signing-key")).Verify(tokens.AccessToken, time.Now()); err != nil {
+			t.Fatalf("Failed to verify access token: %v", err)
+		}
+		if del := server.Delete(t, "/oauth/clients/"+client.Id); del.StatusCode != 204 {
+			t.Fatalf("Expected 204 on client delete, but received: %d", del.StatusCode)
+		}
+		if revoked, err := denyList.IsRevoked(*claims); err != nil || !revoked {
+			t.Fatalf("Expected access tokens to be revoked with the client: %v", err)
+		}
+		if list := server.Get(t, &consents, "/oauth/consents"); list.StatusCode != 200 || len(consents.Items) != 0 {
+			t.Fatalf("Expected consent to be removed with the client, but received: %d, %s", list.StatusCode, list.Body)
+		}
+	})
+
+	t.Run("HouseholdWorkflow", func(t *testing.T) {
+		editor, owner := data.ROLE_EDITOR, data.ROLE_OWNER
+		var recipe recipes.Recipe
+		if created := server.Post(t, &recipe, "/recipes", &recipes.RecipeInput{
+			Name: aws.String("Household Chili"),
+		}); created.StatusCode != 200 {
+			t.Fatalf("Failed to create recipe: %s", created.Body)
+		}
+		var member households.Member
+		invite := server.Post(t, &member, "/households/members", &households.MemberInput{
+			Email: aws.String("Editor@email.com"),
+			Role:  &editor,
+		})
+		if invite.StatusCode != 200 || member.Email != "editor@email.com" || member.Status != data.MEMBERSHIP_INVITED {
+			t.Fatalf("Expected an invitation, but received: %d, %s", invite.StatusCode, invite.Body)
+		}
+		ownerRole := server.Post(t, nil, "/households/members", &households.MemberInput{
+			Email: aws.String("viewer@email.com"),
+			Role:  &owner,
+		})
+		if ownerRole.StatusCode != 400 {
+			t.Fatalf("Expected 400 inviting another owner, but received: %d", ownerRole.StatusCode)
+		}
+		if viewer := server.Post(t, nil, "/households/members", &households.MemberInput{
+			Email: aws.String("viewer@email.com"),
+		}); viewer.StatusCode != 200 {
+			t.Fatalf("Expected 200 inviting a viewer, but received: %d", viewer.StatusCode)
+		}
+
+		server.UpdateIdentity("editor", "editor@email.com")
+		server.Household = "nobody"
+		defer func() {
+			server.UpdateIdentity("nobody", "nobody@email.com")
+			server.Household = ""
+		}()
+		if pending := server.Get(t, nil, "/recipes/"+recipe.Id); pending.StatusCode != 403 {
+			t.Fatalf("Expected an invited member to be forbidden, but received: %d", pending.StatusCode)
+		}
+		var invitations data.QueryResults[households.Member]
+		if list := server.Get(t, &invitations, "/households"); list.StatusCode != 200 || len(invitations.Items) != 1 {
+			t.Fatalf("Expected a single invitation, but received: %d, %s", list.StatusCode, list.Body)
+		}
+		if accept := server.Post(t, &member, "/households/nobody/accept", nil); accept.StatusCode != 200 || member.Status != data.MEMBERSHIP_ACTIVE {
+			t.Fatalf("Expected the invitation to be accepted, but received: %d, %s", accept.StatusCode, accept.Body)
+		}
+		var shared recipes.Recipe
+		if get := server.Get(t, &shared, "/recipes/"+recipe.Id); get.StatusCode != 200 || shared.Name != "Household Chili" {
+			t.Fatalf("Expected the editor to read household recipes, but received: %d", get.StatusCode)
+		}
+		if update := server.Put(t, nil, "/recipes/"+recipe.Id, &recipes.RecipeInput{
+			Name: aws.String("Household Stew"),
+		}); update.StatusCode != 200 {
+			t.Fatalf("Expected the editor to update household recipes, but received: %d, %s", update.StatusCode, update.Body)
+		}
+		if tokens := server.Get(t, nil, "/tokens"); tokens.StatusCode != 403 {
+			t.Fatalf("Expected household tokens to be owner only, but received: %d", tokens.StatusCode)
+		}
+		if manage := server.Post(t, nil, "/households/members", &households.MemberInput{
+			Email: aws.String("other@email.com"),
+		}); manage.StatusCode != 403 {
+			t.Fatalf("Expected members to be managed by the owner, but received: %d", manage.StatusCode)
+		}
+
+		server.UpdateIdentity("viewer", "viewer@email.com")
+		if accept := server.Post(t, nil, "/households/nobody/accept", nil); accept.StatusCode != 200 {
+			t.Fatalf("Expected the viewer to accept, but received: %d", accept.StatusCode)
+		}
+		if get := server.Get(t, nil, "/recipes/"+recipe.Id); get.StatusCode != 200 {
+			t.Fatalf("Expected the viewer to read household recipes, but received: %d", get.StatusCode)
+		}
+		if update := server.Put(t, nil, "/recipes/"+recipe.Id, &recipes.RecipeInput{
+			Name: aws.String("Viewer Stew"),
+		}); update.StatusCode != 403 {
+			t.Fatalf("Expected the viewer to not update recipes, but received: %d", update.StatusCode)
+		}
+		var members data.QueryResults[households.Member]
+		if list := server.Get(t, &members, "/households/members"); list.StatusCode != 200 || len(members.Items) != 2 {
+			t.Fatalf("Expected members to be listed, but received: %d, %s", list.StatusCode, list.Body)
+		}
+
+		server.UpdateIdentity("nobody", "nobody@email.com")
+		server.Household = ""
+		if remove := server.Delete(t, "/households/members/viewer@email.com"); remove.StatusCode != 204 {
+			t.Fatalf("Expected 204 removing a member, but received: %d", remove.StatusCode)
+		}
+		server.UpdateIdentity("viewer", "viewer@email.com")
+		server.Household = "nobody"
+		if removed := server.Get(t, nil, "/recipes/"+recipe.Id); removed.StatusCode != 403 {
+			t.Fatalf("Expected a removed member to be forbidden, but received: %d", removed.StatusCode)
+		}
 	})
 
 	t.Run("SettingsWorkflow", func(t *testing.T) {
@@ -513,6 +1170,20 @@ func TestRouter(t *testing.T) {
 			t.Fatalf("Failed to respond with subscription: %v", createdSubscriber)
 		}
 
+		if createdSubscriber.ConfirmationStatus != data.CONFIRMATION_PENDING {
+			t.Fatalf("Expected the subscription to be pending confirmation: %v", createdSubscriber)
+		}
+
+		var resent subscriptions.Subscription
+		resendResp := server.Post(t, &resent, "/subscriptions/"+createdSubscriber.Id+"/confirmation", nil)
+		if resendResp.StatusCode != 200 {
+			t.Fatalf("Failed to resend confirmation: %s", resendResp.Body)
+		}
+
+		if resent.ConfirmationStatus != data.CONFIRMATION_PENDING {
+			t.Fatalf("Expected the subscription to still be pending: %v", resent)
+		}
+
 		var listSubscribers data.QueryResults[subscriptions.Subscription]
 		listResp := server.Get(t, listSubscribers, "/subscriptions")
 		if listResp.StatusCode != 200 {
@@ -545,6 +1216,71 @@ func TestRouter(t *testing.T) {
 		}
 	})
 
+	t.Run("WebhookWorkflow", func(t *testing.T) {
+		invalid := server.Post(t, nil, "/subscriptions", &subscriptions.SubscriptionInput{
+			Endpoint: aws.String("http://example.com/hook"),
+			Protocol: aws.String("webhook"),
+		})
+		if invalid.StatusCode != 400 {
+			t.Fatalf("Expected a non-https webhook to fail, got %d: %s", invalid.StatusCode, invalid.Body)
+		}
+
+		private := server.Post(t, nil, "/subscriptions", &subscriptions.SubscriptionInput{
+			Endpoint: aws.String("https://169.254.169.254/latest/meta-data"),
+			Protocol: aws.String("webhook"),
+		})
+		if private.StatusCode != 400 {
+			t.Fatalf("Expected a link-local webhook to fail, got %d: %s", private.StatusCode, private.Body)
+		}
+
+		unknown := server.Post(t, nil, "/subscriptions", &subscriptions.SubscriptionInput{
+			Endpoint:   aws.String("https://example.com/hook"),
+			Protocol:   aws.String("webhook"),
+			EventTypes: []string{"recipe.exploded"},
+		})
+		if unknown.StatusCode != 400 {
+			t.Fatalf("Expected an unknown event type to fail, got %d: %s", unknown.StatusCode, unknown.Body)
+		}
+
+		var createdWebhook subscriptions.Subscription
+		created := server.Post(t, &createdWebhook, "/subscriptions", &subscriptions.SubscriptionInput{
+			Endpoint:   aws.String("https://example.com/hook"),
+			Protocol:   aws.String("webhook"),
+			EventTypes: []string{"recipe.updated"},
+		})
+		if created.StatusCode != 200 {
+			t.Fatalf("Failed to create a webhook: %s", created.Body)
+		}
+		if createdWebhook.Secret == nil || len(*createdWebhook.Secret) == 0 {
+			t.Fatalf("Expected a signing secret on create: %s", created.Body)
+		}
+		if createdWebhook.ConfirmationStatus != data.CONFIRMATION_CONFIRMED {
+			t.Fatalf("Expected the webhook to be confirmed: %v", createdWebhook)
+		}
+
+		confirmed := server.Post(t, nil, "/subscriptions/"+createdWebhook.Id+"/confirmation", nil)
+		if confirmed.StatusCode != 400 {
+			t.Fatalf("Expected a confirmed subscription to reject resends, got %d", confirmed.StatusCode)
+		}
+
+		var getWebhook subscriptions.Subscription
+		server.Get(t, &getWebhook, "/subscriptions/"+createdWebhook.Id)
+		if getWebhook.Secret != nil {
+			t.Fatalf("Expected the signing secret to be hidden: %v", getWebhook)
+		}
+
+		var deliveries data.QueryResults[subscriptions.Delivery]
+		listResp := server.Get(t, &deliveries, "/subscriptions/"+createdWebhook.Id+"/deliveries")
+		if listResp.StatusCode != 200 {
+			t.Fatalf("Failed to list deliveries: %s", listResp.Body)
+		}
+
+		deleteResp := server.Delete(t, "/subscriptions/"+createdWebhook.Id)
+		if deleteResp.StatusCode != 204 {
+			t.Fatalf("Failed to delete the webhook: %s", deleteResp.Body)
+		}
+	})
+
 	t.Run("UpdateFailure", func(t *testing.T) {
 		updated := server.Post(t, nil, "/recipes/not-existent", &recipes.RecipeInput{
 			Name: aws.String("Non-Existence"),
@@ -554,6 +1290,24 @@ func TestRouter(t *testing.T) {
 		}
 	})
 
+	t.Run("ValidationFailure", func(t *testing.T) {
+		var failure exceptions.ErrorResponse
+		created := server.Post(t, &failure, "/recipes", &recipes.RecipeInput{
+			Instructions:     aws.String("Nothing to \"name\" here"),
+			NumberOfServings: aws.Int(0),
+		})
+		if created.StatusCode != 400 || failure.Code != exceptions.CODE_VALIDATION_FAILED {
+			t.Fatalf("Expected a validation failure, but got %d: %s", created.StatusCode, created.Body)
+		}
+		if len(failure.Details) != 2 || failure.Details[0].Field != "name" || failure.Details[0].Code != validation.CODE_REQUIRED {
+			t.Fatalf("Expected name and servings to fail, but got %s", created.Body)
+		}
+		malformed := server.Request(t, "POST", "/recipes", nil, &failure, nil)
+		if malformed.StatusCode != 400 || failure.Code != exceptions.CODE_INVALID_INPUT {
+			t.Fatalf("Expected an empty body to be invalid, but got %d: %s", malformed.StatusCode, malformed.Body)
+		}
+	})
+
 	t.Run("CorsPreflight", func(t *testing.T) {
 		preflight := server.Options(t, "/recipes")
 		if preflight.StatusCode != 200 {
@@ -564,8 +1318,8 @@ func TestRouter(t *testing.T) {
 		}
 		expected := map[string]string{
 			"content-length":               "0",
-			"access-control-allow-headers": "Content-Type, Content-Length, Authorization",
-			"access-control-allow-methods": "GET, PUT, POST, DELETE",
+			"access-control-allow-headers": "Content-Type, Content-Length, Authorization, X-Household-Id",
+			"access-control-allow-methods": "GET, HEAD, POST",
 			"access-control-allow-origin":  "*",
 		}
 		if !maps.Equal(preflight.Headers, expected) {
@@ -573,3 +1327,93 @@ func TestRouter(t *testing.T) {
 		}
 	})
 }
+
+type _echoService struct{}
+
+func (es *_echoService) GetRoutes() map[string]routes.Route {
+	echo := func(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
+		body, err := json.Marshal(ctx.Value("Params"))
+		return events.APIGatewayV2HTTPResponse{
+			StatusCode: 200,
+			Body:       string(body),
+			Headers: map[string]string{
+				"Content-Length": fmt.Sprint(len(body)),
+			},
+		}, err
+	}
+	return map[string]routes.Route{
+		"GET:/items/:itemId":    echo,
+		"PATCH:/items/:itemId":  echo,
+		"DELETE:/items/:itemId": echo,
+		"GET:/assets/*path":     echo,
+	}
+}
+
+func TestRouting(t *testing.T) {
+	router := routes.NewRouterWithFilters([]filters.RequestFilter{filters.DefaultCorsFilter()}, &_echoService{})
+	invoke := func(method string, path string) events.APIGatewayV2HTTPResponse {
+		return router.Invoke(events.APIGatewayV2HTTPRequest{
+			RawPath: path,
+			RequestContext: events.APIGatewayV2HTTPRequestContext{
+				HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{
+					Method: method,
+				},
+			},
+		}, context.TODO())
+	}
+
+	t.Run("Patch", func(t *testing.T) {
+		response := invoke("PATCH", "/items/abc")
+		if response.StatusCode != 200 || response.Body != `{"itemId":"abc"}` {
+			t.Fatalf("Expected the patch route, got %d: %s", response.StatusCode, response.Body)
+		}
+	})
+
+	t.Run("Head", func(t *testing.T) {
+		response := invoke("HEAD", "/items/abc")
+		if response.StatusCode != 200 || response.Body != "" || response.Headers["Content-Length"] != "16" {
+			t.Fatalf("Expected the headers of GET without a body, got %d: %v %s", response.StatusCode, response.Headers, response.Body)
+		}
+	})
+
+	t.Run("Wildcard", func(t *testing.T) {
+		expected := map[string]string{
+			"/assets/images/logo.png": `{"path":"images/logo.png"}`,
+			"/assets":                 `{"path":""}`,
+		}
+		for path, body := range expected {
+			if response := invoke("GET", path); response.StatusCode != 200 || response.Body != body {
+				t.Fatalf("Expected %s for %s, got %d: %s", body, path, response.StatusCode, response.Body)
+			}
+		}
+		if response := invoke("GET", "/assetsandmore"); response.StatusCode != 404 {
+			t.Fatalf("Expected wildcards to match whole segments, got %d", response.StatusCode)
+		}
+	})
+
+	t.Run("MethodNotAllowed", func(t *testing.T) {
+		var failure exceptions.ErrorResponse
+		response := invoke("PUT", "/items/abc")
+		if response.StatusCode != 405 || response.Headers["Allow"] != "DELETE, GET, HEAD, PATCH" {
+			t.Fatalf("Expected a 405 allowing the routed methods, got %d: %v", response.StatusCode, response.Headers)
+		}
+		if err := json.Unmarshal([]byte(response.Body), &failure); err != nil || failure.Code != exceptions.CODE_METHOD_NOT_ALLOWED {
+			t.Fatalf("Expected a method not allowed error, got %s", response.Body)
+		}
+		if response := invoke("PUT", "/unknown"); response.StatusCode != 404 {
+			t.Fatalf("Expected unknown paths to not be found, got %d", response.StatusCode)
+		}
+	})
+
+	t.Run("CorsPreflight", func(t *testing.T) {
+		expected := map[string]string{
+			"/items/abc": "DELETE, GET, HEAD, PATCH",
+			"/unknown":   "GET, HEAD, PUT, PATCH, POST, DELETE",
+		}
+		for path, methods := range expected {
+			if response := invoke("OPTIONS", path); response.Headers["access-control-allow-methods"] != methods {
+				t.Fatalf("Expected %s to allow %s, got %v", path, methods, response.Headers)
+			}
+		}
+	})
+}
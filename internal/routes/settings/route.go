@@ -2,7 +2,6 @@ package settings
 
 import (
 	"context"
-	"encoding/json"
 	"time"
 
 	"github.com/aws/aws-lambda-go/events"
@@ -10,6 +9,7 @@ import (
 	"philcali.me/recipes/internal/exceptions"
 	"philcali.me/recipes/internal/routes"
 	"philcali.me/recipes/internal/routes/util"
+	"philcali.me/recipes/internal/validation"
 )
 
 type SettingsService struct {
@@ -29,10 +29,26 @@ func (s *SettingsService) GetRoutes() map[string]routes.Route {
 	}
 }
 
+func (s *SettingsService) DescribeRoutes() map[string]routes.RouteDescription {
+	return map[string]routes.RouteDescription{
+		"GET:/settings": {
+			Summary: "Gets the account settings",
+			Output:  Settings{},
+		},
+		"POST:/settings": {
+			Summary: "Creates or updates the account settings",
+			Input:   SettingsInput{},
+			Output:  Settings{},
+		},
+	}
+}
+
 func _convertSettings(data data.SettingsDTO) Settings {
 	return Settings{
 		AutoShareLists:   data.AutoShareLists,
 		AutoShareRecipes: data.AutoShareRecipes,
+		Categories:       data.Categories,
+		StoreLayout:      data.StoreLayout,
 		CreateTime:       data.CreateTime,
 		UpdateTime:       data.UpdateTime,
 	}
@@ -52,10 +68,11 @@ func (s *SettingsService) GetSettings(event events.APIGatewayV2HTTPRequest, ctx
 }
 
 func (s *SettingsService) PutSettings(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
-	updateItem := data.SettingsInputDTO{}
-	if err := json.Unmarshal([]byte(event.Body), &updateItem); err != nil {
-		return events.APIGatewayV2HTTPResponse{}, exceptions.InvalidInput(err.Error())
+	input := SettingsInput{}
+	if err := util.DecodeInput(event, &input, validation.UPDATE); err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
 	}
+	updateItem := input.ToData()
 	item, err := s.data.CreateWithItemId(util.Username(ctx), updateItem, "Global")
 	if err == nil {
 		return util.SerializeResponseOK(_convertSettings, item, nil)
@@ -1,15 +1,52 @@
 package settings
 
-import "time"
+import (
+	"time"
+
+	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/validation"
+)
+
+const (
+	MAX_CATEGORIES   = 1000
+	MAX_AISLES       = 100
+	MAX_AISLE_LENGTH = 64
+)
 
 type Settings struct {
-	AutoShareLists   bool      `json:"autoShareLists"`
-	AutoShareRecipes bool      `json:"autoShareRecipes"`
-	CreateTime       time.Time `json:"createTime"`
-	UpdateTime       time.Time `json:"updateTime"`
+	AutoShareLists   bool              `json:"autoShareLists"`
+	AutoShareRecipes bool              `json:"autoShareRecipes"`
+	Categories       map[string]string `json:"categories,omitempty"`
+	StoreLayout      []string          `json:"storeLayout,omitempty"`
+	CreateTime       time.Time         `json:"createTime"`
+	UpdateTime       time.Time         `json:"updateTime"`
 }
 
 type SettingsInput struct {
-	AutoShareLists   *bool `json:"autoShareLists"`
-	AutoShareRecipes *bool `json:"autoShareRecipes"`
+	AutoShareLists   *bool              `json:"autoShareLists"`
+	AutoShareRecipes *bool              `json:"autoShareRecipes"`
+	Categories       *map[string]string `json:"categories"`
+	StoreLayout      *[]string          `json:"storeLayout"`
+}
+
+func (s *SettingsInput) Validate(op validation.Operation) error {
+	fields := []validation.Field{
+		validation.Optional("categories", s.Categories, validation.MaxLength(MAX_CATEGORIES)),
+		validation.Optional("storeLayout", s.StoreLayout, validation.MaxLength(MAX_AISLES)),
+	}
+	fields = append(fields, validation.Each("storeLayout", s.StoreLayout, func(aisle string) []validation.Field {
+		return []validation.Field{
+			validation.Required("aisle", aisle, validation.MaxLength(MAX_AISLE_LENGTH)),
+		}
+	})...)
+	return validation.Validate(op, fields...)
+}
+
+func (s *SettingsInput) ToData() data.SettingsInputDTO {
+	return data.SettingsInputDTO{
+		AutoShareLists:   s.AutoShareLists,
+		AutoShareRecipes: s.AutoShareRecipes,
+		Categories:       s.Categories,
+		StoreLayout:      s.StoreLayout,
+	}
 }
@@ -2,7 +2,6 @@ package shares
 
 import (
 	"context"
-	"encoding/json"
 	"os"
 	"strings"
 	"time"
@@ -13,6 +12,7 @@ import (
 	"philcali.me/recipes/internal/exceptions"
 	"philcali.me/recipes/internal/routes"
 	"philcali.me/recipes/internal/routes/util"
+	"philcali.me/recipes/internal/validation"
 )
 
 type ShareRequestService struct {
@@ -52,6 +52,33 @@ func (s *ShareRequestService) GetRoutes() map[string]routes.Route {
 	}
 }
 
+func (s *ShareRequestService) DescribeRoutes() map[string]routes.RouteDescription {
+	return map[string]routes.RouteDescription{
+		"GET:/shares": {
+			Summary: "Lists share requests made, or received when status is REQUESTED",
+			Output:  data.QueryResults[ShareRequest]{},
+			Query:   append([]string{"status"}, routes.LIST_QUERY...),
+		},
+		"GET:/shares/:shareId": {
+			Summary: "Gets a share request",
+			Output:  ShareRequest{},
+		},
+		"POST:/shares": {
+			Summary: "Requests to share resources with another account",
+			Input:   ShareRequestInput{},
+			Output:  ShareRequest{},
+		},
+		"PUT:/shares/:shareId": {
+			Summary: "Approves or rejects a received share request",
+			Input:   ShareRequestInput{},
+			Output:  ShareRequest{},
+		},
+		"DELETE:/shares/:shareId": {
+			Summary: "Deletes a share request",
+		},
+	}
+}
+
 func (s *ShareRequestService) GetShare(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
 	item, err := s.data.Get(util.Username(ctx), util.RequestParam(ctx, "shareId"))
 	return util.SerializeResponseOK(_convertShare, item, err)
@@ -74,13 +101,13 @@ func (s *ShareRequestService) DeleteShare(event events.APIGatewayV2HTTPRequest,
 
 func (s *ShareRequestService) CreateShare(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
 	input := ShareRequestInput{}
-	if err := json.Unmarshal([]byte(event.Body), &input); err != nil {
-		return events.APIGatewayV2HTTPResponse{}, exceptions.InvalidInput(err.Error())
+	if err := util.DecodeInput(event, &input, validation.CREATE); err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
 	}
 	if strings.EqualFold(*input.Approver, util.Username(ctx)) {
 		return events.APIGatewayV2HTTPResponse{}, exceptions.InvalidInput("Cannot share with yourself")
 	}
-	expiresIn := int(time.Now().Add(time.Hour + (24 * 7)).UnixMilli())
+	expiresIn := int(time.Now().Add(data.SHARE_REQUEST_EXPIRY).UnixMilli())
 	claims := util.AuthorizationClaims(event)
 	requested := data.REQUESTED
 	created, err := s.data.Create(util.Username(ctx), data.ShareRequestInputDTO{
@@ -95,8 +122,8 @@ func (s *ShareRequestService) CreateShare(event events.APIGatewayV2HTTPRequest,
 
 func (s *ShareRequestService) UpdateShare(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
 	input := ShareRequestInput{}
-	if err := json.Unmarshal([]byte(event.Body), &input); err != nil {
-		return events.APIGatewayV2HTTPResponse{}, exceptions.InvalidInput(err.Error())
+	if err := util.DecodeInput(event, &input, validation.UPDATE); err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
 	}
 	claims := util.AuthorizationClaims(event)
 	shareId := util.RequestParam(ctx, "shareId")
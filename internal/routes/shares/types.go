@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/validation"
 )
 
 type ShareRequest struct {
@@ -19,3 +20,15 @@ type ShareRequestInput struct {
 	Approver       *string              `json:"approver"`
 	ApprovalStatus *data.ApprovalStatus `json:"approvalStatus"`
 }
+
+// Requests are always created as requested, approvers can only approve or reject
+func (si *ShareRequestInput) Validate(op validation.Operation) error {
+	if op == validation.CREATE {
+		return validation.Validate(op,
+			validation.Required("approver", si.Approver, validation.Email()),
+		)
+	}
+	return validation.Validate(op,
+		validation.Optional("approvalStatus", si.ApprovalStatus, validation.OneOf(data.APPROVED, data.REJECTED)),
+	)
+}
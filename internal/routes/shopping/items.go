@@ -0,0 +1,198 @@
+package shopping
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/google/uuid"
+	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/exceptions"
+	"philcali.me/recipes/internal/routes/util"
+	"philcali.me/recipes/internal/validation"
+)
+
+func _findItem(list data.ShoppingListDTO, itemId string) (int, error) {
+	index := slices.IndexFunc(list.Items, func(item data.ShoppingListItemDTO) bool {
+		return item.Id == itemId
+	})
+	if index < 0 {
+		return index, exceptions.NotFound("item", itemId)
+	}
+	return index, nil
+}
+
+func _itemOf(itemId string) func(data.ShoppingListDTO) ShoppingListItem {
+	return func(list data.ShoppingListDTO) ShoppingListItem {
+		index, _ := _findItem(list, itemId)
+		if index < 0 {
+			return ShoppingListItem{Id: itemId}
+		}
+		return ConvertItemDataToTransfer(list.Items[index])
+	}
+}
+
+// Changes to an item are guarded by its id, as other items may shift it
+func _itemGuard(index int, itemId string) map[string]any {
+	return map[string]any{
+		fmt.Sprintf("items[%d].itemId", index): itemId,
+	}
+}
+
+func _changes() data.ShoppingListInputDTO {
+	return data.ShoppingListInputDTO{
+		UpdateToken: aws.String(uuid.NewString()),
+	}
+}
+
+// Inserting and moving items shift the others, so the list is written whole
+// unless it changed since it was read
+func (sl *ShoppingListService) _writeItems(accountId string, list data.ShoppingListDTO, items []data.ShoppingListItemDTO) (data.ShoppingListDTO, error) {
+	input := _changes()
+	input.Items = &items
+	patch := data.Patch{}
+	if list.UpdateToken != nil {
+		patch.Conditions = map[string]any{"updateToken": *list.UpdateToken}
+	}
+	return sl.data.Patch(accountId, list.SK, input, patch)
+}
+
+// Lists written before items had ids are given them on their first item
+// change, so items can be addressed by the ids they are read with
+func (sl *ShoppingListService) _getList(accountId string, listId string) (data.ShoppingListDTO, error) {
+	list, err := sl.data.Get(accountId, listId)
+	if err != nil {
+		return list, err
+	}
+	items := slices.Clone(list.Items)
+	if !data.FillItemIds(list.SK, items) {
+		return list, nil
+	}
+	return sl._writeItems(accountId, list, items)
+}
+
+func (sl *ShoppingListService) CreateItem(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
+	input := ShoppingListItemInput{}
+	if err := util.DecodeInput(event, &input, validation.CREATE); err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	accountId := util.Username(ctx)
+	list, err := sl._getList(accountId, util.RequestParam(ctx, "shoppingListId"))
+	if err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	if len(list.Items) >= MAX_ITEMS {
+		return events.APIGatewayV2HTTPResponse{}, exceptions.Validation(exceptions.FieldError{
+			Field:   "items",
+			Code:    validation.CODE_TOO_LONG,
+			Message: fmt.Sprintf("items must be at most %d items", MAX_ITEMS),
+		})
+	}
+	item := input.Apply(data.ShoppingListItemDTO{Id: uuid.NewString()})
+	var updated data.ShoppingListDTO
+	if input.Position != nil && *input.Position < len(list.Items) {
+		updated, err = sl._writeItems(accountId, list, slices.Insert(list.Items, *input.Position, item))
+	} else if len(list.Items) == 0 {
+		// Lists without items may have nothing to append to
+		updated, err = sl._writeItems(accountId, list, []data.ShoppingListItemDTO{item})
+	} else {
+		updated, err = sl.data.Patch(accountId, list.SK, _changes(), data.Patch{
+			Changes: []data.PatchChange{
+				{Path: "items", Value: []data.ShoppingListItemDTO{item}, Append: true},
+			},
+		})
+	}
+	return util.SerializeResponseOK(_itemOf(item.Id), updated, err)
+}
+
+func (sl *ShoppingListService) UpdateItem(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
+	input := ShoppingListItemInput{}
+	if err := util.DecodeInput(event, &input, validation.UPDATE); err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	accountId := util.Username(ctx)
+	itemId := util.RequestParam(ctx, "itemId")
+	list, err := sl._getList(accountId, util.RequestParam(ctx, "shoppingListId"))
+	if err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	index, err := _findItem(list, itemId)
+	if err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	item := input.Apply(list.Items[index])
+	if input.Position != nil && *input.Position != index {
+		items := slices.Delete(slices.Clone(list.Items), index, index+1)
+		position := min(*input.Position, len(items))
+		updated, err := sl._writeItems(accountId, list, slices.Insert(items, position, item))
+		return util.SerializeResponseOK(_itemOf(itemId), updated, err)
+	}
+	// Only the fields set are written, so concurrent edits of other fields stay
+	changes := make([]data.PatchChange, 0, 5)
+	field := func(name string, value any, set bool) {
+		if set {
+			changes = append(changes, data.PatchChange{Path: fmt.Sprintf("items[%d].%s", index, name), Value: value})
+		}
+	}
+	field("name", item.Name, input.Name != nil)
+	field("measurement", item.Measurement, input.Measurement != nil)
+	field("amount", item.Amount, input.Amount != nil)
+	field("completed", item.Completed, input.Completed != nil)
+	field("category", item.Category, input.Category != nil)
+	updated, err := sl.data.Patch(accountId, list.SK, _changes(), data.Patch{
+		Changes:    changes,
+		Conditions: _itemGuard(index, itemId),
+	})
+	return util.SerializeResponseOK(_itemOf(itemId), updated, err)
+}
+
+func (sl *ShoppingListService) DeleteItem(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
+	accountId := util.Username(ctx)
+	itemId := util.RequestParam(ctx, "itemId")
+	list, err := sl._getList(accountId, util.RequestParam(ctx, "shoppingListId"))
+	if err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	index, err := _findItem(list, itemId)
+	if err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	_, err = sl.data.Patch(accountId, list.SK, _changes(), data.Patch{
+		Changes: []data.PatchChange{
+			{Path: fmt.Sprintf("items[%d]", index), Remove: true},
+		},
+		Conditions: _itemGuard(index, itemId),
+	})
+	return util.SerializeResponseNoContent(err)
+}
+
+func (sl *ShoppingListService) CompleteItems(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
+	input := CompleteItemsInput{}
+	if err := util.DecodeInput(event, &input, validation.UPDATE); err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	accountId := util.Username(ctx)
+	list, err := sl._getList(accountId, util.RequestParam(ctx, "shoppingListId"))
+	if err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	for _, itemId := range input.ItemIds {
+		if _, err := _findItem(list, itemId); err != nil {
+			return events.APIGatewayV2HTTPResponse{}, err
+		}
+	}
+	items := slices.Clone(list.Items)
+	for i, item := range items {
+		if len(input.ItemIds) > 0 && !slices.Contains(input.ItemIds, item.Id) {
+			continue
+		}
+		items[i].Completed = !item.Completed
+		if input.Completed != nil {
+			items[i].Completed = *input.Completed
+		}
+	}
+	updated, err := sl._writeItems(accountId, list, items)
+	return util.SerializeResponseOK(NewShoppingList, updated, err)
+}
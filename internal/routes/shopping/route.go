@@ -2,22 +2,32 @@ package shopping
 
 import (
 	"context"
-	"encoding/json"
+	"fmt"
 
 	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/uuid"
 	"philcali.me/recipes/internal/data"
 	"philcali.me/recipes/internal/exceptions"
+	"philcali.me/recipes/internal/patch"
 	"philcali.me/recipes/internal/routes"
 	"philcali.me/recipes/internal/routes/util"
+	"philcali.me/recipes/internal/validation"
 )
 
 type ShoppingListService struct {
-	data data.ShoppingListDataService
+	data     data.ShoppingListDataService
+	settings data.SettingsRepository
 }
 
 func NewRoute(data data.ShoppingListDataService) routes.Service {
+	return NewRouteWithSettings(data, nil)
+}
+
+// Settings hold the aisles of items and the store layout they are grouped by
+func NewRouteWithSettings(data data.ShoppingListDataService, settings data.SettingsRepository) routes.Service {
 	return &ShoppingListService{
-		data: data,
+		data:     data,
+		settings: settings,
 	}
 }
 
@@ -27,7 +37,65 @@ func (sl *ShoppingListService) GetRoutes() map[string]routes.Route {
 		"GET:/lists/:shoppingListId":    util.AuthorizedRoute(sl.GetShoppingList),
 		"POST:/lists":                   util.AuthorizedRoute(sl.CreateShoppingList),
 		"PUT:/lists/:shoppingListId":    util.AuthorizedRoute(sl.UpdateShoppingList),
+		"PATCH:/lists/:shoppingListId":  util.AuthorizedRoute(sl.PatchShoppingList),
 		"DELETE:/lists/:shoppingListId": util.AuthorizedRoute(sl.DeleteShoppingList),
+
+		"POST:/lists/:shoppingListId/items":           util.AuthorizedRoute(sl.CreateItem),
+		"PUT:/lists/:shoppingListId/items/:itemId":    util.AuthorizedRoute(sl.UpdateItem),
+		"DELETE:/lists/:shoppingListId/items/:itemId": util.AuthorizedRoute(sl.DeleteItem),
+		"POST:/lists/:shoppingListId/completed":       util.AuthorizedRoute(sl.CompleteItems),
+	}
+}
+
+func (sl *ShoppingListService) DescribeRoutes() map[string]routes.RouteDescription {
+	return map[string]routes.RouteDescription{
+		"GET:/lists": {
+			Summary: "Lists shopping lists",
+			Output:  data.QueryResults[ShoppingList]{},
+			Query:   routes.LIST_QUERY,
+		},
+		"GET:/lists/:shoppingListId": {
+			Summary: "Gets a shopping list, grouping its items by aisle when groupBy=aisle",
+			Output:  ShoppingList{},
+			Query:   []string{"groupBy"},
+		},
+		"POST:/lists": {
+			Summary: "Creates a shopping list",
+			Input:   ShoppingListInput{},
+			Output:  ShoppingList{},
+		},
+		"PUT:/lists/:shoppingListId": {
+			Summary: "Updates the fields of a shopping list that are set",
+			Input:   ShoppingListInput{},
+			Output:  ShoppingList{},
+		},
+		"PATCH:/lists/:shoppingListId": {
+			Summary:     "Patches a shopping list with a merge patch, or a JSON Patch sent as " + patch.JSON_PATCH_CONTENT_TYPE,
+			Input:       ShoppingListInput{},
+			Output:      ShoppingList{},
+			ContentType: patch.MERGE_PATCH_CONTENT_TYPE,
+		},
+		"DELETE:/lists/:shoppingListId": {
+			Summary: "Deletes a shopping list",
+		},
+		"POST:/lists/:shoppingListId/items": {
+			Summary: "Adds an item to a shopping list, at the end unless positioned",
+			Input:   ShoppingListItemInput{},
+			Output:  ShoppingListItem{},
+		},
+		"PUT:/lists/:shoppingListId/items/:itemId": {
+			Summary: "Updates the fields of an item that are set, or moves it",
+			Input:   ShoppingListItemInput{},
+			Output:  ShoppingListItem{},
+		},
+		"DELETE:/lists/:shoppingListId/items/:itemId": {
+			Summary: "Removes an item from a shopping list",
+		},
+		"POST:/lists/:shoppingListId/completed": {
+			Summary: "Completes, or flips, the listed items or every item",
+			Input:   CompleteItemsInput{},
+			Output:  ShoppingList{},
+		},
 	}
 }
 
@@ -37,13 +105,29 @@ func (sl *ShoppingListService) ListShoppingLists(event events.APIGatewayV2HTTPRe
 
 func (sl *ShoppingListService) GetShoppingList(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
 	item, err := sl.data.Get(util.Username(ctx), util.RequestParam(ctx, "shoppingListId"))
-	return util.SerializeResponseOK(NewShoppingList, item, err)
+	groupBy, grouped := event.QueryStringParameters["groupBy"]
+	if err != nil || !grouped {
+		return util.SerializeResponseOK(NewShoppingList, item, err)
+	}
+	if groupBy != GROUP_BY_AISLE {
+		return events.APIGatewayV2HTTPResponse{}, exceptions.InvalidInput(fmt.Sprintf("Items can only be grouped by %s", GROUP_BY_AISLE))
+	}
+	settings := data.SettingsDTO{}
+	if sl.settings != nil {
+		settings, err = sl.settings.Get(util.Username(ctx), "Global")
+		if _, ok := err.(*exceptions.NotFoundError); ok {
+			err = nil
+		}
+	}
+	return util.SerializeResponseOK(func(list data.ShoppingListDTO) ShoppingList {
+		return NewGroupedShoppingList(list, settings)
+	}, item, err)
 }
 
 func (sl *ShoppingListService) CreateShoppingList(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
 	input := ShoppingListInput{}
-	if err := json.Unmarshal([]byte(event.Body), &input); err != nil {
-		return events.APIGatewayV2HTTPResponse{}, exceptions.InvalidInput(err.Error())
+	if err := util.DecodeInput(event, &input, validation.CREATE); err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
 	}
 	claims := util.AuthorizationClaims(event)
 	created, err := sl.data.Create(util.Username(ctx), input.ToData(claims["email"]))
@@ -52,14 +136,37 @@ func (sl *ShoppingListService) CreateShoppingList(event events.APIGatewayV2HTTPR
 
 func (sl *ShoppingListService) UpdateShoppingList(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
 	input := ShoppingListInput{}
-	if err := json.Unmarshal([]byte(event.Body), &input); err != nil {
-		return events.APIGatewayV2HTTPResponse{}, exceptions.InvalidInput(err.Error())
+	if err := util.DecodeInput(event, &input, validation.UPDATE); err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
 	}
 	claims := util.AuthorizationClaims(event)
 	item, err := sl.data.Update(util.Username(ctx), util.RequestParam(ctx, "shoppingListId"), input.ToData(claims["email"]))
 	return util.SerializeResponseOK(NewShoppingList, item, err)
 }
 
+// Items are patched in place, so checking off different items concurrently is safe
+func (sl *ShoppingListService) PatchShoppingList(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
+	return util.PatchResource[data.ShoppingListDTO, data.ShoppingListInputDTO, ShoppingList, ShoppingListInput](
+		sl.data,
+		event,
+		ctx,
+		util.RequestParam(ctx, "shoppingListId"),
+		NewShoppingList,
+		func(list data.ShoppingListDTO) *string {
+			return list.UpdateToken
+		},
+		patch.List{
+			Field: "items",
+			Key:   "itemId",
+			Prepare: func(item map[string]any) {
+				if _, ok := item["itemId"]; !ok {
+					item["itemId"] = uuid.NewString()
+				}
+			},
+		},
+	)
+}
+
 func (sl *ShoppingListService) DeleteShoppingList(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
 	err := sl.data.Delete(util.Username(ctx), util.RequestParam(ctx, "shoppingListId"))
 	return util.SerializeResponseNoContent(err)
@@ -1,19 +1,101 @@
 package shopping
 
 import (
+	"slices"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/google/uuid"
+	"philcali.me/recipes/internal/aisles"
 	"philcali.me/recipes/internal/data"
 	"philcali.me/recipes/internal/routes/util"
+	"philcali.me/recipes/internal/validation"
 )
 
+// Items without an id are given one as they are written
 type ShoppingListItem struct {
+	Id          string  `json:"itemId,omitempty"`
 	Name        string  `json:"name"`
 	Measurement string  `json:"measurement"`
 	Amount      float32 `json:"amount"`
 	Completed   bool    `json:"completed"`
+	Category    *string `json:"category,omitempty"`
+}
+
+func ConvertItemToData(sli ShoppingListItem) data.ShoppingListItemDTO {
+	if sli.Id == "" {
+		sli.Id = uuid.NewString()
+	}
+	return data.ShoppingListItemDTO{
+		Id:          sli.Id,
+		Name:        sli.Name,
+		Measurement: sli.Measurement,
+		Amount:      sli.Amount,
+		Completed:   sli.Completed,
+		Category:    sli.Category,
+	}
+}
+
+func ConvertItemDataToTransfer(slid data.ShoppingListItemDTO) ShoppingListItem {
+	return ShoppingListItem{
+		Id:          slid.Id,
+		Name:        slid.Name,
+		Measurement: slid.Measurement,
+		Amount:      slid.Amount,
+		Completed:   slid.Completed,
+		Category:    slid.Category,
+	}
+}
+
+// Items are listed in the order they are stored, a position moves an item
+type ShoppingListItemInput struct {
+	Name        *string  `json:"name"`
+	Measurement *string  `json:"measurement"`
+	Amount      *float32 `json:"amount"`
+	Completed   *bool    `json:"completed"`
+	Category    *string  `json:"category"`
+	Position    *int     `json:"position"`
+}
+
+func (i *ShoppingListItemInput) Validate(op validation.Operation) error {
+	return validation.Validate(
+		op,
+		validation.Required("name", i.Name, validation.MaxLength(MAX_NAME_LENGTH)),
+		validation.Optional("measurement", i.Measurement, validation.MaxLength(MAX_NAME_LENGTH)),
+		validation.Optional("category", i.Category, validation.MaxLength(MAX_NAME_LENGTH)),
+		validation.Optional("position", i.Position, validation.Range(0, MAX_ITEMS)),
+	)
+}
+
+// Sets the fields of the input that are set on the item
+func (i *ShoppingListItemInput) Apply(item data.ShoppingListItemDTO) data.ShoppingListItemDTO {
+	if i.Name != nil {
+		item.Name = *i.Name
+	}
+	if i.Measurement != nil {
+		item.Measurement = *i.Measurement
+	}
+	if i.Amount != nil {
+		item.Amount = *i.Amount
+	}
+	if i.Completed != nil {
+		item.Completed = *i.Completed
+	}
+	if i.Category != nil {
+		item.Category = i.Category
+	}
+	return item
+}
+
+// Completes the listed items, or every item when none are listed
+type CompleteItemsInput struct {
+	ItemIds []string `json:"itemIds"`
+	// Flips every item when unset
+	Completed *bool `json:"completed"`
+}
+
+func (c *CompleteItemsInput) Validate(op validation.Operation) error {
+	return validation.Validate(op, validation.Optional("itemIds", &c.ItemIds, validation.MaxLength(MAX_ITEMS)))
 }
 
 type ShoppingListInput struct {
@@ -22,6 +104,26 @@ type ShoppingListInput struct {
 	ExpiresIn *time.Time          `json:"expiresIn,omitempty"`
 }
 
+const (
+	MAX_NAME_LENGTH = 256
+	MAX_ITEMS       = 500
+	GROUP_BY_AISLE  = "aisle"
+)
+
+func (l *ShoppingListInput) Validate(op validation.Operation) error {
+	fields := []validation.Field{
+		validation.Required("name", l.Name, validation.MaxLength(MAX_NAME_LENGTH)),
+		validation.Optional("items", l.Items, validation.MaxLength(MAX_ITEMS)),
+	}
+	fields = append(fields, validation.Each("items", l.Items, func(i ShoppingListItem) []validation.Field {
+		return []validation.Field{
+			validation.Required("name", i.Name, validation.MaxLength(MAX_NAME_LENGTH)),
+			validation.Optional("category", i.Category, validation.MaxLength(MAX_NAME_LENGTH)),
+		}
+	})...)
+	return validation.Validate(op, fields...)
+}
+
 func (l *ShoppingListInput) ToData(owner string) data.ShoppingListInputDTO {
 	var expiresIn *int = nil
 	if l.ExpiresIn != nil {
@@ -32,25 +134,26 @@ func (l *ShoppingListInput) ToData(owner string) data.ShoppingListInputDTO {
 		ExpiresIn:   expiresIn,
 		Owner:       &owner,
 		UpdateToken: aws.String(uuid.NewString()),
-		Items: util.MapOnList(l.Items, func(sli ShoppingListItem) data.ShoppingListItemDTO {
-			return data.ShoppingListItemDTO{
-				Name:        sli.Name,
-				Measurement: sli.Measurement,
-				Amount:      sli.Amount,
-				Completed:   sli.Completed,
-			}
-		}),
+		Items:       util.MapOnList(l.Items, ConvertItemToData),
 	}
 }
 
+// Items of an aisle, in the order they are stored
+type ShoppingListGroup struct {
+	Category string             `json:"category"`
+	Items    []ShoppingListItem `json:"items"`
+}
+
 type ShoppingList struct {
-	Id         string             `json:"listId"`
-	Name       string             `json:"name"`
-	Owner      *string            `json:"owner"`
-	Items      []ShoppingListItem `json:"items"`
-	ExpiresIn  *time.Time         `json:"expiresIn,omitempty"`
-	CreateTime time.Time          `json:"createTime"`
-	UpdateTime time.Time          `json:"updateTime"`
+	Id    string             `json:"listId"`
+	Name  string             `json:"name"`
+	Owner *string            `json:"owner"`
+	Items []ShoppingListItem `json:"items"`
+	// Present when grouped, ordered by the store layout
+	Groups     []ShoppingListGroup `json:"groups,omitempty"`
+	ExpiresIn  *time.Time          `json:"expiresIn,omitempty"`
+	CreateTime time.Time           `json:"createTime"`
+	UpdateTime time.Time           `json:"updateTime"`
 }
 
 func NewShoppingList(list data.ShoppingListDTO) ShoppingList {
@@ -58,6 +161,9 @@ func NewShoppingList(list data.ShoppingListDTO) ShoppingList {
 	if list.ExpiresIn != nil {
 		expiresIn = aws.Time(time.Unix(int64(*list.ExpiresIn), 0))
 	}
+	// Items are read with the ids they are given on their first change
+	items := slices.Clone(list.Items)
+	data.FillItemIds(list.SK, items)
 	return ShoppingList{
 		Id:         list.SK,
 		Name:       list.Name,
@@ -65,13 +171,34 @@ func NewShoppingList(list data.ShoppingListDTO) ShoppingList {
 		UpdateTime: list.UpdateTime,
 		ExpiresIn:  expiresIn,
 		Owner:      list.Owner,
-		Items: *util.MapOnList(&list.Items, func(slid data.ShoppingListItemDTO) ShoppingListItem {
-			return ShoppingListItem{
-				Name:        slid.Name,
-				Measurement: slid.Measurement,
-				Amount:      slid.Amount,
-				Completed:   slid.Completed,
-			}
-		}),
+		Items:      *util.MapOnList(&items, ConvertItemDataToTransfer),
+	}
+}
+
+// Items without a category are placed in an aisle by their names
+func NewGroupedShoppingList(list data.ShoppingListDTO, settings data.SettingsDTO) ShoppingList {
+	rtn := NewShoppingList(list)
+	categorizer := aisles.NewCategorizer(settings.Categories)
+	groups := make(map[string][]ShoppingListItem)
+	var categories []string
+	for _, item := range rtn.Items {
+		category := aws.ToString(item.Category)
+		if category == "" {
+			category = categorizer.Categorize(item.Name)
+			item.Category = &category
+		}
+		if _, ok := groups[category]; !ok {
+			categories = append(categories, category)
+		}
+		groups[category] = append(groups[category], item)
+	}
+	aisles.Sort(categories, settings.StoreLayout)
+	rtn.Groups = make([]ShoppingListGroup, len(categories))
+	for i, category := range categories {
+		rtn.Groups[i] = ShoppingListGroup{
+			Category: category,
+			Items:    groups[category],
+		}
 	}
+	return rtn
 }
@@ -2,34 +2,79 @@ package subscriptions
 
 import (
 	"context"
-	"encoding/json"
+	"fmt"
+	"os"
 
 	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"philcali.me/recipes/internal/data"
 	"philcali.me/recipes/internal/exceptions"
 	"philcali.me/recipes/internal/notifications"
 	"philcali.me/recipes/internal/routes"
 	"philcali.me/recipes/internal/routes/util"
+	"philcali.me/recipes/internal/validation"
+	"philcali.me/recipes/internal/webhooks"
 )
 
 type SubscriptionService struct {
 	data          data.SubscriptionDataService
+	deliveries    data.DeliveryRepository
 	notifications notifications.NotificationService
+	indexName     string
 }
 
-func NewRoute(data data.SubscriptionDataService, notifications notifications.NotificationService) routes.Service {
+func NewRouteWithIndex(data data.SubscriptionDataService, deliveries data.DeliveryRepository, notifications notifications.NotificationService, indexName string) routes.Service {
 	return &SubscriptionService{
 		data:          data,
+		deliveries:    deliveries,
 		notifications: notifications,
+		indexName:     indexName,
 	}
 }
 
+func NewRoute(data data.SubscriptionDataService, deliveries data.DeliveryRepository, notifications notifications.NotificationService) routes.Service {
+	return NewRouteWithIndex(data, deliveries, notifications, os.Getenv("INDEX_NAME_1"))
+}
+
 func (s *SubscriptionService) GetRoutes() map[string]routes.Route {
 	return map[string]routes.Route{
-		"GET:/subscriptions":                  util.AuthorizedRoute(s.ListSubscriptions),
-		"GET:/subscriptions/:subscriberId":    util.AuthorizedRoute(s.GetSubscription),
-		"POST:/subscriptions":                 util.AuthorizedRoute(s.CreateSubscription),
-		"DELETE:/subscriptions/:subscriberId": util.AuthorizedRoute(s.DeleteSubscription),
+		"GET:/subscriptions":                             util.AuthorizedRoute(s.ListSubscriptions),
+		"GET:/subscriptions/:subscriberId":               util.AuthorizedRoute(s.GetSubscription),
+		"GET:/subscriptions/:subscriberId/deliveries":    util.AuthorizedRoute(s.ListDeliveries),
+		"POST:/subscriptions":                            util.AuthorizedRoute(s.CreateSubscription),
+		"POST:/subscriptions/:subscriberId/confirmation": util.AuthorizedRoute(s.ResendConfirmation),
+		"DELETE:/subscriptions/:subscriberId":            util.AuthorizedRoute(s.DeleteSubscription),
+	}
+}
+
+func (s *SubscriptionService) DescribeRoutes() map[string]routes.RouteDescription {
+	return map[string]routes.RouteDescription{
+		"GET:/subscriptions": {
+			Summary: "Lists subscriptions to resource events",
+			Output:  data.QueryResults[Subscription]{},
+			Query:   routes.LIST_QUERY,
+		},
+		"GET:/subscriptions/:subscriberId": {
+			Summary: "Gets a subscription",
+			Output:  Subscription{},
+		},
+		"GET:/subscriptions/:subscriberId/deliveries": {
+			Summary: "Lists the webhook deliveries of a subscription",
+			Output:  data.QueryResults[Delivery]{},
+			Query:   routes.LIST_QUERY,
+		},
+		"POST:/subscriptions": {
+			Summary: "Subscribes an endpoint, webhook responses include the signing secret",
+			Input:   SubscriptionInput{},
+			Output:  Subscription{},
+		},
+		"POST:/subscriptions/:subscriberId/confirmation": {
+			Summary: "Resends the confirmation of a pending subscription",
+			Output:  Subscription{},
+		},
+		"DELETE:/subscriptions/:subscriberId": {
+			Summary: "Unsubscribes an endpoint",
+		},
 	}
 }
 
@@ -39,27 +84,119 @@ func (s *SubscriptionService) ListSubscriptions(event events.APIGatewayV2HTTPReq
 
 func (s *SubscriptionService) GetSubscription(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
 	item, err := s.data.Get(util.Username(ctx), util.RequestParam(ctx, "subscriberId"))
+	if err == nil && item.ConfirmationStatus == data.CONFIRMATION_PENDING {
+		item, err = s.refreshConfirmation(util.Username(ctx), item)
+	}
 	return util.SerializeResponseOK(NewSubscription, item, err)
 }
 
-func (s *SubscriptionService) CreateSubscription(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
-	input := SubscriptionInput{}
-	if err := json.Unmarshal([]byte(event.Body), &input); err != nil {
-		return events.APIGatewayV2HTTPResponse{}, exceptions.InvalidInput(err.Error())
+func _confirmationStatus(pending bool) data.ConfirmationStatus {
+	if pending {
+		return data.CONFIRMATION_PENDING
+	}
+	return data.CONFIRMATION_CONFIRMED
+}
+
+func (s *SubscriptionService) refreshConfirmation(accountId string, item data.SubscriptionDTO) (data.SubscriptionDTO, error) {
+	pending, err := s.notifications.IsPendingConfirmation(item.SubscriberArn)
+	if err != nil {
+		return item, exceptions.InternalServer(err.Error())
 	}
+	if pending {
+		return item, nil
+	}
+	status := data.CONFIRMATION_CONFIRMED
+	return s.data.Update(accountId, item.SK, data.SubscriptionInputDTO{
+		ConfirmationStatus: &status,
+	})
+}
 
+func (s *SubscriptionService) ResendConfirmation(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
+	item, err := s.data.Get(util.Username(ctx), util.RequestParam(ctx, "subscriberId"))
+	if err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	if item.ConfirmationStatus != data.CONFIRMATION_PENDING {
+		return events.APIGatewayV2HTTPResponse{}, exceptions.InvalidInput(fmt.Sprintf("Subscription %s is already confirmed", item.SK))
+	}
+	// Subscribing again with the same endpoint has SNS send a new confirmation
 	subscription, err := s.notifications.Subscribe(notifications.SubscribeInput{
-		Endpoint: input.Endpoint,
-		Protocol: input.Protocol,
+		AccountId:     aws.String(util.Username(ctx)),
+		Endpoint:      aws.String(item.Endpoint),
+		Protocol:      aws.String(item.Protocol),
+		EventTypes:    item.EventTypes,
+		ResourceTypes: item.ResourceTypes,
 	})
 	if err != nil {
 		return events.APIGatewayV2HTTPResponse{}, exceptions.InternalServer(err.Error())
 	}
+	status := _confirmationStatus(subscription.PendingConfirmation)
+	updated, err := s.data.Update(util.Username(ctx), item.SK, data.SubscriptionInputDTO{
+		SubscriberArn:      &subscription.SubscriberId,
+		ConfirmationStatus: &status,
+	})
+	return util.SerializeResponseOK(NewSubscription, updated, err)
+}
+
+func (s *SubscriptionService) ListDeliveries(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
+	subscriber, err := s.data.Get(util.Username(ctx), util.RequestParam(ctx, "subscriberId"))
+	if err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	hash := fmt.Sprintf("%s:%s", util.Username(ctx), subscriber.SK)
+	return util.SerializeListByIndexAndHash(s.deliveries, NewDelivery, s.indexName, event, hash)
+}
 
+func (s *SubscriptionService) createWebhook(input SubscriptionInput, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
+	if err := webhooks.ValidateEndpoint(*input.Endpoint); err != nil {
+		return events.APIGatewayV2HTTPResponse{}, exceptions.InvalidInput(err.Error())
+	}
+	secret, err := webhooks.GenerateSecret()
+	if err != nil {
+		return events.APIGatewayV2HTTPResponse{}, exceptions.InternalServer(err.Error())
+	}
+	// Webhooks require no confirmation handshake
+	status := data.CONFIRMATION_CONFIRMED
 	created, err := s.data.Create(util.Username(ctx), data.SubscriptionInputDTO{
+		Endpoint:           input.Endpoint,
+		Protocol:           input.Protocol,
+		Secret:             &secret,
+		EventTypes:         input.EventTypes,
+		ResourceTypes:      input.ResourceTypes,
+		ConfirmationStatus: &status,
+	})
+	return util.SerializeResponseOK(NewSubscriptionWithSecret, created, err)
+}
+
+func (s *SubscriptionService) CreateSubscription(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
+	input := SubscriptionInput{}
+	if err := util.DecodeInput(event, &input, validation.CREATE); err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+
+	if input.Protocol != nil && *input.Protocol == data.WEBHOOK_PROTOCOL {
+		return s.createWebhook(input, ctx)
+	}
+
+	subscription, err := s.notifications.Subscribe(notifications.SubscribeInput{
+		AccountId:     aws.String(util.Username(ctx)),
 		Endpoint:      input.Endpoint,
 		Protocol:      input.Protocol,
-		SubscriberArn: &subscription.SubscriberId,
+		EventTypes:    input.EventTypes,
+		ResourceTypes: input.ResourceTypes,
+	})
+	if err != nil {
+		return events.APIGatewayV2HTTPResponse{}, exceptions.InternalServer(err.Error())
+	}
+
+	status := _confirmationStatus(subscription.PendingConfirmation)
+	created, err := s.data.Create(util.Username(ctx), data.SubscriptionInputDTO{
+		Endpoint:           input.Endpoint,
+		Protocol:           input.Protocol,
+		SubscriberArn:      &subscription.SubscriberId,
+		EventTypes:         input.EventTypes,
+		ResourceTypes:      input.ResourceTypes,
+		ConfirmationStatus: &status,
 	})
 	return util.SerializeResponseOK(NewSubscription, created, err)
 }
@@ -75,9 +212,11 @@ func (s *SubscriptionService) DeleteSubscription(event events.APIGatewayV2HTTPRe
 		}
 	}
 
-	err = s.notifications.Unsubscribe(subscriber.SubscriberArn)
-	if err != nil {
-		return events.APIGatewayV2HTTPResponse{}, exceptions.InternalServer(err.Error())
+	if subscriber.Protocol != data.WEBHOOK_PROTOCOL {
+		err = s.notifications.Unsubscribe(subscriber.SubscriberArn)
+		if err != nil {
+			return events.APIGatewayV2HTTPResponse{}, exceptions.InternalServer(err.Error())
+		}
 	}
 
 	return util.SerializeResponseNoContent(s.data.Delete(util.Username(ctx), subscriber.SK))
@@ -4,27 +4,109 @@ import (
 	"time"
 
 	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/notifications"
+	"philcali.me/recipes/internal/routes/util"
+	"philcali.me/recipes/internal/validation"
 )
 
 type Subscription struct {
-	Endpoint   string    `json:"endpoint"`
-	Protocol   string    `json:"protocol"`
-	Id         string    `json:"subscriberId"`
-	CreateTime time.Time `json:"createTime"`
-	UpdateTime time.Time `json:"updateTime"`
+	Endpoint           string                  `json:"endpoint"`
+	Protocol           string                  `json:"protocol"`
+	Id                 string                  `json:"subscriberId"`
+	Secret             *string                 `json:"secret,omitempty"`
+	EventTypes         []string                `json:"eventTypes"`
+	ResourceTypes      []string                `json:"resourceTypes"`
+	ConfirmationStatus data.ConfirmationStatus `json:"confirmationStatus"`
+	CreateTime         time.Time               `json:"createTime"`
+	UpdateTime         time.Time               `json:"updateTime"`
 }
 
 type SubscriptionInput struct {
-	Endpoint *string `json:"endpoint"`
-	Protocol *string `json:"protocol"`
+	Endpoint      *string  `json:"endpoint"`
+	Protocol      *string  `json:"protocol"`
+	EventTypes    []string `json:"eventTypes"`
+	ResourceTypes []string `json:"resourceTypes"`
+}
+
+// Empty filters match everything, so filters are optional
+func (si *SubscriptionInput) Validate(op validation.Operation) error {
+	return validation.Validate(op,
+		validation.Required("endpoint", si.Endpoint),
+		validation.Required("protocol", si.Protocol),
+		validation.Optional("eventTypes", si.EventTypes, validation.OneOf(notifications.EVENT_TYPES...)),
+		validation.Optional("resourceTypes", si.ResourceTypes, validation.OneOf(notifications.RESOURCE_TYPES...)),
+	)
+}
+
+type DeliveryAttempt struct {
+	StatusCode  int       `json:"statusCode"`
+	Error       *string   `json:"error,omitempty"`
+	AttemptTime time.Time `json:"attemptTime"`
+}
+
+type Delivery struct {
+	Id              string              `json:"deliveryId"`
+	EventId         string              `json:"eventId"`
+	EventType       string              `json:"eventType"`
+	Endpoint        string              `json:"endpoint"`
+	Status          data.DeliveryStatus `json:"status"`
+	Attempts        []DeliveryAttempt   `json:"attempts"`
+	NextAttemptTime *time.Time          `json:"nextAttemptTime,omitempty"`
+	CreateTime      time.Time           `json:"createTime"`
+	UpdateTime      time.Time           `json:"updateTime"`
 }
 
 func NewSubscription(entry data.SubscriptionDTO) Subscription {
-	return Subscription{
-		Endpoint:   entry.Endpoint,
-		Protocol:   entry.Protocol,
+	subscription := Subscription{
+		Endpoint:           entry.Endpoint,
+		Protocol:           entry.Protocol,
+		Id:                 entry.SK,
+		EventTypes:         entry.EventTypes,
+		ResourceTypes:      entry.ResourceTypes,
+		ConfirmationStatus: entry.ConfirmationStatus,
+		CreateTime:         entry.CreateTime,
+		UpdateTime:         entry.UpdateTime,
+	}
+	if subscription.EventTypes == nil {
+		subscription.EventTypes = []string{}
+	}
+	if subscription.ResourceTypes == nil {
+		subscription.ResourceTypes = []string{}
+	}
+	// Subscriptions created before confirmation tracking were confirmed by SNS
+	if subscription.ConfirmationStatus == "" {
+		subscription.ConfirmationStatus = data.CONFIRMATION_CONFIRMED
+	}
+	return subscription
+}
+
+// The signing secret is only ever revealed on creation
+func NewSubscriptionWithSecret(entry data.SubscriptionDTO) Subscription {
+	subscription := NewSubscription(entry)
+	subscription.Secret = entry.Secret
+	return subscription
+}
+
+func NewDelivery(entry data.DeliveryDTO) Delivery {
+	delivery := Delivery{
 		Id:         entry.SK,
+		EventId:    entry.EventId,
+		EventType:  entry.EventType,
+		Endpoint:   entry.Endpoint,
+		Status:     entry.Status,
 		CreateTime: entry.CreateTime,
 		UpdateTime: entry.UpdateTime,
+		Attempts: *util.MapOnList(&entry.Attempts, func(ad data.DeliveryAttemptDTO) DeliveryAttempt {
+			return DeliveryAttempt{
+				StatusCode:  ad.StatusCode,
+				Error:       ad.Error,
+				AttemptTime: ad.AttemptTime,
+			}
+		}),
+	}
+	if entry.NextAttemptTime != nil {
+		next := time.Unix(int64(*entry.NextAttemptTime), 0)
+		delivery.NextAttemptTime = &next
 	}
+	return delivery
 }
@@ -0,0 +1,116 @@
+package templates
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/routes"
+	"philcali.me/recipes/internal/routes/shopping"
+	"philcali.me/recipes/internal/routes/util"
+	"philcali.me/recipes/internal/templates"
+	"philcali.me/recipes/internal/validation"
+)
+
+type ShoppingListTemplateService struct {
+	data         data.ShoppingListTemplateRepository
+	instantiator *templates.Instantiator
+}
+
+// Lists are made from templates on demand, the scheduler makes those that recur
+func NewRoute(data data.ShoppingListTemplateRepository, lists data.ShoppingListDataService) routes.Service {
+	return &ShoppingListTemplateService{
+		data: data,
+		instantiator: &templates.Instantiator{
+			Templates: data,
+			Lists:     lists,
+		},
+	}
+}
+
+func (ts *ShoppingListTemplateService) GetRoutes() map[string]routes.Route {
+	return map[string]routes.Route{
+		"GET:/templates":                    util.AuthorizedRoute(ts.ListTemplates),
+		"GET:/templates/:templateId":        util.AuthorizedRoute(ts.GetTemplate),
+		"POST:/templates":                   util.AuthorizedRoute(ts.CreateTemplate),
+		"PUT:/templates/:templateId":        util.AuthorizedRoute(ts.UpdateTemplate),
+		"DELETE:/templates/:templateId":     util.AuthorizedRoute(ts.DeleteTemplate),
+		"POST:/templates/:templateId/lists": util.AuthorizedRoute(ts.InstantiateTemplate),
+	}
+}
+
+func (ts *ShoppingListTemplateService) DescribeRoutes() map[string]routes.RouteDescription {
+	return map[string]routes.RouteDescription{
+		"GET:/templates": {
+			Summary: "Lists shopping list templates",
+			Output:  data.QueryResults[ShoppingListTemplate]{},
+			Query:   routes.LIST_QUERY,
+		},
+		"GET:/templates/:templateId": {
+			Summary: "Gets a shopping list template",
+			Output:  ShoppingListTemplate{},
+		},
+		"POST:/templates": {
+			Summary: "Creates a shopping list template, scheduling its first list when it recurs",
+			Input:   ShoppingListTemplateInput{},
+			Output:  ShoppingListTemplate{},
+		},
+		"PUT:/templates/:templateId": {
+			Summary: "Updates the fields of a template that are set, rescheduling it when its recurrence is set",
+			Input:   ShoppingListTemplateInput{},
+			Output:  ShoppingListTemplate{},
+		},
+		"DELETE:/templates/:templateId": {
+			Summary: "Deletes a shopping list template",
+		},
+		"POST:/templates/:templateId/lists": {
+			Summary: "Makes a shopping list from a template now, leaving its schedule alone",
+			Output:  shopping.ShoppingList{},
+		},
+	}
+}
+
+func (ts *ShoppingListTemplateService) ListTemplates(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
+	return util.SerializeList(ts.data, NewShoppingListTemplate, event, ctx)
+}
+
+func (ts *ShoppingListTemplateService) GetTemplate(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
+	item, err := ts.data.Get(util.Username(ctx), util.RequestParam(ctx, "templateId"))
+	return util.SerializeResponseOK(NewShoppingListTemplate, item, err)
+}
+
+func (ts *ShoppingListTemplateService) CreateTemplate(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
+	input := ShoppingListTemplateInput{}
+	if err := util.DecodeInput(event, &input, validation.CREATE); err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	claims := util.AuthorizationClaims(event)
+	created, err := ts.data.Create(util.Username(ctx), input.ToData(claims["email"], time.Now()))
+	return util.SerializeResponseOK(NewShoppingListTemplate, created, err)
+}
+
+func (ts *ShoppingListTemplateService) UpdateTemplate(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
+	input := ShoppingListTemplateInput{}
+	if err := util.DecodeInput(event, &input, validation.UPDATE); err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	claims := util.AuthorizationClaims(event)
+	item, err := ts.data.Update(util.Username(ctx), util.RequestParam(ctx, "templateId"), input.ToData(claims["email"], time.Now()))
+	return util.SerializeResponseOK(NewShoppingListTemplate, item, err)
+}
+
+func (ts *ShoppingListTemplateService) DeleteTemplate(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
+	err := ts.data.Delete(util.Username(ctx), util.RequestParam(ctx, "templateId"))
+	return util.SerializeResponseNoContent(err)
+}
+
+func (ts *ShoppingListTemplateService) InstantiateTemplate(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
+	accountId := util.Username(ctx)
+	template, err := ts.data.Get(accountId, util.RequestParam(ctx, "templateId"))
+	if err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	list, err := ts.instantiator.Instantiate(accountId, template, time.Now(), nil)
+	return util.SerializeResponseOK(shopping.NewShoppingList, list, err)
+}
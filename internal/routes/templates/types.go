@@ -0,0 +1,155 @@
+package templates
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/routes/shopping"
+	"philcali.me/recipes/internal/routes/util"
+	"philcali.me/recipes/internal/templates"
+	"philcali.me/recipes/internal/validation"
+)
+
+const (
+	MAX_INTERVAL      = 52
+	MAX_EXPIRES_AFTER = 365
+)
+
+// Runs at the hour of the day, 0 to 23, in the IANA time zone or UTC
+type Recurrence struct {
+	Frequency  *data.Frequency `json:"frequency"`
+	Interval   *int            `json:"interval,omitempty"`
+	DayOfWeek  *string         `json:"dayOfWeek,omitempty"`
+	DayOfMonth *int            `json:"dayOfMonth,omitempty"`
+	Hour       *int            `json:"hour,omitempty"`
+	TimeZone   *string         `json:"timeZone,omitempty"`
+}
+
+func _timeZone() validation.Rule {
+	return func(value reflect.Value) (string, string) {
+		if _, err := time.LoadLocation(value.String()); err != nil {
+			return validation.CODE_INVALID_VALUE, "must be an IANA time zone"
+		}
+		return "", ""
+	}
+}
+
+// Recurrences are replaced whole, so are validated as they are created
+func (r *Recurrence) Validate(op validation.Operation) error {
+	dayOfWeek := validation.Optional
+	if r.Frequency != nil && *r.Frequency == data.FREQUENCY_WEEKLY {
+		dayOfWeek = validation.Required
+	}
+	return validation.Validate(
+		validation.CREATE,
+		validation.Required("recurrence.frequency", r.Frequency, validation.OneOf(data.FREQUENCY_DAILY, data.FREQUENCY_WEEKLY, data.FREQUENCY_MONTHLY)),
+		validation.Optional("recurrence.interval", r.Interval, validation.Range(1, MAX_INTERVAL)),
+		dayOfWeek("recurrence.dayOfWeek", r.DayOfWeek, validation.OneOf(templates.WEEKDAYS...)),
+		validation.Optional("recurrence.dayOfMonth", r.DayOfMonth, validation.Range(1, templates.MAX_DAY_OF_MONTH)),
+		validation.Optional("recurrence.hour", r.Hour, validation.Range(0, 23)),
+		validation.Optional("recurrence.timeZone", r.TimeZone, _timeZone()),
+	)
+}
+
+func (r *Recurrence) ToData() data.RecurrenceDTO {
+	return data.RecurrenceDTO{
+		Frequency:  *r.Frequency,
+		Interval:   max(aws.ToInt(r.Interval), 1),
+		DayOfWeek:  r.DayOfWeek,
+		DayOfMonth: r.DayOfMonth,
+		Hour:       aws.ToInt(r.Hour),
+		TimeZone:   aws.ToString(r.TimeZone),
+	}
+}
+
+func NewRecurrence(rd data.RecurrenceDTO) *Recurrence {
+	return &Recurrence{
+		Frequency:  &rd.Frequency,
+		Interval:   &rd.Interval,
+		DayOfWeek:  rd.DayOfWeek,
+		DayOfMonth: rd.DayOfMonth,
+		Hour:       &rd.Hour,
+		TimeZone:   &rd.TimeZone,
+	}
+}
+
+type ShoppingListTemplateInput struct {
+	Name       *string                      `json:"name,omitempty"`
+	Items      *[]shopping.ShoppingListItem `json:"items,omitempty"`
+	Recurrence *Recurrence                  `json:"recurrence,omitempty"`
+	// Carries the items left unchecked on the last list over to the next
+	MergeUnchecked   *bool `json:"mergeUnchecked,omitempty"`
+	ExpiresAfterDays *int  `json:"expiresAfterDays,omitempty"`
+}
+
+func (t *ShoppingListTemplateInput) Validate(op validation.Operation) error {
+	fields := []validation.Field{
+		validation.Required("name", t.Name, validation.MaxLength(shopping.MAX_NAME_LENGTH)),
+		validation.Optional("items", t.Items, validation.MaxLength(shopping.MAX_ITEMS)),
+		validation.Optional("expiresAfterDays", t.ExpiresAfterDays, validation.Range(1, MAX_EXPIRES_AFTER)),
+	}
+	fields = append(fields, validation.Each("items", t.Items, func(i shopping.ShoppingListItem) []validation.Field {
+		return []validation.Field{
+			validation.Required("name", i.Name, validation.MaxLength(shopping.MAX_NAME_LENGTH)),
+			validation.Optional("category", i.Category, validation.MaxLength(shopping.MAX_NAME_LENGTH)),
+		}
+	})...)
+	if err := validation.Validate(op, fields...); err != nil || t.Recurrence == nil {
+		return err
+	}
+	return t.Recurrence.Validate(op)
+}
+
+// Templates with a recurrence are scheduled from now
+func (t *ShoppingListTemplateInput) ToData(owner string, now time.Time) data.ShoppingListTemplateInputDTO {
+	input := data.ShoppingListTemplateInputDTO{
+		Name:             t.Name,
+		Owner:            &owner,
+		Items:            util.MapOnList(t.Items, shopping.ConvertItemToData),
+		MergeUnchecked:   t.MergeUnchecked,
+		ExpiresAfterDays: t.ExpiresAfterDays,
+	}
+	if t.Recurrence != nil {
+		rule := t.Recurrence.ToData()
+		next, _ := templates.Next(rule, now)
+		input.Recurrence = &rule
+		input.NextRunTime = &next
+	}
+	return input
+}
+
+type ShoppingListTemplate struct {
+	Id               string                      `json:"templateId"`
+	Name             string                      `json:"name"`
+	Owner            *string                     `json:"owner"`
+	Items            []shopping.ShoppingListItem `json:"items"`
+	Recurrence       *Recurrence                 `json:"recurrence,omitempty"`
+	MergeUnchecked   bool                        `json:"mergeUnchecked"`
+	ExpiresAfterDays *int                        `json:"expiresAfterDays,omitempty"`
+	NextRunTime      *time.Time                  `json:"nextRunTime,omitempty"`
+	LastListId       *string                     `json:"lastListId,omitempty"`
+	CreateTime       time.Time                   `json:"createTime"`
+	UpdateTime       time.Time                   `json:"updateTime"`
+}
+
+func NewShoppingListTemplate(template data.ShoppingListTemplateDTO) ShoppingListTemplate {
+	var recurrence *Recurrence
+	if template.Recurrence != nil {
+		recurrence = NewRecurrence(*template.Recurrence)
+	}
+	return ShoppingListTemplate{
+		Id:               template.SK,
+		Name:             template.Name,
+		Owner:            template.Owner,
+		Items:            *util.MapOnList(&template.Items, shopping.ConvertItemDataToTransfer),
+		Recurrence:       recurrence,
+		MergeUnchecked:   template.MergeUnchecked,
+		ExpiresAfterDays: template.ExpiresAfterDays,
+		NextRunTime:      template.NextRunTime,
+		LastListId:       template.LastListId,
+		CreateTime:       template.CreateTime,
+		UpdateTime:       template.UpdateTime,
+	}
+}
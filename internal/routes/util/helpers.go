@@ -1,24 +1,31 @@
 package util
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/aws/aws-lambda-go/events"
 	"philcali.me/recipes/internal/data"
 	"philcali.me/recipes/internal/exceptions"
 	"philcali.me/recipes/internal/routes"
+	"philcali.me/recipes/internal/validation"
 )
 
+// Selects the household a request acts on
+const HOUSEHOLD_HEADER = "X-Household-Id"
+
+// Unset lists stay unset, so updates leave them alone
 func MapOnList[I interface{}, O interface{}](ls *[]I, thunk func(I) O) *[]O {
-	var result []O
-	if ls != nil {
-		result = make([]O, len(*ls))
-		for i, elem := range *ls {
-			result[i] = thunk(elem)
-		}
+	if ls == nil {
+		return nil
+	}
+	result := make([]O, len(*ls))
+	for i, elem := range *ls {
+		result[i] = thunk(elem)
 	}
 	return &result
 }
@@ -42,13 +49,132 @@ func AuthorizationClaims(event events.APIGatewayV2HTTPRequest) map[string]string
 	return nil
 }
 
+// Scopes granted by the lambda authorizer, false when the caller is unrestricted
+func AuthorizationScopes(event events.APIGatewayV2HTTPRequest) ([]data.Scope, bool) {
+	jwt := event.RequestContext.Authorizer.JWT
+	lambda := event.RequestContext.Authorizer.Lambda
+	if jwt != nil || lambda == nil {
+		return nil, false
+	}
+	collection, ok := lambda["scopes"].([]interface{})
+	if !ok {
+		return nil, false
+	}
+	scopes := make([]data.Scope, len(collection))
+	for i, scope := range collection {
+		scopes[i] = data.Scope(fmt.Sprintf("%v", scope))
+	}
+	return scopes, true
+}
+
+// Scopes can never be granted beyond what the caller itself holds
+func ValidateScopes(event events.APIGatewayV2HTTPRequest, scopes []data.Scope) error {
+	for _, scope := range scopes {
+		if err := scope.Validate(); err != nil {
+			return exceptions.InvalidInput(err.Error())
+		}
+	}
+	granted, restricted := AuthorizationScopes(event)
+	if !restricted {
+		return nil
+	}
+	if scope, uncovered := data.UncoveredScope(granted, scopes); uncovered {
+		return exceptions.Forbidden(fmt.Sprintf("Scope %s is broader than the scopes of the caller", scope))
+	}
+	return nil
+}
+
+// Decodes the body into input, validating inputs that describe their own rules
+func DecodeInput(event events.APIGatewayV2HTTPRequest, input any, op validation.Operation) error {
+	return _decode([]byte(event.Body), input, op, false)
+}
+
+// Strict decoding rejects fields the input does not have
+func _decode(body []byte, input any, op validation.Operation, strict bool) error {
+	var err error
+	if strict {
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(input)
+	} else {
+		err = json.Unmarshal(body, input)
+	}
+	if err != nil {
+		if te, ok := err.(*json.UnmarshalTypeError); ok && te.Field != "" {
+			return exceptions.Validation(exceptions.FieldError{
+				Field:   te.Field,
+				Code:    validation.CODE_INVALID_TYPE,
+				Message: fmt.Sprintf("%s cannot be a %s", te.Field, te.Value),
+			})
+		}
+		return exceptions.InvalidInput(fmt.Sprintf("Request body is not valid JSON: %s", err.Error()))
+	}
+	if validator, ok := input.(validation.Validator); ok {
+		return validator.Validate(op)
+	}
+	return nil
+}
+
+func _header(event events.APIGatewayV2HTTPRequest, name string) string {
+	for key, value := range event.Headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
+
+// Memberships are kept by the lower-cased email of the member, which access
+// tokens do not carry
+func Email(event events.APIGatewayV2HTTPRequest) string {
+	return strings.ToLower(AuthorizationClaims(event)["email"])
+}
+
+// Resolves the account a request acts on, callers own their account and act
+// on a household only through an active membership
+func ResolveMembership(event events.APIGatewayV2HTTPRequest, ctx context.Context, username string) (string, data.Role, error) {
+	household := _header(event, HOUSEHOLD_HEADER)
+	if household == "" || household == username {
+		return username, data.ROLE_OWNER, nil
+	}
+	memberships, ok := ctx.Value("Memberships").(data.MembershipRepository)
+	if !ok {
+		return "", "", exceptions.InvalidInput("Households are not supported")
+	}
+	email := Email(event)
+	if email == "" {
+		return "", "", exceptions.Forbidden("Households require a token carrying an email, ie: an ID token")
+	}
+	membership, err := memberships.Get(household, email)
+	if err != nil {
+		if _, ok := err.(*exceptions.NotFoundError); ok {
+			return "", "", exceptions.Forbidden(fmt.Sprintf("Not a member of household %s", household))
+		}
+		return "", "", err
+	}
+	if membership.Status != data.MEMBERSHIP_ACTIVE || membership.MemberId == nil || *membership.MemberId != username {
+		return "", "", exceptions.Forbidden(fmt.Sprintf("Not a member of household %s", household))
+	}
+	return household, membership.Role, nil
+}
+
 func AuthorizedRoute(route routes.Route) routes.Route {
 	return func(event events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
 		claims := AuthorizationClaims(event)
-		if username, ok := claims["username"]; ok {
-			return route(event, context.WithValue(ctx, "Username", username))
+		username, ok := claims["username"]
+		if !ok {
+			return events.APIGatewayV2HTTPResponse{}, exceptions.InternalServer("Unexpected internal error")
+		}
+		accountId, role, err := ResolveMembership(event, ctx, username)
+		if err != nil {
+			return events.APIGatewayV2HTTPResponse{}, err
+		}
+		if !role.Grants(event.RequestContext.HTTP.Method, event.RawPath) {
+			return events.APIGatewayV2HTTPResponse{}, exceptions.Forbidden(fmt.Sprintf("Role %s cannot %s %s", role, event.RequestContext.HTTP.Method, event.RawPath))
 		}
-		return events.APIGatewayV2HTTPResponse{}, exceptions.InternalServer("Unexpected internal error")
+		ctx = context.WithValue(ctx, "Username", accountId)
+		ctx = context.WithValue(ctx, "Member", username)
+		return route(event, context.WithValue(ctx, "Role", role))
 	}
 }
 
@@ -56,10 +182,20 @@ func RequestParam(ctx context.Context, param string) string {
 	return ctx.Value("Params").(map[string]string)[param]
 }
 
+// The account a request acts on, which is a household for its members
 func Username(ctx context.Context) string {
 	return ctx.Value("Username").(string)
 }
 
+// The user making the request, regardless of the household it acts on
+func Member(ctx context.Context) string {
+	return ctx.Value("Member").(string)
+}
+
+func Role(ctx context.Context) data.Role {
+	return ctx.Value("Role").(data.Role)
+}
+
 func SerializeResponse[T interface{}, R interface{}](delayed func(T) R, thing T, err error, statusCode int) (events.APIGatewayV2HTTPResponse, error) {
 	if err != nil {
 		return events.APIGatewayV2HTTPResponse{}, err
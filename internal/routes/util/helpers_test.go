@@ -0,0 +1,69 @@
+package util
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/exceptions"
+)
+
+type _memberships struct {
+	data.MembershipRepository
+	Items map[string]data.MembershipDTO
+}
+
+func (m *_memberships) Get(accountId string, itemId string) (data.MembershipDTO, error) {
+	membership, ok := m.Items[accountId+"/"+itemId]
+	if !ok {
+		return membership, exceptions.NotFound("membership", itemId)
+	}
+	return membership, nil
+}
+
+func _householdRequest(household string, claims map[string]string) events.APIGatewayV2HTTPRequest {
+	return events.APIGatewayV2HTTPRequest{
+		Headers: map[string]string{"x-household-id": household},
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			Authorizer: &events.APIGatewayV2HTTPRequestContextAuthorizerDescription{
+				JWT: &events.APIGatewayV2HTTPRequestContextAuthorizerJWTDescription{
+					Claims: claims,
+				},
+			},
+		},
+	}
+}
+
+func TestResolveMembership(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "Memberships", &_memberships{
+		Items: map[string]data.MembershipDTO{
+			"household/member@example.com": {
+				MemberId: aws.String("member"),
+				Role:     data.ROLE_EDITOR,
+				Status:   data.MEMBERSHIP_ACTIVE,
+			},
+		},
+	})
+
+	// Identity providers can keep the case of the email the member signed up with
+	event := _householdRequest("household", map[string]string{"username": "member", "email": "Member@Example.com"})
+	accountId, role, err := ResolveMembership(event, ctx, "member")
+	if err != nil || accountId != "household" || role != data.ROLE_EDITOR {
+		t.Fatalf("Expected a mixed-case email to resolve the membership, got %s %s: %v", accountId, role, err)
+	}
+
+	// Access tokens carry no email
+	event = _householdRequest("household", map[string]string{"username": "member"})
+	if _, _, err := ResolveMembership(event, ctx, "member"); err == nil {
+		t.Fatal("Expected a token without an email to be forbidden")
+	} else if _, ok := err.(*exceptions.ForbiddenError); !ok {
+		t.Fatalf("Expected a token without an email to be forbidden, got %v", err)
+	}
+
+	accountId, role, err = ResolveMembership(_householdRequest("", nil), ctx, "member")
+	if err != nil || accountId != "member" || role != data.ROLE_OWNER {
+		t.Fatalf("Expected callers to own their account, got %s %s: %v", accountId, role, err)
+	}
+}
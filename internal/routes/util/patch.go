@@ -0,0 +1,134 @@
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/exceptions"
+	"philcali.me/recipes/internal/patch"
+	"philcali.me/recipes/internal/validation"
+)
+
+// Inputs of patchable resources, validated whole once patched
+type PatchableInput[I interface{}, In interface{}] interface {
+	*In
+	validation.Validator
+	ToData(owner string) I
+}
+
+func _convert(from any, to any) error {
+	content, err := json.Marshal(from)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(content, to)
+}
+
+// Absent and null fields are the same to a patch
+func _document(input any) (map[string]any, error) {
+	document := make(map[string]any)
+	if err := _convert(input, &document); err != nil {
+		return nil, err
+	}
+	for field, value := range document {
+		if value == nil {
+			delete(document, field)
+		}
+	}
+	return document, nil
+}
+
+// Clears every field of the input not named by its JSON name
+func _select(input any, fields []string) {
+	value := reflect.ValueOf(input).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		name, _, _ := strings.Cut(value.Type().Field(i).Tag.Get("json"), ",")
+		if !slices.Contains(fields, name) {
+			value.Field(i).SetZero()
+		}
+	}
+}
+
+// Patches a resource with a merge patch or JSON Patch, depending on the content
+// type. The resource is patched as its input and validated whole, then only its
+// changes are written. Removed fields are removed by their JSON names, so those
+// must match their attribute names.
+func PatchResource[T interface{}, I interface{}, R interface{}, In interface{}, P PatchableInput[I, In]](
+	repo data.Repository[T, I],
+	event events.APIGatewayV2HTTPRequest,
+	ctx context.Context,
+	itemId string,
+	thunk func(T) R,
+	updateToken func(T) *string,
+	lists ...patch.List,
+) (events.APIGatewayV2HTTPResponse, error) {
+	contentType, _, _ := strings.Cut(_header(event, "Content-Type"), ";")
+	contentType = strings.ToLower(strings.TrimSpace(contentType))
+	if contentType != patch.MERGE_PATCH_CONTENT_TYPE && contentType != patch.JSON_PATCH_CONTENT_TYPE {
+		return events.APIGatewayV2HTTPResponse{}, exceptions.UnsupportedMediaType(contentType)
+	}
+	accountId := Username(ctx)
+	current, err := repo.Get(accountId, itemId)
+	if err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	var currentInput In
+	if err := _convert(thunk(current), &currentInput); err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	original, err := _document(currentInput)
+	if err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	var patched any
+	var operations []patch.Operation
+	if contentType == patch.MERGE_PATCH_CONTENT_TYPE {
+		var mergePatch any
+		if err := json.Unmarshal([]byte(event.Body), &mergePatch); err != nil {
+			return events.APIGatewayV2HTTPResponse{}, exceptions.InvalidInput(fmt.Sprintf("Request body is not valid JSON: %s", err.Error()))
+		}
+		patched = patch.Merge(original, mergePatch)
+	} else {
+		if err := json.Unmarshal([]byte(event.Body), &operations); err != nil {
+			return events.APIGatewayV2HTTPResponse{}, exceptions.InvalidInput(fmt.Sprintf("Request body is not a list of operations: %s", err.Error()))
+		}
+		if patched, err = patch.Apply(original, operations); err != nil {
+			return events.APIGatewayV2HTTPResponse{}, err
+		}
+	}
+	patchedDocument, ok := patched.(map[string]any)
+	if !ok {
+		return events.APIGatewayV2HTTPResponse{}, exceptions.InvalidInput("The patched document must be an object")
+	}
+	body, err := json.Marshal(patchedDocument)
+	if err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	var input In
+	if err := _decode(body, &input, validation.CREATE, true); err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	plan := patch.MergePlan(original, patchedDocument)
+	if contentType == patch.JSON_PATCH_CONTENT_TYPE {
+		plan = patch.JSONPlan(original, patchedDocument, operations, lists...)
+	}
+	_select(&input, plan.Set)
+	changes := plan.Changes
+	for _, field := range plan.Remove {
+		changes = append(changes, data.PatchChange{Path: field, Remove: true})
+	}
+	if token := updateToken(current); plan.Guarded && token != nil {
+		plan.Conditions["updateToken"] = *token
+	}
+	item, err := repo.Patch(accountId, itemId, P(&input).ToData(AuthorizationClaims(event)["email"]), data.Patch{
+		Changes:    changes,
+		Conditions: plan.Conditions,
+	})
+	return SerializeResponseOK(thunk, item, err)
+}
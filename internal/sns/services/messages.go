@@ -0,0 +1,34 @@
+package services
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"philcali.me/recipes/internal/notifications"
+)
+
+type MessageSNSChannel struct {
+	Sns      sns.Client
+	TopicArn string
+}
+
+func (m *MessageSNSChannel) Send(input notifications.MessageInput) error {
+	_, err := m.Sns.Publish(context.TODO(), &sns.PublishInput{
+		TopicArn: aws.String(m.TopicArn),
+		Subject:  aws.String(input.Subject),
+		Message:  aws.String(input.Body),
+		MessageAttributes: map[string]types.MessageAttributeValue{
+			"recipient": {
+				DataType:    aws.String("String"),
+				StringValue: aws.String(input.Recipient),
+			},
+			"type": {
+				DataType:    aws.String("String"),
+				StringValue: aws.String(input.Type),
+			},
+		},
+	})
+	return err
+}
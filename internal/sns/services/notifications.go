@@ -2,9 +2,12 @@ package services
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sns/types"
 	"philcali.me/recipes/internal/notifications"
 )
 
@@ -13,11 +16,37 @@ type NotificationSNSService struct {
 	TopicArn string
 }
 
+func _filterPolicy(input notifications.SubscribeInput) map[string][]string {
+	policy := map[string][]string{}
+	if input.AccountId != nil {
+		// Subscribers only ever receive events for their own account
+		policy["accountId"] = []string{*input.AccountId}
+	}
+	if len(input.EventTypes) > 0 {
+		policy["eventType"] = input.EventTypes
+	}
+	if len(input.ResourceTypes) > 0 {
+		policy["resourceType"] = input.ResourceTypes
+	}
+	return policy
+}
+
 func (n *NotificationSNSService) Subscribe(input notifications.SubscribeInput) (*notifications.SubscribeOutput, error) {
+	var attributes map[string]string
+	if policy := _filterPolicy(input); len(policy) > 0 {
+		content, err := json.Marshal(policy)
+		if err != nil {
+			return nil, err
+		}
+		attributes = map[string]string{
+			"FilterPolicy": string(content),
+		}
+	}
 	output, err := n.Sns.Subscribe(context.TODO(), &sns.SubscribeInput{
 		Endpoint:              input.Endpoint,
 		Protocol:              input.Protocol,
 		TopicArn:              aws.String(n.TopicArn),
+		Attributes:            attributes,
 		ReturnSubscriptionArn: true,
 	})
 
@@ -25,11 +54,48 @@ func (n *NotificationSNSService) Subscribe(input notifications.SubscribeInput) (
 		return nil, err
 	}
 
+	pending, err := n.IsPendingConfirmation(*output.SubscriptionArn)
+	if err != nil {
+		return nil, err
+	}
+
 	return &notifications.SubscribeOutput{
-		SubscriberId: *output.SubscriptionArn,
+		SubscriberId:        *output.SubscriptionArn,
+		PendingConfirmation: pending,
 	}, nil
 }
 
+func (n *NotificationSNSService) IsPendingConfirmation(subscriberId string) (bool, error) {
+	output, err := n.Sns.GetSubscriptionAttributes(context.TODO(), &sns.GetSubscriptionAttributesInput{
+		SubscriptionArn: aws.String(subscriberId),
+	})
+	var notFound *types.NotFoundException
+	if errors.As(err, &notFound) {
+		return false, notifications.ErrSubscriptionNotFound
+	}
+	if err != nil {
+		return false, err
+	}
+	return output.Attributes["PendingConfirmation"] == "true", nil
+}
+
+func (n *NotificationSNSService) SetFilter(subscriberId string, input notifications.SubscribeInput) error {
+	content, err := json.Marshal(_filterPolicy(input))
+	if err != nil {
+		return err
+	}
+	_, err = n.Sns.SetSubscriptionAttributes(context.TODO(), &sns.SetSubscriptionAttributesInput{
+		SubscriptionArn: aws.String(subscriberId),
+		AttributeName:   aws.String("FilterPolicy"),
+		AttributeValue:  aws.String(string(content)),
+	})
+	var notFound *types.NotFoundException
+	if errors.As(err, &notFound) {
+		return notifications.ErrSubscriptionNotFound
+	}
+	return err
+}
+
 func (n *NotificationSNSService) Unsubscribe(subscriberId string) error {
 	_, err := n.Sns.Unsubscribe(context.TODO(), &sns.UnsubscribeInput{
 		SubscriptionArn: aws.String(subscriberId),
@@ -37,3 +103,36 @@ func (n *NotificationSNSService) Unsubscribe(subscriberId string) error {
 
 	return err
 }
+
+func (n *NotificationSNSService) Publish(input notifications.PublishInput) (*notifications.PublishOutput, error) {
+	output, err := n.Sns.Publish(context.TODO(), &sns.PublishInput{
+		TopicArn: aws.String(n.TopicArn),
+		Message:  aws.String(input.Message),
+		MessageAttributes: map[string]types.MessageAttributeValue{
+			"accountId": {
+				DataType:    aws.String("String"),
+				StringValue: aws.String(input.AccountId),
+			},
+			"eventType": {
+				DataType:    aws.String("String"),
+				StringValue: aws.String(input.EventType),
+			},
+			"resourceType": {
+				DataType:    aws.String("String"),
+				StringValue: aws.String(input.ResourceType),
+			},
+			"resourceId": {
+				DataType:    aws.String("String"),
+				StringValue: aws.String(input.ResourceId),
+			},
+		},
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &notifications.PublishOutput{
+		MessageId: *output.MessageId,
+	}, nil
+}
@@ -0,0 +1,154 @@
+package templates
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/google/uuid"
+	"philcali.me/recipes/internal/aisles"
+	"philcali.me/recipes/internal/data"
+	"philcali.me/recipes/internal/exceptions"
+)
+
+// Makes shopping lists from templates
+type Instantiator struct {
+	Templates data.ShoppingListTemplateRepository
+	Lists     data.ShoppingListDataService
+}
+
+// Items of the template, followed by those left unchecked on the previous list
+// that the template does not already have. Every item is given a new id.
+func MergeItems(template []data.ShoppingListItemDTO, previous []data.ShoppingListItemDTO) []data.ShoppingListItemDTO {
+	items := make([]data.ShoppingListItemDTO, 0, len(template)+len(previous))
+	names := make([]string, 0, len(template)+len(previous))
+	add := func(item data.ShoppingListItemDTO) {
+		item.Id = uuid.NewString()
+		item.Completed = false
+		items = append(items, item)
+		names = append(names, aisles.Normalize(item.Name))
+	}
+	for _, item := range template {
+		add(item)
+	}
+	for _, item := range previous {
+		if !item.Completed && !slices.Contains(names, aisles.Normalize(item.Name)) {
+			add(item)
+		}
+	}
+	return items
+}
+
+// Lists of a scheduled run are named by it, so a repeated run finds the list
+// it already made rather than making another
+func _listId(template data.ShoppingListTemplateDTO, runTime time.Time) string {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(template.SK+"@"+runTime.UTC().Format(time.RFC3339))).String()
+}
+
+func (in *Instantiator) _previousItems(accountId string, template data.ShoppingListTemplateDTO) ([]data.ShoppingListItemDTO, error) {
+	if !template.MergeUnchecked || template.LastListId == nil {
+		return nil, nil
+	}
+	previous, err := in.Lists.Get(accountId, *template.LastListId)
+	if err != nil {
+		// The previous list may have expired or been deleted
+		if _, ok := err.(*exceptions.NotFoundError); ok {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return previous.Items, nil
+}
+
+// Makes a list for the run of a template, and records it as the template's
+// last list. The next run time is left to the caller.
+func (in *Instantiator) Instantiate(accountId string, template data.ShoppingListTemplateDTO, runTime time.Time, next *time.Time) (data.ShoppingListDTO, error) {
+	previous, err := in._previousItems(accountId, template)
+	if err != nil {
+		return data.ShoppingListDTO{}, err
+	}
+	items := MergeItems(template.Items, previous)
+	input := data.ShoppingListInputDTO{
+		Name:        aws.String(template.Name),
+		Owner:       template.Owner,
+		UpdateToken: aws.String(uuid.NewString()),
+		Items:       &items,
+	}
+	if template.ExpiresAfterDays != nil {
+		input.ExpiresIn = aws.Int(int(runTime.AddDate(0, 0, *template.ExpiresAfterDays).Unix()))
+	}
+	listId := _listId(template, runTime)
+	list, err := in.Lists.CreateWithItemId(accountId, input, listId)
+	if _, ok := err.(*exceptions.ConflictError); ok {
+		list, err = in.Lists.Get(accountId, listId)
+	}
+	if err != nil {
+		return list, err
+	}
+	changes := data.ShoppingListTemplateInputDTO{
+		LastListId:  aws.String(listId),
+		NextRunTime: next,
+	}
+	patch := data.Patch{}
+	if next != nil && template.NextRunTime != nil {
+		// Only one run advances the template
+		patch.Conditions = map[string]any{"nextRunTime": *template.NextRunTime}
+	}
+	_, err = in.Templates.Patch(accountId, template.SK, changes, patch)
+	return list, err
+}
+
+// Instantiates the due templates of every account, found in the index where
+// they are kept under one account
+type Scheduler struct {
+	Instantiator
+	IndexAccount string
+	IndexName    string
+}
+
+func (s *Scheduler) _due(template data.ShoppingListTemplateDTO, now time.Time) bool {
+	return template.Recurrence != nil && template.NextRunTime != nil && !template.NextRunTime.After(now)
+}
+
+// Runs every due template, a template failing leaves the others to run. Returns
+// the lists that were made.
+func (s *Scheduler) Run(now time.Time) ([]data.ShoppingListDTO, error) {
+	var lists []data.ShoppingListDTO
+	var errs []error
+	params := data.QueryParams{}
+	for {
+		results, err := s.Templates.ListByIndex(s.IndexAccount, s.IndexName, params)
+		if err != nil {
+			return lists, errors.Join(append(errs, err)...)
+		}
+		for _, template := range results.Items {
+			if !s._due(template, now) {
+				continue
+			}
+			accountId, _, _ := strings.Cut(template.PK, ":")
+			next, err := Following(*template.Recurrence, *template.NextRunTime, now)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("template %s: %w", template.SK, err))
+				continue
+			}
+			list, err := s.Instantiate(accountId, template, *template.NextRunTime, &next)
+			if _, ok := err.(*exceptions.ConflictError); ok {
+				// Another run advanced the template first
+				continue
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("template %s: %w", template.SK, err))
+				continue
+			}
+			lists = append(lists, list)
+		}
+		if results.NextToken == nil {
+			break
+		}
+		params.NextToken = results.NextToken
+	}
+	return lists, errors.Join(errs...)
+}
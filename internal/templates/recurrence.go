@@ -0,0 +1,75 @@
+package templates
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"philcali.me/recipes/internal/data"
+)
+
+const MAX_DAY_OF_MONTH = 28
+
+// Weekday names as they are written in rules, ie: Sunday
+var WEEKDAYS = []string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"}
+
+func ParseWeekday(name string) (time.Weekday, error) {
+	for i, weekday := range WEEKDAYS {
+		if strings.EqualFold(weekday, name) {
+			return time.Weekday(i), nil
+		}
+	}
+	return time.Sunday, fmt.Errorf("%s is not a day of the week", name)
+}
+
+// The first run of the rule strictly after the given time
+func Next(rule data.RecurrenceDTO, after time.Time) (time.Time, error) {
+	location, err := time.LoadLocation(rule.TimeZone)
+	if err != nil {
+		return after, err
+	}
+	local := after.In(location)
+	next := time.Date(local.Year(), local.Month(), local.Day(), rule.Hour, 0, 0, 0, location)
+	step := func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }
+	switch rule.Frequency {
+	case data.FREQUENCY_DAILY:
+	case data.FREQUENCY_WEEKLY:
+		weekday, err := ParseWeekday(aws.ToString(rule.DayOfWeek))
+		if err != nil {
+			return after, err
+		}
+		next = next.AddDate(0, 0, (int(weekday)-int(next.Weekday())+7)%7)
+		step = func(t time.Time) time.Time { return t.AddDate(0, 0, 7) }
+	case data.FREQUENCY_MONTHLY:
+		day := 1
+		if rule.DayOfMonth != nil {
+			day = min(max(*rule.DayOfMonth, 1), MAX_DAY_OF_MONTH)
+		}
+		next = time.Date(local.Year(), local.Month(), day, rule.Hour, 0, 0, 0, location)
+		step = func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }
+	default:
+		return after, fmt.Errorf("%s is not a frequency", rule.Frequency)
+	}
+	for !next.After(after) {
+		next = step(next)
+	}
+	return next, nil
+}
+
+// The run following a scheduled one, skipping the runs between intervals.
+// Runs missed since then are skipped too, so a late scheduler makes one list.
+func Following(rule data.RecurrenceDTO, scheduled time.Time, now time.Time) (time.Time, error) {
+	next := scheduled
+	for {
+		var err error
+		for i := 0; i < max(rule.Interval, 1); i++ {
+			if next, err = Next(rule, next); err != nil {
+				return next, err
+			}
+		}
+		if next.After(now) {
+			return next, nil
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package templates
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"philcali.me/recipes/internal/data"
+)
+
+func _time(t *testing.T, value string) time.Time {
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t.Fatalf("Failed to parse %s: %v", value, err)
+	}
+	return parsed
+}
+
+func TestNext(t *testing.T) {
+	weekly := data.RecurrenceDTO{
+		Frequency: data.FREQUENCY_WEEKLY,
+		DayOfWeek: aws.String("sunday"),
+		Hour:      8,
+		TimeZone:  "America/Los_Angeles",
+	}
+	for _, test := range []struct {
+		rule     data.RecurrenceDTO
+		after    string
+		expected string
+	}{
+		// Wednesday to the following Sunday
+		{weekly, "2024-05-01T12:00:00Z", "2024-05-05T15:00:00Z"},
+		// Sunday morning, before and after the hour
+		{weekly, "2024-05-05T14:00:00Z", "2024-05-05T15:00:00Z"},
+		{weekly, "2024-05-05T15:00:00Z", "2024-05-12T15:00:00Z"},
+		// Daylight saving ends between the runs
+		{weekly, "2024-10-30T00:00:00Z", "2024-11-03T16:00:00Z"},
+		{data.RecurrenceDTO{Frequency: data.FREQUENCY_DAILY, Hour: 6}, "2024-05-01T07:00:00Z", "2024-05-02T06:00:00Z"},
+		{data.RecurrenceDTO{Frequency: data.FREQUENCY_MONTHLY, DayOfMonth: aws.Int(15)}, "2024-12-20T00:00:00Z", "2025-01-15T00:00:00Z"},
+	} {
+		next, err := Next(test.rule, _time(t, test.after))
+		if err != nil {
+			t.Fatalf("Failed to find the run after %s: %v", test.after, err)
+		}
+		if !next.Equal(_time(t, test.expected)) {
+			t.Fatalf("Expected the run after %s to be %s, got %s", test.after, test.expected, next.UTC())
+		}
+	}
+	for _, rule := range []data.RecurrenceDTO{
+		{Frequency: data.FREQUENCY_WEEKLY, DayOfWeek: aws.String("Someday")},
+		{Frequency: data.FREQUENCY_DAILY, TimeZone: "Nowhere/Special"},
+		{Frequency: "HOURLY"},
+	} {
+		if _, err := Next(rule, time.Now()); err == nil {
+			t.Fatalf("Expected %v to fail", rule)
+		}
+	}
+}
+
+func TestFollowing(t *testing.T) {
+	biweekly := data.RecurrenceDTO{
+		Frequency: data.FREQUENCY_WEEKLY,
+		Interval:  2,
+		DayOfWeek: aws.String("Sunday"),
+	}
+	scheduled := _time(t, "2024-05-05T00:00:00Z")
+	next, err := Following(biweekly, scheduled, scheduled)
+	if err != nil || !next.Equal(_time(t, "2024-05-19T00:00:00Z")) {
+		t.Fatalf("Expected the run two weeks on, got %s: %v", next, err)
+	}
+	next, err = Following(biweekly, scheduled, _time(t, "2024-06-01T00:00:00Z"))
+	if err != nil || !next.Equal(_time(t, "2024-06-02T00:00:00Z")) {
+		t.Fatalf("Expected the missed runs to be skipped, got %s: %v", next, err)
+	}
+}
+
+func TestMergeItems(t *testing.T) {
+	template := []data.ShoppingListItemDTO{
+		{Id: "a", Name: "Milk", Amount: 1},
+		{Id: "b", Name: "Bread", Amount: 1},
+	}
+	previous := []data.ShoppingListItemDTO{
+		{Id: "c", Name: "milk", Completed: true},
+		{Id: "d", Name: " bread ", Completed: false},
+		{Id: "e", Name: "Eggs", Completed: false},
+		{Id: "f", Name: "Jam", Completed: true},
+	}
+	items := MergeItems(template, previous)
+	if len(items) != 3 || items[0].Name != "Milk" || items[1].Name != "Bread" || items[2].Name != "Eggs" {
+		t.Fatalf("Expected the template items and the unchecked eggs, got %v", items)
+	}
+	for _, item := range items {
+		if item.Completed || item.Id == "" || item.Id == "a" || item.Id == "e" {
+			t.Fatalf("Expected a new, unchecked item, got %v", item)
+		}
+	}
+}
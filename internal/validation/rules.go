@@ -0,0 +1,221 @@
+package validation
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+
+	"philcali.me/recipes/internal/exceptions"
+)
+
+type Operation int
+
+const (
+	CREATE Operation = iota
+	UPDATE
+)
+
+// Field failure codes, the request as a whole fails with VALIDATION_FAILED
+const (
+	CODE_REQUIRED      = "REQUIRED"
+	CODE_TOO_LONG      = "TOO_LONG"
+	CODE_TOO_SHORT     = "TOO_SHORT"
+	CODE_OUT_OF_RANGE  = "OUT_OF_RANGE"
+	CODE_INVALID_VALUE = "INVALID_VALUE"
+	CODE_INVALID_TYPE  = "INVALID_TYPE"
+)
+
+// Inputs describing their own rules are validated as they are decoded
+type Validator interface {
+	Validate(op Operation) error
+}
+
+// Checks a present value, returning the code and message of a failure or
+// an empty code when the value is valid
+type Rule func(value reflect.Value) (string, string)
+
+type Field struct {
+	Name     string
+	Value    any
+	Required bool
+	Rules    []Rule
+}
+
+// Required fields must be present on create, and never blank when present
+func Required(name string, value any, rules ...Rule) Field {
+	return Field{
+		Name:     name,
+		Value:    value,
+		Required: true,
+		Rules:    rules,
+	}
+}
+
+func Optional(name string, value any, rules ...Rule) Field {
+	return Field{
+		Name:  name,
+		Value: value,
+		Rules: rules,
+	}
+}
+
+// Validates every item of a list, naming fields after their position, ie: ingredients[0].name
+func Each[T any](name string, items *[]T, fields func(T) []Field) []Field {
+	if items == nil {
+		return nil
+	}
+	var rtn []Field
+	for i, item := range *items {
+		for _, field := range fields(item) {
+			field.Name = fmt.Sprintf("%s[%d].%s", name, i, field.Name)
+			rtn = append(rtn, field)
+		}
+	}
+	return rtn
+}
+
+func _resolve(value any) (reflect.Value, bool) {
+	resolved := reflect.ValueOf(value)
+	for resolved.IsValid() && (resolved.Kind() == reflect.Pointer || resolved.Kind() == reflect.Interface) {
+		if resolved.IsNil() {
+			return resolved, false
+		}
+		resolved = resolved.Elem()
+	}
+	return resolved, resolved.IsValid()
+}
+
+func _isBlank(value reflect.Value) bool {
+	return value.Kind() == reflect.String && strings.TrimSpace(value.String()) == ""
+}
+
+// Collects the failure of every field, so clients can correct them all at once
+func Validate(op Operation, fields ...Field) error {
+	var failures []exceptions.FieldError
+	for _, field := range fields {
+		value, present := _resolve(field.Value)
+		if field.Required && ((!present && op == CREATE) || (present && _isBlank(value))) {
+			failures = append(failures, exceptions.FieldError{
+				Field:   field.Name,
+				Code:    CODE_REQUIRED,
+				Message: fmt.Sprintf("%s is required", field.Name),
+			})
+			continue
+		}
+		if !present {
+			continue
+		}
+		for _, rule := range field.Rules {
+			if code, message := rule(value); code != "" {
+				failures = append(failures, exceptions.FieldError{
+					Field:   field.Name,
+					Code:    code,
+					Message: fmt.Sprintf("%s %s", field.Name, message),
+				})
+				break
+			}
+		}
+	}
+	if len(failures) > 0 {
+		return exceptions.Validation(failures...)
+	}
+	return nil
+}
+
+func _length(value reflect.Value) int {
+	if value.Kind() == reflect.String {
+		return len([]rune(value.String()))
+	}
+	return value.Len()
+}
+
+func _unit(value reflect.Value) string {
+	if value.Kind() == reflect.String {
+		return "characters"
+	}
+	return "items"
+}
+
+// Applies to strings, counted in characters, and lists, counted in items
+func MaxLength(max int) Rule {
+	return func(value reflect.Value) (string, string) {
+		if _length(value) > max {
+			return CODE_TOO_LONG, fmt.Sprintf("must be at most %d %s", max, _unit(value))
+		}
+		return "", ""
+	}
+}
+
+func MinLength(min int) Rule {
+	return func(value reflect.Value) (string, string) {
+		if _length(value) < min {
+			return CODE_TOO_SHORT, fmt.Sprintf("must be at least %d %s", min, _unit(value))
+		}
+		return "", ""
+	}
+}
+
+func Range(min float64, max float64) Rule {
+	return func(value reflect.Value) (string, string) {
+		var number float64
+		switch {
+		case value.CanInt():
+			number = float64(value.Int())
+		case value.CanUint():
+			number = float64(value.Uint())
+		case value.CanFloat():
+			number = value.Float()
+		}
+		if number < min || number > max {
+			return CODE_OUT_OF_RANGE, fmt.Sprintf("must be between %v and %v", min, max)
+		}
+		return "", ""
+	}
+}
+
+// Values of string types, or every value of a list of them, must be one of allowed
+func OneOf[T ~string](allowed ...T) Rule {
+	return func(value reflect.Value) (string, string) {
+		values := []reflect.Value{value}
+		if value.Kind() == reflect.Slice {
+			values = values[:0]
+			for i := 0; i < value.Len(); i++ {
+				values = append(values, value.Index(i))
+			}
+		}
+		for _, v := range values {
+			if !slices.Contains(allowed, T(v.String())) {
+				names := make([]string, len(allowed))
+				for i, a := range allowed {
+					names[i] = string(a)
+				}
+				return CODE_INVALID_VALUE, fmt.Sprintf("must be one of %s", strings.Join(names, ", "))
+			}
+		}
+		return "", ""
+	}
+}
+
+// Values must be absolute URLs with one of the schemes
+func URL(schemes ...string) Rule {
+	return func(value reflect.Value) (string, string) {
+		parsed, err := url.Parse(value.String())
+		if err != nil || parsed.Host == "" || !slices.Contains(schemes, parsed.Scheme) {
+			return CODE_INVALID_VALUE, fmt.Sprintf("must be a %s URL", strings.Join(schemes, " or "))
+		}
+		return "", ""
+	}
+}
+
+// Values must contain an @ with something on either side
+func Email() Rule {
+	return func(value reflect.Value) (string, string) {
+		local, domain, ok := strings.Cut(value.String(), "@")
+		if !ok || local == "" || domain == "" || strings.ContainsAny(value.String(), " \t") {
+			return CODE_INVALID_VALUE, "must be an email address"
+		}
+		return "", ""
+	}
+}
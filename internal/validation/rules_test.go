@@ -0,0 +1,120 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"philcali.me/recipes/internal/exceptions"
+)
+
+type _item struct {
+	Name string
+}
+
+type _input struct {
+	Name     *string
+	Email    *string
+	Servings *int
+	Kind     *string
+	Items    *[]_item
+}
+
+func (i *_input) Validate(op Operation) error {
+	fields := []Field{
+		Required("name", i.Name, MaxLength(5)),
+		Optional("email", i.Email, Email()),
+		Optional("servings", i.Servings, Range(1, 10)),
+		Optional("kind", i.Kind, OneOf("Food", "Drink")),
+		Optional("items", i.Items, MaxLength(2)),
+	}
+	fields = append(fields, Each("items", i.Items, func(item _item) []Field {
+		return []Field{Required("name", item.Name)}
+	})...)
+	return Validate(op, fields...)
+}
+
+func _codes(t *testing.T, err error) map[string]string {
+	if err == nil {
+		return nil
+	}
+	ve, ok := err.(*exceptions.ValidationError)
+	if !ok {
+		t.Fatalf("Expected a validation error, got %v", err)
+	}
+	codes := make(map[string]string, len(ve.Fields))
+	for _, field := range ve.Fields {
+		codes[field.Field] = field.Code
+	}
+	return codes
+}
+
+func TestValidate(t *testing.T) {
+	t.Run("Required", func(t *testing.T) {
+		input := _input{}
+		if codes := _codes(t, input.Validate(CREATE)); codes["name"] != CODE_REQUIRED {
+			t.Fatalf("Expected name to be required on create, got %v", codes)
+		}
+		if err := input.Validate(UPDATE); err != nil {
+			t.Fatalf("Expected missing fields to be left alone on update, got %v", err)
+		}
+		input.Name = aws.String("  ")
+		if codes := _codes(t, input.Validate(UPDATE)); codes["name"] != CODE_REQUIRED {
+			t.Fatalf("Expected a blank name to be required on update, got %v", codes)
+		}
+	})
+
+	t.Run("Rules", func(t *testing.T) {
+		input := _input{
+			Name:     aws.String("Too long"),
+			Email:    aws.String("nobody"),
+			Servings: aws.Int(0),
+			Kind:     aws.String("Snack"),
+			Items:    &[]_item{{Name: "bread"}, {}, {Name: "eggs"}},
+		}
+		expected := map[string]string{
+			"name":          CODE_TOO_LONG,
+			"email":         CODE_INVALID_VALUE,
+			"servings":      CODE_OUT_OF_RANGE,
+			"kind":          CODE_INVALID_VALUE,
+			"items":         CODE_TOO_LONG,
+			"items[1].name": CODE_REQUIRED,
+		}
+		codes := _codes(t, input.Validate(CREATE))
+		if len(codes) != len(expected) {
+			t.Fatalf("Expected failures %v, got %v", expected, codes)
+		}
+		for field, code := range expected {
+			if codes[field] != code {
+				t.Fatalf("Expected %s to fail with %s, got %s", field, code, codes[field])
+			}
+		}
+	})
+
+	t.Run("Valid", func(t *testing.T) {
+		input := _input{
+			Name:     aws.String("Soup"),
+			Email:    aws.String("nobody@email.com"),
+			Servings: aws.Int(2),
+			Kind:     aws.String("Food"),
+			Items:    &[]_item{{Name: "beans"}},
+		}
+		if err := input.Validate(CREATE); err != nil {
+			t.Fatalf("Expected a valid input, got %v", err)
+		}
+	})
+}
+
+func TestErrorResponse(t *testing.T) {
+	err := Validate(CREATE, Required("name", (*string)(nil)))
+	statusCode, response := exceptions.NewErrorResponse(err, "request-1")
+	if statusCode != 400 || response.Code != exceptions.CODE_VALIDATION_FAILED {
+		t.Fatalf("Expected a 400 validation failure, got %d %s", statusCode, response.Code)
+	}
+	if len(response.Details) != 1 || response.Details[0].Field != "name" || response.RequestId != "request-1" {
+		t.Fatalf("Unexpected response %v", response)
+	}
+	statusCode, response = exceptions.NewErrorResponse(exceptions.NotFound("recipe", `"quoted"`), "")
+	if statusCode != 404 || response.Code != exceptions.CODE_NOT_FOUND || response.Details != nil {
+		t.Fatalf("Unexpected response %d %v", statusCode, response)
+	}
+}
@@ -0,0 +1,187 @@
+package webhooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	SIGNATURE_HEADER = "X-Recipes-Signature"
+	TIMESTAMP_HEADER = "X-Recipes-Timestamp"
+	DELIVERY_HEADER  = "X-Recipes-Delivery"
+	EVENT_HEADER     = "X-Recipes-Event"
+)
+
+func GenerateSecret() (string, error) {
+	randomBytes := make([]byte, 32)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(randomBytes), nil
+}
+
+// Signs the timestamp and payload, so a captured request cannot be replayed
+// with a different timestamp
+func Sign(secret string, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func Verify(secret string, timestamp string, payload []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, payload)), []byte(signature))
+}
+
+type DeliveryRequest struct {
+	Id        string
+	Endpoint  string
+	Secret    string
+	EventType string
+	Payload   []byte
+}
+
+type DeliveryAttempt struct {
+	StatusCode  int
+	Error       error
+	AttemptTime time.Time
+}
+
+var ErrPrivateEndpoint = errors.New("webhook endpoints must not be loopback, link-local or private hosts")
+
+// Addresses on the service's own networks, which webhooks may not reach
+func IsPrivateAddress(ip net.IP) bool {
+	return ip.IsLoopback() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsPrivate() ||
+		ip.IsUnspecified()
+}
+
+// Endpoints must be https, and not name a private host. Host names are
+// resolved when delivered, where the dialer refuses private addresses.
+func ValidateEndpoint(endpoint string) error {
+	parsed, err := url.Parse(endpoint)
+	if err != nil || parsed.Scheme != "https" || parsed.Hostname() == "" {
+		return errors.New("webhook endpoints must be an https URL")
+	}
+	host := strings.ToLower(parsed.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrPrivateEndpoint
+	}
+	if ip := net.ParseIP(host); ip != nil && IsPrivateAddress(ip) {
+		return ErrPrivateEndpoint
+	}
+	return nil
+}
+
+func _refusePrivate(network string, address string, conn syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || IsPrivateAddress(ip) {
+		return ErrPrivateEndpoint
+	}
+	return nil
+}
+
+// Deliveries are attempted once as they happen, then retried by the scheduler
+// with exponential backoff until MaxAttempts are made
+type Deliverer struct {
+	Client      *http.Client
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+func NewDeliverer() *Deliverer {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: _refusePrivate,
+	}
+	return &Deliverer{
+		Client: &http.Client{
+			Timeout: 5 * time.Second,
+			Transport: &http.Transport{
+				DialContext:         dialer.DialContext,
+				TLSHandshakeTimeout: 5 * time.Second,
+			},
+		},
+		MaxAttempts: 5,
+		BaseDelay:   5 * time.Minute,
+		MaxDelay:    6 * time.Hour,
+	}
+}
+
+func (d *Deliverer) Backoff(attempt int) time.Duration {
+	delay := d.BaseDelay << attempt
+	if delay <= 0 || delay > d.MaxDelay {
+		return d.MaxDelay
+	}
+	return delay
+}
+
+func _retryable(statusCode int) bool {
+	return statusCode == 0 ||
+		statusCode == http.StatusRequestTimeout ||
+		statusCode == http.StatusTooManyRequests ||
+		statusCode >= 500
+}
+
+func (d *Deliverer) attempt(request DeliveryRequest) DeliveryAttempt {
+	now := time.Now()
+	attempt := DeliveryAttempt{AttemptTime: now}
+	req, err := http.NewRequest(http.MethodPost, request.Endpoint, bytes.NewReader(request.Payload))
+	if err != nil {
+		attempt.Error = err
+		return attempt
+	}
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TIMESTAMP_HEADER, timestamp)
+	req.Header.Set(DELIVERY_HEADER, request.Id)
+	req.Header.Set(EVENT_HEADER, request.EventType)
+	req.Header.Set(SIGNATURE_HEADER, Sign(request.Secret, timestamp, request.Payload))
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		attempt.Error = err
+		return attempt
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	attempt.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		attempt.Error = fmt.Errorf("endpoint responded with %d", resp.StatusCode)
+	}
+	return attempt
+}
+
+// Makes one attempt after the previous ones, returning when the next attempt
+// is due, or nil when the endpoint accepted the payload or will not
+func (d *Deliverer) Deliver(request DeliveryRequest, previous int) (DeliveryAttempt, *time.Time) {
+	attempt := d.attempt(request)
+	if attempt.Error == nil || !_retryable(attempt.StatusCode) || previous+1 >= d.MaxAttempts {
+		return attempt, nil
+	}
+	// Private hosts stay private
+	if errors.Is(attempt.Error, ErrPrivateEndpoint) {
+		return attempt, nil
+	}
+	next := attempt.AttemptTime.Add(d.Backoff(previous))
+	return attempt, &next
+}
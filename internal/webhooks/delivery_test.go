@@ -0,0 +1,134 @@
+package webhooks_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"philcali.me/recipes/internal/webhooks"
+)
+
+func TestDeliverer(t *testing.T) {
+	secret, err := webhooks.GenerateSecret()
+	if err != nil {
+		t.Fatalf("Failed to generate secret: %s", err)
+	}
+	payload := []byte(`{"type":"recipe.created"}`)
+	deliverer := webhooks.NewDeliverer()
+	// Test servers listen on loopback, which the default client refuses
+	deliverer.Client = &http.Client{Timeout: time.Second}
+
+	t.Run("SignedAttempt", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			timestamp := r.Header.Get(webhooks.TIMESTAMP_HEADER)
+			if !webhooks.Verify(secret, timestamp, body, r.Header.Get(webhooks.SIGNATURE_HEADER)) {
+				t.Error("Invalid signature")
+			}
+			if r.Header.Get(webhooks.DELIVERY_HEADER) != "delivery-1" {
+				t.Errorf("Expected delivery header, got %s", r.Header.Get(webhooks.DELIVERY_HEADER))
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		attempt, next := deliverer.Deliver(webhooks.DeliveryRequest{
+			Id:        "delivery-1",
+			Endpoint:  server.URL,
+			Secret:    secret,
+			EventType: "recipe.created",
+			Payload:   payload,
+		}, 0)
+		if attempt.Error != nil || next != nil {
+			t.Fatalf("Expected delivery to succeed, got %v", attempt)
+		}
+	})
+
+	t.Run("Backoff", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		request := webhooks.DeliveryRequest{
+			Id:       "delivery-2",
+			Endpoint: server.URL,
+			Secret:   secret,
+			Payload:  payload,
+		}
+		for previous, expected := range []time.Duration{5 * time.Minute, 10 * time.Minute} {
+			attempt, next := deliverer.Deliver(request, previous)
+			if attempt.StatusCode != http.StatusServiceUnavailable || attempt.Error == nil {
+				t.Fatalf("Expected the attempt to record the failure, got %v", attempt)
+			}
+			if next == nil || next.Sub(attempt.AttemptTime) != expected {
+				t.Fatalf("Expected the next attempt after %v, got %v", expected, next)
+			}
+		}
+		if _, next := deliverer.Deliver(request, deliverer.MaxAttempts-1); next != nil {
+			t.Fatalf("Expected the last attempt to dead-letter, got %v", next)
+		}
+	})
+
+	t.Run("PermanentFailure", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusGone)
+		}))
+		defer server.Close()
+
+		attempt, next := deliverer.Deliver(webhooks.DeliveryRequest{
+			Id:       "delivery-3",
+			Endpoint: server.URL,
+			Secret:   secret,
+			Payload:  payload,
+		}, 0)
+		if attempt.Error == nil || next != nil {
+			t.Fatalf("Expected a failed attempt without a retry, got %v", attempt)
+		}
+	})
+
+	t.Run("PrivateEndpoints", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Error("Expected the private endpoint to not be reached")
+		}))
+		defer server.Close()
+
+		attempt, next := webhooks.NewDeliverer().Deliver(webhooks.DeliveryRequest{
+			Id:       "delivery-4",
+			Endpoint: server.URL,
+			Secret:   secret,
+			Payload:  payload,
+		}, 0)
+		if !errors.Is(attempt.Error, webhooks.ErrPrivateEndpoint) || next != nil {
+			t.Fatalf("Expected the loopback endpoint to be refused, got %v", attempt.Error)
+		}
+		for endpoint, valid := range map[string]bool{
+			"https://hooks.example.com/recipes": true,
+			"https://93.184.216.34/hook":        true,
+			"http://hooks.example.com/recipes":  false,
+			"https://localhost:8443/hook":       false,
+			"https://127.0.0.1/hook":            false,
+			"https://169.254.169.254/latest":    false,
+			"https://10.0.0.5/hook":             false,
+			"https://[::1]/hook":                false,
+			"https://[fd00::1]/hook":            false,
+		} {
+			if err := webhooks.ValidateEndpoint(endpoint); (err == nil) != valid {
+				t.Fatalf("Expected %s to be valid %v, got %v", endpoint, valid, err)
+			}
+		}
+	})
+
+	t.Run("Verify", func(t *testing.T) {
+		signature := webhooks.Sign(secret, "1000", payload)
+		if webhooks.Verify(secret, "1001", payload, signature) {
+			t.Fatal("Expected a different timestamp to fail verification")
+		}
+		if webhooks.Verify("other", "1000", payload, signature) {
+			t.Fatal("Expected a different secret to fail verification")
+		}
+	})
+}